@@ -17,19 +17,25 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/internal/utils"
 )
 
-var debug = flag.Bool("debug", false, "Enable debug mode to log all commands")
+var (
+	debug          = flag.Bool("debug", false, "Enable debug mode to log all commands")
+	port           = flag.Int("port", 6379, "Port to listen on")
+	dir            = flag.String("dir", commands.ServerConfig["dir"], "Directory where the RDB file is stored")
+	dbfilename     = flag.String("dbfilename", commands.ServerConfig["dbfilename"], "Name of the RDB file")
+	appendonly     = flag.String("appendonly", commands.ServerConfig["appendonly"], "Log every write to the append only file (yes|no)")
+	appendfsync    = flag.String("appendfsync", commands.ServerConfig["appendfsync"], "How often the append only file is fsynced (always|everysec|no)")
+	appendfilename = flag.String("appendfilename", commands.ServerConfig["appendfilename"], "Name of the append only file")
+)
 
 func handleConn(c net.Conn) {
-	defer c.Close()
-
 	reader := bufio.NewReader(c)
-	writer := bufio.NewWriter(c)
-	defer writer.Flush()
 
-	Conn := pubsub.Connection{
-		W:        writer,
-		Channels: make(map[string]struct{}),
-	}
+	// The connection owns c from now on, it writes out the replies and messages queued for
+	// the client from its own goroutine and closes c
+	Conn := pubsub.NewConnection(c)
+	defer Conn.Close()
+	commands.OpenConnection(Conn)
+	defer commands.CloseConnection(Conn)
 
 	for {
 		msg, err := parser.Parse(reader)
@@ -37,19 +43,24 @@ func handleConn(c net.Conn) {
 			if err == io.EOF {
 				return
 			}
-			writer.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
-			writer.Flush()
+			Conn.W.Write([]byte(fmt.Sprintf("-ERR %s\r\n", err.Error())))
+			Conn.Flush()
 			return
 		}
 
-		commands.ExecuteCommands(msg, &Conn)
-		writer.Flush()
+		commands.ExecuteCommands(msg, Conn)
+		Conn.Flush()
 	}
 }
 
 func main() {
 	flag.Parse()
 	commands.DebugMode = *debug
+	commands.ServerConfig["dir"] = *dir
+	commands.ServerConfig["dbfilename"] = *dbfilename
+	commands.ServerConfig["appendonly"] = *appendonly
+	commands.ServerConfig["appendfsync"] = *appendfsync
+	commands.ServerConfig["appendfilename"] = *appendfilename
 
 	go func() {
 		log.Println("pprof listening on http://localhost:6060")
@@ -61,15 +72,20 @@ func main() {
 		log.Printf("DEBUG MODE ENABLED")
 	}
 
-	l, err := net.Listen("tcp", "0.0.0.0:6379")
+	l, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", *port))
 	if err != nil {
-		log.Println("Failed to bind to port 6379")
+		log.Printf("Failed to bind to port %d", *port)
 		os.Exit(1)
 	}
 	defer l.Close()
 
 	utils.GlobalInitFunction()
 
+	if err := commands.LoadPersistedData(); err != nil {
+		log.Printf("Failed to load persisted data: %v", err)
+		os.Exit(1)
+	}
+
 	for {
 		conn, err := l.Accept()
 		log.Printf("RECEIVED A CONNECTION")
@@ -0,0 +1,299 @@
+package aof
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// Fsync policies, same meaning as the appendfsync setting in Redis
+const (
+	FsyncAlways   = "always"
+	FsyncEverySec = "everysec"
+	FsyncNo       = "no"
+)
+
+// AOF is the append-only log of every write applied to the dataset
+type AOF struct {
+	mu    sync.Mutex
+	f     *os.File // nil while the AOF is disabled
+	fsync string
+	dirty bool // data was written since the last fsync
+
+	rewriting bool   // a BGREWRITEAOF is running
+	buffering bool   // commands fed during a rewrite are also kept in buf
+	buf       []byte // writes that happened after the rewrite snapshot was taken
+
+	inMulti  bool // an EXEC is running, its writes are wrapped in MULTI and EXEC
+	fedMulti bool // MULTI was logged for the running EXEC
+}
+
+var (
+	instance   = AOF{fsync: FsyncEverySec}
+	syncerOnce sync.Once
+)
+
+// ValidFsync reports whether policy is a known appendfsync value
+func ValidFsync(policy string) bool {
+	return policy == FsyncAlways || policy == FsyncEverySec || policy == FsyncNo
+}
+
+// Open starts appending to the file at path, creating it if needed
+func Open(path string, fsync string) error {
+	if !ValidFsync(fsync) {
+		return fmt.Errorf("invalid appendfsync policy %q", fsync)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if instance.f != nil {
+		instance.f.Close()
+	}
+	instance.f = f
+	instance.fsync = fsync
+
+	syncerOnce.Do(func() {
+		go backgroundFsync()
+	})
+	log.Printf("AOF: Appending to %s (appendfsync %s)", path, fsync)
+	return nil
+}
+
+// Close flushes and stops appending to the AOF
+func Close() {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if instance.f == nil {
+		return
+	}
+	instance.f.Sync()
+	instance.f.Close()
+	instance.f = nil
+}
+
+// Enabled reports whether writes are currently being logged
+func Enabled() bool {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	return instance.f != nil
+}
+
+// Rewriting reports whether a rewrite is in progress
+func Rewriting() bool {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	return instance.rewriting
+}
+
+// SetFsync changes the fsync policy of the running AOF
+func SetFsync(policy string) error {
+	if !ValidFsync(policy) {
+		return fmt.Errorf("invalid appendfsync policy %q", policy)
+	}
+	instance.mu.Lock()
+	instance.fsync = policy
+	instance.mu.Unlock()
+	return nil
+}
+
+// Feed appends a write command to the log. Callers feed from inside the critical section
+// that applied the write, so commands touching the same key are logged in the order they
+// were applied
+func Feed(argv ...[]byte) {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if instance.f == nil && !instance.buffering {
+		return
+	}
+
+	payload := encode(argv)
+	if instance.inMulti && !instance.fedMulti {
+		payload = append(encode([][]byte{[]byte("MULTI")}), payload...)
+		instance.fedMulti = true
+	}
+	instance.write(payload)
+}
+
+// BeginTransaction starts wrapping the fed commands in MULTI and EXEC, so that loading the
+// file applies the writes of a transaction all at once or not at all. MULTI is only logged
+// with the first write, a transaction that writes nothing leaves no trace
+func BeginTransaction() {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	instance.inMulti = true
+	instance.fedMulti = false
+}
+
+// EndTransaction closes the transaction opened by BeginTransaction
+func EndTransaction() {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if instance.fedMulti {
+		instance.write(encode([][]byte{[]byte("EXEC")}))
+	}
+	instance.inMulti = false
+	instance.fedMulti = false
+}
+
+// write appends an encoded command to the file and to the rewrite buffer, the caller holds mu
+func (a *AOF) write(payload []byte) {
+	if a.f == nil && !a.buffering {
+		return
+	}
+	if a.buffering {
+		a.buf = append(a.buf, payload...)
+	}
+	if a.f == nil {
+		return
+	}
+
+	if _, err := a.f.Write(payload); err != nil {
+		log.Printf("AOF: Error writing to the append only file: %v", err)
+		return
+	}
+	if a.fsync == FsyncAlways {
+		a.f.Sync()
+		return
+	}
+	a.dirty = true
+}
+
+// backgroundFsync flushes the file to disk once per second under the everysec policy
+func backgroundFsync() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		instance.mu.Lock()
+		if instance.f != nil && instance.dirty && instance.fsync == FsyncEverySec {
+			instance.f.Sync()
+			instance.dirty = false
+		}
+		instance.mu.Unlock()
+	}
+}
+
+func encode(argv [][]byte) []byte {
+	arr := resp.Array{Val: make([]resp.Message, len(argv))}
+	for i, arg := range argv {
+		arr.Val[i] = &resp.BulkString{Str: arg, Size: len(arg)}
+	}
+	return arr.ToBytes()
+}
+
+// BeginRewrite marks a rewrite as running, it returns false if one already is
+func BeginRewrite() bool {
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+	if instance.rewriting {
+		return false
+	}
+	instance.rewriting = true
+	return true
+}
+
+// StartRewriteBuffer starts keeping a copy of every fed command. It must be called while
+// writers are stopped for the rewrite snapshot, so that each write ends up either in the
+// snapshot or in the buffer but never in both
+func StartRewriteBuffer() {
+	instance.mu.Lock()
+	instance.buffering = true
+	instance.buf = nil
+	instance.mu.Unlock()
+}
+
+// CancelRewrite clears the state set up by BeginRewrite and StartRewriteBuffer
+func CancelRewrite() {
+	instance.mu.Lock()
+	instance.rewriting = false
+	instance.buffering = false
+	instance.buf = nil
+	instance.mu.Unlock()
+}
+
+// FinishRewrite writes a compacted log to path: the commands produced by write followed by
+// the writes buffered while it ran. The new file replaces the old one atomically and, when
+// the AOF is enabled, becomes the file that is appended to
+func FinishRewrite(path string, write func(emit func(argv ...[]byte))) error {
+	defer CancelRewrite()
+
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-rewriteaof-%d.aof", os.Getpid()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := &rewriteWriter{f: f}
+	write(w.emit)
+	if w.err == nil {
+		w.flush()
+	}
+	if w.err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return w.err
+	}
+
+	// Writers block on the lock while the buffered tail is appended and the files are swapped
+	instance.mu.Lock()
+	defer instance.mu.Unlock()
+
+	if _, err := f.Write(instance.buf); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if instance.f != nil {
+		instance.f.Close()
+		instance.f = f
+		instance.dirty = false
+	} else {
+		f.Close()
+	}
+	log.Printf("AOF: Rewrite of %s terminated with success", path)
+	return nil
+}
+
+// rewriteWriter batches rewritten commands before writing them to the temporary file
+type rewriteWriter struct {
+	f   *os.File
+	buf []byte
+	err error
+}
+
+func (w *rewriteWriter) emit(argv ...[]byte) {
+	w.buf = append(w.buf, encode(argv)...)
+	if len(w.buf) >= 64*1024 {
+		w.flush()
+	}
+}
+
+func (w *rewriteWriter) flush() {
+	if w.err != nil {
+		w.buf = w.buf[:0]
+		return
+	}
+	_, w.err = w.f.Write(w.buf)
+	w.buf = w.buf[:0]
+}
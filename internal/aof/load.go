@@ -0,0 +1,93 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// countingReader keeps track of how many bytes were read from the file
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Load replays every command stored in the AOF at path through exec. If the file ends in
+// the middle of a command (the server crashed during a write) and truncated is true, the
+// partial command is cut off and loading succeeds with everything before it. A transaction
+// missing its EXEC is cut off from its MULTI the same way, none of its writes are applied
+func Load(path string, truncated bool, exec func(*resp.Array)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	counter := &countingReader{r: f}
+	reader := bufio.NewReader(counter)
+	loaded := 0
+	valid := int64(0)  // offset just past the last complete command
+	multi := int64(-1) // offset of the MULTI of an unfinished transaction
+
+	for {
+		msg, err := parser.Parse(reader)
+		if err != nil {
+			if err == io.EOF && valid == info.Size() && multi < 0 {
+				break
+			}
+			if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return fmt.Errorf("bad file format reading the append only file at offset %d: %w", valid, err)
+			}
+			if multi >= 0 {
+				valid = multi
+			}
+			if !truncated {
+				return fmt.Errorf("unexpected end of file at offset %d, set aof-load-truncated to yes to load anyway", valid)
+			}
+			log.Printf("AOF: %s is truncated, dropping the last %d bytes", path, info.Size()-valid)
+			if err := os.Truncate(path, valid); err != nil {
+				return err
+			}
+			break
+		}
+
+		cmd, ok := msg.(*resp.Array)
+		if !ok || len(cmd.Val) == 0 {
+			return fmt.Errorf("bad file format reading the append only file at offset %d", valid)
+		}
+		exec(cmd)
+		loaded++
+
+		start := valid
+		valid = counter.n - int64(reader.Buffered())
+		if name, ok := cmd.Val[0].(*resp.BulkString); ok {
+			switch strings.ToLower(string(name.Str)) {
+			case "multi":
+				multi = start
+			case "exec":
+				multi = -1
+			}
+		}
+	}
+
+	log.Printf("AOF: Replayed %d commands from %s", loaded, path)
+	return nil
+}
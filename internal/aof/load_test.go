@@ -0,0 +1,61 @@
+package aof
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func TestLoad(t *testing.T) {
+	complete := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n*2\r\n$3\r\nDEL\r\n$1\r\nb\r\n"
+	multi := "*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nc\r\n$1\r\n3\r\n"
+	exec := "*1\r\n$4\r\nEXEC\r\n"
+
+	tests := []struct {
+		name      string
+		content   string
+		truncated bool
+		wantCmds  int
+		wantSize  int
+		wantErr   bool
+	}{
+		{"complete file", complete, true, 2, len(complete), false},
+		{"empty file", "", true, 0, 0, false},
+		{"truncated inside bulk string", complete + "*2\r\n$3\r\nDEL\r\n$1\r\n", true, 2, len(complete), false},
+		{"truncated inside length", complete + "*2\r", true, 2, len(complete), false},
+		{"truncated not allowed", complete + "*2\r\n$3\r\nDE", false, 2, len(complete) + 10, true},
+		{"transaction", complete + multi + exec, true, 5, len(complete + multi + exec), false},
+		{"unfinished transaction", complete + multi, true, 4, len(complete), false},
+		{"truncated inside transaction", complete + multi + "*1\r\n$4\r\nEX", true, 4, len(complete), false},
+		{"unfinished transaction not allowed", complete + multi, false, 4, len(complete + multi), true},
+		{"not a command", complete + "+OK\r\n", true, 2, len(complete) + 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.aof")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cmds := 0
+			err := Load(path, tt.truncated, func(*resp.Array) { cmds++ })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if cmds != tt.wantCmds {
+				t.Errorf("Load() replayed %d commands, want %d", cmds, tt.wantCmds)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != int64(tt.wantSize) {
+				t.Errorf("file size = %d, want %d", info.Size(), tt.wantSize)
+			}
+		})
+	}
+}
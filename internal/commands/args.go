@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// stringArgs returns every argument of a command, including its name, as strings. ok is
+// false if one of them isn't a bulk string
+func stringArgs(args *resp.Array) ([]string, bool) {
+	argv := make([]string, len(args.Val))
+	for i, arg := range args.Val {
+		bs, ok := arg.(*resp.BulkString)
+		if !ok {
+			return nil, false
+		}
+		argv[i] = string(bs.Str)
+	}
+	return argv, true
+}
+
+// bulkString wraps s in a bulk string reply
+func bulkString(s string) *resp.BulkString {
+	return &resp.BulkString{Str: []byte(s), Size: len(s)}
+}
+
+// nullReply is the reply for a missing value: a null bulk string in RESP2, the null type in
+// RESP3
+func nullReply(conn *pubsub.Connection) resp.Message {
+	if conn.RESP3() {
+		return &resp.Null{}
+	}
+	return &resp.BulkString{Size: -1}
+}
+
+// nullArray is the encoded reply for a missing array, like a blocking command that timed out
+func nullArray(conn *pubsub.Connection) []byte {
+	if conn.RESP3() {
+		return []byte("_\r\n")
+	}
+	return []byte("*-1\r\n")
+}
+
+// encodedReply is a reply that is already encoded
+type encodedReply []byte
+
+func (r encodedReply) ToBytes() []byte {
+	return r
+}
+
+// nullArrayReply is nullArray for a missing array nested in a reply
+func nullArrayReply(conn *pubsub.Connection) resp.Message {
+	return encodedReply(nullArray(conn))
+}
+
+// writeShardReply writes a reply encoded by a shard, which only knows RESP2
+func writeShardReply(conn *pubsub.Connection, reply []byte) {
+	if string(reply) == resp.NULLBULKSTRING {
+		conn.W.Write(nullReply(conn).ToBytes())
+		return
+	}
+	conn.W.Write(reply)
+}
+
+// mapReply returns entries as a map to RESP3 clients and as a flat array of keys and values
+// to RESP2 ones
+func mapReply(conn *pubsub.Connection, entries []resp.MapEntry) resp.Message {
+	if conn.RESP3() {
+		return &resp.Map{Val: entries}
+	}
+	arr := &resp.Array{Val: make([]resp.Message, 0, 2*len(entries))}
+	for _, e := range entries {
+		arr.Val = append(arr.Val, e.Key, e.Val)
+	}
+	return arr
+}
+
+// setReply returns members as a set to RESP3 clients and as an array to RESP2 ones
+func setReply(conn *pubsub.Connection, members []resp.Message) resp.Message {
+	if conn.RESP3() {
+		return &resp.Set{Val: members}
+	}
+	return &resp.Array{Val: members}
+}
+
+// doubleReply returns f as a double to RESP3 clients and as a bulk string to RESP2 ones
+func doubleReply(conn *pubsub.Connection, f float64) resp.Message {
+	if conn.RESP3() {
+		return &resp.Double{Val: f}
+	}
+	return bulkString(resp.FormatDouble(f))
+}
+
+// pushReply returns an out of band message, like a pub/sub confirmation, as a push to
+// RESP3 clients and as an array to RESP2 ones
+func pushReply(conn *pubsub.Connection, elements []resp.Message) resp.Message {
+	if conn.RESP3() {
+		return &resp.Push{Val: elements}
+	}
+	return &resp.Array{Val: elements}
+}
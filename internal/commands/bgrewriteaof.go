@@ -0,0 +1,240 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/rdb"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// Same as AOF_REWRITE_ITEMS_PER_CMD in Redis, big lists are rewritten as several RPUSHes
+const aofRewriteItemsPerCmd = 64
+
+var errRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+
+// AOFPath returns the location of the append only file based on the dir and appendfilename config
+func AOFPath() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return aofPath()
+}
+
+// aofPath is AOFPath for callers already holding configMu
+func aofPath() string {
+	return filepath.Join(ServerConfig["dir"], ServerConfig["appendfilename"])
+}
+
+// LoadPersistedData restores the dataset on startup. With appendonly enabled the AOF is the
+// source of truth, if it doesn't exist yet the RDB file is loaded and written out as the
+// starting point of a new AOF
+func LoadPersistedData() error {
+	if configValue("appendonly") != "yes" {
+		return rdb.Load(RDBPath())
+	}
+
+	path := AOFPath()
+	_, err := os.Stat(path)
+	if err == nil {
+		// Replayed commands run like any client's, their replies are thrown away
+		loader := &pubsub.Connection{W: bufio.NewWriter(io.Discard), Channels: make(map[string]struct{}), Patterns: make(map[string]struct{}), ShardChannels: make(map[string]struct{})}
+		loader.Proto.Store(2)
+		err := aof.Load(path, configValue("aof-load-truncated") == "yes", func(cmd *resp.Array) {
+			ExecuteCommands(cmd, loader)
+		})
+		if err != nil {
+			return err
+		}
+		return aof.Open(path, configValue("appendfsync"))
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := rdb.Load(RDBPath()); err != nil {
+		return err
+	}
+	return startAOF(path, configValue("appendfsync"))
+}
+
+// startAOF turns on logging to path and synchronously rewrites the file from the current
+// dataset, so the AOF never starts out missing keys that were written before it was enabled
+func startAOF(path, fsync string) error {
+	if !aof.BeginRewrite() {
+		return errRewriteInProgress
+	}
+
+	if err := aof.Open(path, fsync); err != nil {
+		aof.CancelRewrite()
+		return err
+	}
+
+	snap := db.TakeSnapshot(aof.StartRewriteBuffer)
+	err := aof.FinishRewrite(path, func(emit func(...[]byte)) {
+		rewriteSnapshot(snap, emit)
+	})
+	if err != nil {
+		// Logging stays off like appendonly says, rather than appending to a file that is
+		// missing the dataset
+		aof.Close()
+	}
+	return err
+}
+
+func bgrewriteaof(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'bgrewriteaof' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	if !aof.BeginRewrite() {
+		msg := resp.SimpleError{Val: []byte(errRewriteInProgress.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// Buffering starts while writers are stopped, every write lands either in the snapshot
+	// or in the buffer that is appended once the rewritten file is complete
+	snap := db.TakeSnapshot(aof.StartRewriteBuffer)
+	path := AOFPath()
+
+	go func() {
+		err := aof.FinishRewrite(path, func(emit func(...[]byte)) {
+			rewriteSnapshot(snap, emit)
+		})
+		if err != nil {
+			log.Printf("AOF: Background rewrite failed: %v", err)
+		}
+	}()
+
+	msg := resp.SimpleString{Val: []byte("Background append only file rewriting started")}
+	conn.W.Write(msg.ToBytes())
+}
+
+// rewriteSnapshot emits the shortest list of commands that rebuilds the snapshot
+func rewriteSnapshot(snap *db.Snapshot, emit func(...[]byte)) {
+	for key, entry := range snap.Keys {
+		switch entry.Type {
+		case db.TypeString:
+			if entry.ExpiresAt.IsZero() {
+				emit([]byte("SET"), []byte(key), entry.Value)
+				continue
+			}
+			emit([]byte("SET"), []byte(key), entry.Value, []byte("PXAT"), []byte(strconv.FormatInt(entry.ExpiresAt.UnixMilli(), 10)))
+		case db.TypeList:
+			for start := 0; start < len(entry.List); start += aofRewriteItemsPerCmd {
+				end := min(start+aofRewriteItemsPerCmd, len(entry.List))
+				argv := [][]byte{[]byte("RPUSH"), []byte(key)}
+				for _, item := range entry.List[start:end] {
+					argv = append(argv, []byte(item))
+				}
+				emit(argv...)
+			}
+		case db.TypeSet:
+			argv := [][]byte{[]byte("SADD"), []byte(key)}
+			for member := range entry.Set.Members() {
+				argv = append(argv, []byte(member))
+				if len(argv) == 2+aofRewriteItemsPerCmd {
+					emit(argv...)
+					argv = argv[:2]
+				}
+			}
+			if len(argv) > 2 {
+				emit(argv...)
+			}
+		case db.TypeHash:
+			argv := [][]byte{[]byte("HSET"), []byte(key)}
+			for field, value := range entry.Hash {
+				argv = append(argv, []byte(field), []byte(value))
+				if len(argv) == 2+2*aofRewriteItemsPerCmd {
+					emit(argv...)
+					argv = argv[:2]
+				}
+			}
+			if len(argv) > 2 {
+				emit(argv...)
+			}
+		case db.TypeZSet:
+			argv := [][]byte{[]byte("ZADD"), []byte(key)}
+			for _, e := range entry.ZSet {
+				argv = append(argv, []byte(formatScore(e.Score)), []byte(e.Member))
+				if len(argv) == 2+2*aofRewriteItemsPerCmd {
+					emit(argv...)
+					argv = argv[:2]
+				}
+			}
+			if len(argv) > 2 {
+				emit(argv...)
+			}
+		case db.TypeStream:
+			stream := entry.Stream
+			for _, se := range stream.Entries {
+				argv := [][]byte{[]byte("XADD"), []byte(key), []byte(se.ID.String())}
+				for _, s := range se.Fields {
+					argv = append(argv, []byte(s))
+				}
+				emit(argv...)
+			}
+			// An empty stream is created by adding an entry trimmed right away, XSETID then
+			// restores the IDs and counters the entries alone don't carry
+			if len(stream.Entries) == 0 {
+				id := stream.LastID
+				if id.IsZero() {
+					id.Seq = 1
+				}
+				emit([]byte("XADD"), []byte(key), []byte("MAXLEN"), []byte("0"), []byte(id.String()), []byte("x"), []byte("y"))
+			}
+			emit([]byte("XSETID"), []byte(key), []byte(stream.LastID.String()),
+				[]byte("ENTRIESADDED"), []byte(strconv.FormatUint(stream.EntriesAdded, 10)),
+				[]byte("MAXDELETEDID"), []byte(stream.MaxDeletedID.String()))
+			for _, g := range entry.Stream.Groups {
+				emitGroup(emit, key, g)
+			}
+		}
+
+		// Strings carry their deadline in the SET, other types get it once they exist
+		if entry.Type != db.TypeString && !entry.ExpiresAt.IsZero() {
+			emit([]byte("PEXPIREAT"), []byte(key), []byte(strconv.FormatInt(entry.ExpiresAt.UnixMilli(), 10)))
+		}
+	}
+}
+
+// emitGroup recreates a consumer group with its consumers and pending entries
+func emitGroup(emit func(...[]byte), key string, g *streams.ConsumerGroup) {
+	emit([]byte("XGROUP"), []byte("CREATE"), []byte(key), []byte(g.Name), []byte(g.LastID.String()), []byte("MKSTREAM"),
+		[]byte("ENTRIESREAD"), []byte(strconv.FormatInt(g.EntriesRead, 10)))
+	names := make([]string, 0, len(g.Consumers))
+	for name := range g.Consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		emit([]byte("XGROUP"), []byte("CREATECONSUMER"), []byte(key), []byte(g.Name), []byte(name))
+	}
+	for _, pe := range g.PendingFrom(streams.StreamID{}) {
+		emit(claimArgs(key, g.Name, pe)...)
+	}
+}
+
+// propagate feeds a command to the AOF exactly as the client sent it
+func propagate(args *resp.Array) {
+	argv := make([][]byte, 0, len(args.Val))
+	for _, arg := range args.Val {
+		if bs, ok := arg.(*resp.BulkString); ok {
+			argv = append(argv, bs.Str)
+		}
+	}
+	aof.Feed(argv...)
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// parseTimeout parses the timeout of a blocking command, in seconds with a fractional part
+func parseTimeout(s string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, errors.New("ERR timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, errors.New("ERR timeout is negative")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// errUnblocked is the reply of a blocking command interrupted by CLIENT UNBLOCK ERROR
+var errUnblocked = errors.New("UNBLOCKED client unblocked via CLIENT UNBLOCK")
+
+// blockOn implements the waiting part of every blocking command. attempt is run with the
+// keys held and returns the reply once the command can be served, or nil if it has to
+// wait. Checking and registering happen inside the same db.Do, so a write landing between
+// the two can't be missed. A timeout of zero waits forever, blockOn returns nil when the
+// timeout expires. Inside a transaction there is no waiting, the command behaves as if the
+// timeout expired right away.
+//
+// The client stays registered on its keys until it is served, so clients blocked on a key
+// are served in the order they blocked (see db.Keyspace.Wake). CLIENT UNBLOCK releases the
+// client as if the timeout expired, or with an error
+func blockOn(conn *pubsub.Connection, keys []string, timeout time.Duration, attempt func(ks *db.Keyspace) resp.Message) resp.Message {
+	return blockOnHolding(conn, keys, keys, timeout, attempt)
+}
+
+// blockOnHolding is blockOn for commands that write to keys they don't wait on, like the
+// destination of BLMOVE. attempt runs with every key in held, which must include keys, so
+// the pop and the push happen atomically
+func blockOnHolding(conn *pubsub.Connection, keys, held []string, timeout time.Duration, attempt func(ks *db.Keyspace) resp.Message) resp.Message {
+	if conn.Held != nil {
+		return attempt(conn.Held)
+	}
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+
+	var chs []chan struct{}
+	var unblocked <-chan bool
+	for {
+		var reply resp.Message
+		result := waitWoken
+		if chs != nil {
+			result = waitForAny(chs, timer, unblocked)
+		}
+
+		do(conn, held, func(ks *db.Keyspace) {
+			if chs == nil {
+				reply = attempt(ks)
+				if reply == nil {
+					chs = make([]chan struct{}, len(keys))
+					for i, key := range keys {
+						chs[i] = ks.Block(key)
+					}
+				}
+				return
+			}
+
+			// A wakeup racing with the timeout is still used, CLIENT UNBLOCK always wins
+			if result == waitWoken || (result == waitTimedOut && anyWoken(ks, keys, chs)) {
+				reply = attempt(ks)
+			}
+			if reply != nil || result != waitWoken {
+				unregister(ks, keys, chs)
+				return
+			}
+			for i, key := range keys {
+				ks.Rearm(key, chs[i])
+			}
+		})
+
+		switch {
+		case reply != nil:
+			return reply
+		case result == waitUnblockedError:
+			return &resp.SimpleError{Val: []byte(errUnblocked.Error())}
+		case result != waitWoken:
+			return nil
+		}
+
+		if unblocked == nil {
+			unblocked = conn.BeginBlocking()
+			defer conn.EndBlocking()
+		}
+	}
+}
+
+// anyWoken reports whether one of the registrations has been signaled
+func anyWoken(ks *db.Keyspace, keys []string, chs []chan struct{}) bool {
+	for i, ch := range chs {
+		if ks.Woken(keys[i], ch) {
+			return true
+		}
+	}
+	return false
+}
+
+// unregister drops the registrations of a client that is done waiting. A wakeup it received
+// may not be the one that served it, so it is passed on to the next client blocked on the
+// same key rather than lost
+func unregister(ks *db.Keyspace, keys []string, chs []chan struct{}) {
+	for i, ch := range chs {
+		if ks.Unblock(keys[i], ch) {
+			ks.Wake(keys[i])
+		}
+	}
+}
+
+type waitResult int
+
+const (
+	waitWoken waitResult = iota
+	waitTimedOut
+	waitUnblocked
+	waitUnblockedError
+)
+
+// waitForAny blocks until one of chs fires, the timer expires or CLIENT UNBLOCK releases
+// the client through unblocked
+func waitForAny(chs []chan struct{}, timer <-chan time.Time, unblocked <-chan bool) waitResult {
+	// Use reflect.Select for dynamic number of channels
+	cases := make([]reflect.SelectCase, len(chs)+2)
+	for i, ch := range chs {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+	}
+	cases[len(chs)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer)}
+	cases[len(chs)+1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(unblocked)}
+
+	chosen, value, _ := reflect.Select(cases)
+	switch chosen {
+	case len(chs):
+		return waitTimedOut
+	case len(chs) + 1:
+		if value.Bool() {
+			return waitUnblockedError
+		}
+		return waitUnblocked
+	}
+	return waitWoken
+}
@@ -1,12 +1,38 @@
 package commands
 
 import (
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
+// clients maps the id of every connected client to its connection, for CLIENT UNBLOCK
+var clients = struct {
+	sync.Mutex
+	byID   map[int64]*pubsub.Connection
+	nextID int64
+}{byID: make(map[int64]*pubsub.Connection)}
+
+// OpenConnection assigns a new connection its client id and registers it, CloseConnection
+// must be called once it goes away
+func OpenConnection(conn *pubsub.Connection) {
+	clients.Lock()
+	defer clients.Unlock()
+	clients.nextID++
+	conn.ID = clients.nextID
+	clients.byID[conn.ID] = conn
+}
+
+// lookupClient returns the connected client with the given id, or nil
+func lookupClient(id int64) *pubsub.Connection {
+	clients.Lock()
+	defer clients.Unlock()
+	return clients.byID[id]
+}
+
 // client handles the CLIENT command and its subcommands
 func client(args *resp.Array, conn *pubsub.Connection) {
 	if len(args.Val) < 2 {
@@ -43,13 +69,69 @@ func client(args *resp.Array, conn *pubsub.Connection) {
 	case "getname":
 		// CLIENT GETNAME returns the connection name
 		if conn.Name == "" {
-			conn.W.Write([]byte("$-1\r\n"))
+			conn.W.Write(nullReply(conn).ToBytes())
 		} else {
 			res := resp.BulkString{Str: []byte(conn.Name), Size: len(conn.Name)}
 			conn.W.Write(res.ToBytes())
 		}
+	case "id":
+		msg := resp.Integer{Val: conn.ID}
+		conn.W.Write(msg.ToBytes())
+	case "unblock":
+		clientUnblock(args, conn)
+	case "tracking":
+		clientTracking(args, conn)
+	case "caching":
+		clientCaching(args, conn)
+	case "getredir":
+		clientGetRedir(conn)
 	default:
 		msg := resp.SimpleString{Val: []byte("OK")}
 		conn.W.Write(msg.ToBytes())
 	}
 }
+
+// clientUnblock handles CLIENT UNBLOCK id [TIMEOUT|ERROR]. The client waiting in a blocking
+// command is released as if its timeout expired, or with an UNBLOCKED error. The reply is 1
+// if the client was blocked and 0 otherwise
+func clientUnblock(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 && len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'client|unblock' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'client' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	id, err := strconv.ParseInt(argv[2], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	withError := false
+	if len(argv) == 4 {
+		switch strings.ToLower(argv[3]) {
+		case "timeout":
+		case "error":
+			withError = true
+		default:
+			msg := resp.SimpleError{Val: []byte("ERR CLIENT UNBLOCK reason should be TIMEOUT or ERROR")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	unblocked := int64(0)
+	if target := lookupClient(id); target != nil && target.Unblock(withError) {
+		unblocked = 1
+	}
+	msg := resp.Integer{Val: unblocked}
+	conn.W.Write(msg.ToBytes())
+}
@@ -2,16 +2,45 @@ package commands
 
 import (
 	"bytes"
+	"fmt"
+	"maps"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
-// ServerConfig holds the configuration parameters for the Redis server
+// ServerConfig holds the configuration parameters for the Redis server. Once clients are
+// served it is only accessed with configMu held
 var ServerConfig = map[string]string{
-	"dir":        "/tmp",
-	"dbfilename": "dump.rdb",
+	"dir":                "/tmp",
+	"dbfilename":         "dump.rdb",
+	"appendonly":         "no",
+	"appendfsync":        "everysec",
+	"appendfilename":     "appendonly.aof",
+	"aof-load-truncated": "yes",
+	"hz":                 "10",
+
+	// Classes of keyspace events published over pub/sub, none by default
+	"notify-keyspace-events": "",
+
+	// Only the pubsub class is enforced, the others are kept for CONFIG GET
+	"client-output-buffer-limit": "normal 0 0 0 slave 268435456 67108864 60 pubsub 33554432 8388608 60",
+}
+
+// configMu guards ServerConfig, CONFIG SET holds it for writing while the new value is applied
+var configMu sync.RWMutex
+
+// configValue returns the current value of a configuration parameter
+func configValue(param string) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return ServerConfig[param]
 }
 
 func config(args *resp.Array, conn *pubsub.Connection) {
@@ -54,9 +83,14 @@ func configGet(args *resp.Array, conn *pubsub.Connection) {
 	}
 
 	patternStr := string(pattern.Str)
-	result := &resp.Array{Val: []resp.Message{}}
+	var entries []resp.MapEntry
 
-	for key, value := range ServerConfig {
+	// The reply is built from a copy so CONFIG SET isn't held up by a slow client
+	configMu.RLock()
+	params := maps.Clone(ServerConfig)
+	configMu.RUnlock()
+
+	for key, value := range params {
 		matched, err := filepath.Match(patternStr, key)
 		if err != nil {
 			continue
@@ -64,11 +98,11 @@ func configGet(args *resp.Array, conn *pubsub.Connection) {
 		if matched {
 			keyBulk := &resp.BulkString{Str: []byte(key), Size: len(key)}
 			valueBulk := &resp.BulkString{Str: []byte(value), Size: len(value)}
-			result.Val = append(result.Val, keyBulk, valueBulk)
+			entries = append(entries, resp.MapEntry{Key: keyBulk, Val: valueBulk})
 		}
 	}
 
-	conn.W.Write(result.ToBytes())
+	conn.W.Write(mapReply(conn, entries).ToBytes())
 }
 
 func configSet(args *resp.Array, conn *pubsub.Connection) {
@@ -95,6 +129,9 @@ func configSet(args *resp.Array, conn *pubsub.Connection) {
 	paramStr := string(param.Str)
 	valueStr := string(value.Str)
 
+	configMu.Lock()
+	defer configMu.Unlock()
+
 	// Only allow setting known config parameters
 	if _, exists := ServerConfig[paramStr]; !exists {
 		msg := resp.SimpleError{Val: []byte("ERR unknown configuration parameter '" + paramStr + "'")}
@@ -102,6 +139,132 @@ func configSet(args *resp.Array, conn *pubsub.Connection) {
 		return
 	}
 
+	// The value is stored only once it was validated and applied, a rejected value is never
+	// visible to CONFIG GET or to the code reading the configuration
+	valueStr, err := applyConfig(paramStr, valueStr)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte("ERR CONFIG SET failed (possibly related to argument '" + paramStr + "') - " + err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
 	ServerConfig[paramStr] = valueStr
 	conn.W.Write([]byte("+OK\r\n"))
 }
+
+// applyConfig validates a new value and performs the side effects of changing it at runtime,
+// it returns the value to store. configMu must be held for writing
+func applyConfig(param, value string) (string, error) {
+	switch param {
+	case "appendonly", "aof-load-truncated":
+		if value != "yes" && value != "no" {
+			return "", fmt.Errorf("argument must be 'yes' or 'no'")
+		}
+		if param != "appendonly" || value == ServerConfig["appendonly"] {
+			return value, nil
+		}
+		if value == "no" {
+			aof.Close()
+			return value, nil
+		}
+		return value, startAOF(aofPath(), ServerConfig["appendfsync"])
+	case "appendfsync":
+		if !aof.ValidFsync(value) {
+			return "", fmt.Errorf("argument(s) must be one of the following: always, everysec, no")
+		}
+		return value, aof.SetFsync(value)
+	case "hz":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("argument couldn't be parsed into an integer")
+		}
+		// Out of range values are clamped like Redis does
+		n = min(max(n, db.MinHz), db.MaxHz)
+		db.SetHz(n)
+		return strconv.FormatInt(n, 10), nil
+	case "client-output-buffer-limit":
+		return applyOutputBufferLimit(value)
+	case "notify-keyspace-events":
+		flags, err := db.ParseNotifyFlags(value)
+		if err != nil {
+			return "", err
+		}
+		db.SetNotifyFlags(flags)
+		return db.NotifyFlagsString(flags), nil
+	}
+	return value, nil
+}
+
+// outputBufferClasses lists the client classes of client-output-buffer-limit in the order
+// CONFIG GET prints them
+var outputBufferClasses = []string{"normal", "slave", "pubsub"}
+
+// applyOutputBufferLimit sets the limits of the classes given as "class hard soft seconds"
+// groups, the other classes keep theirs. It returns the limits of every class with the
+// sizes in bytes
+func applyOutputBufferLimit(value string) (string, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields)%4 != 0 {
+		return "", fmt.Errorf("Wrong number of arguments in buffer limit configuration.")
+	}
+
+	limits := make(map[string][]string)
+	current := strings.Fields(ServerConfig["client-output-buffer-limit"])
+	for i := 0; i+3 < len(current); i += 4 {
+		limits[current[i]] = current[i+1 : i+4]
+	}
+	for i := 0; i < len(fields); i += 4 {
+		class := strings.ToLower(fields[i])
+		if class == "replica" {
+			class = "slave"
+		}
+		if _, ok := limits[class]; !ok {
+			return "", fmt.Errorf("Invalid client class specified in buffer limit configuration.")
+		}
+		hard, errHard := parseMemory(fields[i+1])
+		soft, errSoft := parseMemory(fields[i+2])
+		seconds, errSeconds := strconv.ParseInt(fields[i+3], 10, 64)
+		if errHard != nil || errSoft != nil || errSeconds != nil || seconds < 0 {
+			return "", fmt.Errorf("Error in hard, soft or soft_seconds setting in buffer limit configuration.")
+		}
+		limits[class] = []string{strconv.FormatInt(hard, 10), strconv.FormatInt(soft, 10), strconv.FormatInt(seconds, 10)}
+	}
+
+	pubsubLimit := limits["pubsub"]
+	hard, _ := strconv.ParseInt(pubsubLimit[0], 10, 64)
+	soft, _ := strconv.ParseInt(pubsubLimit[1], 10, 64)
+	seconds, _ := strconv.ParseInt(pubsubLimit[2], 10, 64)
+	pubsub.SetPubSubLimit(pubsub.OutputBufferLimit{Hard: hard, Soft: soft, SoftSeconds: seconds})
+
+	var parts []string
+	for _, class := range outputBufferClasses {
+		parts = append(parts, class)
+		parts = append(parts, limits[class]...)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// parseMemory parses a size the way Redis configuration does: a number of bytes, optionally
+// followed by k, m or g for powers of 1000 or kb, mb or gb for powers of 1024
+func parseMemory(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mul    int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000}, {"b", 1},
+	}
+	lower := strings.ToLower(s)
+	mul := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(lower, u.suffix) {
+			lower, mul = strings.TrimSuffix(lower, u.suffix), u.mul
+			break
+		}
+	}
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory size %q", s)
+	}
+	return n * mul, nil
+}
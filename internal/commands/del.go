@@ -32,9 +32,7 @@ func del(args *resp.Array, conn *pubsub.Connection) {
 		channel := make(chan []byte, 1)
 		cmd := db.NewCommand(keyStr, nil, 0, channel, db.DEL)
 
-		// Route to the appropriate shard based on key
-		shardCh := db.GetShardChannel(keyStr)
-		shardCh <- cmd
+		dispatch(conn, cmd)
 
 		value, ok := <-channel
 		if ok {
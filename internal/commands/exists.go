@@ -30,18 +30,11 @@ func exists(args *resp.Array, conn *pubsub.Connection) {
 
 		keyStr := string(key.Str)
 
-		// Check if key exists as a list first
-		if db.GetList(keyStr) != nil {
-			existsCount++
-			continue
-		}
-
 		// Check in KV store
 		channel := make(chan []byte, 1)
 		cmd := db.NewCommand(keyStr, nil, 0, channel, db.EXISTS)
 
-		shardCh := db.GetShardChannel(keyStr)
-		shardCh <- cmd
+		dispatch(conn, cmd)
 
 		value, ok := <-channel
 		if ok {
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func expire(args *resp.Array, conn *pubsub.Connection) {
+	expireGeneric(args, conn, "expire", 1000, false)
+}
+
+func pexpire(args *resp.Array, conn *pubsub.Connection) {
+	expireGeneric(args, conn, "pexpire", 1, false)
+}
+
+func expireat(args *resp.Array, conn *pubsub.Connection) {
+	expireGeneric(args, conn, "expireat", 1000, true)
+}
+
+func pexpireat(args *resp.Array, conn *pubsub.Connection) {
+	expireGeneric(args, conn, "pexpireat", 1, true)
+}
+
+// expireGeneric implements the EXPIRE family. unit is the number of milliseconds in one unit
+// of the amount argument, absolute tells whether the amount is a unix time or a ttl
+func expireGeneric(args *resp.Array, conn *pubsub.Connection, name string, unit int64, absolute bool) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	amountArg, ok := args.Val[2].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 3rd argument of '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	amount, err := strconv.ParseInt(string(amountArg.Str), 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// Parse optional arguments: NX, XX, GT, LT
+	var nx, xx, gt, lt bool
+	for i := 3; i < len(args.Val); i++ {
+		opt, ok := args.Val[i].(*resp.BulkString)
+		if !ok {
+			msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		switch strings.ToLower(string(opt.Str)) {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "gt":
+			gt = true
+		case "lt":
+			lt = true
+		default:
+			msg := resp.SimpleError{Val: []byte("ERR Unsupported option " + string(opt.Str))}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	if nx && (xx || gt || lt) {
+		msg := resp.SimpleError{Val: []byte("ERR NX and XX, GT or LT options at the same time are not compatible")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if gt && lt {
+		msg := resp.SimpleError{Val: []byte("ERR GT and LT options at the same time are not compatible")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	invalid := amount > math.MaxInt64/unit || amount < math.MinInt64/unit
+	deadline := amount * unit
+	if !absolute && !invalid {
+		invalid = deadline > math.MaxInt64-now
+		deadline += now
+	}
+	if invalid {
+		msg := resp.SimpleError{Val: []byte("ERR invalid expire time in '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	keyStr := string(key.Str)
+	var updated bool
+	do(conn, []string{keyStr}, func(ks *db.Keyspace) {
+		e := ks.Lookup(keyStr)
+		if e == nil {
+			return
+		}
+
+		// A key without a ttl counts as never expiring when comparing with GT and LT
+		persistent := e.ExpiresAt.IsZero()
+		current := e.ExpiresAt.UnixMilli()
+		switch {
+		case nx && !persistent, xx && persistent:
+			return
+		case gt && (persistent || deadline <= current):
+			return
+		case lt && !persistent && deadline >= current:
+			return
+		}
+
+		updated = true
+		if deadline <= now {
+			ks.Delete(keyStr)
+			ks.Touch(keyStr)
+			aof.Feed([]byte("DEL"), key.Str)
+			db.Notify(db.NotifyGeneric, "del", keyStr)
+			return
+		}
+		ks.SetExpiry(keyStr, time.UnixMilli(deadline))
+		// Always logged as an absolute deadline so that replaying doesn't extend the ttl
+		ks.Touch(keyStr)
+		aof.Feed([]byte("PEXPIREAT"), key.Str, []byte(strconv.FormatInt(deadline, 10)))
+		db.Notify(db.NotifyGeneric, "expire", keyStr)
+	})
+
+	res := resp.Integer{Val: 0}
+	if updated {
+		res.Val = 1
+	}
+	conn.W.Write(res.ToBytes())
+}
@@ -28,13 +28,11 @@ func get(args *resp.Array, conn *pubsub.Connection) {
 	channel := make(chan []byte, 1)
 	cmd := db.NewCommand(keyStr, nil, -1, channel, db.GET)
 
-	// Route to the appropriate shard based on key
-	shardCh := db.GetShardChannel(keyStr)
-	shardCh <- cmd
+	dispatch(conn, cmd)
 
 	value, ok := <-channel
 	if ok {
-		conn.W.Write(value)
+		writeShardReply(conn, value)
 		close(channel)
 		return
 	}
@@ -31,11 +31,159 @@ var allowedInSubscribedMode = map[string]struct{}{
 	"unsubscribe":  {},
 	"psubscribe":   {},
 	"punsubscribe": {},
+	"ssubscribe":   {},
+	"sunsubscribe": {},
 	"ping":         {},
 	"quit":         {},
 	"reset":        {},
 }
 
+// commandFunc runs a command and writes its reply to the connection
+type commandFunc func(args *resp.Array, conn *pubsub.Connection)
+
+// commandTable maps every lowercase command name to its implementation. It is filled in init
+// because EXEC runs queued commands through ExecuteCommands, which reads the table
+var commandTable map[string]commandFunc
+
+func init() {
+	commandTable = map[string]commandFunc{
+		"echo":             echo,
+		"ping":             ping,
+		"hello":            hello,
+		"client":           client,
+		"command":          command,
+		"set":              set,
+		"setnx":            setnx,
+		"get":              get,
+		"del":              del,
+		"exists":           exists,
+		"expire":           expire,
+		"pexpire":          pexpire,
+		"expireat":         expireat,
+		"pexpireat":        pexpireat,
+		"ttl":              ttl,
+		"pttl":             pttl,
+		"expiretime":       expiretime,
+		"pexpiretime":      pexpiretime,
+		"persist":          persist,
+		"rpush":            rpush,
+		"lpush":            lpush,
+		"llen":             llen,
+		"lrange":           lrange,
+		"lpop":             lpop,
+		"blpop":            blpop,
+		"rpop":             rpop,
+		"lindex":           lindex,
+		"lset":             lset,
+		"linsert":          linsert,
+		"lrem":             lrem,
+		"ltrim":            ltrim,
+		"lpos":             lpos,
+		"lpushx":           lpushx,
+		"rpushx":           rpushx,
+		"brpop":            brpop,
+		"lmove":            lmove,
+		"blmove":           blmove,
+		"rpoplpush":        rpoplpush,
+		"brpoplpush":       brpoplpush,
+		"lmpop":            lmpop,
+		"blmpop":           blmpop,
+		"config":           config,
+		"object":           object,
+		"type":             typeCommand,
+		"subscribe":        subscribe,
+		"publish":          publish,
+		"unsubscribe":      unsubscribe,
+		"psubscribe":       psubscribe,
+		"punsubscribe":     punsubscribe,
+		"ssubscribe":       ssubscribe,
+		"sunsubscribe":     sunsubscribe,
+		"spublish":         spublish,
+		"pubsub":           pubsubCommand,
+		"hset":             hset,
+		"hmset":            hmset,
+		"hsetnx":           hsetnx,
+		"hget":             hget,
+		"hmget":            hmget,
+		"hgetall":          hgetall,
+		"hkeys":            hkeys,
+		"hvals":            hvals,
+		"hdel":             hdel,
+		"hexists":          hexists,
+		"hlen":             hlen,
+		"hstrlen":          hstrlen,
+		"hincrby":          hincrby,
+		"hincrbyfloat":     hincrbyfloat,
+		"hrandfield":       hrandfield,
+		"hscan":            hscan,
+		"sadd":             sadd,
+		"srem":             srem,
+		"smembers":         smembers,
+		"sismember":        sismember,
+		"smismember":       smismember,
+		"scard":            scard,
+		"spop":             spop,
+		"srandmember":      srandmember,
+		"smove":            smove,
+		"sinter":           sinter,
+		"sintercard":       sintercard,
+		"sinterstore":      sinterstore,
+		"sunion":           sunion,
+		"sunionstore":      sunionstore,
+		"sdiff":            sdiff,
+		"sdiffstore":       sdiffstore,
+		"sscan":            sscan,
+		"zadd":             zadd,
+		"zincrby":          zincrby,
+		"zrem":             zrem,
+		"zscore":           zscore,
+		"zmscore":          zmscore,
+		"zcard":            zcard,
+		"zcount":           zcount,
+		"zlexcount":        zlexcount,
+		"zrank":            zrank,
+		"zrevrank":         zrevrank,
+		"zrange":           zrange,
+		"zrevrange":        zrevrange,
+		"zrangebyscore":    zrangebyscore,
+		"zrevrangebyscore": zrevrangebyscore,
+		"zrangebylex":      zrangebylex,
+		"zrevrangebylex":   zrevrangebylex,
+		"zpopmin":          zpopmin,
+		"zpopmax":          zpopmax,
+		"bzpopmin":         bzpopmin,
+		"bzpopmax":         bzpopmax,
+		"zunionstore":      zunionstore,
+		"zinterstore":      zinterstore,
+		"zscan":            zscan,
+		"xadd":             xadd,
+		"xrange":           xrange,
+		"xrevrange":        xrevrange,
+		"xread":            xread,
+		"xgroup":           xgroup,
+		"xreadgroup":       xreadgroup,
+		"xack":             xack,
+		"xpending":         xpending,
+		"xclaim":           xclaim,
+		"xautoclaim":       xautoclaim,
+		"xlen":             xlen,
+		"xdel":             xdel,
+		"xtrim":            xtrim,
+		"xsetid":           xsetid,
+		"xinfo":            xinfo,
+		"save":             save,
+		"bgsave":           bgsave,
+		"lastsave":         lastsave,
+		"bgrewriteaof":     bgrewriteaof,
+		"info":             info,
+		"multi":            multi,
+		"exec":             exec,
+		"discard":          discard,
+		"watch":            watch,
+		"unwatch":          unwatch,
+	}
+}
+
 func ExecuteCommands(msg resp.Message, conn *pubsub.Connection) {
 	arr, ok := msg.(*resp.Array)
 	if !ok {
@@ -51,8 +199,9 @@ func ExecuteCommands(msg resp.Message, conn *pubsub.Connection) {
 
 	cmdLower := string(bytes.ToLower(cmd.Str))
 
-	// Check if client is in subscribed mode
-	if len(conn.Channels) > 0 {
+	// Check if client is in subscribed mode, RESP3 clients tell pushes apart from replies so
+	// they can keep running any command
+	if conn.Subscribed() && !conn.RESP3() {
 		if _, allowed := allowedInSubscribedMode[cmdLower]; !allowed {
 			errMsg := fmt.Sprintf(
 				"ERR Can't execute '%s': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context",
@@ -63,56 +212,19 @@ func ExecuteCommands(msg resp.Message, conn *pubsub.Connection) {
 		}
 	}
 
-	switch cmdLower {
-	case "echo":
-		echo(arr, conn)
-	case "ping":
-		ping(arr, conn)
-	case "hello":
-		hello(arr, conn)
-	case "client":
-		client(arr, conn)
-	case "command":
-		command(arr, conn)
-	case "set":
-		set(arr, conn)
-	case "setnx":
-		setnx(arr, conn)
-	case "get":
-		get(arr, conn)
-	case "del":
-		del(arr, conn)
-	case "exists":
-		exists(arr, conn)
-	case "rpush":
-		rpush(arr, conn)
-	case "lpush":
-		lpush(arr, conn)
-	case "llen":
-		llen(arr, conn)
-	case "lrange":
-		lrange(arr, conn)
-	case "lpop":
-		lpop(arr, conn)
-	case "blpop":
-		blpop(arr, conn)
-	case "config":
-		config(arr, conn)
-	case "type":
-		typeCommand(arr, conn)
-	case "subscribe":
-		subscribe(arr, conn)
-	case "publish":
-		publish(arr, conn)
-	case "unsubscribe":
-		unsubscribe(arr, conn)
-	case "xadd":
-		xadd(arr, conn)
-	case "xrange":
-		xrange(arr, conn)
-	case "xread":
-		xread(arr, conn)
-	default:
+	if conn.InMulti {
+		if queueCommand(cmdLower, arr, conn) {
+			return
+		}
+	}
+
+	run, ok := commandTable[cmdLower]
+	if !ok {
 		commandDoesntExist(arr, conn)
+		return
 	}
+	conn.Reader = trackingReader(cmdLower, conn)
+	run(arr, conn)
+	conn.Reader = nil
+	endCaching(cmdLower, arr, conn)
 }
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hdel removes fields from a hash and replies with the number of fields that existed. The
+// key is deleted along with its last field
+func hdel(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hdel' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hdel' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, fields := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if hash == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		removed := 0
+		for _, field := range fields {
+			if hash.Delete(field) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			if hash.Len() == 0 {
+				ks.Delete(key)
+			}
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifyHash, "hdel", key)
+			if hash.Len() == 0 {
+				db.Notify(db.NotifyGeneric, "del", key)
+			}
+		}
+		res = &resp.Integer{Val: int64(removed)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
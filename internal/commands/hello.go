@@ -1,50 +1,83 @@
 package commands
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
-// hello handles the HELLO command
-// Since this server only supports RESP2, we return NOPROTO error for RESP3 requests
+// hello handles HELLO [protover [AUTH username password] [SETNAME clientname]]. A protover
+// of 3 switches the connection to RESP3, 2 switches it back. The reply describes the server
+// and is a map when the connection speaks RESP3
 func hello(args *resp.Array, conn *pubsub.Connection) {
-	// HELLO with no args or HELLO 2 is valid for RESP2
-	if len(args.Val) == 1 {
-		// No version specified, return server info as array
-		sendHelloResponse(conn)
-		return
-	}
-
-	version, ok := args.Val[1].(*resp.BulkString)
+	argv, ok := stringArgs(args)
 	if !ok {
-		msg := resp.SimpleError{Val: []byte("ERR Protocol version is not an integer or out of range")}
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hello' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	versionStr := string(version.Str)
-	if versionStr == "2" {
-		sendHelloResponse(conn)
-		return
+	proto := int(conn.Proto.Load())
+	if len(argv) > 1 {
+		version, err := strconv.Atoi(argv[1])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte("ERR Protocol version is not an integer or out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		if version != 2 && version != 3 {
+			msg := resp.SimpleError{Val: []byte("NOPROTO unsupported protocol version")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		proto = version
 	}
 
-	// For RESP3 (version 3) or higher, return NOPROTO error
-	msg := resp.SimpleError{Val: []byte("NOPROTO sorry this Redis does not support RESP3")}
-	conn.W.Write(msg.ToBytes())
+	name, setName := "", false
+	for i := 2; i < len(argv); i++ {
+		switch strings.ToLower(argv[i]) {
+		case "auth":
+			// There is no authentication, the credentials are accepted as they are
+			if i+2 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte("ERR Syntax error in HELLO option 'auth'")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			i += 2
+		case "setname":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte("ERR Syntax error in HELLO option 'setname'")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			i++
+			name, setName = argv[i], true
+		default:
+			msg := resp.SimpleError{Val: []byte("ERR Syntax error in HELLO option '" + argv[i] + "'")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	// Nothing changes unless every option is valid
+	conn.Proto.Store(int32(proto))
+	if setName {
+		conn.Name = name
+	}
+	sendHelloResponse(conn)
 }
 
 func sendHelloResponse(conn *pubsub.Connection) {
-	// Return minimal server info as an array (RESP2 style)
-	// Real Redis returns a map, but RESP2 uses arrays
-	response := resp.Array{
-		Val: []resp.Message{
-			&resp.BulkString{Str: []byte("server"), Size: 6},
-			&resp.BulkString{Str: []byte("redis"), Size: 5},
-			&resp.BulkString{Str: []byte("version"), Size: 7},
-			&resp.BulkString{Str: []byte("7.0.0"), Size: 5},
-			&resp.BulkString{Str: []byte("proto"), Size: 5},
-			&resp.Integer{Val: 2},
-		},
-	}
+	response := mapReply(conn, []resp.MapEntry{
+		{Key: bulkString("server"), Val: bulkString("redis")},
+		{Key: bulkString("version"), Val: bulkString("7.0.0")},
+		{Key: bulkString("proto"), Val: &resp.Integer{Val: int64(conn.Proto.Load())}},
+		{Key: bulkString("id"), Val: &resp.Integer{Val: conn.ID}},
+		{Key: bulkString("mode"), Val: bulkString("standalone")},
+		{Key: bulkString("role"), Val: bulkString("master")},
+		{Key: bulkString("modules"), Val: &resp.Array{Val: []resp.Message{}}},
+	})
 	conn.W.Write(response.ToBytes())
 }
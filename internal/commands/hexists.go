@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hexists replies 1 if the hash stored at key has the field, 0 otherwise
+func hexists(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hexists' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hexists' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field := argv[1], argv[2]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		if _, ok := hash.Get(field); ok {
+			res = &resp.Integer{Val: 1}
+			return
+		}
+		res = &resp.Integer{Val: 0}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
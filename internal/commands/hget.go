@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hget replies with the value of a field, or null if the field or the key doesn't exist
+func hget(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hget' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hget' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field := argv[1], argv[2]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		value, ok := hash.Get(field)
+		if !ok {
+			res = nullReply(conn)
+			return
+		}
+		res = bulkString(value)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// hmget replies with the values of several fields, null for the ones that don't exist
+func hmget(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hmget' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hmget' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, fields := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		values := &resp.Array{Val: make([]resp.Message, 0, len(fields))}
+		for _, field := range fields {
+			value, ok := hash.Get(field)
+			if !ok {
+				values.Val = append(values.Val, nullReply(conn))
+				continue
+			}
+			values.Val = append(values.Val, bulkString(value))
+		}
+		res = values
+	})
+
+	conn.W.Write(res.ToBytes())
+}
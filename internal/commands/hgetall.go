@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func hgetall(args *resp.Array, conn *pubsub.Connection) {
+	hashDump(args, conn, "hgetall", true, true)
+}
+
+func hkeys(args *resp.Array, conn *pubsub.Connection) {
+	hashDump(args, conn, "hkeys", true, false)
+}
+
+func hvals(args *resp.Array, conn *pubsub.Connection) {
+	hashDump(args, conn, "hvals", false, true)
+}
+
+// hashDump replies with every field and/or value of a hash, a missing key is an empty hash.
+// Fields with their values are a map for RESP3 clients and a flat array for RESP2 ones
+func hashDump(args *resp.Array, conn *pubsub.Connection, name string, fields, values bool) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		if fields && values {
+			entries := make([]resp.MapEntry, 0, hash.Len())
+			for field, value := range hash.All() {
+				entries = append(entries, resp.MapEntry{Key: bulkString(field), Val: bulkString(value)})
+			}
+			res = mapReply(conn, entries)
+			return
+		}
+
+		arr := &resp.Array{Val: make([]resp.Message, 0, hash.Len())}
+		for field, value := range hash.All() {
+			if fields {
+				arr.Val = append(arr.Val, bulkString(field))
+			} else {
+				arr.Val = append(arr.Val, bulkString(value))
+			}
+		}
+		res = arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
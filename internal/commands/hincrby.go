@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hincrby adds an integer to the value of a field, a missing field counts as 0
+func hincrby(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hincrby' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hincrby' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field := argv[1], argv[2]
+
+	incr, err := strconv.ParseInt(argv[3], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		var current int64
+		if value, ok := hash.Get(field); ok {
+			current, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				res = &resp.SimpleError{Val: []byte("ERR hash value is not an integer")}
+				return
+			}
+		}
+		if (incr > 0 && current > math.MaxInt64-incr) || (incr < 0 && current < math.MinInt64-incr) {
+			res = &resp.SimpleError{Val: []byte("ERR increment or decrement would overflow")}
+			return
+		}
+
+		// The hash is only created once the increment is known to succeed, so a failed
+		// command never leaves an empty hash behind
+		hash, _ = ks.CreateHash(key)
+		hash.Set(field, strconv.FormatInt(current+incr, 10))
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyHash, "hincrby", key)
+		res = &resp.Integer{Val: current + incr}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// hincrbyfloat adds a floating point number to the value of a field, a missing field counts
+// as 0
+func hincrbyfloat(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hincrbyfloat' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hincrbyfloat' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field := argv[1], argv[2]
+
+	incr, err := strconv.ParseFloat(argv[3], 64)
+	if err != nil || math.IsNaN(incr) || math.IsInf(incr, 0) {
+		msg := resp.SimpleError{Val: []byte("ERR value is not a valid float")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		var current float64
+		if value, ok := hash.Get(field); ok {
+			current, err = strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+				res = &resp.SimpleError{Val: []byte("ERR hash value is not a float")}
+				return
+			}
+		}
+		sum := current + incr
+		if math.IsNaN(sum) || math.IsInf(sum, 0) {
+			res = &resp.SimpleError{Val: []byte("ERR increment would produce NaN or Infinity")}
+			return
+		}
+
+		hash, _ = ks.CreateHash(key)
+		value := strconv.FormatFloat(sum, 'f', -1, 64)
+		hash.Set(field, value)
+		// The result is logged instead of the increment so replaying never accumulates
+		// floating point rounding differently
+		ks.Touch(key)
+		aof.Feed([]byte("HSET"), []byte(key), []byte(field), []byte(value))
+		db.Notify(db.NotifyHash, "hincrbyfloat", key)
+		res = bulkString(value)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hlen replies with the number of fields in a hash, 0 if the key doesn't exist
+func hlen(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'hlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		res = &resp.Integer{Val: int64(hash.Len())}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// hstrlen replies with the length of a field's value, 0 if the field doesn't exist
+func hstrlen(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hstrlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hstrlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field := argv[1], argv[2]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		value, _ := hash.Get(field)
+		res = &resp.Integer{Val: int64(len(value))}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hrandfield replies with random fields of a hash. Without a count a single field is
+// returned, a positive count returns up to count distinct fields and a negative one returns
+// exactly -count fields that may repeat
+func hrandfield(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 || len(args.Val) > 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hrandfield' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hrandfield' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	var count int64
+	hasCount, withValues := len(argv) >= 3, false
+	if hasCount {
+		var err error
+		count, err = strconv.ParseInt(argv[2], 10, 64)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+			msg := resp.SimpleError{Val: []byte("ERR value is out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+	if len(argv) == 4 {
+		if strings.ToLower(argv[3]) != "withvalues" {
+			msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		withValues = true
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		fields := make([]string, 0, hash.Len())
+		for field := range hash.All() {
+			fields = append(fields, field)
+		}
+
+		if !hasCount {
+			if len(fields) == 0 {
+				res = nullReply(conn)
+				return
+			}
+			res = bulkString(fields[rand.IntN(len(fields))])
+			return
+		}
+
+		var picked []string
+		switch {
+		case count < 0 && len(fields) > 0:
+			picked = make([]string, -count)
+			for i := range picked {
+				picked[i] = fields[rand.IntN(len(fields))]
+			}
+		case count > 0:
+			rand.Shuffle(len(fields), func(i, j int) { fields[i], fields[j] = fields[j], fields[i] })
+			picked = fields[:min(count, int64(len(fields)))]
+		}
+
+		// With values RESP3 clients get [field, value] pairs, RESP2 ones a flat array
+		arr := &resp.Array{Val: make([]resp.Message, 0, len(picked))}
+		for _, field := range picked {
+			value, _ := hash.Get(field)
+			switch {
+			case withValues && conn.RESP3():
+				arr.Val = append(arr.Val, &resp.Array{Val: []resp.Message{bulkString(field), bulkString(value)}})
+			case withValues:
+				arr.Val = append(arr.Val, bulkString(field), bulkString(value))
+			default:
+				arr.Val = append(arr.Val, bulkString(field))
+			}
+		}
+		res = arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hscan iterates over the fields of a hash, see ds.ScanTable.Scan for the guarantees of the
+// cursor
+func hscan(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'hscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	opts, errMsg := parseScanOptions(args, true)
+	if errMsg != nil {
+		conn.W.Write(errMsg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		hash, err := ks.Hash(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		fields, cursor := hash.Scan(opts.cursor, opts.count)
+		fields = scanMatch(fields, opts)
+		if opts.noValues {
+			res = scanReply(cursor, fields)
+			return
+		}
+		items := make([]string, 0, 2*len(fields))
+		for _, field := range fields {
+			value, _ := hash.Get(field)
+			items = append(items, field, value)
+		}
+		res = scanReply(cursor, items)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
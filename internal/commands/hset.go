@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// hset sets field-value pairs and replies with the number of fields that were added
+func hset(args *resp.Array, conn *pubsub.Connection) {
+	hsetGeneric(args, conn, "hset")
+}
+
+// hmset is the deprecated form of HSET that replies OK
+func hmset(args *resp.Array, conn *pubsub.Connection) {
+	hsetGeneric(args, conn, "hmset")
+}
+
+func hsetGeneric(args *resp.Array, conn *pubsub.Connection, name string) {
+	if len(args.Val) < 4 || len(args.Val)%2 != 0 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.CreateHash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		added := 0
+		for i := 2; i < len(argv); i += 2 {
+			if hash.Set(argv[i], argv[i+1]) {
+				added++
+			}
+		}
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyHash, "hset", key)
+
+		if name == "hmset" {
+			res = &resp.SimpleString{Val: []byte("OK")}
+			return
+		}
+		res = &resp.Integer{Val: int64(added)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// hsetnx sets a field only if it doesn't exist yet, it replies 1 if the field was set
+func hsetnx(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'hsetnx' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'hsetnx' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, field, value := argv[1], argv[2], argv[3]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		hash, err := ks.CreateHash(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		if _, exists := hash.Get(field); exists {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		hash.Set(field, value)
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyHash, "hset", key)
+		res = &resp.Integer{Val: 1}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+var startTime = time.Now()
+
+// infoSections lists the sections in the order INFO prints them
+var infoSections = []struct {
+	name  string
+	write func(b *strings.Builder)
+}{
+	{"server", infoServer},
+	{"persistence", infoPersistence},
+	{"stats", infoStats},
+	{"keyspace", infoKeyspace},
+}
+
+// info implements INFO [section [section ...]], without arguments every section is returned
+func info(args *resp.Array, conn *pubsub.Connection) {
+	wanted := make(map[string]bool)
+	for _, arg := range args.Val[1:] {
+		section, ok := arg.(*resp.BulkString)
+		if !ok {
+			msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		wanted[strings.ToLower(string(section.Str))] = true
+	}
+	all := len(wanted) == 0 || wanted["all"] || wanted["default"] || wanted["everything"]
+
+	var b strings.Builder
+	for _, section := range infoSections {
+		if !all && !wanted[section.name] {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		fmt.Fprintf(&b, "# %s%s\r\n", strings.ToUpper(section.name[:1]), section.name[1:])
+		section.write(&b)
+	}
+
+	// RESP3 clients get the text as a verbatim string, meant to be shown as is
+	var res resp.Message = &resp.BulkString{Str: []byte(b.String()), Size: b.Len()}
+	if conn.RESP3() {
+		res = &resp.VerbatimString{Format: "txt", Str: []byte(b.String())}
+	}
+	conn.W.Write(res.ToBytes())
+}
+
+func infoServer(b *strings.Builder) {
+	fmt.Fprintf(b, "redis_version:7.2.0\r\n")
+	fmt.Fprintf(b, "redis_mode:standalone\r\n")
+	fmt.Fprintf(b, "process_id:%d\r\n", os.Getpid())
+	fmt.Fprintf(b, "uptime_in_seconds:%d\r\n", int64(time.Since(startTime).Seconds()))
+	fmt.Fprintf(b, "hz:%s\r\n", configValue("hz"))
+}
+
+func infoPersistence(b *strings.Builder) {
+	persistence.mu.Lock()
+	bgsave, lastSave := persistence.inProgress, persistence.lastSave
+	persistence.mu.Unlock()
+
+	fmt.Fprintf(b, "rdb_bgsave_in_progress:%d\r\n", boolToInt(bgsave))
+	fmt.Fprintf(b, "rdb_last_save_time:%d\r\n", lastSave)
+	fmt.Fprintf(b, "aof_enabled:%d\r\n", boolToInt(aof.Enabled()))
+	fmt.Fprintf(b, "aof_rewrite_in_progress:%d\r\n", boolToInt(aof.Rewriting()))
+}
+
+func infoStats(b *strings.Builder) {
+	stats := db.GetExpireStats()
+	fmt.Fprintf(b, "expired_keys:%d\r\n", stats.ExpiredKeys)
+	fmt.Fprintf(b, "expired_stale_perc:%.2f\r\n", stats.StalePerc)
+	fmt.Fprintf(b, "expired_time_cap_reached_count:%d\r\n", stats.TimeCapReachedCount)
+	fmt.Fprintf(b, "expire_cycle_cpu_milliseconds:%d\r\n", stats.CycleCPUMilliseconds)
+	fmt.Fprintf(b, "client_output_buffer_limit_disconnections:%d\r\n", pubsub.LimitDisconnections())
+}
+
+func infoKeyspace(b *strings.Builder) {
+	keys, volatile := db.Size()
+	if keys > 0 {
+		fmt.Fprintf(b, "db0:keys=%d,expires=%d,avg_ttl=0\r\n", keys, volatile)
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
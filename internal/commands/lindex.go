@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// listIndex turns a list index, negative ones counting from the tail, into a position in a
+// list of n elements. ok is false if the index is out of range
+func listIndex(index int64, n int) (int, bool) {
+	if index < 0 {
+		index += int64(n)
+	}
+	if index < 0 || index >= int64(n) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// lindex replies with the element at an index, or null if the index is out of range
+func lindex(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lindex' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lindex' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	index, err := strconv.ParseInt(argv[2], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = nullReply(conn)
+			return
+		}
+
+		i, ok := listIndex(index, list.Q.Len())
+		if !ok {
+			res = nullReply(conn)
+			return
+		}
+		res = bulkString(list.Q.At(i))
+	})
+
+	conn.W.Write(res.ToBytes())
+}
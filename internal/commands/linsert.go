@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// linsert inserts an element before or after the first occurrence of a pivot. It replies
+// with the new length, -1 if the pivot wasn't found and 0 if the key doesn't exist
+func linsert(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 5 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'linsert' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'linsert' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, pivot, element := argv[1], argv[3], argv[4]
+
+	var after bool
+	switch strings.ToLower(argv[2]) {
+	case "before":
+	case "after":
+		after = true
+	default:
+		msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		for i := range list.Q.Len() {
+			if list.Q.At(i) != pivot {
+				continue
+			}
+			if after {
+				i++
+			}
+			list.Q.Insert(i, element)
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifyList, "linsert", key)
+			res = &resp.Integer{Val: int64(list.Q.Len())}
+			return
+		}
+		res = &resp.Integer{Val: -1}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -23,13 +23,19 @@ func llen(args *resp.Array, conn *pubsub.Connection) {
 		return
 	}
 
-	list := db.GetList(string(key.Str))
-	if list == nil {
-		msg := resp.Integer{Val: 0}
-		conn.W.Write(msg.ToBytes())
-		return
-	}
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		list, err := ks.List(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		res = &resp.Integer{Val: int64(list.Q.Len())}
+	})
 
-	msg := resp.Integer{Val: int64(list.Q.Len())}
-	conn.W.Write(msg.ToBytes())
+	conn.W.Write(res.ToBytes())
 }
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"log"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// parseListEnd parses a LEFT or RIGHT argument, it returns true for LEFT
+func parseListEnd(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "left":
+		return true, nil
+	case "right":
+		return false, nil
+	}
+	return false, errSyntax
+}
+
+// listEndName is the inverse of parseListEnd
+func listEndName(front bool) string {
+	if front {
+		return "LEFT"
+	}
+	return "RIGHT"
+}
+
+// listMove pops an element from one end of source and pushes it to one end of destination,
+// both keys must be held by ks. It returns the moved element, or nil if source doesn't exist.
+// source and destination may be the same list, which rotates it
+func listMove(ks *db.Keyspace, source, destination string, from, to bool) resp.Message {
+	src, err := ks.List(source)
+	if err != nil {
+		return &resp.SimpleError{Val: []byte(err.Error())}
+	}
+	if src == nil {
+		return nil
+	}
+	// The destination is checked before anything is popped, a failed move changes nothing
+	if _, err := ks.List(destination); err != nil {
+		return &resp.SimpleError{Val: []byte(err.Error())}
+	}
+
+	val := popEnd(src, from)
+	db.Notify(db.NotifyList, strings.ToLower(popCommand(from)), source)
+	dst, _ := ks.CreateList(destination)
+	if to {
+		dst.Q.PushFront(val)
+		db.Notify(db.NotifyList, "lpush", destination)
+	} else {
+		dst.Q.PushBack(val)
+		db.Notify(db.NotifyList, "rpush", destination)
+	}
+
+	if src.Q.Len() == 0 {
+		ks.Delete(source)
+		db.Notify(db.NotifyGeneric, "del", source)
+		log.Printf("List %s is empty, deleting...", source)
+	}
+	ks.Touch(source)
+	ks.Touch(destination)
+	// Replaying a blocking move must never block, it is logged as the LMOVE it turned into
+	aof.Feed([]byte("LMOVE"), []byte(source), []byte(destination), []byte(listEndName(from)), []byte(listEndName(to)))
+
+	// The pushed element can serve a client blocked on the destination
+	ks.Wake(destination)
+	return bulkString(val)
+}
+
+func lmove(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 5 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lmove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lmove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	from, err := parseListEnd(argv[3])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	to, err := parseListEnd(argv[4])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	listMoveCommand(conn, argv[1], argv[2], from, to)
+}
+
+// rpoplpush is LMOVE source destination RIGHT LEFT
+func rpoplpush(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'rpoplpush' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'rpoplpush' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	listMoveCommand(conn, argv[1], argv[2], false, true)
+}
+
+// listMoveCommand runs a non-blocking move and replies with the moved element, or null if
+// source doesn't exist
+func listMoveCommand(conn *pubsub.Connection, source, destination string, from, to bool) {
+	var res resp.Message
+	do(conn, []string{source, destination}, func(ks *db.Keyspace) {
+		res = listMove(ks, source, destination, from, to)
+	})
+	if res == nil {
+		conn.W.Write(nullReply(conn).ToBytes())
+		return
+	}
+	conn.W.Write(res.ToBytes())
+}
+
+func blmove(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 6 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'blmove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'blmove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	from, err := parseListEnd(argv[3])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	to, err := parseListEnd(argv[4])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	blockingMove(conn, argv[1], argv[2], from, to, argv[5])
+}
+
+// brpoplpush is BLMOVE source destination RIGHT LEFT timeout
+func brpoplpush(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'brpoplpush' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'brpoplpush' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	blockingMove(conn, argv[1], argv[2], false, true, argv[3])
+}
+
+// blockingMove waits on source until it has an element to move. Both lists are held while
+// the move happens, so a client pushing to either of them concurrently can't see the
+// element in neither or both
+func blockingMove(conn *pubsub.Connection, source, destination string, from, to bool, timeoutArg string) {
+	timeout, err := parseTimeout(timeoutArg)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	res := blockOnHolding(conn, []string{source}, []string{source, destination}, timeout, func(ks *db.Keyspace) resp.Message {
+		return listMove(ks, source, destination, from, to)
+	})
+	if res == nil {
+		conn.W.Write(nullReply(conn).ToBytes())
+		return
+	}
+	conn.W.Write(res.ToBytes())
+}
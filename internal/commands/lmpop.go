@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// parseMultiPop parses the "numkeys key [key ...] LEFT|RIGHT [COUNT count]" arguments shared
+// by LMPOP and BLMPOP
+func parseMultiPop(argv []string) (keys []string, front bool, count int, err error) {
+	numKeys, err := strconv.Atoi(argv[0])
+	if err != nil || numKeys <= 0 {
+		return nil, false, 0, errors.New("ERR numkeys should be greater than 0")
+	}
+	if numKeys+1 >= len(argv) {
+		return nil, false, 0, errSyntax
+	}
+	keys = argv[1 : 1+numKeys]
+
+	front, err = parseListEnd(argv[1+numKeys])
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	count = 1
+	rest := argv[2+numKeys:]
+	for len(rest) > 0 {
+		if strings.ToLower(rest[0]) != "count" || len(rest) < 2 {
+			return nil, false, 0, errSyntax
+		}
+		count, err = strconv.Atoi(rest[1])
+		if err != nil || count <= 0 {
+			return nil, false, 0, errors.New("ERR count should be greater than 0")
+		}
+		rest = rest[2:]
+	}
+	return keys, front, count, nil
+}
+
+// multiPop pops up to count elements from the first non-empty list among keys and returns
+// the [key, [element ...]] reply, or nil if all of them are empty
+func multiPop(ks *db.Keyspace, keys []string, front bool, count int) resp.Message {
+	for _, key := range keys {
+		list, err := ks.List(key)
+		if err != nil {
+			return &resp.SimpleError{Val: []byte(err.Error())}
+		}
+		if list == nil {
+			continue
+		}
+
+		n := min(count, list.Q.Len())
+		elements := make([]resp.Message, 0, n)
+		for range n {
+			elements = append(elements, bulkString(popEnd(list, front)))
+		}
+		ks.Touch(key)
+		aof.Feed([]byte(popCommand(front)), []byte(key), []byte(strconv.Itoa(n)))
+		db.Notify(db.NotifyList, strings.ToLower(popCommand(front)), key)
+		if list.Q.Len() == 0 {
+			ks.Delete(key)
+			db.Notify(db.NotifyGeneric, "del", key)
+			log.Printf("List %s is empty, deleting...", key)
+		}
+
+		return &resp.Array{Val: []resp.Message{bulkString(key), &resp.Array{Val: elements}}}
+	}
+	return nil
+}
+
+func lmpop(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lmpop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lmpop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	keys, front, count, err := parseMultiPop(argv[1:])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, keys, func(ks *db.Keyspace) {
+		res = multiPop(ks, keys, front, count)
+	})
+	if res == nil {
+		conn.W.Write(nullArray(conn))
+		return
+	}
+	conn.W.Write(res.ToBytes())
+}
+
+// blmpop is the blocking variant of LMPOP, the timeout comes before numkeys
+func blmpop(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 5 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'blmpop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'blmpop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	timeout, err := parseTimeout(argv[1])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys, front, count, err := parseMultiPop(argv[2:])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	res := blockOn(conn, keys, timeout, func(ks *db.Keyspace) resp.Message {
+		return multiPop(ks, keys, front, count)
+	})
+	if res == nil {
+		conn.W.Write(nullArray(conn))
+		return
+	}
+	conn.W.Write(res.ToBytes())
+}
@@ -3,22 +3,34 @@ package commands
 import (
 	"log"
 	"strconv"
+	"strings"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
 	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
 func lpop(args *resp.Array, conn *pubsub.Connection) {
+	listPop(args, conn, "lpop", true)
+}
+
+func rpop(args *resp.Array, conn *pubsub.Connection) {
+	listPop(args, conn, "rpop", false)
+}
+
+// listPop implements LPOP and RPOP, popping from the head of the list when front is set and
+// from its tail otherwise
+func listPop(args *resp.Array, conn *pubsub.Connection, name string, front bool) {
 	if len(args.Val) != 3 && len(args.Val) != 2 {
-		msg := resp.SimpleError{Val: []byte("wrong number of arguments for 'lpop' command")}
+		msg := resp.SimpleError{Val: []byte("wrong number of arguments for '" + name + "' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
 	key, ok := args.Val[1].(*resp.BulkString)
 	if !ok {
-		msg := resp.SimpleError{Val: []byte("wrong data type of 1st argument for 'lpop' command")}
+		msg := resp.SimpleError{Val: []byte("wrong data type of 1st argument for '" + name + "' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
@@ -27,49 +39,65 @@ func lpop(args *resp.Array, conn *pubsub.Connection) {
 	if len(args.Val) == 3 {
 		numberString, ok := args.Val[2].(*resp.BulkString)
 		if !ok {
-			msg := resp.SimpleError{Val: []byte("wrong data type of 3rd argument for 'lpop' command")}
+			msg := resp.SimpleError{Val: []byte("wrong data type of 3rd argument for '" + name + "' command")}
 			conn.W.Write(msg.ToBytes())
 			return
 		}
 		number, err := strconv.ParseInt(string(numberString.Str), 10, 64)
 		if err != nil {
-			msg := resp.SimpleError{Val: []byte("error while parsing the 3rd argument of 'lpop' command")}
+			msg := resp.SimpleError{Val: []byte("error while parsing the 3rd argument of '" + name + "' command")}
 			conn.W.Write(msg.ToBytes())
 			return
 		}
 		num = number
 	}
 
-	list := db.GetList(string(key.Str))
-	if list == nil {
-		conn.W.Write([]byte(resp.NULLBULKSTRING))
-		return
-	}
-	res := resp.Array{Val: make([]resp.Message, 0)}
+	var reply resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		list, err := ks.List(string(key.Str))
+		if err != nil {
+			reply = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			reply = nullReply(conn)
+			return
+		}
 
-	list.Mu.Lock()
-	log.Printf("Lock for list %s acquired by the 'lpop' command goroutine", key.Str)
+		res := &resp.Array{Val: make([]resp.Message, 0)}
+		for i := int64(0); i < num; i++ {
+			var val string
+			var ok bool
+			if front {
+				val, ok = list.Q.PopFront()
+			} else {
+				val, ok = list.Q.PopBack()
+			}
+			if ok {
+				element := &resp.BulkString{Str: []byte(val), Size: len(val)}
+				res.Val = append(res.Val, element)
+			}
+		}
 
-	for i := int64(0); i < num; i++ {
-		val, ok := list.Q.PopFront()
-		if ok {
-			element := &resp.BulkString{Str: []byte(val), Size: len(val)}
-			res.Val = append(res.Val, element)
+		if popped := len(res.Val); popped > 0 {
+			ks.Touch(string(key.Str))
+			aof.Feed([]byte(strings.ToUpper(name)), key.Str, []byte(strconv.Itoa(popped)))
+			db.Notify(db.NotifyList, name, string(key.Str))
 		}
-	}
 
-	shouldDelete := list.Q.Len() == 0 && list.B.Len() == 0
-	list.Mu.Unlock()
+		// Empty lists don't exist, the key goes away with its last element
+		if list.Q.Len() == 0 {
+			ks.Delete(string(key.Str))
+			db.Notify(db.NotifyGeneric, "del", string(key.Str))
+			log.Printf("List %s is empty, deleting...", key.Str)
+		}
 
-	log.Printf("Lock for list %s released by the 'lpop' command goroutine", key.Str)
-	if shouldDelete {
-		db.DeleteList(string(key.Str))
-		log.Printf("Element and channel queue is empty for list %s, deleting...", key.Str)
-	}
+		if len(args.Val) == 2 {
+			reply = res.Val[0]
+			return
+		}
+		reply = res
+	})
 
-	if len(args.Val) == 2 {
-		conn.W.Write(res.Val[0].ToBytes())
-		return
-	}
-	conn.W.Write(res.ToBytes())
+	conn.W.Write(reply.ToBytes())
 }
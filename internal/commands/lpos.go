@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// lpos replies with the index of an element in a list.
+//
+// RANK picks which match is returned: 2 is the second one from the head, -1 the first one
+// from the tail. COUNT returns up to that many matches as an array, 0 meaning all of them,
+// and MAXLEN stops the scan after that many elements
+func lpos(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lpos' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lpos' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, element := argv[1], argv[2]
+
+	rank, count, maxLen := 1, 0, 0
+	hasCount := false
+	for i := 3; i < len(argv); i += 2 {
+		if i+1 >= len(argv) {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		n, err := strconv.Atoi(argv[i+1])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+
+		var errMsg string
+		switch strings.ToLower(argv[i]) {
+		case "rank":
+			switch n {
+			case 0:
+				errMsg = "ERR RANK can't be zero: use 1 to start from the first match, 2 from the second ... or use negative to start from the end of the list"
+			case math.MinInt:
+				// Its opposite, the number of matches to skip from the tail, doesn't fit
+				errMsg = "ERR value is out of range, value must between -9223372036854775807 and 9223372036854775807"
+			}
+			rank = n
+		case "count":
+			if n < 0 {
+				errMsg = "ERR COUNT can't be negative"
+			}
+			count, hasCount = n, true
+		case "maxlen":
+			if n < 0 {
+				errMsg = "ERR MAXLEN can't be negative"
+			}
+			maxLen = n
+		default:
+			errMsg = errSyntax.Error()
+		}
+		if errMsg != "" {
+			msg := resp.SimpleError{Val: []byte(errMsg)}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		var matches []int
+		if list != nil {
+			n := list.Q.Len()
+			scan := n
+			if maxLen > 0 {
+				scan = min(maxLen, n)
+			}
+			skip := max(rank, -rank) - 1
+			// Without COUNT the first match is enough, COUNT 0 collects all of them
+			limit := 1
+			if hasCount {
+				limit = count
+			}
+			for j := range scan {
+				i := j
+				if rank < 0 {
+					i = n - 1 - j
+				}
+				if list.Q.At(i) != element {
+					continue
+				}
+				if skip > 0 {
+					skip--
+					continue
+				}
+				matches = append(matches, i)
+				if len(matches) == limit {
+					break
+				}
+			}
+		}
+
+		if !hasCount {
+			if len(matches) == 0 {
+				res = nullReply(conn)
+				return
+			}
+			res = &resp.Integer{Val: int64(matches[0])}
+			return
+		}
+		arr := &resp.Array{Val: make([]resp.Message, 0, len(matches))}
+		for _, i := range matches {
+			arr.Val = append(arr.Val, &resp.Integer{Val: int64(i)})
+		}
+		res = arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -1,44 +1,89 @@
 package commands
 
 import (
-	"log"
-
 	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
 func lpush(args *resp.Array, conn *pubsub.Connection) {
+	listPush(args, conn, "lpush", true, false)
+}
+
+// lpushx is LPUSH for lists that already exist, a missing key is left alone
+func lpushx(args *resp.Array, conn *pubsub.Connection) {
+	listPush(args, conn, "lpushx", true, true)
+}
+
+// listPush implements LPUSH, RPUSH, LPUSHX and RPUSHX. Elements are pushed one after the
+// other at the head of the list when front is set and at its tail otherwise. With existing
+// set nothing happens unless the list exists
+func listPush(args *resp.Array, conn *pubsub.Connection, name string, front, existing bool) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
 	key, ok := args.Val[1].(*resp.BulkString)
 	if !ok {
-		msg := resp.SimpleError{Val: []byte("wrong data type of 1st argument for 'lpush' command")}
+		msg := resp.SimpleError{Val: []byte("wrong data type of 1st argument for '" + name + "' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	list := db.CreateOrGetList(string(key.Str))
-
-	list.Mu.Lock()
-	log.Printf("Lock for list %s acquired by the 'lpush' command goroutine", key.Str)
-
+	values := make([]string, 0, len(args.Val)-2)
 	for i := 2; i < len(args.Val); i++ {
 		val, ok := args.Val[i].(*resp.BulkString)
 		if !ok {
-			msg := resp.SimpleError{Val: []byte("wrong data type of list entry in 'lpush' command")}
+			msg := resp.SimpleError{Val: []byte("wrong data type of list entry in '" + name + "' command")}
 			conn.W.Write(msg.ToBytes())
 			return
 		}
-		list.Q.PushFront(string(val.Str))
+		values = append(values, string(val.Str))
 	}
 
-	res := resp.Integer{Val: int64(list.Q.Len())}
-	ch, ok := list.B.PopBack()
-	list.Mu.Unlock()
-	log.Printf("Lock for list %s released by the 'lpush' command goroutine", key.Str)
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		var list *db.ListEntry
+		var err error
+		if existing {
+			list, err = ks.List(string(key.Str))
+		} else {
+			list, err = ks.CreateList(string(key.Str))
+		}
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
 
-	if ok {
-		ch <- struct{}{}
-	}
+		for _, val := range values {
+			if front {
+				list.Q.PushFront(val)
+			} else {
+				list.Q.PushBack(val)
+			}
+		}
+		ks.Touch(string(key.Str))
+		propagate(args)
+		if front {
+			db.Notify(db.NotifyList, "lpush", string(key.Str))
+		} else {
+			db.Notify(db.NotifyList, "rpush", string(key.Str))
+		}
+
+		// Every pushed element can serve one blocked client
+		for range values {
+			if !ks.Wake(string(key.Str)) {
+				break
+			}
+		}
+		res = &resp.Integer{Val: int64(list.Q.Len())}
+	})
 
 	conn.W.Write(res.ToBytes())
 }
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"log"
 	"strconv"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/db"
@@ -11,6 +10,12 @@ import (
 )
 
 func lrange(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lrange' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
 	key, ok := args.Val[1].(*resp.BulkString)
 	if !ok {
 		msg := resp.SimpleError{Val: []byte("wrong data type of 1st argument for 'lrange' command")}
@@ -44,30 +49,27 @@ func lrange(args *resp.Array, conn *pubsub.Connection) {
 		return
 	}
 
-	list := db.GetList(string(key.Str))
-	if list == nil {
-		res := resp.Array{Val: make([]resp.Message, 0)}
-		conn.W.Write(res.ToBytes())
-		return
-	}
-	list.Mu.Lock()
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		list, err := ks.List(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
 
-	// Check if indices are valid
-	if !utils.ValidateIndices(start, stop, uint(len(list.Q.Buf))) {
-		res := resp.Array{Val: make([]resp.Message, 0)}
-		conn.W.Write(res.ToBytes())
-		list.Mu.Unlock()
-		log.Printf("Lock for list %s released by the 'lrange' command goroutine", key.Str)
-		return
-	}
+		// Check if indices are valid
+		if list == nil || !utils.ValidateIndices(start, stop, uint(list.Q.Len())) {
+			res = &resp.Array{Val: make([]resp.Message, 0)}
+			return
+		}
+
+		// No need to validate indices here as that is already done above, we can assume that these are valid indices
+		start, _ = utils.GetPositiveIndex(uint(list.Q.Len()), start)
+		stop, _ = utils.GetPositiveIndex(uint(list.Q.Len()), stop)
 
-	// No need to validate indices here as that is already done above, we can assume that these are valid indices
-	start, _ = utils.GetPositiveIndex(uint(len(list.Q.Buf)), start)
-	stop, _ = utils.GetPositiveIndex(uint(len(list.Q.Buf)), stop)
+		arr := utils.GetRespArrayBulkString(list.Q.GetSlice(start, stop)) // stop index is included in this slice
+		res = &arr
+	})
 
-	slice := list.Q.Buf[start : stop+1] // stop index is included in this slice
-	res := utils.GetRespArrayBulkString(slice)
-	list.Mu.Unlock()
-	log.Printf("Lock for list %s released by the 'lrange' command goroutine", key.Str)
 	conn.W.Write(res.ToBytes())
 }
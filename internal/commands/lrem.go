@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// lrem removes the first count occurrences of an element, the last -count ones for a
+// negative count or all of them for a zero count, and replies with how many were removed
+func lrem(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lrem' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lrem' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, element := argv[1], argv[3]
+
+	count, err := strconv.Atoi(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		removed := list.Q.RemoveN(element, count)
+		if removed > 0 {
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifyList, "lrem", key)
+			// Empty lists don't exist, the key goes away with its last element
+			if list.Q.Len() == 0 {
+				ks.Delete(key)
+				db.Notify(db.NotifyGeneric, "del", key)
+			}
+		}
+		res = &resp.Integer{Val: int64(removed)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
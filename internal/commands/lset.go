@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// lset replaces the element at an index of an existing list
+func lset(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'lset' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'lset' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, element := argv[1], argv[3]
+
+	index, err := strconv.ParseInt(argv[2], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if list == nil {
+			res = &resp.SimpleError{Val: []byte("ERR no such key")}
+			return
+		}
+
+		i, ok := listIndex(index, list.Q.Len())
+		if !ok {
+			res = &resp.SimpleError{Val: []byte("ERR index out of range")}
+			return
+		}
+		list.Q.Set(i, element)
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyList, "lset", key)
+		res = &resp.SimpleString{Val: []byte("OK")}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// ltrim keeps only the elements between start and stop, both inclusive. Negative indexes
+// count from the tail and out of range ones are clamped, a range left empty deletes the key
+func ltrim(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'ltrim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'ltrim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	start, err := strconv.ParseInt(argv[2], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	stop, err := strconv.ParseInt(argv[3], 10, 64)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		list, err := ks.List(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		res = &resp.SimpleString{Val: []byte("OK")}
+		if list == nil {
+			return
+		}
+
+		n := int64(list.Q.Len())
+		if start < 0 {
+			start = max(start+n, 0)
+		}
+		if stop < 0 {
+			stop += n
+		}
+		stop = min(stop, n-1)
+		if start == 0 && stop == n-1 {
+			return
+		}
+
+		// Empty lists don't exist, the key goes away with its last element
+		if start > stop {
+			ks.Delete(key)
+		} else {
+			list.Q.Trim(int(start), int(stop)+1)
+		}
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyList, "ltrim", key)
+		if start > stop {
+			db.Notify(db.NotifyGeneric, "del", key)
+		}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
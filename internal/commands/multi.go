@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// notAllowedInMulti lists the commands that can't be queued in a transaction, mostly because
+// they need to park every shard themselves while EXEC already holds them
+var notAllowedInMulti = map[string]struct{}{
+	"save":         {},
+	"bgsave":       {},
+	"bgrewriteaof": {},
+	"config":       {},
+	"info":         {},
+	"subscribe":    {},
+	"unsubscribe":  {},
+	"psubscribe":   {},
+	"punsubscribe": {},
+	"ssubscribe":   {},
+	"sunsubscribe": {},
+}
+
+// do runs fn with exclusive access to the shards owning keys, see db.Do. While EXEC runs the
+// queued commands of a transaction it already holds every shard, so fn runs right away.
+// When the command's reads are tracked, keys are remembered before the shards are released
+// so that no change can slip in between the read and the registration
+func do(conn *pubsub.Connection, keys []string, fn func(ks *db.Keyspace)) {
+	run := func(ks *db.Keyspace) {
+		fn(ks)
+		if conn.Reader != nil {
+			for _, key := range keys {
+				ks.Track(key, conn.Reader)
+			}
+		}
+	}
+	if conn.Held != nil {
+		run(conn.Held)
+		return
+	}
+	db.Do(keys, func(ks *db.Keyspace) {
+		run(ks.For(conn.ID))
+	})
+}
+
+// dispatch runs a command on the shard owning its key on behalf of conn, see db.Dispatch
+func dispatch(conn *pubsub.Connection, cmd db.Command) {
+	db.Dispatch(conn.Held, cmd.For(conn.ID, conn.Reader))
+}
+
+// queueCommand queues a command sent between MULTI and EXEC and replies QUEUED. It returns
+// false for the commands that control the transaction, which run right away
+func queueCommand(name string, args *resp.Array, conn *pubsub.Connection) bool {
+	switch name {
+	case "exec", "discard", "multi", "watch":
+		return false
+	}
+
+	// A command that can't be queued fails the whole transaction, EXEC will refuse to run it
+	if _, ok := commandTable[name]; !ok {
+		conn.MultiFailed = true
+		commandDoesntExist(args, conn)
+		return true
+	}
+	if _, ok := notAllowedInMulti[name]; ok {
+		conn.MultiFailed = true
+		msg := resp.SimpleError{Val: []byte("ERR Command not allowed inside a transaction")}
+		conn.W.Write(msg.ToBytes())
+		return true
+	}
+
+	conn.Queued = append(conn.Queued, args)
+	conn.W.Write([]byte("+QUEUED\r\n"))
+	return true
+}
+
+// multi starts a transaction, the following commands are queued until EXEC or DISCARD
+func multi(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'multi' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if conn.InMulti {
+		msg := resp.SimpleError{Val: []byte("ERR MULTI calls can not be nested")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	conn.InMulti = true
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// discard drops the queued commands and the watched keys
+func discard(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'discard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if !conn.InMulti {
+		msg := resp.SimpleError{Val: []byte("ERR DISCARD without MULTI")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	resetMulti(conn)
+	unwatchAll(conn)
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// exec runs the queued commands atomically and replies with the array of their replies. The
+// transaction is aborted with a null reply if a watched key was modified after WATCH.
+//
+// Every shard is parked for the whole transaction, so no other client can observe a partial
+// result even when the keys live on different shards. Replies are collected in memory and
+// only written once the shards are released, a slow client can't stall the server
+func exec(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'exec' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if !conn.InMulti {
+		msg := resp.SimpleError{Val: []byte("ERR EXEC without MULTI")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	queued, failed := conn.Queued, conn.MultiFailed
+	resetMulti(conn)
+	if failed {
+		unwatchAll(conn)
+		msg := resp.SimpleError{Val: []byte("EXECABORT Transaction discarded because of previous errors.")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var replies bytes.Buffer
+	aborted := false
+	db.DoAll(func(ks *db.Keyspace) {
+		if conn.Watcher != nil {
+			aborted = conn.Watcher.Dirty()
+			ks.Unwatch(conn.Watcher)
+			conn.Watcher = nil
+		}
+		if aborted {
+			return
+		}
+
+		w := conn.W
+		conn.W = bufio.NewWriter(&replies)
+		conn.Held = ks.For(conn.ID)
+		defer func() {
+			conn.W.Flush()
+			conn.W, conn.Held = w, nil
+		}()
+
+		// Writes are logged inside MULTI and EXEC so that replaying the AOF applies them
+		// together as well
+		aof.BeginTransaction()
+		defer aof.EndTransaction()
+		for _, cmd := range queued {
+			ExecuteCommands(cmd, conn)
+		}
+	})
+
+	if aborted {
+		conn.W.Write(nullArray(conn))
+		return
+	}
+	conn.W.Write([]byte("*" + strconv.Itoa(len(queued)) + "\r\n"))
+	conn.W.Write(replies.Bytes())
+}
+
+// watch marks keys to be checked by the next EXEC, which fails if any of them is modified in
+// the meantime
+func watch(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'watch' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if conn.InMulti {
+		msg := resp.SimpleError{Val: []byte("ERR WATCH inside MULTI is not allowed")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'watch' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys := argv[1:]
+
+	if conn.Watcher == nil {
+		conn.Watcher = db.NewWatcher()
+	}
+	do(conn, keys, func(ks *db.Keyspace) {
+		for _, key := range keys {
+			ks.Watch(key, conn.Watcher)
+		}
+	})
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// unwatch forgets every watched key
+func unwatch(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'unwatch' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	unwatchAll(conn)
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// resetMulti leaves the MULTI state, dropping the queued commands
+func resetMulti(conn *pubsub.Connection) {
+	conn.InMulti = false
+	conn.Queued = nil
+	conn.MultiFailed = false
+}
+
+// unwatchAll drops the registrations of every key watched by the connection
+func unwatchAll(conn *pubsub.Connection) {
+	if conn.Watcher == nil {
+		return
+	}
+	w := conn.Watcher
+	do(conn, w.Keys(), func(ks *db.Keyspace) {
+		ks.Unwatch(w)
+	})
+	conn.Watcher = nil
+}
+
+// CloseConnection releases the server side state of a connection that went away
+func CloseConnection(conn *pubsub.Connection) {
+	unwatchAll(conn)
+	stopTracking(conn)
+	pubsub.Instance.Forget(conn)
+
+	clients.Lock()
+	delete(clients.byID, conn.ID)
+	clients.Unlock()
+}
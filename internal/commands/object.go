@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// object implements OBJECT ENCODING, which reports how the value of a key is stored
+func object(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'object' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'object' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	if strings.ToLower(argv[1]) != "encoding" {
+		msg := resp.SimpleError{Val: []byte("ERR unknown subcommand '" + argv[1] + "'")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if len(argv) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'object|encoding' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{argv[2]}, func(ks *db.Keyspace) {
+		e := ks.Lookup(argv[2])
+		if e == nil {
+			res = nullReply(conn)
+			return
+		}
+		res = bulkString(e.Encoding())
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// persist removes the expiry of a key, it replies 1 if a ttl was removed and 0 otherwise
+func persist(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'persist' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'persist' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	keyStr := string(key.Str)
+	res := resp.Integer{Val: 0}
+	do(conn, []string{keyStr}, func(ks *db.Keyspace) {
+		e := ks.Lookup(keyStr)
+		if e == nil || e.ExpiresAt.IsZero() {
+			return
+		}
+		ks.SetExpiry(keyStr, time.Time{})
+		ks.Touch(keyStr)
+		aof.Feed([]byte("PERSIST"), key.Str)
+		db.Notify(db.NotifyGeneric, "persist", keyStr)
+		res.Val = 1
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -6,7 +6,7 @@ import (
 )
 
 func ping(args *resp.Array, conn *pubsub.Connection) {
-	if len(conn.Channels) > 0 {
+	if conn.Subscribed() && !conn.RESP3() {
 		if len(args.Val) > 2 {
 			msg := resp.SimpleError{Val: []byte("wrong number of arguments for 'ping' command")}
 			conn.W.Write(msg.ToBytes())
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// psubscribe subscribes the client to glob-style patterns, it then receives the messages
+// published to every channel matching one of them
+func psubscribe(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'psubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	patterns, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'psubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	pubsub.Instance.Mu.Lock()
+	for _, pattern := range patterns[1:] {
+		conn.Patterns[pattern] = struct{}{} // update the connection to pattern mapping
+		// update the pattern to connection mapping
+		if _, ok := pubsub.Instance.PatternToClient[pattern]; !ok {
+			pubsub.Instance.PatternToClient[pattern] = map[*pubsub.Connection]struct{}{conn: {}}
+		} else {
+			pubsub.Instance.PatternToClient[pattern][conn] = struct{}{}
+		}
+		res := pushReply(conn, []resp.Message{
+			bulkString("psubscribe"),
+			bulkString(pattern),
+			&resp.Integer{Val: int64(conn.Subscriptions())},
+		})
+		conn.W.Write(res.ToBytes())
+	}
+	pubsub.Instance.Mu.Unlock()
+}
+
+// punsubscribe unsubscribes the client from the given patterns, or from all of its patterns
+// when none is given
+func punsubscribe(args *resp.Array, conn *pubsub.Connection) {
+	patterns, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'punsubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	patterns = patterns[1:]
+
+	pubsub.Instance.Mu.Lock()
+	defer pubsub.Instance.Mu.Unlock()
+
+	if len(patterns) == 0 {
+		// Without patterns there is still one confirmation, with a null pattern
+		if len(conn.Patterns) == 0 {
+			res := pushReply(conn, []resp.Message{
+				bulkString("punsubscribe"),
+				nullReply(conn),
+				&resp.Integer{Val: int64(conn.Subscriptions())},
+			})
+			conn.W.Write(res.ToBytes())
+			return
+		}
+		for pattern := range conn.Patterns {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	for _, pattern := range patterns {
+		delete(conn.Patterns, pattern) // unlink the pattern from the connection struct
+		// unlink the connection from the pattern to client mapping, dropping patterns nobody
+		// is subscribed to anymore
+		if cons, ok := pubsub.Instance.PatternToClient[pattern]; ok {
+			delete(cons, conn)
+			if len(cons) == 0 {
+				delete(pubsub.Instance.PatternToClient, pattern)
+			}
+		}
+		res := pushReply(conn, []resp.Message{
+			bulkString("punsubscribe"),
+			bulkString(pattern),
+			&resp.Integer{Val: int64(conn.Subscriptions())},
+		})
+		conn.W.Write(res.ToBytes())
+	}
+}
@@ -1,10 +1,19 @@
 package commands
 
 import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
 )
 
+// Keyspace events are published to ordinary channels, the same way PUBLISH does it
+func init() {
+	db.SetNotifier(func(channel, message string) {
+		publishMessage(bulkString(channel), bulkString(message))
+	})
+}
+
 func publish(args *resp.Array, conn *pubsub.Connection) {
 	if len(args.Val) != 3 {
 		msg := resp.SimpleError{
@@ -32,20 +41,68 @@ func publish(args *resp.Array, conn *pubsub.Connection) {
 		return
 	}
 
-	payload := resp.Array{
-		Val: []resp.Message{
-			&resp.BulkString{Str: []byte("message"), Size: 7},
-			channel,
-			message,
-		},
+	count := publishMessage(channel, message)
+	res := resp.Integer{Val: count}
+	conn.W.Write(res.ToBytes())
+}
+
+// publishMessage delivers a message to the subscribers of channel and of the patterns
+// matching it, and returns how many deliveries were made
+func publishMessage(channel, message *resp.BulkString) int64 {
+	payload := []resp.Message{
+		&resp.BulkString{Str: []byte("message"), Size: 7},
+		channel,
+		message,
 	}
 
 	cons := pubsub.Instance.GetMap(string(channel.Str))
+	patterns := pubsub.Instance.GetPatterns(func(pattern string) bool {
+		return utils.MatchPattern(pattern, string(channel.Str))
+	})
 
+	// A client subscribed to the channel and to matching patterns gets the message once for
+	// each of them, and counts as many times
 	count := int64(len(cons))
+	for _, pcons := range patterns {
+		count += int64(len(pcons))
+	}
 
-	go pubsub.Instance.DeliverMessage(cons, payload.ToBytes())
+	pubsub.Instance.DeliverMessage(cons, payload)
+	for pattern, pcons := range patterns {
+		pubsub.Instance.DeliverMessage(pcons, []resp.Message{
+			bulkString("pmessage"),
+			bulkString(pattern),
+			channel,
+			message,
+		})
+	}
 
-	res := resp.Integer{Val: count}
+	return count
+}
+
+// spublish sends a message to the subscribers of a shard channel. Only the slot of the
+// channel is locked, so publishing to channels of different slots doesn't contend
+func spublish(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'spublish' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'spublish' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	cons := pubsub.Instance.ShardSubscribers(argv[1])
+	pubsub.DeliverTo(cons, []resp.Message{
+		bulkString("smessage"),
+		bulkString(argv[1]),
+		bulkString(argv[2]),
+	})
+
+	res := resp.Integer{Val: int64(len(cons))}
 	conn.W.Write(res.ToBytes())
 }
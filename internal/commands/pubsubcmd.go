@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+// pubsubCommand handles the PUBSUB command, which describes the state of the pub/sub
+// registries
+func pubsubCommand(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'pubsub' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'pubsub' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	sub := strings.ToLower(argv[1])
+	switch sub {
+	case "channels", "shardchannels":
+		// PUBSUB CHANNELS [pattern]
+		// PUBSUB SHARDCHANNELS [pattern]
+		if len(argv) > 3 {
+			msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'pubsub|" + sub + "' command")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		match := func(string) bool { return true }
+		if len(argv) == 3 {
+			match = func(channel string) bool { return utils.MatchPattern(argv[2], channel) }
+		}
+		var names []string
+		if sub == "channels" {
+			names = pubsub.Instance.ChannelNames(match)
+		} else {
+			names = pubsub.Instance.ShardChannelNames(match)
+		}
+		res := make([]resp.Message, 0, len(names))
+		for _, name := range names {
+			res = append(res, bulkString(name))
+		}
+		conn.W.Write((&resp.Array{Val: res}).ToBytes())
+	case "numsub", "shardnumsub":
+		// PUBSUB NUMSUB [channel [channel ...]]
+		// PUBSUB SHARDNUMSUB [channel [channel ...]]
+		numSub := pubsub.Instance.NumSub
+		if sub == "shardnumsub" {
+			numSub = pubsub.Instance.ShardNumSub
+		}
+		// Redis answers with a flat array of channel and count pairs even in RESP3
+		res := make([]resp.Message, 0, 2*(len(argv)-2))
+		for _, channel := range argv[2:] {
+			res = append(res, bulkString(channel), &resp.Integer{Val: int64(numSub(channel))})
+		}
+		conn.W.Write((&resp.Array{Val: res}).ToBytes())
+	case "numpat":
+		// PUBSUB NUMPAT
+		if len(argv) != 2 {
+			msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'pubsub|numpat' command")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		res := resp.Integer{Val: int64(pubsub.Instance.NumPat())}
+		conn.W.Write(res.ToBytes())
+	default:
+		msg := resp.SimpleError{Val: []byte("ERR unknown subcommand '" + argv[1] + "'")}
+		conn.W.Write(msg.ToBytes())
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// sadd adds members to a set and replies with the number of members that were new
+func sadd(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'sadd' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'sadd' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, members := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		set, err := ks.CreateSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		added := 0
+		for _, member := range members {
+			if set.Add(member) {
+				added++
+			}
+		}
+		if added > 0 {
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifySet, "sadd", key)
+		}
+		res = &resp.Integer{Val: int64(added)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
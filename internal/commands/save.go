@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/rdb"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// persistence tracks the state shared by SAVE, BGSAVE and LASTSAVE
+var persistence = struct {
+	mu         sync.Mutex
+	inProgress bool  // a background save is running
+	lastSave   int64 // unix time of the last successful save
+}{lastSave: time.Now().Unix()}
+
+// RDBPath returns the location of the snapshot file based on the dir and dbfilename config
+func RDBPath() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return filepath.Join(ServerConfig["dir"], ServerConfig["dbfilename"])
+}
+
+func save(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'save' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	persistence.mu.Lock()
+	defer persistence.mu.Unlock()
+
+	if persistence.inProgress {
+		msg := resp.SimpleError{Val: []byte("ERR Background save already in progress")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	if err := rdb.Save(RDBPath(), db.TakeSnapshot(nil)); err != nil {
+		log.Printf("RDB: Save failed: %v", err)
+		msg := resp.SimpleError{Val: []byte("ERR " + err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	persistence.lastSave = time.Now().Unix()
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+func bgsave(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'bgsave' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	persistence.mu.Lock()
+	if persistence.inProgress {
+		persistence.mu.Unlock()
+		msg := resp.SimpleError{Val: []byte("ERR Background save already in progress")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	persistence.inProgress = true
+	persistence.mu.Unlock()
+
+	// The copy is taken up front so that the dump reflects the moment BGSAVE was issued,
+	// only the encoding and disk IO happen in the background
+	snap := db.TakeSnapshot(nil)
+	path := RDBPath()
+
+	go func() {
+		err := rdb.Save(path, snap)
+
+		persistence.mu.Lock()
+		defer persistence.mu.Unlock()
+		persistence.inProgress = false
+		if err != nil {
+			log.Printf("RDB: Background save failed: %v", err)
+			return
+		}
+		persistence.lastSave = time.Now().Unix()
+		log.Printf("RDB: Background saving terminated with success")
+	}()
+
+	msg := resp.SimpleString{Val: []byte("Background saving started")}
+	conn.W.Write(msg.ToBytes())
+}
+
+func lastsave(_ *resp.Array, conn *pubsub.Connection) {
+	persistence.mu.Lock()
+	res := resp.Integer{Val: persistence.lastSave}
+	persistence.mu.Unlock()
+	conn.W.Write(res.ToBytes())
+}
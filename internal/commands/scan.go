@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+// scanOptions are the arguments shared by the SCAN family
+type scanOptions struct {
+	cursor   uint64
+	match    string // empty when every member is wanted
+	count    int
+	noValues bool // HSCAN only
+}
+
+// parseScanOptions parses `cursor [MATCH pattern] [COUNT count]` starting at args.Val[2],
+// NOVALUES is accepted when allowNoValues is set. The error is the reply to send
+func parseScanOptions(args *resp.Array, allowNoValues bool) (scanOptions, *resp.SimpleError) {
+	opts := scanOptions{count: 10}
+
+	cursorArg, ok := args.Val[2].(*resp.BulkString)
+	if !ok {
+		return opts, &resp.SimpleError{Val: []byte("ERR invalid cursor")}
+	}
+	cursor, err := strconv.ParseUint(string(cursorArg.Str), 10, 64)
+	if err != nil {
+		return opts, &resp.SimpleError{Val: []byte("ERR invalid cursor")}
+	}
+	opts.cursor = cursor
+
+	for i := 3; i < len(args.Val); i++ {
+		opt, ok := args.Val[i].(*resp.BulkString)
+		if !ok {
+			return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+		}
+		switch strings.ToLower(string(opt.Str)) {
+		case "match":
+			if i+1 >= len(args.Val) {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			pattern, ok := args.Val[i+1].(*resp.BulkString)
+			if !ok {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			opts.match = string(pattern.Str)
+			if opts.match == "*" {
+				opts.match = ""
+			}
+			i++
+		case "count":
+			if i+1 >= len(args.Val) {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			countArg, ok := args.Val[i+1].(*resp.BulkString)
+			if !ok {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			count, err := strconv.Atoi(string(countArg.Str))
+			if err != nil {
+				return opts, &resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+			}
+			if count < 1 {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			opts.count = count
+			i++
+		case "novalues":
+			if !allowNoValues {
+				return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+			}
+			opts.noValues = true
+		default:
+			return opts, &resp.SimpleError{Val: []byte("ERR syntax error")}
+		}
+	}
+	return opts, nil
+}
+
+// scanMatch keeps the members of a batch matching the MATCH pattern. Like Redis the pattern
+// filters the members after the batch was picked, so a batch may be empty while the cursor
+// is not 0
+func scanMatch(batch []string, opts scanOptions) []string {
+	if opts.match == "" {
+		return batch
+	}
+	matched := batch[:0]
+	for _, member := range batch {
+		if utils.MatchPattern(opts.match, member) {
+			matched = append(matched, member)
+		}
+	}
+	return matched
+}
+
+// scanReply builds the two element reply of the SCAN family
+func scanReply(cursor uint64, items []string) *resp.Array {
+	list := utils.GetRespArrayBulkString(items)
+	return &resp.Array{Val: []resp.Message{bulkString(strconv.FormatUint(cursor, 10)), &list}}
+}
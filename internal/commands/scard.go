@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// scard replies with the number of members in a set, 0 if the key doesn't exist
+func scard(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'scard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'scard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		set, err := ks.Set(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if set == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		res = &resp.Integer{Val: int64(set.Len())}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
@@ -40,7 +41,7 @@ func set(args *resp.Array, conn *pubsub.Connection) {
 	ttl := int64(-1) // default: no expiry
 	nx := false
 
-	// Parse optional arguments: NX, EX, PX
+	// Parse optional arguments: NX, EX, PX, EXAT, PXAT
 	i := 3
 	for i < len(args.Val) {
 		opt, ok := args.Val[i].(*resp.BulkString)
@@ -58,7 +59,7 @@ func set(args *resp.Array, conn *pubsub.Connection) {
 		case "nx":
 			nx = true
 			i++
-		case "ex", "px":
+		case "ex", "px", "exat", "pxat":
 			if i+1 >= len(args.Val) {
 				msg := resp.SimpleError{
 					Val: []byte("syntax error"),
@@ -85,10 +86,18 @@ func set(args *resp.Array, conn *pubsub.Connection) {
 				return
 			}
 
-			if optStr == "ex" {
+			switch optStr {
+			case "ex":
 				ttl = parsedTTL * 1000 // convert seconds to milliseconds
-			} else {
+			case "px":
 				ttl = parsedTTL
+			case "exat", "pxat":
+				deadline := parsedTTL
+				if optStr == "exat" {
+					deadline *= 1000
+				}
+				// A deadline that already passed leaves a key that is expired right away
+				ttl = max(deadline-time.Now().UnixMilli(), 1)
 			}
 			i += 2
 		default:
@@ -103,13 +112,11 @@ func set(args *resp.Array, conn *pubsub.Connection) {
 	channel := make(chan []byte, 1)
 	cmd := db.NewCommandWithOptions(keyStr, val.Str, ttl, channel, db.SET, nx)
 
-	// Route to the appropriate shard based on key
-	shardCh := db.GetShardChannel(keyStr)
-	shardCh <- cmd
+	dispatch(conn, cmd)
 
 	result, ok := <-channel
 	if ok {
-		conn.W.Write(result)
+		writeShardReply(conn, result)
 		close(channel)
 	}
 }
@@ -147,8 +154,7 @@ func setnx(args *resp.Array, conn *pubsub.Connection) {
 	channel := make(chan []byte, 1)
 	cmd := db.NewCommandWithOptions(keyStr, val.Str, -1, channel, db.SET, true) // nx=true
 
-	shardCh := db.GetShardChannel(keyStr)
-	shardCh <- cmd
+	dispatch(conn, cmd)
 
 	result, ok := <-channel
 	if ok {
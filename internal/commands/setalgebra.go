@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+type setOp int
+
+const (
+	setUnion setOp = iota
+	setInter
+	setDiff
+)
+
+func sunion(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraCommand(args, conn, "sunion", setUnion)
+}
+
+func sinter(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraCommand(args, conn, "sinter", setInter)
+}
+
+func sdiff(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraCommand(args, conn, "sdiff", setDiff)
+}
+
+func sunionstore(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraStoreCommand(args, conn, "sunionstore", setUnion)
+}
+
+func sinterstore(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraStoreCommand(args, conn, "sinterstore", setInter)
+}
+
+func sdiffstore(args *resp.Array, conn *pubsub.Connection) {
+	setAlgebraStoreCommand(args, conn, "sdiffstore", setDiff)
+}
+
+// setAlgebra computes op over the sets stored at keys, a missing key is an empty set. Every
+// key must be held by ks, which is what makes the result consistent across shards
+func setAlgebra(ks *db.Keyspace, op setOp, keys []string) ([]string, error) {
+	sets := make([]*db.SetEntry, len(keys))
+	for i, key := range keys {
+		set, err := ks.Set(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	var result []string
+	switch op {
+	case setUnion:
+		seen := make(map[string]struct{})
+		for _, set := range sets {
+			if set == nil {
+				continue
+			}
+			for member := range set.Members() {
+				if _, ok := seen[member]; !ok {
+					seen[member] = struct{}{}
+					result = append(result, member)
+				}
+			}
+		}
+	case setInter:
+		if slices.Contains(sets, nil) {
+			return nil, nil
+		}
+		// Walking the smallest set keeps the number of lookups down
+		slices.SortFunc(sets, func(a, b *db.SetEntry) int { return a.Len() - b.Len() })
+		for member := range sets[0].Members() {
+			if setsContain(sets[1:], member) {
+				result = append(result, member)
+			}
+		}
+	case setDiff:
+		if sets[0] == nil {
+			return nil, nil
+		}
+		for member := range sets[0].Members() {
+			if !setsContainAny(sets[1:], member) {
+				result = append(result, member)
+			}
+		}
+	}
+	return result, nil
+}
+
+// setsContain reports whether every set contains member
+func setsContain(sets []*db.SetEntry, member string) bool {
+	for _, set := range sets {
+		if !set.Contains(member) {
+			return false
+		}
+	}
+	return true
+}
+
+// setsContainAny reports whether one of the sets contains member, nil sets are empty
+func setsContainAny(sets []*db.SetEntry, member string) bool {
+	for _, set := range sets {
+		if set != nil && set.Contains(member) {
+			return true
+		}
+	}
+	return false
+}
+
+// setAlgebraCommand implements SUNION, SINTER and SDIFF
+func setAlgebraCommand(args *resp.Array, conn *pubsub.Connection, name string, op setOp) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys := argv[1:]
+
+	var res resp.Message
+	do(conn, keys, func(ks *db.Keyspace) {
+		members, err := setAlgebra(ks, op, keys)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		arr := utils.GetRespArrayBulkString(members)
+		res = setReply(conn, arr.Val)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// setAlgebraStoreCommand implements SUNIONSTORE, SINTERSTORE and SDIFFSTORE. The destination
+// is replaced by the result, or deleted if the result is empty
+func setAlgebraStoreCommand(args *resp.Array, conn *pubsub.Connection, name string, op setOp) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	destination, keys := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, argv[1:], func(ks *db.Keyspace) {
+		members, err := setAlgebra(ks, op, keys)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		// An empty result deletes the destination, which is only an event if it existed
+		existed := false
+		if len(members) == 0 {
+			existed = ks.Delete(destination)
+		} else {
+			ks.Put(destination, &db.Entry{Type: db.TypeSet, Set: db.NewSetEntry(members)})
+		}
+		ks.Touch(destination)
+		propagate(args)
+		switch {
+		case len(members) > 0:
+			db.Notify(db.NotifySet, name, destination)
+		case existed:
+			db.Notify(db.NotifyGeneric, "del", destination)
+		}
+		res = &resp.Integer{Val: int64(len(members))}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// sintercard replies with the size of the intersection of the sets, counting stops once
+// LIMIT members were found
+func sintercard(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'sintercard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'sintercard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	numKeys, err := strconv.Atoi(argv[1])
+	if err != nil || numKeys <= 0 {
+		msg := resp.SimpleError{Val: []byte("ERR numkeys should be greater than 0")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if numKeys > len(argv)-2 {
+		msg := resp.SimpleError{Val: []byte("ERR Number of keys can't be greater than number of args")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys := argv[2 : 2+numKeys]
+
+	limit := 0
+	for i := 2 + numKeys; i < len(argv); i += 2 {
+		if strings.ToLower(argv[i]) != "limit" || i+1 >= len(argv) {
+			msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		limit, err = strconv.Atoi(argv[i+1])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		if limit < 0 {
+			msg := resp.SimpleError{Val: []byte("ERR LIMIT can't be negative")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, keys, func(ks *db.Keyspace) {
+		sets := make([]*db.SetEntry, len(keys))
+		for i, key := range keys {
+			set, err := ks.Set(key)
+			if err != nil {
+				res = &resp.SimpleError{Val: []byte(err.Error())}
+				return
+			}
+			sets[i] = set
+		}
+		if slices.Contains(sets, nil) {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		slices.SortFunc(sets, func(a, b *db.SetEntry) int { return a.Len() - b.Len() })
+		count := 0
+		for member := range sets[0].Members() {
+			if setsContain(sets[1:], member) {
+				count++
+				if count == limit {
+					break
+				}
+			}
+		}
+		res = &resp.Integer{Val: int64(count)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
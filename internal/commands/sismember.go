@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// sismember replies 1 if member is in the set stored at key, 0 otherwise
+func sismember(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'sismember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'sismember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, member := argv[1], argv[2]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		set, err := ks.Set(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if set != nil && set.Contains(member) {
+			res = &resp.Integer{Val: 1}
+			return
+		}
+		res = &resp.Integer{Val: 0}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// smismember replies with 1 or 0 for every member, telling whether it is in the set
+func smismember(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'smismember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'smismember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, members := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		set, err := ks.Set(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		arr := &resp.Array{Val: make([]resp.Message, 0, len(members))}
+		for _, member := range members {
+			if set != nil && set.Contains(member) {
+				arr.Val = append(arr.Val, &resp.Integer{Val: 1})
+				continue
+			}
+			arr.Val = append(arr.Val, &resp.Integer{Val: 0})
+		}
+		res = arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
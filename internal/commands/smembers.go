@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"slices"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+// smembers replies with every member of a set, empty if the key doesn't exist
+func smembers(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'smembers' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'smembers' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		set, err := ks.Set(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		var members []string
+		if set != nil {
+			members = slices.Collect(set.Members())
+		}
+		arr := utils.GetRespArrayBulkString(members)
+		res = setReply(conn, arr.Val)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// smove moves a member from one set to another, it replies 1 if the member was moved and 0
+// if it wasn't in the source set. Both keys are held for the whole move, so no client can
+// see the member in neither or both sets even when they live on different shards
+func smove(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'smove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'smove' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	source, destination, member := argv[1], argv[2], argv[3]
+
+	var res resp.Message
+	do(conn, []string{source, destination}, func(ks *db.Keyspace) {
+		src, err := ks.Set(source)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if _, err := ks.Set(destination); err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		if src == nil || !src.Contains(member) {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		if source == destination {
+			res = &resp.Integer{Val: 1}
+			return
+		}
+
+		src.Remove(member)
+		if src.Len() == 0 {
+			ks.Delete(source)
+		}
+		dst, _ := ks.CreateSet(destination)
+		dst.Add(member)
+		ks.Touch(source)
+		ks.Touch(destination)
+		propagate(args)
+		db.Notify(db.NotifySet, "srem", source)
+		if src.Len() == 0 {
+			db.Notify(db.NotifyGeneric, "del", source)
+		}
+		db.Notify(db.NotifySet, "sadd", destination)
+		res = &resp.Integer{Val: 1}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
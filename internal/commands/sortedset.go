@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+var (
+	errSyntax        = errors.New("ERR syntax error")
+	errNotInteger    = errors.New("ERR value is not an integer or out of range")
+	errNotFloat      = errors.New("ERR value is not a valid float")
+	errScoreNaN      = errors.New("ERR resulting score is not a number (NaN)")
+	errMinMaxFloat   = errors.New("ERR min or max is not a float")
+	errMinMaxLex     = errors.New("ERR min or max not valid string range item")
+	errCountNegative = errors.New("ERR value is out of range, must be positive")
+)
+
+// parseScore parses a score argument, "inf", "+inf" and "-inf" are accepted but NaN is not
+func parseScore(s string) (float64, error) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, errNotFloat
+	}
+	return score, nil
+}
+
+// formatScore renders a score the way Redis replies with it, see resp.FormatDouble
+func formatScore(score float64) string {
+	return resp.FormatDouble(score)
+}
+
+// scoreBound is one end of a score range, "(1.5" is an exclusive bound
+type scoreBound struct {
+	value     float64
+	exclusive bool
+}
+
+func parseScoreBound(s string) (scoreBound, error) {
+	var b scoreBound
+	if strings.HasPrefix(s, "(") {
+		b.exclusive = true
+		s = s[1:]
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(value) {
+		return b, errMinMaxFloat
+	}
+	b.value = value
+	return b, nil
+}
+
+// scoreRange returns the ranks [start, stop) of the entries with a score between min and max
+func scoreRange(zset *ds.SortedSet, min, max scoreBound) (int, int) {
+	return zset.ScoreRank(min.value, min.exclusive), zset.ScoreRank(max.value, !max.exclusive)
+}
+
+// rangeLen returns the number of ranks in [start, stop), a range whose min is above its max
+// is empty
+func rangeLen(start, stop int) int {
+	if stop < start {
+		return 0
+	}
+	return stop - start
+}
+
+// lexBound is one end of a lexicographical range: "[a" is inclusive, "(a" exclusive, and
+// "-" and "+" are the smallest and largest possible strings
+type lexBound struct {
+	value     string
+	exclusive bool
+	inf       int // -1 for "-", 1 for "+"
+}
+
+func parseLexBound(s string) (lexBound, error) {
+	switch {
+	case s == "-":
+		return lexBound{inf: -1}, nil
+	case s == "+":
+		return lexBound{inf: 1}, nil
+	case strings.HasPrefix(s, "["):
+		return lexBound{value: s[1:]}, nil
+	case strings.HasPrefix(s, "("):
+		return lexBound{value: s[1:], exclusive: true}, nil
+	}
+	return lexBound{}, errMinMaxLex
+}
+
+// lexRank returns the rank of the first entry after the bound, for a range starting at b
+// when start is set and for a range ending at b otherwise
+func lexRank(zset *ds.SortedSet, b lexBound, start bool) int {
+	switch b.inf {
+	case -1:
+		return 0
+	case 1:
+		return zset.Len()
+	}
+	if start {
+		return zset.LexRank(b.value, b.exclusive)
+	}
+	return zset.LexRank(b.value, !b.exclusive)
+}
+
+// lexRange returns the ranks [start, stop) of the entries with a member between min and max
+func lexRange(zset *ds.SortedSet, min, max lexBound) (int, int) {
+	return lexRank(zset, min, true), lexRank(zset, max, false)
+}
+
+// zsetReply builds the reply listing entries. With scores it is a flat array of members each
+// followed by its score for RESP2 clients, and an array of [member, score] pairs for RESP3
+// ones
+func zsetReply(conn *pubsub.Connection, entries []ds.ZEntry, withScores bool) *resp.Array {
+	if withScores && conn.RESP3() {
+		arr := &resp.Array{Val: make([]resp.Message, 0, len(entries))}
+		for _, e := range entries {
+			arr.Val = append(arr.Val, &resp.Array{Val: []resp.Message{bulkString(e.Member), &resp.Double{Val: e.Score}}})
+		}
+		return arr
+	}
+
+	arr := &resp.Array{Val: make([]resp.Message, 0, 2*len(entries))}
+	for _, e := range entries {
+		arr.Val = append(arr.Val, bulkString(e.Member))
+		if withScores {
+			arr.Val = append(arr.Val, bulkString(formatScore(e.Score)))
+		}
+	}
+	return arr
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+// spop removes and returns random members of a set. Without a count a single member is
+// returned, or null if the key doesn't exist
+func spop(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 || len(args.Val) > 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'spop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'spop' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	count, hasCount := int64(1), len(argv) == 3
+	if hasCount {
+		var err error
+		count, err = strconv.ParseInt(argv[2], 10, 64)
+		if err != nil || count < 0 {
+			msg := resp.SimpleError{Val: []byte("ERR value is out of range, must be positive")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		set, err := ks.Set(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if set == nil {
+			if hasCount {
+				res = &resp.Array{Val: make([]resp.Message, 0)}
+				return
+			}
+			res = nullReply(conn)
+			return
+		}
+
+		var popped []string
+		if count >= int64(set.Len()) {
+			popped = slices.Collect(set.Members())
+		} else if count == 1 {
+			popped = []string{set.Random()}
+		} else {
+			members := slices.Collect(set.Members())
+			rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+			popped = members[:count]
+		}
+		if len(popped) == 0 {
+			res = &resp.Array{Val: make([]resp.Message, 0)}
+			return
+		}
+
+		// The members are picked at random, so the AOF records which ones were removed
+		argv := [][]byte{[]byte("SREM"), []byte(key)}
+		for _, member := range popped {
+			set.Remove(member)
+			argv = append(argv, []byte(member))
+		}
+		if set.Len() == 0 {
+			ks.Delete(key)
+		}
+		ks.Touch(key)
+		aof.Feed(argv...)
+		db.Notify(db.NotifySet, "spop", key)
+		if set.Len() == 0 {
+			db.Notify(db.NotifyGeneric, "del", key)
+		}
+
+		if !hasCount {
+			res = bulkString(popped[0])
+			return
+		}
+		arr := utils.GetRespArrayBulkString(popped)
+		res = setReply(conn, arr.Val)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
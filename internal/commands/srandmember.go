@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/utils"
+)
+
+// srandmember replies with random members of a set without removing them. Without a count a
+// single member is returned, a positive count returns up to count distinct members and a
+// negative one returns exactly -count members that may repeat
+func srandmember(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 || len(args.Val) > 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'srandmember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'srandmember' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	var count int64
+	hasCount := len(argv) == 3
+	if hasCount {
+		var err error
+		count, err = strconv.ParseInt(argv[2], 10, 64)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+			msg := resp.SimpleError{Val: []byte("ERR value is out of range")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		set, err := ks.Set(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		if !hasCount {
+			if set == nil {
+				res = nullReply(conn)
+				return
+			}
+			res = bulkString(set.Random())
+			return
+		}
+
+		var picked []string
+		switch {
+		case set == nil:
+		case count < 0:
+			picked = make([]string, -count)
+			for i := range picked {
+				picked[i] = set.Random()
+			}
+		case count >= int64(set.Len()):
+			picked = slices.Collect(set.Members())
+		case count > 0:
+			members := slices.Collect(set.Members())
+			rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+			picked = members[:count]
+		}
+		arr := utils.GetRespArrayBulkString(picked)
+		res = &arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// sscan iterates over the members of a set, see ds.ScanTable.Scan for the guarantees of the
+// cursor
+func sscan(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'sscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'sscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	opts, errMsg := parseScanOptions(args, false)
+	if errMsg != nil {
+		conn.W.Write(errMsg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		set, err := ks.Set(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if set == nil {
+			res = scanReply(0, nil)
+			return
+		}
+
+		members, cursor := set.Scan(opts.cursor, opts.count)
+		members = scanMatch(members, opts)
+		res = scanReply(cursor, members)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// ssubscribe subscribes the client to shard channels, which receive the messages sent with
+// SPUBLISH. The count in the confirmations only covers shard channels
+func ssubscribe(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'ssubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	channels, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'ssubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	for _, channel := range channels[1:] {
+		pubsub.Instance.ShardSubscribe(conn, channel)
+		res := pushReply(conn, []resp.Message{
+			bulkString("ssubscribe"),
+			bulkString(channel),
+			&resp.Integer{Val: int64(len(conn.ShardChannels))},
+		})
+		conn.W.Write(res.ToBytes())
+	}
+}
+
+// sunsubscribe unsubscribes the client from the given shard channels, or from all of them
+// when none is given
+func sunsubscribe(args *resp.Array, conn *pubsub.Connection) {
+	channels, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'sunsubscribe' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	channels = channels[1:]
+
+	if len(channels) == 0 {
+		// Without channels there is still one confirmation, with a null channel
+		if len(conn.ShardChannels) == 0 {
+			res := pushReply(conn, []resp.Message{
+				bulkString("sunsubscribe"),
+				nullReply(conn),
+				&resp.Integer{Val: 0},
+			})
+			conn.W.Write(res.ToBytes())
+			return
+		}
+		for channel := range conn.ShardChannels {
+			channels = append(channels, channel)
+		}
+	}
+
+	for _, channel := range channels {
+		pubsub.Instance.ShardUnsubscribe(conn, channel)
+		res := pushReply(conn, []resp.Message{
+			bulkString("sunsubscribe"),
+			bulkString(channel),
+			&resp.Integer{Val: int64(len(conn.ShardChannels))},
+		})
+		conn.W.Write(res.ToBytes())
+	}
+}
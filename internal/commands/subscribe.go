@@ -37,13 +37,11 @@ func subscribe(args *resp.Array, conn *pubsub.Connection) {
 		} else {
 			pubsub.Instance.ChannelToClient[string(ch)][conn] = struct{}{}
 		}
-		res := resp.Array{
-			Val: []resp.Message{
-				&resp.BulkString{Str: []byte("subscribe"), Size: 9},
-				&resp.BulkString{Str: ch, Size: len(ch)},
-				&resp.Integer{Val: int64(len(conn.Channels))},
-			},
-		}
+		res := pushReply(conn, []resp.Message{
+			&resp.BulkString{Str: []byte("subscribe"), Size: 9},
+			&resp.BulkString{Str: ch, Size: len(ch)},
+			&resp.Integer{Val: int64(conn.Subscriptions())},
+		})
 		conn.W.Write(res.ToBytes())
 	}
 	pubsub.Instance.Mu.Unlock()
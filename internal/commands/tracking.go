@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// invalidationChannel is the channel RESP2 clients subscribe to when tracking clients
+// redirect their invalidations to them
+const invalidationChannel = "__redis__:invalidate"
+
+// readOnlyCommands lists the commands whose keys are remembered for a client with CLIENT
+// TRACKING on, the commands that only read keys
+var readOnlyCommands = map[string]struct{}{
+	"get": {}, "exists": {}, "type": {}, "object": {},
+	"ttl": {}, "pttl": {}, "expiretime": {}, "pexpiretime": {},
+	"llen": {}, "lrange": {}, "lindex": {}, "lpos": {},
+	"hget": {}, "hmget": {}, "hgetall": {}, "hkeys": {}, "hvals": {}, "hexists": {},
+	"hlen": {}, "hstrlen": {}, "hrandfield": {}, "hscan": {},
+	"smembers": {}, "sismember": {}, "smismember": {}, "scard": {}, "srandmember": {},
+	"sinter": {}, "sintercard": {}, "sunion": {}, "sdiff": {}, "sscan": {},
+	"zscore": {}, "zmscore": {}, "zcard": {}, "zcount": {}, "zlexcount": {}, "zrank": {},
+	"zrevrank": {}, "zrange": {}, "zrevrange": {}, "zrangebyscore": {}, "zrevrangebyscore": {},
+	"zrangebylex": {}, "zrevrangebylex": {}, "zscan": {},
+	"xrange": {}, "xrevrange": {}, "xread": {}, "xlen": {}, "xpending": {}, "xinfo": {},
+}
+
+// trackingReader returns the tracker the keys read by the command name should be
+// remembered for, nil if there is none
+func trackingReader(name string, conn *pubsub.Connection) *db.Tracker {
+	t := conn.Tracker
+	if t == nil || t.BCAST {
+		return nil
+	}
+	if _, ok := readOnlyCommands[name]; !ok {
+		return nil
+	}
+	if (conn.OptIn && !conn.Caching) || (conn.OptOut && conn.Caching) {
+		return nil
+	}
+	return t
+}
+
+// endCaching forgets CLIENT CACHING once the command following it ran. MULTI extends it to
+// the whole transaction, until EXEC or DISCARD
+func endCaching(name string, args *resp.Array, conn *pubsub.Connection) {
+	if !conn.Caching || conn.InMulti || conn.Held != nil {
+		return
+	}
+	if name == "client" && len(args.Val) > 1 {
+		if sub, ok := args.Val[1].(*resp.BulkString); ok && strings.EqualFold(string(sub.Str), "caching") {
+			return
+		}
+	}
+	conn.Caching = false
+}
+
+// sendInvalidation tells the client tracking with conn that key changed. The message goes
+// to conn itself or to the client its invalidations are redirected to: as an invalidate push
+// with RESP3, as a message of __redis__:invalidate with RESP2 if the client subscribed to it.
+// caller is the client that changed key, when it is the one receiving the message it gets it
+// after the reply to its command like Redis does
+func sendInvalidation(conn *pubsub.Connection, redirect int64, key string, caller int64) {
+	target := conn
+	if redirect != 0 {
+		if target = lookupClient(redirect); target == nil {
+			// The invalidations are lost, RESP3 clients are told so they can flush their cache
+			if conn.RESP3() {
+				pubsub.DeliverTo([]*pubsub.Connection{conn}, []resp.Message{
+					bulkString("tracking-redir-broken"),
+					&resp.Integer{Val: redirect},
+				})
+			}
+			return
+		}
+	}
+
+	keys := &resp.Array{Val: []resp.Message{bulkString(key)}}
+	var payload []byte
+	switch {
+	case target.RESP3():
+		payload = (&resp.Push{Val: []resp.Message{bulkString("invalidate"), keys}}).ToBytes()
+	case pubsub.Instance.IsSubscribed(target, invalidationChannel):
+		payload = (&resp.Array{Val: []resp.Message{
+			bulkString("message"),
+			bulkString(invalidationChannel),
+			keys,
+		}}).ToBytes()
+	default:
+		return
+	}
+	if caller == target.ID {
+		target.PushAfterReply(payload)
+		return
+	}
+	target.Push(payload)
+}
+
+// stopTracking turns CLIENT TRACKING off for conn and drops the keys it was tracking
+func stopTracking(conn *pubsub.Connection) {
+	t := conn.Tracker
+	if t == nil {
+		return
+	}
+	t.Stop()
+	do(conn, t.Keys(), func(ks *db.Keyspace) {
+		ks.Untrack(t)
+	})
+	conn.Tracker = nil
+	conn.OptIn, conn.OptOut, conn.Caching = false, false, false
+}
+
+// clientTracking handles CLIENT TRACKING ON|OFF [REDIRECT id] [PREFIX prefix ...] [BCAST]
+// [OPTIN] [OPTOUT] [NOLOOP]. Calling it again while tracking is on changes the redirection
+// and NOLOOP and adds prefixes, the keys already tracked stay tracked
+func clientTracking(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'client|tracking' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'client' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var on bool
+	switch strings.ToLower(argv[2]) {
+	case "on":
+		on = true
+	case "off":
+	default:
+		msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var redirect int64
+	var prefixes []string
+	var bcast, optin, optout, noloop bool
+	for i := 3; i < len(argv); i++ {
+		switch strings.ToLower(argv[i]) {
+		case "redirect":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			id, err := strconv.ParseInt(argv[i+1], 10, 64)
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			redirect = id
+			i++
+		case "prefix":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			prefixes = append(prefixes, argv[i+1])
+			i++
+		case "bcast":
+			bcast = true
+		case "optin":
+			optin = true
+		case "optout":
+			optout = true
+		case "noloop":
+			noloop = true
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	if !on {
+		stopTracking(conn)
+		conn.W.Write([]byte("+OK\r\n"))
+		return
+	}
+
+	var errMsg string
+	current := conn.Tracker
+	switch {
+	case len(prefixes) > 0 && !bcast:
+		errMsg = "ERR PREFIX option requires BCAST mode to be enabled"
+	case optin && optout:
+		errMsg = "ERR You can't use both OPTIN and OPTOUT"
+	case bcast && (optin || optout):
+		errMsg = "ERR OPTIN and OPTOUT are not compatible with BCAST"
+	case current != nil && current.BCAST != bcast:
+		errMsg = "ERR You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode."
+	case current != nil && (conn.OptIn != optin || conn.OptOut != optout):
+		errMsg = "ERR You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode."
+	case redirect != 0 && lookupClient(redirect) == nil:
+		errMsg = "ERR The client ID you want redirect to does not exist"
+	}
+	if errMsg != "" {
+		msg := resp.SimpleError{Val: []byte(errMsg)}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	t := current
+	if t == nil {
+		t = db.NewTracker(conn.ID, bcast, func(key string, caller int64) {
+			sendInvalidation(conn, t.Redirect.Load(), key, caller)
+		})
+		conn.Tracker, conn.OptIn, conn.OptOut = t, optin, optout
+	}
+	t.Redirect.Store(redirect)
+	t.NoLoop.Store(noloop)
+	if bcast {
+		// Without PREFIX every key is broadcast
+		if len(prefixes) == 0 {
+			prefixes = []string{""}
+		}
+		t.Broadcast(prefixes)
+	}
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// clientCaching handles CLIENT CACHING YES|NO, which decides whether the keys read by the
+// next command are tracked: YES in OPTIN mode, NO in OPTOUT mode
+func clientCaching(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'client|caching' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'client' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var errMsg string
+	switch mode := strings.ToLower(argv[2]); {
+	case conn.Tracker == nil || (!conn.OptIn && !conn.OptOut):
+		errMsg = "ERR CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled"
+	case mode == "yes" && !conn.OptIn:
+		errMsg = "ERR CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode."
+	case mode == "no" && !conn.OptOut:
+		errMsg = "ERR CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode."
+	case mode != "yes" && mode != "no":
+		errMsg = errSyntax.Error()
+	}
+	if errMsg != "" {
+		msg := resp.SimpleError{Val: []byte(errMsg)}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	conn.Caching = true
+	conn.W.Write([]byte("+OK\r\n"))
+}
+
+// clientGetRedir handles CLIENT GETREDIR: the id of the client invalidations are redirected
+// to, 0 when they aren't redirected and -1 when tracking is off
+func clientGetRedir(conn *pubsub.Connection) {
+	redirect := int64(-1)
+	if conn.Tracker != nil {
+		redirect = conn.Tracker.Redirect.Load()
+	}
+	msg := resp.Integer{Val: redirect}
+	conn.W.Write(msg.ToBytes())
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func ttl(args *resp.Array, conn *pubsub.Connection) {
+	ttlGeneric(args, conn, "ttl", false, false)
+}
+
+func pttl(args *resp.Array, conn *pubsub.Connection) {
+	ttlGeneric(args, conn, "pttl", true, false)
+}
+
+func expiretime(args *resp.Array, conn *pubsub.Connection) {
+	ttlGeneric(args, conn, "expiretime", false, true)
+}
+
+func pexpiretime(args *resp.Array, conn *pubsub.Connection) {
+	ttlGeneric(args, conn, "pexpiretime", true, true)
+}
+
+// ttlGeneric implements TTL, PTTL, EXPIRETIME and PEXPIRETIME. It replies -2 if the key
+// doesn't exist and -1 if it has no expiry, otherwise either the remaining time or the
+// unix time at which the key expires
+func ttlGeneric(args *resp.Array, conn *pubsub.Connection, name string, millis bool, absolute bool) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	keyStr := string(key.Str)
+	res := resp.Integer{Val: -2}
+	do(conn, []string{keyStr}, func(ks *db.Keyspace) {
+		e := ks.Lookup(keyStr)
+		if e == nil {
+			return
+		}
+		if e.ExpiresAt.IsZero() {
+			res.Val = -1
+			return
+		}
+
+		ms := e.ExpiresAt.UnixMilli()
+		if !absolute {
+			ms = max(time.Until(e.ExpiresAt).Milliseconds(), 0)
+		}
+		switch {
+		case millis:
+			res.Val = ms
+		case absolute:
+			res.Val = ms / 1000
+		default:
+			res.Val = (ms + 500) / 1000 // rounded like Redis does
+		}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -23,19 +23,13 @@ func unsubscribe(args *resp.Array, conn *pubsub.Connection) {
 		return
 	}
 	delete(conn.Channels, string(channel.Str)) // unlink the channel from the connection struct
-	pubsub.Instance.Mu.Lock()
 	// unlink the connection from the channel to client mapping IF it exists
-	if _, ok := pubsub.Instance.ChannelToClient[string(channel.Str)]; ok {
-		delete(pubsub.Instance.ChannelToClient[string(channel.Str)], conn)
-	}
-	pubsub.Instance.Mu.Unlock()
+	pubsub.Instance.Unsubscribe(conn, string(channel.Str))
 
-	res := resp.Array{
-		Val: []resp.Message{
-			&resp.BulkString{Str: []byte("unsubscribe"), Size: 11},
-			channel,
-			&resp.Integer{Val: int64(len(conn.Channels))},
-		},
-	}
+	res := pushReply(conn, []resp.Message{
+		&resp.BulkString{Str: []byte("unsubscribe"), Size: 11},
+		channel,
+		&resp.Integer{Val: int64(conn.Subscriptions())},
+	})
 	conn.W.Write(res.ToBytes())
 }
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xack removes entries from the PEL of a group once their consumer processed them, and
+// returns how many of them were pending
+func xack(args *resp.Array, conn *pubsub.Connection) {
+	// XACK key group id [id ...]
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xack' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xack' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, group := argv[1], argv[2]
+	ids := make([]streams.StreamID, 0, len(argv)-3)
+	for _, raw := range argv[3:] {
+		id, err := parseStrictID(raw)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(err.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		// A missing key or group has nothing to acknowledge
+		var g *streams.ConsumerGroup
+		if stream != nil {
+			g = stream.Group(group)
+		}
+		if g == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		acked := [][]byte{[]byte("XACK"), []byte(key), []byte(group)}
+		for _, id := range ids {
+			if g.Ack(id) {
+				acked = append(acked, []byte(id.String()))
+			}
+		}
+		if len(acked) > 3 {
+			ks.Touch(key)
+			aof.Feed(acked...)
+		}
+		res = &resp.Integer{Val: int64(len(acked) - 3)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
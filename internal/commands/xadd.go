@@ -1,145 +1,140 @@
 package commands
 
 import (
+	"strings"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 	"github.com/codecrafters-io/redis-starter-go/internal/streams"
 )
 
+// xadd appends an entry to a stream, creating the stream unless NOMKSTREAM is given, and
+// optionally trims the stream once the entry is added
 func xadd(args *resp.Array, conn *pubsub.Connection) {
+	// XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]
 	if len(args.Val) < 5 {
-		msg := resp.SimpleError{Val: []byte("too few arguments for 'xadd' command")}
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xadd' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
-	streamKey, ok := args.Val[1].(*resp.BulkString)
+
+	argv, ok := stringArgs(args)
 	if !ok {
 		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xadd' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	rawStreamID, ok := args.Val[2].(*resp.BulkString)
-	if !ok {
-		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xadd' command")}
+	key := argv[1]
+	rest := argv[2:]
+	noMkStream := false
+	var trim *trimSpec
+	for len(rest) > 0 {
+		opt := strings.ToLower(rest[0])
+		if opt == "nomkstream" {
+			noMkStream = true
+			rest = rest[1:]
+			continue
+		}
+		if opt != "maxlen" && opt != "minid" {
+			break
+		}
+		spec, next, err := parseTrim(rest)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(err.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		trim = &spec
+		rest = next
+	}
+	if len(rest) < 3 || len(rest)%2 != 1 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xadd' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	streamID, err := streams.NewStreamID(string(rawStreamID.Str))
+	streamID, err := streams.NewStreamID(rest[0])
 	if err != nil {
-		msg := resp.SimpleError{Val: []byte("Invalid stream ID for 'xadd' command")}
+		msg := resp.SimpleError{Val: []byte(errInvalidStreamID.Error())}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	hashmap := make(map[string]string)
-	for i := 3; i < len(args.Val); i += 2 {
-		if i+1 > len(args.Val) {
-			msg := resp.SimpleError{Val: []byte("Invalid number of args for 'xadd' command")}
-			conn.W.Write(msg.ToBytes())
+	var reply resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		existingStream, err := ks.Stream(key)
+		if err != nil {
+			reply = &resp.SimpleError{Val: []byte(err.Error())}
 			return
 		}
-		key, ok := args.Val[i].(*resp.BulkString)
-		if !ok {
-			msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xadd' command")}
-			conn.W.Write(msg.ToBytes())
+		if existingStream == nil && noMkStream {
+			reply = nullReply(conn)
 			return
 		}
 
-		val, ok := args.Val[i+1].(*resp.BulkString)
-		if !ok {
-			msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xadd' command")}
-			conn.W.Write(msg.ToBytes())
-			return
+		// The last ID outlives the entry it belongs to, a new entry can't reuse a deleted ID
+		var last streams.StreamID
+		if existingStream != nil {
+			last = existingStream.LastID()
 		}
 
-		hashmap[string(key.Str)] = string(val.Str)
-	}
-
-	streams.Global.Mu.Lock()
-	existingStream, streamExists := streams.Global.KV[string(streamKey.Str)]
-
-	// Handle auto-generation of time part (when ID is just "*")
-	if streamID.AutoMs {
-		streamID.Ms = uint64(time.Now().UnixMilli())
-	}
+		// Handle auto-generation of time part (when ID is just "*"). If the clock went
+		// backwards the entry gets the next sequence number of the last ID instead
+		if streamID.AutoMs {
+			streamID.Ms = max(uint64(time.Now().UnixMilli()), last.Ms)
+		}
 
-	// Handle auto-sequence generation
-	if streamID.AutoSeq {
-		if !streamExists || existingStream.LastEntry == nil {
-			// Stream is empty or doesn't exist
-			if streamID.Ms == 0 {
-				// Special case: if time part is 0, sequence starts at 1
-				streamID.Seq = 1
+		// Handle auto-sequence generation, a time part of 0 starts at 1 as 0-0 is invalid
+		if streamID.AutoSeq {
+			if streamID.Ms == last.Ms {
+				streamID.Seq = last.Seq + 1
 			} else {
-				// Otherwise sequence starts at 0
 				streamID.Seq = 0
 			}
-		} else {
-			// Stream has entries
-			lastEntry := existingStream.LastEntry
-			if lastEntry.ID.Ms == streamID.Ms {
-				// Same time part, increment sequence
-				streamID.Seq = lastEntry.ID.Seq + 1
-			} else {
-				// Different time part
-				if streamID.Ms == 0 {
-					streamID.Seq = 1
-				} else {
-					streamID.Seq = 0
-				}
-			}
 		}
-	}
 
-	streamEntry := &streams.StreamEntry{
-		ID:    streamID,
-		Entry: hashmap,
-	}
+		// Validate entry ID: 0-0 is always invalid
+		if streamID.IsZero() {
+			reply = &resp.SimpleError{Val: []byte("ERR The ID specified in XADD must be greater than 0-0")}
+			return
+		}
 
-	// Validate entry ID: 0-0 is always invalid
-	if streamID.IsZero() {
-		streams.Global.Mu.Unlock()
-		msg := resp.SimpleError{Val: []byte("ERR The ID specified in XADD must be greater than 0-0")}
-		conn.W.Write(msg.ToBytes())
-		return
-	}
+		// Existing stream - validate that new ID > last ID
+		if existingStream != nil && streamID.Compare(&last) <= 0 {
+			reply = &resp.SimpleError{Val: []byte("ERR The ID specified in XADD is equal or smaller than the target stream top item")}
+			return
+		}
 
-	// Generate the actual ID string to return
-	actualIDStr := streamID.String()
-	actualIDBulk := &resp.BulkString{Str: []byte(actualIDStr), Size: len(actualIDStr)}
+		stream, _ := ks.CreateStream(key)
+		stream.Insert(&streams.StreamEntry{ID: streamID, Fields: rest[1:]})
 
-	if !streamExists {
-		// New stream - just create it (ID already validated to be > 0-0)
-		streams.Global.KV[string(streamKey.Str)] = streams.NewStream(streamEntry)
-		streams.Global.Mu.Unlock()
-		conn.W.Write(actualIDBulk.ToBytes())
-		return
-	}
+		// Generate the actual ID string to return
+		actualIDStr := streamID.String()
 
-	// Existing stream - validate that new ID > last entry's ID
-	if existingStream.LastEntry != nil && streamID.Compare(existingStream.LastEntry.ID) <= 0 {
-		streams.Global.Mu.Unlock()
-		msg := resp.SimpleError{Val: []byte("ERR The ID specified in XADD is equal or smaller than the target stream top item")}
-		conn.W.Write(msg.ToBytes())
-		return
-	}
+		// Auto generated IDs are logged resolved, replaying must recreate the exact same entry.
+		// The trim is logged on its own, see feedTrim
+		feed := make([][]byte, 0, len(rest)+2)
+		feed = append(feed, []byte("XADD"), []byte(key), []byte(actualIDStr))
+		for _, arg := range rest[1:] {
+			feed = append(feed, []byte(arg))
+		}
+		ks.Touch(key)
+		aof.Feed(feed...)
+		db.Notify(db.NotifyStream, "xadd", key)
+		if trim != nil && trim.apply(stream) > 0 {
+			feedTrim(key, stream)
+			db.Notify(db.NotifyStream, "xtrim", key)
+		}
 
-	existingStream.Insert(streamEntry, streamID.InternalKey())
+		// Readers blocked on the stream check for themselves whether the entry is new to them
+		ks.WakeAll(key)
 
-	// Notify blocking listeners
-	for _, listener := range existingStream.BlockingListeners {
-		if streamID.Compare(listener.WaitingID) > 0 {
-			select {
-			case listener.Channel <- struct{}{}:
-			default:
-			}
-		}
-	}
+		reply = &resp.BulkString{Str: []byte(actualIDStr), Size: len(actualIDStr)}
+	})
 
-	streams.Global.Mu.Unlock()
-	conn.W.Write(actualIDBulk.ToBytes())
+	conn.W.Write(reply.ToBytes())
 }
@@ -0,0 +1,349 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// claimArgs is the XCLAIM that recreates a pending entry exactly as it is, with its owner,
+// delivery time and delivery count. Every change to the PEL is logged this way, replaying it
+// doesn't depend on when the AOF is loaded
+func claimArgs(key, group string, pe *streams.PendingEntry) [][]byte {
+	return [][]byte{
+		[]byte("XCLAIM"), []byte(key), []byte(group), []byte(pe.Consumer.Name), []byte("0"), []byte(pe.ID.String()),
+		[]byte("TIME"), []byte(strconv.FormatInt(pe.DeliveryTime.UnixMilli(), 10)),
+		[]byte("RETRYCOUNT"), []byte(strconv.FormatInt(pe.DeliveryCount, 10)),
+		[]byte("FORCE"), []byte("JUSTID"),
+	}
+}
+
+// parseMinIdle parses the min-idle-time of XCLAIM and XAUTOCLAIM, in milliseconds
+func parseMinIdle(s string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	return time.Duration(max(ms, 0)) * time.Millisecond, nil
+}
+
+// claim makes c the owner of a pending entry and records a delivery at the given time.
+// Entries deleted from the stream are dropped from the PEL instead, claim returns false for
+// them
+func claim(stream *streams.Stream, g *streams.ConsumerGroup, c *streams.Consumer, key string, pe *streams.PendingEntry, deliveryTime time.Time, deliveryCount int64) bool {
+	if !stream.Contains(pe.ID) {
+		g.Ack(pe.ID)
+		aof.Feed([]byte("XACK"), []byte(key), []byte(g.Name), []byte(pe.ID.String()))
+		return false
+	}
+	g.Transfer(pe, c)
+	pe.DeliveryTime = deliveryTime
+	pe.DeliveryCount = deliveryCount
+	aof.Feed(claimArgs(key, g.Name, pe)...)
+	return true
+}
+
+// xclaim changes the ownership of pending entries that have been idle for at least
+// min-idle-time, typically because their consumer died, and returns the claimed entries
+func xclaim(args *resp.Array, conn *pubsub.Connection) {
+	// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME unix-time-milliseconds]
+	//        [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID lastid]
+	if len(args.Val) < 6 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xclaim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xclaim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, group, consumer := argv[1], argv[2], argv[3]
+	minIdle, err := parseMinIdle(argv[4])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// The IDs go on until the first argument that isn't one, the options follow
+	var ids []streams.StreamID
+	i := 5
+	for ; i < len(argv); i++ {
+		id, err := parseStrictID(argv[i])
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		msg := resp.SimpleError{Val: []byte(errInvalidStreamID.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	now := time.Now()
+	deliveryTime := now
+	retryCount := int64(-1)
+	force, justid := false, false
+	var lastID *streams.StreamID
+	for ; i < len(argv); i++ {
+		opt := strings.ToLower(argv[i])
+		switch opt {
+		case "force":
+			force = true
+			continue
+		case "justid":
+			justid = true
+			continue
+		case "idle", "time", "retrycount", "lastid":
+		default:
+			msg := resp.SimpleError{Val: []byte("ERR Unrecognized XCLAIM option '" + argv[i] + "'")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		if i+1 >= len(argv) {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		i++
+		if opt == "lastid" {
+			id, err := parseStrictID(argv[i])
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(err.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			lastID = &id
+			continue
+		}
+		n, err := strconv.ParseInt(argv[i], 10, 64)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte("ERR Invalid " + strings.ToUpper(opt) + " option argument for XCLAIM")}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		switch opt {
+		case "idle":
+			deliveryTime = now.Add(-time.Duration(n) * time.Millisecond)
+		case "time":
+			deliveryTime = time.UnixMilli(n)
+		case "retrycount":
+			retryCount = n
+		}
+	}
+	// A delivery time in the future would make the entry idle for a negative time
+	if deliveryTime.After(now) {
+		deliveryTime = now
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, g, errMsg := lookupGroup(ks, key, group)
+		if errMsg != nil {
+			res = errMsg
+			return
+		}
+
+		changed := false
+		if lastID != nil && lastID.Compare(&g.LastID) > 0 {
+			g.LastID = *lastID
+			aof.Feed(setIDArgs(key, g)...)
+			changed = true
+		}
+
+		c, created := g.Consumer(consumer, true, now)
+		if created {
+			aof.Feed([]byte("XGROUP"), []byte("CREATECONSUMER"), []byte(key), []byte(group), []byte(consumer))
+			db.Notify(db.NotifyStream, "xgroup-createconsumer", key)
+			changed = true
+		}
+		c.SeenTime = now
+
+		claimed := []resp.Message{}
+		for _, id := range ids {
+			pe := g.Pending(id)
+			if pe == nil {
+				// FORCE adds entries of the stream missing from the PEL, as if they had
+				// been delivered once
+				if !force || !stream.Contains(id) {
+					continue
+				}
+				pe = g.Deliver(id, c, now)
+			} else if pe.Idle(now) < minIdle {
+				continue
+			}
+
+			// JUSTID doesn't count as a delivery
+			deliveryCount := pe.DeliveryCount
+			if retryCount >= 0 {
+				deliveryCount = retryCount
+			} else if !justid {
+				deliveryCount++
+			}
+			changed = true
+			if !claim(stream, g, c, key, pe, deliveryTime, deliveryCount) {
+				continue
+			}
+			if justid {
+				claimed = append(claimed, bulkString(id.String()))
+			} else {
+				claimed = append(claimed, entryReply(id, stream.Entry(id)))
+			}
+		}
+		if len(claimed) > 0 {
+			c.ActiveTime = now
+		}
+		if changed {
+			ks.Touch(key)
+		}
+		res = &resp.Array{Val: claimed}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// xautoclaim claims the pending entries idle for at least min-idle-time starting from a
+// cursor, like XCLAIM over a scan of the PEL. It returns the cursor to continue from, 0-0
+// once the whole PEL was scanned, the claimed entries and the IDs of the pending entries
+// that were deleted from the stream
+func xautoclaim(args *resp.Array, conn *pubsub.Connection) {
+	// XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+	if len(args.Val) < 6 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xautoclaim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xautoclaim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, group, consumer := argv[1], argv[2], argv[3]
+	minIdle, err := parseMinIdle(argv[4])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	start, err := streams.NewStreamIDForRange(argv[5], false)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errInvalidStreamID.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	count := 100
+	justid := false
+	for i := 6; i < len(argv); i++ {
+		switch strings.ToLower(argv[i]) {
+		case "justid":
+			justid = true
+		case "count":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			n, err := strconv.Atoi(argv[i+1])
+			if err != nil || n <= 0 {
+				msg := resp.SimpleError{Val: []byte("ERR COUNT must be > 0")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			count = n
+			i++
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, g, errMsg := lookupGroup(ks, key, group)
+		if errMsg != nil {
+			res = errMsg
+			return
+		}
+
+		now := time.Now()
+		c, created := g.Consumer(consumer, true, now)
+		if created {
+			aof.Feed([]byte("XGROUP"), []byte("CREATECONSUMER"), []byte(key), []byte(group), []byte(consumer))
+			db.Notify(db.NotifyStream, "xgroup-createconsumer", key)
+		}
+		c.SeenTime = now
+
+		// The scan looks at no more than 10 entries per entry asked for, so a PEL full of
+		// entries that aren't idle enough doesn't stall the server. claim may remove
+		// entries from the PEL, the scan walks a copy
+		pel := g.PendingFrom(*start)
+		pel = append([]*streams.PendingEntry(nil), pel[:min(len(pel), count*10)]...)
+		claimed := []resp.Message{}
+		deleted := []resp.Message{}
+		examined := 0
+		for _, pe := range pel {
+			if len(claimed) == count {
+				break
+			}
+			examined++
+			if pe.Idle(now) < minIdle {
+				continue
+			}
+			deliveryCount := pe.DeliveryCount
+			if !justid {
+				deliveryCount++
+			}
+			if !claim(stream, g, c, key, pe, now, deliveryCount) {
+				deleted = append(deleted, bulkString(pe.ID.String()))
+				continue
+			}
+			if justid {
+				claimed = append(claimed, bulkString(pe.ID.String()))
+			} else {
+				claimed = append(claimed, entryReply(pe.ID, stream.Entry(pe.ID)))
+			}
+		}
+		if len(claimed) > 0 {
+			c.ActiveTime = now
+		}
+		if created || len(claimed) > 0 || len(deleted) > 0 {
+			ks.Touch(key)
+		}
+
+		// The cursor is the first pending entry after the last one examined
+		next := streams.StreamID{}
+		if examined > 0 {
+			last := pel[examined-1].ID
+			for _, pe := range g.PendingFrom(last) {
+				if pe.ID != last {
+					next = pe.ID
+					break
+				}
+			}
+		}
+
+		res = &resp.Array{Val: []resp.Message{
+			bulkString(next.String()),
+			&resp.Array{Val: claimed},
+			&resp.Array{Val: deleted},
+		}}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
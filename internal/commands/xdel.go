@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xdel deletes entries from a stream and returns how many existed. The stream is kept even
+// once it is empty, with its last ID, and entries still pending in consumer groups stay in
+// their PEL
+func xdel(args *resp.Array, conn *pubsub.Connection) {
+	// XDEL key id [id ...]
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xdel' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xdel' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key := argv[1]
+	ids := make([]streams.StreamID, 0, len(argv)-2)
+	for _, raw := range argv[2:] {
+		id, err := parseStrictID(raw)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(err.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if stream == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		deleted := [][]byte{[]byte("XDEL"), []byte(key)}
+		for _, id := range ids {
+			if stream.Delete(id) {
+				deleted = append(deleted, []byte(id.String()))
+			}
+		}
+		if len(deleted) > 2 {
+			ks.Touch(key)
+			aof.Feed(deleted...)
+			db.Notify(db.NotifyStream, "xdel", key)
+		}
+		res = &resp.Integer{Val: int64(len(deleted) - 2)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,219 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+var errInvalidStreamID = errors.New("ERR Invalid stream ID specified as stream command argument")
+
+// parseStrictID parses an explicit entry ID, a missing sequence number defaults to 0
+func parseStrictID(s string) (streams.StreamID, error) {
+	if s == "-" || s == "+" {
+		return streams.StreamID{}, errInvalidStreamID
+	}
+	id, err := streams.NewStreamIDForRange(s, false)
+	if err != nil {
+		return streams.StreamID{}, errInvalidStreamID
+	}
+	return *id, nil
+}
+
+// setIDArgs is the XGROUP SETID that moves a group to its current position, with the
+// number of entries it has read
+func setIDArgs(key string, g *streams.ConsumerGroup) [][]byte {
+	return [][]byte{
+		[]byte("XGROUP"), []byte("SETID"), []byte(key), []byte(g.Name), []byte(g.LastID.String()),
+		[]byte("ENTRIESREAD"), []byte(strconv.FormatInt(g.EntriesRead, 10)),
+	}
+}
+
+// lookupGroup returns the stream at key and one of its consumer groups. The reply is set
+// instead when the key holds another type or the group doesn't exist
+func lookupGroup(ks *db.Keyspace, key, group string) (*streams.Stream, *streams.ConsumerGroup, resp.Message) {
+	stream, err := ks.Stream(key)
+	if err != nil {
+		return nil, nil, &resp.SimpleError{Val: []byte(err.Error())}
+	}
+	var g *streams.ConsumerGroup
+	if stream != nil {
+		g = stream.Group(group)
+	}
+	if g == nil {
+		return nil, nil, &resp.SimpleError{Val: []byte("NOGROUP No such key '" + key + "' or consumer group '" + group + "'")}
+	}
+	return stream, g, nil
+}
+
+// entryReply is the [id, [field, value ...]] reply for a stream entry, the fields are nil
+// if the entry was deleted
+func entryReply(id streams.StreamID, entry *streams.StreamEntry) resp.Message {
+	idStr := id.String()
+	if entry == nil {
+		return &resp.Array{Val: []resp.Message{bulkString(idStr), &resp.BulkString{Size: -1}}}
+	}
+	kvPairs := make([]resp.Message, 0, len(entry.Fields))
+	for _, s := range entry.Fields {
+		kvPairs = append(kvPairs, bulkString(s))
+	}
+	return &resp.Array{Val: []resp.Message{bulkString(idStr), &resp.Array{Val: kvPairs}}}
+}
+
+// xgroup handles the XGROUP command and its subcommands, which manage the consumer groups
+// of a stream
+func xgroup(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xgroup' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xgroup' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	sub := strings.ToLower(argv[1])
+	var valid bool
+	switch sub {
+	case "create":
+		valid = len(argv) >= 5 && len(argv) <= 8
+	case "setid":
+		valid = len(argv) >= 5 && len(argv) <= 7
+	case "createconsumer", "delconsumer":
+		valid = len(argv) == 5
+	case "destroy":
+		valid = len(argv) == 4
+	default:
+		msg := resp.SimpleError{Val: []byte("ERR unknown subcommand '" + argv[1] + "'")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if !valid {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xgroup|" + sub + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, group := argv[2], argv[3]
+	mkstream := false
+	entriesRead := int64(streams.UnknownEntriesRead)
+	for i := 5; i < len(argv); i++ {
+		switch opt := strings.ToLower(argv[i]); {
+		case opt == "mkstream" && sub == "create":
+			mkstream = true
+		case opt == "entriesread" && i+1 < len(argv):
+			i++
+			n, err := strconv.ParseInt(argv[i], 10, 64)
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			if n < 0 && n != streams.UnknownEntriesRead {
+				msg := resp.SimpleError{Val: []byte("ERR value for ENTRIESREAD must be positive or -1")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			entriesRead = n
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if stream == nil && !mkstream {
+			res = &resp.SimpleError{Val: []byte("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")}
+			return
+		}
+
+		var g *streams.ConsumerGroup
+		if sub != "create" && sub != "destroy" {
+			g = stream.Group(group)
+			if g == nil {
+				res = &resp.SimpleError{Val: []byte("NOGROUP No such consumer group '" + group + "' for key name '" + key + "'")}
+				return
+			}
+		}
+
+		switch sub {
+		case "create", "setid":
+			// $ is resolved here so the AOF replays the group at the same position
+			var lastID streams.StreamID
+			if argv[4] == "$" {
+				if stream != nil {
+					lastID = stream.LastID()
+				}
+			} else if lastID, err = parseStrictID(argv[4]); err != nil {
+				res = &resp.SimpleError{Val: []byte(err.Error())}
+				return
+			}
+
+			if sub == "create" {
+				stream, _ = ks.CreateStream(key)
+				if stream.CreateGroup(group, lastID, entriesRead) == nil {
+					res = &resp.SimpleError{Val: []byte("BUSYGROUP Consumer Group name already exists")}
+					return
+				}
+				aof.Feed([]byte("XGROUP"), []byte("CREATE"), []byte(key), []byte(group), []byte(lastID.String()), []byte("MKSTREAM"),
+					[]byte("ENTRIESREAD"), []byte(strconv.FormatInt(entriesRead, 10)))
+			} else {
+				g.LastID = lastID
+				g.EntriesRead = entriesRead
+				aof.Feed(setIDArgs(key, g)...)
+			}
+			ks.Touch(key)
+			db.Notify(db.NotifyStream, "xgroup-"+sub, key)
+			res = &resp.SimpleString{Val: []byte("OK")}
+		case "destroy":
+			if !stream.DestroyGroup(group) {
+				res = &resp.Integer{Val: 0}
+				return
+			}
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifyStream, "xgroup-destroy", key)
+			// Clients blocked in XREADGROUP on the group find out it is gone
+			ks.WakeAll(key)
+			res = &resp.Integer{Val: 1}
+		case "createconsumer":
+			_, created := g.Consumer(argv[4], true, time.Now())
+			if created {
+				ks.Touch(key)
+				propagate(args)
+				db.Notify(db.NotifyStream, "xgroup-createconsumer", key)
+				res = &resp.Integer{Val: 1}
+			} else {
+				res = &resp.Integer{Val: 0}
+			}
+		case "delconsumer":
+			pending, existed := g.DeleteConsumer(argv[4])
+			if existed {
+				ks.Touch(key)
+				propagate(args)
+				db.Notify(db.NotifyStream, "xgroup-delconsumer", key)
+			}
+			res = &resp.Integer{Val: int64(pending)}
+		}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
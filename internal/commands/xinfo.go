@@ -0,0 +1,290 @@
+package commands
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xinfo handles the XINFO command, which describes a stream, its consumer groups or the
+// consumers of a group
+func xinfo(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xinfo' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xinfo' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// XINFO STREAM key [FULL [COUNT count]]
+	// XINFO GROUPS key
+	// XINFO CONSUMERS key group
+	sub := strings.ToLower(argv[1])
+	var valid bool
+	switch sub {
+	case "stream":
+		valid = len(argv) >= 3 && len(argv) <= 6
+	case "groups":
+		valid = len(argv) == 3
+	case "consumers":
+		valid = len(argv) == 4
+	default:
+		msg := resp.SimpleError{Val: []byte("ERR unknown subcommand '" + argv[1] + "'")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if !valid {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xinfo|" + sub + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// FULL lists up to count entries, and pending entries per group and consumer, 0 for all
+	full := false
+	count := 10
+	if sub == "stream" && len(argv) > 3 {
+		full = strings.ToLower(argv[3]) == "full"
+		switch {
+		case !full || len(argv) == 5:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		case len(argv) == 6:
+			if strings.ToLower(argv[4]) != "count" {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			n, err := strconv.Atoi(argv[5])
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			count = max(n, 0)
+		}
+	}
+
+	key := argv[2]
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if stream == nil {
+			res = &resp.SimpleError{Val: []byte("ERR no such key")}
+			return
+		}
+
+		now := time.Now()
+		switch sub {
+		case "stream":
+			if full {
+				res = streamInfoFull(conn, stream, count)
+			} else {
+				res = streamInfo(conn, stream)
+			}
+		case "groups":
+			groups := stream.Groups()
+			infos := make([]resp.Message, 0, len(groups))
+			for _, g := range groups {
+				infos = append(infos, mapReply(conn, []resp.MapEntry{
+					{Key: bulkString("name"), Val: bulkString(g.Name)},
+					{Key: bulkString("consumers"), Val: &resp.Integer{Val: int64(len(g.Consumers))}},
+					{Key: bulkString("pending"), Val: &resp.Integer{Val: int64(g.PendingLen())}},
+					{Key: bulkString("last-delivered-id"), Val: bulkString(g.LastID.String())},
+					{Key: bulkString("entries-read"), Val: entriesReadReply(conn, g)},
+					{Key: bulkString("lag"), Val: lagReply(conn, stream, g)},
+				}))
+			}
+			res = &resp.Array{Val: infos}
+		case "consumers":
+			g := stream.Group(argv[3])
+			if g == nil {
+				res = &resp.SimpleError{Val: []byte("NOGROUP No such consumer group '" + argv[3] + "' for key name '" + key + "'")}
+				return
+			}
+			consumers := sortedConsumers(g)
+			infos := make([]resp.Message, 0, len(consumers))
+			for _, c := range consumers {
+				infos = append(infos, mapReply(conn, []resp.MapEntry{
+					{Key: bulkString("name"), Val: bulkString(c.Name)},
+					{Key: bulkString("pending"), Val: &resp.Integer{Val: int64(c.Pending)}},
+					{Key: bulkString("idle"), Val: &resp.Integer{Val: now.Sub(c.SeenTime).Milliseconds()}},
+					{Key: bulkString("inactive"), Val: &resp.Integer{Val: inactiveMillis(c, now)}},
+				}))
+			}
+			res = &resp.Array{Val: infos}
+		}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// streamInfo is the reply of XINFO STREAM
+func streamInfo(conn *pubsub.Connection, stream *streams.Stream) resp.Message {
+	info := streamInfoHeader(stream)
+	info = append(info,
+		resp.MapEntry{Key: bulkString("groups"), Val: &resp.Integer{Val: int64(len(stream.Groups()))}},
+		resp.MapEntry{Key: bulkString("first-entry"), Val: streamEntryOrNil(conn, stream.FirstEntry())},
+		resp.MapEntry{Key: bulkString("last-entry"), Val: streamEntryOrNil(conn, stream.LastEntry())},
+	)
+	return mapReply(conn, info)
+}
+
+// streamInfoFull is the reply of XINFO STREAM FULL: the first count entries and every group
+// with its PEL and consumers, their pending entries also capped to count
+func streamInfoFull(conn *pubsub.Connection, stream *streams.Stream, count int) resp.Message {
+	minID := &streams.StreamID{}
+	maxID := &streams.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+	entries := []resp.Message{}
+	for _, entry := range stream.Range(minID, maxID, count) {
+		entries = append(entries, entryReply(*entry.ID, entry))
+	}
+
+	groups := stream.Groups()
+	groupInfos := make([]resp.Message, 0, len(groups))
+	for _, g := range groups {
+		pel := g.PendingFrom(streams.StreamID{})
+		pending := []resp.Message{}
+		for _, pe := range pel {
+			if count > 0 && len(pending) == count {
+				break
+			}
+			pending = append(pending, &resp.Array{Val: []resp.Message{
+				bulkString(pe.ID.String()),
+				bulkString(pe.Consumer.Name),
+				&resp.Integer{Val: pe.DeliveryTime.UnixMilli()},
+				&resp.Integer{Val: pe.DeliveryCount},
+			}})
+		}
+
+		consumers := sortedConsumers(g)
+		consumerInfos := make([]resp.Message, 0, len(consumers))
+		for _, c := range consumers {
+			owned := []resp.Message{}
+			for _, pe := range pel {
+				if count > 0 && len(owned) == count {
+					break
+				}
+				if pe.Consumer != c {
+					continue
+				}
+				owned = append(owned, &resp.Array{Val: []resp.Message{
+					bulkString(pe.ID.String()),
+					&resp.Integer{Val: pe.DeliveryTime.UnixMilli()},
+					&resp.Integer{Val: pe.DeliveryCount},
+				}})
+			}
+			consumerInfos = append(consumerInfos, mapReply(conn, []resp.MapEntry{
+				{Key: bulkString("name"), Val: bulkString(c.Name)},
+				{Key: bulkString("seen-time"), Val: &resp.Integer{Val: c.SeenTime.UnixMilli()}},
+				{Key: bulkString("active-time"), Val: &resp.Integer{Val: activeMillis(c)}},
+				{Key: bulkString("pel-count"), Val: &resp.Integer{Val: int64(c.Pending)}},
+				{Key: bulkString("pending"), Val: &resp.Array{Val: owned}},
+			}))
+		}
+
+		groupInfos = append(groupInfos, mapReply(conn, []resp.MapEntry{
+			{Key: bulkString("name"), Val: bulkString(g.Name)},
+			{Key: bulkString("last-delivered-id"), Val: bulkString(g.LastID.String())},
+			{Key: bulkString("entries-read"), Val: entriesReadReply(conn, g)},
+			{Key: bulkString("lag"), Val: lagReply(conn, stream, g)},
+			{Key: bulkString("pel-count"), Val: &resp.Integer{Val: int64(len(pel))}},
+			{Key: bulkString("pending"), Val: &resp.Array{Val: pending}},
+			{Key: bulkString("consumers"), Val: &resp.Array{Val: consumerInfos}},
+		}))
+	}
+
+	info := streamInfoHeader(stream)
+	info = append(info,
+		resp.MapEntry{Key: bulkString("entries"), Val: &resp.Array{Val: entries}},
+		resp.MapEntry{Key: bulkString("groups"), Val: &resp.Array{Val: groupInfos}},
+	)
+	return mapReply(conn, info)
+}
+
+// streamInfoHeader is the part of the XINFO STREAM reply shared with its FULL form. The keys
+// of the radix tree are the nodes of the stream
+func streamInfoHeader(stream *streams.Stream) []resp.MapEntry {
+	var first streams.StreamID
+	if entry := stream.FirstEntry(); entry != nil {
+		first = *entry.ID
+	}
+	last, maxDeleted := stream.LastID(), stream.MaxDeletedID()
+	return []resp.MapEntry{
+		{Key: bulkString("length"), Val: &resp.Integer{Val: int64(stream.Len())}},
+		{Key: bulkString("radix-tree-keys"), Val: &resp.Integer{Val: int64(stream.Radix.Len())}},
+		{Key: bulkString("radix-tree-nodes"), Val: &resp.Integer{Val: int64(stream.Radix.Nodes())}},
+		{Key: bulkString("last-generated-id"), Val: bulkString(last.String())},
+		{Key: bulkString("max-deleted-entry-id"), Val: bulkString(maxDeleted.String())},
+		{Key: bulkString("entries-added"), Val: &resp.Integer{Val: int64(stream.EntriesAdded())}},
+		{Key: bulkString("recorded-first-entry-id"), Val: bulkString(first.String())},
+	}
+}
+
+// streamEntryOrNil is an entry as XRANGE returns it, or null for a missing one
+func streamEntryOrNil(conn *pubsub.Connection, entry *streams.StreamEntry) resp.Message {
+	if entry == nil {
+		return nullReply(conn)
+	}
+	return entryReply(*entry.ID, entry)
+}
+
+// entriesReadReply is the number of entries a group has read, null when it is unknown
+func entriesReadReply(conn *pubsub.Connection, g *streams.ConsumerGroup) resp.Message {
+	if g.EntriesRead == streams.UnknownEntriesRead {
+		return nullReply(conn)
+	}
+	return &resp.Integer{Val: g.EntriesRead}
+}
+
+// lagReply is the number of entries a group has yet to read, null when it is unknown
+func lagReply(conn *pubsub.Connection, stream *streams.Stream, g *streams.ConsumerGroup) resp.Message {
+	lag, ok := stream.GroupLag(g)
+	if !ok {
+		return nullReply(conn)
+	}
+	return &resp.Integer{Val: int64(lag)}
+}
+
+// sortedConsumers returns the consumers of a group ordered by name
+func sortedConsumers(g *streams.ConsumerGroup) []*streams.Consumer {
+	consumers := make([]*streams.Consumer, 0, len(g.Consumers))
+	for _, c := range g.Consumers {
+		consumers = append(consumers, c)
+	}
+	slices.SortFunc(consumers, func(a, b *streams.Consumer) int { return strings.Compare(a.Name, b.Name) })
+	return consumers
+}
+
+// activeMillis is the unix time in milliseconds the consumer was last given entries, -1 if
+// never
+func activeMillis(c *streams.Consumer) int64 {
+	if c.ActiveTime.IsZero() {
+		return -1
+	}
+	return c.ActiveTime.UnixMilli()
+}
+
+// inactiveMillis is how long ago the consumer was last given entries, -1 if never
+func inactiveMillis(c *streams.Consumer, now time.Time) int64 {
+	if c.ActiveTime.IsZero() {
+		return -1
+	}
+	return now.Sub(c.ActiveTime).Milliseconds()
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// xlen returns the number of entries of a stream, 0 if the key doesn't exist
+func xlen(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xlen' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{argv[1]}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(argv[1])
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		n := 0
+		if stream != nil {
+			n = stream.Len()
+		}
+		res = &resp.Integer{Val: int64(n)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xpending inspects the PEL of a group. Without a range it summarizes it: the number of
+// pending entries, the smallest and greatest pending IDs and how many entries each consumer
+// owns. With a range it lists the entries with their owner, idle time and delivery count
+func xpending(args *resp.Array, conn *pubsub.Connection) {
+	// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xpending' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xpending' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, group := argv[1], argv[2]
+	rest := argv[3:]
+	extended := len(rest) > 0
+	var minIdle time.Duration
+	if len(rest) > 0 && strings.ToLower(rest[0]) == "idle" {
+		if len(rest) < 2 {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		ms, err := strconv.ParseInt(rest[1], 10, 64)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		minIdle = time.Duration(max(ms, 0)) * time.Millisecond
+		rest = rest[2:]
+	}
+
+	var start, end *streams.StreamID
+	count := 0
+	consumer := ""
+	if extended {
+		if len(rest) != 3 && len(rest) != 4 {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		var err error
+		start, err = streams.NewStreamIDForRange(rest[0], false)
+		if err == nil {
+			end, err = streams.NewStreamIDForRange(rest[1], true)
+		}
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(errInvalidStreamID.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		n, err := strconv.Atoi(rest[2])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		count = max(n, 0)
+		if len(rest) == 4 {
+			consumer = rest[3]
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		_, g, errMsg := lookupGroup(ks, key, group)
+		if errMsg != nil {
+			res = errMsg
+			return
+		}
+
+		if !extended {
+			res = pendingSummary(conn, g)
+			return
+		}
+
+		now := time.Now()
+		var c *streams.Consumer
+		if consumer != "" {
+			if c, _ = g.Consumer(consumer, false, now); c == nil {
+				res = &resp.Array{Val: []resp.Message{}}
+				return
+			}
+		}
+
+		entries := []resp.Message{}
+		for _, pe := range g.PendingFrom(*start) {
+			if len(entries) == count || pe.ID.Compare(end) > 0 {
+				break
+			}
+			if (c != nil && pe.Consumer != c) || pe.Idle(now) < minIdle {
+				continue
+			}
+			entries = append(entries, &resp.Array{Val: []resp.Message{
+				bulkString(pe.ID.String()),
+				bulkString(pe.Consumer.Name),
+				&resp.Integer{Val: pe.Idle(now).Milliseconds()},
+				&resp.Integer{Val: pe.DeliveryCount},
+			}})
+		}
+		res = &resp.Array{Val: entries}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// pendingSummary is the reply of XPENDING without a range
+func pendingSummary(conn *pubsub.Connection, g *streams.ConsumerGroup) resp.Message {
+	pel := g.PendingFrom(streams.StreamID{})
+	if len(pel) == 0 {
+		return &resp.Array{Val: []resp.Message{&resp.Integer{Val: 0}, nullReply(conn), nullReply(conn), nullArrayReply(conn)}}
+	}
+
+	names := make([]string, 0, len(g.Consumers))
+	for name, c := range g.Consumers {
+		if c.Pending > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	consumers := make([]resp.Message, 0, len(names))
+	for _, name := range names {
+		// The count is a bulk string, as in Redis
+		consumers = append(consumers, &resp.Array{Val: []resp.Message{
+			bulkString(name),
+			bulkString(strconv.Itoa(g.Consumers[name].Pending)),
+		}})
+	}
+
+	return &resp.Array{Val: []resp.Message{
+		&resp.Integer{Val: int64(len(pel))},
+		bulkString(pel[0].ID.String()),
+		bulkString(pel[len(pel)-1].ID.String()),
+		&resp.Array{Val: consumers},
+	}}
+}
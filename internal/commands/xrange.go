@@ -1,89 +1,139 @@
 package commands
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 	"github.com/codecrafters-io/redis-starter-go/internal/streams"
 )
 
-func xrange(args *resp.Array, conn *pubsub.Connection) {
-	// XRANGE key start end
-	if len(args.Val) < 4 {
-		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xrange' command")}
-		conn.W.Write(msg.ToBytes())
-		return
+// parseRangeID parses a bound of XRANGE or XREVRANGE. A missing sequence number defaults to
+// 0 for the start and to the greatest one for the end, "-" and "+" are the smallest and
+// greatest IDs and a "(" prefix excludes the ID itself from the range
+func parseRangeID(s string, isEnd bool) (*streams.StreamID, error) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+		if s == "-" || s == "+" {
+			return nil, errInvalidStreamID
+		}
 	}
 
-	streamKey, ok := args.Val[1].(*resp.BulkString)
-	if !ok {
-		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xrange' command")}
-		conn.W.Write(msg.ToBytes())
-		return
+	id, err := streams.NewStreamIDForRange(s, isEnd)
+	if err != nil {
+		return nil, errInvalidStreamID
 	}
+	if exclusive {
+		if !isEnd && !id.Incr() {
+			return nil, errors.New("ERR invalid start ID for the interval")
+		}
+		if isEnd && !id.Decr() {
+			return nil, errors.New("ERR invalid end ID for the interval")
+		}
+	}
+	return id, nil
+}
 
-	startIDStr, ok := args.Val[2].(*resp.BulkString)
-	if !ok {
-		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xrange' command")}
+func xrange(args *resp.Array, conn *pubsub.Connection) {
+	rangeGeneric(args, conn, false)
+}
+
+func xrevrange(args *resp.Array, conn *pubsub.Connection) {
+	rangeGeneric(args, conn, true)
+}
+
+// rangeGeneric implements XRANGE and XREVRANGE, XREVRANGE takes the end of the range first
+// and returns the entries newest first
+func rangeGeneric(args *resp.Array, conn *pubsub.Connection, rev bool) {
+	// XRANGE key start end [COUNT count]
+	// XREVRANGE key end start [COUNT count]
+	name := "xrange"
+	if rev {
+		name = "xrevrange"
+	}
+	if len(args.Val) != 4 && len(args.Val) != 6 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	endIDStr, ok := args.Val[3].(*resp.BulkString)
+	argv, ok := stringArgs(args)
 	if !ok {
-		msg := resp.SimpleError{Val: []byte("ERR invalid argument for 'xrange' command")}
+		msg := resp.SimpleError{Val: []byte("ERR invalid argument for '" + name + "' command")}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
+	key, rawStart, rawEnd := argv[1], argv[2], argv[3]
+	if rev {
+		rawStart, rawEnd = rawEnd, rawStart
+	}
+
 	// Parse start ID (sequence defaults to 0)
-	startID, err := streams.NewStreamIDForRange(string(startIDStr.Str), false)
+	startID, err := parseRangeID(rawStart, false)
 	if err != nil {
-		msg := resp.SimpleError{Val: []byte("ERR Invalid stream ID for 'xrange' command")}
+		msg := resp.SimpleError{Val: []byte(err.Error())}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
 	// Parse end ID (sequence defaults to max uint64)
-	endID, err := streams.NewStreamIDForRange(string(endIDStr.Str), true)
+	endID, err := parseRangeID(rawEnd, true)
 	if err != nil {
-		msg := resp.SimpleError{Val: []byte("ERR Invalid stream ID for 'xrange' command")}
+		msg := resp.SimpleError{Val: []byte(err.Error())}
 		conn.W.Write(msg.ToBytes())
 		return
 	}
 
-	streams.Global.Mu.Lock()
-	stream, exists := streams.Global.KV[string(streamKey.Str)]
-	if !exists {
-		streams.Global.Mu.Unlock()
-		// Return empty array if stream doesn't exist
-		emptyArr := &resp.Array{Val: []resp.Message{}}
-		conn.W.Write(emptyArr.ToBytes())
-		return
+	// COUNT 0 or less asks for no entries, while Range takes a count of 0 as no limit
+	count := 0
+	noEntries := false
+	if len(argv) == 6 {
+		if strings.ToLower(argv[4]) != "count" {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		n, err := strconv.Atoi(argv[5])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		count = n
+		noEntries = n <= 0
 	}
 
-	// Get entries in range
-	entries := stream.Range(startID, endID)
-	streams.Global.Mu.Unlock()
+	var entries []*streams.StreamEntry
+	var wrongType error
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil || stream == nil || noEntries {
+			wrongType = err
+			return
+		}
+
+		// Get entries in range, they are decoded copies so they can be read after the keyspace is released
+		if rev {
+			entries = stream.RevRange(startID, endID, count)
+		} else {
+			entries = stream.Range(startID, endID, count)
+		}
+	})
+	if wrongType != nil {
+		msg := resp.SimpleError{Val: []byte(wrongType.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
 
 	// Build response: array of [id, [key1, val1, key2, val2, ...]]
 	resultArr := make([]resp.Message, 0, len(entries))
 	for _, entry := range entries {
-		// Build the key-value array
-		kvArr := make([]resp.Message, 0, len(entry.Entry)*2)
-		for k, v := range entry.Entry {
-			kvArr = append(kvArr, &resp.BulkString{Str: []byte(k), Size: len(k)})
-			kvArr = append(kvArr, &resp.BulkString{Str: []byte(v), Size: len(v)})
-		}
-
-		// Build the entry array: [id, [key-value pairs]]
-		idStr := entry.ID.String()
-		entryArr := &resp.Array{
-			Val: []resp.Message{
-				&resp.BulkString{Str: []byte(idStr), Size: len(idStr)},
-				&resp.Array{Val: kvArr},
-			},
-		}
-		resultArr = append(resultArr, entryArr)
+		resultArr = append(resultArr, entryReply(*entry.ID, entry))
 	}
 
 	result := &resp.Array{Val: resultArr}
@@ -2,10 +2,10 @@ package commands
 
 import (
 	"bytes"
-	"reflect"
 	"strconv"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
 	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 	"github.com/codecrafters-io/redis-starter-go/internal/streams"
@@ -15,6 +15,7 @@ func xread(args *resp.Array, conn *pubsub.Connection) {
 	// XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
 
 	blockMs := int64(-1)
+	count := 0
 	streamsIdx := -1
 
 	for i := 0; i < len(args.Val); i++ {
@@ -46,6 +47,28 @@ func xread(args *resp.Array, conn *pubsub.Connection) {
 				blockMs = parsedBlockMs
 				i++ // Skip the value
 			}
+			if argStr == "count" {
+				if i+1 >= len(args.Val) {
+					msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+					conn.W.Write(msg.ToBytes())
+					return
+				}
+				countArg, ok := args.Val[i+1].(*resp.BulkString)
+				if !ok {
+					msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+					conn.W.Write(msg.ToBytes())
+					return
+				}
+				parsedCount, err := strconv.Atoi(string(countArg.Str))
+				if err != nil {
+					msg := resp.SimpleError{Val: []byte("ERR value is not an integer or out of range")}
+					conn.W.Write(msg.ToBytes())
+					return
+				}
+				// COUNT 0 or less reads everything, like no COUNT at all
+				count = max(parsedCount, 0)
+				i++ // Skip the value
+			}
 		}
 	}
 
@@ -86,171 +109,104 @@ func xread(args *resp.Array, conn *pubsub.Connection) {
 		rawIDs[i] = string(idBS.Str)
 	}
 
-	streams.Global.Mu.Lock()
-
-	// Resolve IDs (especially $)
-	for i := 0; i < numStreams; i++ {
-		if rawIDs[i] == "$" {
-			stream, exists := streams.Global.KV[keys[i]]
-			if exists && stream.LastEntry != nil {
-				ids[i] = stream.LastEntry.ID
-			} else {
+	// Resolve IDs (especially $) the first time the streams are looked at, so that $ means
+	// the last entry at the moment XREAD was called
+	resolved := false
+	resolve := func(ks *db.Keyspace) resp.Message {
+		for i := 0; i < numStreams; i++ {
+			if rawIDs[i] == "$" {
+				stream, err := ks.Stream(keys[i])
+				if err != nil {
+					return &resp.SimpleError{Val: []byte(err.Error())}
+				}
 				ids[i] = &streams.StreamID{Ms: 0, Seq: 0}
+				if stream != nil {
+					last := stream.LastID()
+					ids[i] = &last
+				}
+			} else {
+				id, err := parseStrictID(rawIDs[i])
+				if err != nil {
+					return &resp.SimpleError{Val: []byte(err.Error())}
+				}
+				ids[i] = &id
 			}
-		} else {
-			id, err := streams.NewStreamID(rawIDs[i])
-			if err != nil {
-				streams.Global.Mu.Unlock()
-				msg := resp.SimpleError{Val: []byte("ERR Invalid stream ID")}
-				conn.W.Write(msg.ToBytes())
-				return
-			}
-			ids[i] = id
 		}
+		resolved = true
+		return nil
 	}
-	// Helper to fetch data
-	fetchData := func() []resp.Message {
-		var responseStreams []resp.Message
+
+	// Helper to fetch data, returns nil when none of the streams has new entries
+	fetchData := func(ks *db.Keyspace) resp.Message {
+		if !resolved {
+			if errMsg := resolve(ks); errMsg != nil {
+				return errMsg
+			}
+		}
+
+		var responseStreams []resp.MapEntry
 		for i := 0; i < numStreams; i++ {
 			key := keys[i]
 			startID := ids[i]
 
-			stream, exists := streams.Global.KV[key]
-			if !exists {
+			stream, err := ks.Stream(key)
+			if err != nil {
+				return &resp.SimpleError{Val: []byte(err.Error())}
+			}
+			if stream == nil {
 				continue
 			}
 
+			// The ID itself is excluded, nothing comes after the greatest ID
+			first := *startID
+			if !first.Incr() {
+				continue
+			}
 			maxID := &streams.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
-			allEntries := stream.Range(startID, maxID)
-
 			var filteredEntries []resp.Message
-			for _, entry := range allEntries {
-				if entry.ID.Compare(startID) > 0 {
-					kvPairs := make([]resp.Message, 0, len(entry.Entry)*2)
-					for k, v := range entry.Entry {
-						kvPairs = append(kvPairs, &resp.BulkString{Str: []byte(k), Size: len(k)})
-						kvPairs = append(kvPairs, &resp.BulkString{Str: []byte(v), Size: len(v)})
-					}
-
-					idStr := entry.ID.String()
-					entryArr := &resp.Array{
-						Val: []resp.Message{
-							&resp.BulkString{Str: []byte(idStr), Size: len(idStr)},
-							&resp.Array{Val: kvPairs},
-						},
-					}
-					filteredEntries = append(filteredEntries, entryArr)
-				}
+			for _, entry := range stream.Range(&first, maxID, count) {
+				filteredEntries = append(filteredEntries, entryReply(*entry.ID, entry))
 			}
 
 			if len(filteredEntries) > 0 {
-				streamRes := &resp.Array{
-					Val: []resp.Message{
-						&resp.BulkString{Str: []byte(key), Size: len(key)},
-						&resp.Array{Val: filteredEntries},
-					},
-				}
-				responseStreams = append(responseStreams, streamRes)
+				responseStreams = append(responseStreams, resp.MapEntry{
+					Key: &resp.BulkString{Str: []byte(key), Size: len(key)},
+					Val: &resp.Array{Val: filteredEntries},
+				})
 			}
 		}
-		return responseStreams
-	}
-
-	data := fetchData()
-	if len(data) > 0 || blockMs == -1 {
-		streams.Global.Mu.Unlock()
-		if len(data) == 0 {
-			conn.W.Write([]byte("*-1\r\n"))
-		} else {
-			finalResponse := &resp.Array{Val: data}
-			conn.W.Write(finalResponse.ToBytes())
-		}
-		return
-	}
-
-	// Blocking logic
-	// Register listeners
-	channels := make([]chan struct{}, 0, numStreams)
-	listeners := make([]*streams.BlockingListener, 0, numStreams)
-
-	for i := 0; i < numStreams; i++ {
-		key := keys[i]
-		startID := ids[i]
-
-		stream, exists := streams.Global.KV[key]
-		if !exists {
-			stream = streams.NewEmptyStream()
-			streams.Global.KV[key] = stream
-		}
-
-		ch := make(chan struct{}, 1)
-		listener := &streams.BlockingListener{
-			Channel:   ch,
-			WaitingID: startID,
+		if len(responseStreams) == 0 {
+			return nil
 		}
-		stream.BlockingListeners = append(stream.BlockingListeners, listener)
-		listeners = append(listeners, listener)
-		channels = append(channels, ch)
+		return streamsReply(conn, responseStreams)
 	}
-	streams.Global.Mu.Unlock()
 
-	// Wait
-	// Build select cases
-	cases := make([]reflect.SelectCase, len(channels)+1)
-	for i, ch := range channels {
-		cases[i] = reflect.SelectCase{
-			Dir:  reflect.SelectRecv,
-			Chan: reflect.ValueOf(ch),
-		}
-	}
-
-	// Timeout case
-	var timeoutCh <-chan time.Time
-	if blockMs > 0 {
-		timer := time.NewTimer(time.Duration(blockMs) * time.Millisecond)
-		defer timer.Stop()
-		timeoutCh = timer.C
-	}
-
-	cases[len(channels)] = reflect.SelectCase{
-		Dir:  reflect.SelectRecv,
-		Chan: reflect.ValueOf(timeoutCh),
-	}
-	if blockMs == 0 {
-	}
-
-	chosen, _, _ := reflect.Select(cases)
-
-	// Cleanup listeners
-	streams.Global.Mu.Lock()
-	for i := 0; i < numStreams; i++ {
-		key := keys[i]
-		if stream, exists := streams.Global.KV[key]; exists {
-			newListeners := make([]*streams.BlockingListener, 0, len(stream.BlockingListeners))
-			for _, l := range stream.BlockingListeners {
-				if l != listeners[i] {
-					newListeners = append(newListeners, l)
-				}
-			}
-			stream.BlockingListeners = newListeners
-		}
+	var data resp.Message
+	if blockMs == -1 {
+		do(conn, keys, func(ks *db.Keyspace) {
+			data = fetchData(ks)
+		})
+	} else {
+		// BLOCK 0 waits forever, same as a zero timeout for blockOn
+		data = blockOn(conn, keys, time.Duration(blockMs)*time.Millisecond, fetchData)
 	}
 
-	if chosen == len(channels) {
-		// Timeout
-		streams.Global.Mu.Unlock()
-		conn.W.Write([]byte("*-1\r\n"))
+	if data == nil {
+		conn.W.Write(nullArray(conn))
 		return
 	}
+	conn.W.Write(data.ToBytes())
+}
 
-	// Data arrived
-	data = fetchData()
-	streams.Global.Mu.Unlock()
-
-	if len(data) == 0 {
-		conn.W.Write([]byte("*-1\r\n"))
-	} else {
-		finalResponse := &resp.Array{Val: data}
-		conn.W.Write(finalResponse.ToBytes())
+// streamsReply lists the entries read from each stream: a map keyed by stream for RESP3
+// clients and an array of [key, entries] pairs for RESP2 ones
+func streamsReply(conn *pubsub.Connection, streams []resp.MapEntry) resp.Message {
+	if conn.RESP3() {
+		return &resp.Map{Val: streams}
+	}
+	arr := &resp.Array{Val: make([]resp.Message, 0, len(streams))}
+	for _, s := range streams {
+		arr.Val = append(arr.Val, &resp.Array{Val: []resp.Message{s.Key, s.Val}})
 	}
+	return arr
 }
@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xreadgroup reads from streams on behalf of a consumer of a group. The special ID > asks
+// for entries never delivered to the group, any other ID reads back the entries pending for
+// the consumer after it
+func xreadgroup(args *resp.Array, conn *pubsub.Connection) {
+	// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+	if len(args.Val) < 7 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xreadgroup' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xreadgroup' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var group, consumer string
+	hasGroup := false
+	count := 0
+	blockMs := int64(-1)
+	noack := false
+	streamsIdx := -1
+	for i := 1; i < len(argv) && streamsIdx == -1; i++ {
+		switch strings.ToLower(argv[i]) {
+		case "group":
+			if i+2 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			group, consumer = argv[i+1], argv[i+2]
+			hasGroup = true
+			i += 2
+		case "count":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			n, err := strconv.Atoi(argv[i+1])
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			count = max(n, 0)
+			i++
+		case "block":
+			if i+1 >= len(argv) {
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			ms, err := strconv.ParseInt(argv[i+1], 10, 64)
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			if ms < 0 {
+				msg := resp.SimpleError{Val: []byte("ERR timeout is negative")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			blockMs = ms
+			i++
+		case "noack":
+			noack = true
+		case "streams":
+			streamsIdx = i
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+	if !hasGroup {
+		msg := resp.SimpleError{Val: []byte("ERR Missing GROUP option for XREADGROUP")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if streamsIdx == -1 {
+		msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	rest := argv[streamsIdx+1:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		msg := resp.SimpleError{Val: []byte("ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	numStreams := len(rest) / 2
+	keys := rest[:numStreams]
+	// nil stands for >, the entries never delivered to the group
+	ids := make([]*streams.StreamID, numStreams)
+	history := false
+	for i, raw := range rest[numStreams:] {
+		if raw == ">" {
+			continue
+		}
+		id, err := parseStrictID(raw)
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(err.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		ids[i] = &id
+		history = true
+	}
+
+	// Reading the history never blocks: its reply, possibly empty, is always ready
+	fetchData := func(ks *db.Keyspace) resp.Message {
+		now := time.Now()
+		var responseStreams []resp.MapEntry
+		for i, key := range keys {
+			stream, err := ks.Stream(key)
+			if err != nil {
+				return &resp.SimpleError{Val: []byte(err.Error())}
+			}
+			var g *streams.ConsumerGroup
+			if stream != nil {
+				g = stream.Group(group)
+			}
+			if g == nil {
+				return &resp.SimpleError{Val: []byte("NOGROUP No such key '" + key + "' or consumer group '" + group + "' in XREADGROUP with GROUP option")}
+			}
+
+			c, created := g.Consumer(consumer, true, now)
+			if created {
+				aof.Feed([]byte("XGROUP"), []byte("CREATECONSUMER"), []byte(key), []byte(group), []byte(consumer))
+				ks.Touch(key)
+				db.Notify(db.NotifyStream, "xgroup-createconsumer", key)
+			}
+			c.SeenTime = now
+
+			var entries []resp.Message
+			if ids[i] == nil {
+				entries = readNewEntries(ks, stream, g, c, key, count, noack, now)
+				if len(entries) == 0 {
+					continue
+				}
+			} else {
+				entries = readPendingEntries(ks, stream, g, c, key, *ids[i], count, now)
+			}
+			if len(entries) > 0 {
+				c.ActiveTime = now
+			}
+			responseStreams = append(responseStreams, resp.MapEntry{
+				Key: bulkString(key),
+				Val: &resp.Array{Val: entries},
+			})
+		}
+		if len(responseStreams) == 0 {
+			return nil
+		}
+		return streamsReply(conn, responseStreams)
+	}
+
+	var data resp.Message
+	if blockMs == -1 || history {
+		do(conn, keys, func(ks *db.Keyspace) {
+			data = fetchData(ks)
+		})
+	} else {
+		data = blockOn(conn, keys, time.Duration(blockMs)*time.Millisecond, fetchData)
+	}
+
+	if data == nil {
+		conn.W.Write(nullArray(conn))
+		return
+	}
+	conn.W.Write(data.ToBytes())
+}
+
+// readNewEntries delivers up to count entries never delivered to the group to consumer c
+// and adds them to the PEL, unless noack is set
+func readNewEntries(ks *db.Keyspace, stream *streams.Stream, g *streams.ConsumerGroup, c *streams.Consumer, key string, count int, noack bool, now time.Time) []resp.Message {
+	first := g.LastID
+	if !first.Incr() {
+		return nil
+	}
+	maxID := &streams.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+	var entries []resp.Message
+	var delivered []*streams.PendingEntry
+	for _, entry := range stream.Range(&first, maxID, count) {
+		id := streams.StreamID{Ms: entry.ID.Ms, Seq: entry.ID.Seq}
+		stream.AdvanceGroup(g, id)
+		if !noack {
+			delivered = append(delivered, g.Deliver(id, c, now))
+		}
+		entries = append(entries, entryReply(id, entry))
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ks.Touch(key)
+	aof.Feed(setIDArgs(key, g)...)
+	for _, pe := range delivered {
+		aof.Feed(claimArgs(key, g.Name, pe)...)
+	}
+	return entries
+}
+
+// readPendingEntries reads back up to count entries of the PEL owned by consumer c whose ID
+// is greater than after. They count as delivered again
+func readPendingEntries(ks *db.Keyspace, stream *streams.Stream, g *streams.ConsumerGroup, c *streams.Consumer, key string, after streams.StreamID, count int, now time.Time) []resp.Message {
+	entries := []resp.Message{}
+	for _, pe := range g.PendingFrom(after) {
+		if count > 0 && len(entries) == count {
+			break
+		}
+		if pe.Consumer != c || pe.ID == after {
+			continue
+		}
+		pe.DeliveryTime = now
+		pe.DeliveryCount++
+		entries = append(entries, entryReply(pe.ID, stream.Entry(pe.ID)))
+		aof.Feed(claimArgs(key, g.Name, pe)...)
+	}
+	if len(entries) > 0 {
+		ks.Touch(key)
+	}
+	return entries
+}
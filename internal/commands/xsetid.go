@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// xsetid sets the last ID of a stream and optionally its entries added counter and max
+// deleted ID. It's mostly used by the AOF rewrite to recreate a stream whose entries were
+// deleted
+func xsetid(args *resp.Array, conn *pubsub.Connection) {
+	// XSETID key last-id [ENTRIESADDED entries-added] [MAXDELETEDID max-deleted-id]
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xsetid' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xsetid' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key := argv[1]
+	lastID, err := parseStrictID(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	entriesAdded := int64(-1)
+	var maxDeletedID *streams.StreamID
+	for i := 3; i < len(argv); i += 2 {
+		if i+1 >= len(argv) {
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		switch strings.ToLower(argv[i]) {
+		case "entriesadded":
+			n, err := strconv.ParseInt(argv[i+1], 10, 64)
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			if n < 0 {
+				msg := resp.SimpleError{Val: []byte("ERR entries_added must be positive")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			entriesAdded = n
+		case "maxdeletedid":
+			id, err := parseStrictID(argv[i+1])
+			if err != nil {
+				msg := resp.SimpleError{Val: []byte(err.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			if lastID.Compare(&id) < 0 {
+				msg := resp.SimpleError{Val: []byte("ERR The ID specified in XSETID is smaller than the provided max_deleted_entry_id")}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			maxDeletedID = &id
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if stream == nil {
+			res = &resp.SimpleError{Val: []byte("ERR no such key")}
+			return
+		}
+
+		if last := stream.LastEntry(); last != nil && lastID.Compare(last.ID) < 0 {
+			res = &resp.SimpleError{Val: []byte("ERR The ID specified in XSETID is smaller than the target stream top item")}
+			return
+		}
+		if entriesAdded >= 0 && entriesAdded < int64(stream.Len()) {
+			res = &resp.SimpleError{Val: []byte("ERR The entries_added specified in XSETID is smaller than the target stream length")}
+			return
+		}
+
+		if entriesAdded < 0 {
+			entriesAdded = int64(stream.EntriesAdded())
+		}
+		if maxDeletedID == nil {
+			id := stream.MaxDeletedID()
+			maxDeletedID = &id
+		}
+		// Going below an ID that was deleted would let XADD reuse it
+		if lastID.Compare(maxDeletedID) < 0 {
+			res = &resp.SimpleError{Val: []byte("ERR The ID specified in XSETID is smaller than current max_deleted_entry_id")}
+			return
+		}
+		stream.RestoreMetadata(lastID, *maxDeletedID, uint64(entriesAdded))
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyStream, "xsetid", key)
+		res = &resp.SimpleString{Val: []byte("OK")}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// trimSpec is a MAXLEN or MINID trimming strategy, shared by XTRIM and XADD
+type trimSpec struct {
+	maxLen int              // MAXLEN threshold, -1 when trimming by MINID
+	minID  streams.StreamID // MINID threshold
+	approx bool             // ~, only whole batches of entries are deleted
+	limit  int              // most entries an approximate trim deletes, 0 for no limit
+}
+
+// parseTrim parses "MAXLEN|MINID [=|~] threshold [LIMIT count]" at the start of argv and
+// returns the arguments that follow
+func parseTrim(argv []string) (trimSpec, []string, error) {
+	spec := trimSpec{maxLen: -1}
+	strategy := strings.ToLower(argv[0])
+	argv = argv[1:]
+	if len(argv) > 0 && (argv[0] == "=" || argv[0] == "~") {
+		spec.approx = argv[0] == "~"
+		argv = argv[1:]
+	}
+	if len(argv) == 0 {
+		return spec, nil, errSyntax
+	}
+
+	if strategy == "maxlen" {
+		n, err := strconv.Atoi(argv[0])
+		if err != nil {
+			return spec, nil, errNotInteger
+		}
+		if n < 0 {
+			return spec, nil, errors.New("ERR The MAXLEN argument must be >= 0.")
+		}
+		spec.maxLen = n
+	} else {
+		id, err := parseStrictID(argv[0])
+		if err != nil {
+			return spec, nil, err
+		}
+		spec.minID = id
+	}
+	argv = argv[1:]
+
+	// Like Redis, an approximate trim without LIMIT deletes at most 100 batches at once
+	if spec.approx {
+		spec.limit = 100 * streams.NodeMaxEntries
+	}
+	if len(argv) > 0 && strings.ToLower(argv[0]) == "limit" {
+		if len(argv) < 2 {
+			return spec, nil, errSyntax
+		}
+		n, err := strconv.Atoi(argv[1])
+		if err != nil {
+			return spec, nil, errNotInteger
+		}
+		if n < 0 {
+			return spec, nil, errors.New("ERR The LIMIT argument must be >= 0.")
+		}
+		if !spec.approx {
+			return spec, nil, errors.New("ERR syntax error, LIMIT cannot be used without the special ~ option")
+		}
+		spec.limit = n
+		argv = argv[2:]
+	}
+	return spec, argv, nil
+}
+
+// apply trims the stream and returns the number of deleted entries
+func (spec trimSpec) apply(stream *streams.Stream) int {
+	if spec.maxLen >= 0 {
+		return stream.TrimMaxLen(spec.maxLen, spec.approx, spec.limit)
+	}
+	return stream.TrimMinID(spec.minID, spec.approx, spec.limit)
+}
+
+// feedTrim logs a trim that deleted entries. An approximate trim depends on how entries
+// were batched, it is logged as the exact trim to the length it left
+func feedTrim(key string, stream *streams.Stream) {
+	aof.Feed([]byte("XTRIM"), []byte(key), []byte("MAXLEN"), []byte(strconv.Itoa(stream.Len())))
+}
+
+// xtrim deletes the oldest entries of a stream, either past a length or before an ID
+func xtrim(args *resp.Array, conn *pubsub.Connection) {
+	// XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'xtrim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'xtrim' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key := argv[1]
+	if s := strings.ToLower(argv[2]); s != "maxlen" && s != "minid" {
+		msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	spec, rest, err := parseTrim(argv[2:])
+	if err == nil && len(rest) > 0 {
+		err = errSyntax
+	}
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		stream, err := ks.Stream(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if stream == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		deleted := spec.apply(stream)
+		if deleted > 0 {
+			ks.Touch(key)
+			feedTrim(key, stream)
+			db.Notify(db.NotifyStream, "xtrim", key)
+		}
+		res = &resp.Integer{Val: int64(deleted)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zaddFlags are the options of ZADD
+type zaddFlags struct {
+	nx, xx, gt, lt, ch, incr bool
+}
+
+// zadd adds members to a sorted set or updates their scores. It replies with the number of
+// members added, or added and updated with CH, or the new score with INCR
+func zadd(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zadd' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zadd' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	// Options come first, the score-member pairs start at the first argument that isn't one
+	var flags zaddFlags
+	i := 2
+	for ; i < len(argv); i++ {
+		switch strings.ToLower(argv[i]) {
+		case "nx":
+			flags.nx = true
+		case "xx":
+			flags.xx = true
+		case "gt":
+			flags.gt = true
+		case "lt":
+			flags.lt = true
+		case "ch":
+			flags.ch = true
+		case "incr":
+			flags.incr = true
+		default:
+			goto pairs
+		}
+	}
+pairs:
+	if i == len(argv) || (len(argv)-i)%2 != 0 {
+		msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if flags.nx && flags.xx {
+		msg := resp.SimpleError{Val: []byte("ERR XX and NX options at the same time are not compatible")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if (flags.gt && flags.nx) || (flags.lt && flags.nx) || (flags.gt && flags.lt) {
+		msg := resp.SimpleError{Val: []byte("ERR GT, LT, and/or NX options at the same time are not compatible")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if flags.incr && len(argv)-i > 2 {
+		msg := resp.SimpleError{Val: []byte("ERR INCR option supports a single increment-element pair")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	// Every score is validated before anything is written
+	entries := make([]ds.ZEntry, 0, (len(argv)-i)/2)
+	for ; i < len(argv); i += 2 {
+		score, err := parseScore(argv[i])
+		if err != nil {
+			msg := resp.SimpleError{Val: []byte(err.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+		entries = append(entries, ds.ZEntry{Member: argv[i+1], Score: score})
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		added, updated, score, err := zaddGeneric(ks, key, entries, flags)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if added+updated > 0 {
+			ks.Touch(key)
+			propagate(args)
+			if flags.incr {
+				db.Notify(db.NotifyZSet, "zincr", key)
+			} else {
+				db.Notify(db.NotifyZSet, "zadd", key)
+			}
+		}
+
+		switch {
+		case flags.incr && added+updated == 0:
+			res = nullReply(conn)
+		case flags.incr:
+			res = doubleReply(conn, score)
+		case flags.ch:
+			res = &resp.Integer{Val: int64(added + updated)}
+		default:
+			res = &resp.Integer{Val: int64(added)}
+		}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zaddGeneric applies ZADD to the sorted set at key and returns how many members were added
+// and updated, along with the last score written. The key is only created if a member is
+// added, and every new member wakes one client blocked on the key
+func zaddGeneric(ks *db.Keyspace, key string, entries []ds.ZEntry, flags zaddFlags) (added, updated int, score float64, err error) {
+	zset, err := ks.ZSet(key)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	for _, e := range entries {
+		var current float64
+		exists := false
+		if zset != nil {
+			current, exists = zset.Score(e.Member)
+		}
+
+		if exists {
+			if flags.nx {
+				continue
+			}
+			score = e.Score
+			if flags.incr {
+				score += current
+				if score != score {
+					return added, updated, 0, errScoreNaN
+				}
+			}
+			if (flags.gt && score <= current) || (flags.lt && score >= current) {
+				continue
+			}
+			if score != current {
+				zset.Add(e.Member, score)
+				updated++
+			}
+			continue
+		}
+
+		if flags.xx {
+			continue
+		}
+		if zset == nil {
+			zset, _ = ks.CreateZSet(key)
+		}
+		score = e.Score
+		zset.Add(e.Member, score)
+		added++
+	}
+
+	for range added {
+		if !ks.Wake(key) {
+			break
+		}
+	}
+	return added, updated, score, nil
+}
+
+// zincrby adds increment to the score of member, a missing member starts at 0
+func zincrby(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zincrby' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zincrby' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	incr, err := parseScore(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		_, _, score, err := zaddGeneric(ks, key, []ds.ZEntry{{Member: argv[3], Score: incr}}, zaddFlags{incr: true})
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		ks.Touch(key)
+		propagate(args)
+		db.Notify(db.NotifyZSet, "zincr", key)
+		res = doubleReply(conn, score)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zcard replies with the number of members in a sorted set, 0 if the key doesn't exist
+func zcard(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 2 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zcard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'zcard' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		res = &resp.Integer{Val: int64(zset.Len())}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zcount replies with the number of members whose score is between min and max. Both ends
+// are found walking the skiplist, so it doesn't depend on how many members match
+func zcount(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zcount' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zcount' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	min, err := parseScoreBound(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	max, err := parseScoreBound(argv[3])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		start, stop := scoreRange(zset, min, max)
+		res = &resp.Integer{Val: int64(rangeLen(start, stop))}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zlexcount replies with the number of members between min and max in lexicographical order,
+// for sorted sets where every member has the same score
+func zlexcount(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zlexcount' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zlexcount' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	min, err := parseLexBound(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	max, err := parseLexBound(argv[3])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+		start, stop := lexRange(zset, min, max)
+		res = &resp.Integer{Val: int64(rangeLen(start, stop))}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zpopmin removes and returns the members with the lowest scores
+func zpopmin(args *resp.Array, conn *pubsub.Connection) {
+	zpopGeneric(args, conn, "zpopmin", false)
+}
+
+// zpopmax removes and returns the members with the highest scores
+func zpopmax(args *resp.Array, conn *pubsub.Connection) {
+	zpopGeneric(args, conn, "zpopmax", true)
+}
+
+// zpopGeneric implements ZPOPMIN and ZPOPMAX. The reply lists the popped members with their
+// scores like zsetReply, empty if the key doesn't exist
+func zpopGeneric(args *resp.Array, conn *pubsub.Connection, name string, max bool) {
+	if len(args.Val) < 2 || len(args.Val) > 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	count := int64(1)
+	if len(argv) == 3 {
+		var err error
+		count, err = strconv.ParseInt(argv[2], 10, 64)
+		if err != nil || count < 0 {
+			msg := resp.SimpleError{Val: []byte(errCountNegative.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil || count == 0 {
+			res = zsetReply(conn, nil, true)
+			return
+		}
+
+		popped := zpop(ks, key, zset, count, max)
+		ks.Touch(key)
+		propagate(args)
+		notifyZPop(key, zset, max)
+		// Without a count RESP3 clients get the single entry as is rather than in a list
+		if len(argv) == 2 && conn.RESP3() {
+			res = &resp.Array{Val: []resp.Message{bulkString(popped[0].Member), doubleReply(conn, popped[0].Score)}}
+			return
+		}
+		res = zsetReply(conn, popped, true)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zpop removes up to count entries from one end of the sorted set stored at key, deleting
+// the key if it ends up empty
+func zpop(ks *db.Keyspace, key string, zset *ds.SortedSet, count int64, max bool) []ds.ZEntry {
+	n := int(min(count, int64(zset.Len())))
+	var popped []ds.ZEntry
+	if max {
+		popped = zset.Range(zset.Len()-n, zset.Len(), true)
+	} else {
+		popped = zset.Range(0, n, false)
+	}
+	for _, e := range popped {
+		zset.Remove(e.Member)
+	}
+	if zset.Len() == 0 {
+		ks.Delete(key)
+	}
+	return popped
+}
+
+// notifyZPop publishes the events of a pop from the sorted set stored at key, and its
+// deletion if the pop emptied it
+func notifyZPop(key string, zset *ds.SortedSet, max bool) {
+	if max {
+		db.Notify(db.NotifyZSet, "zpopmax", key)
+	} else {
+		db.Notify(db.NotifyZSet, "zpopmin", key)
+	}
+	if zset.Len() == 0 {
+		db.Notify(db.NotifyGeneric, "del", key)
+	}
+}
+
+// bzpopmin is the blocking variant of ZPOPMIN
+func bzpopmin(args *resp.Array, conn *pubsub.Connection) {
+	bzpopGeneric(args, conn, "bzpopmin", false)
+}
+
+// bzpopmax is the blocking variant of ZPOPMAX
+func bzpopmax(args *resp.Array, conn *pubsub.Connection) {
+	bzpopGeneric(args, conn, "bzpopmax", true)
+}
+
+// bzpopGeneric pops one entry from the first non-empty sorted set among the keys, waiting up
+// to the timeout for one to be written. It replies with [key, member, score], or a null array
+// on timeout
+func bzpopGeneric(args *resp.Array, conn *pubsub.Connection, name string, max bool) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys := argv[1 : len(argv)-1]
+
+	timeout, err := parseTimeout(argv[len(argv)-1])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	popCmd := "ZPOPMIN"
+	if max {
+		popCmd = "ZPOPMAX"
+	}
+
+	res := blockOn(conn, keys, timeout, func(ks *db.Keyspace) resp.Message {
+		for _, key := range keys {
+			zset, err := ks.ZSet(key)
+			if err != nil {
+				return &resp.SimpleError{Val: []byte(err.Error())}
+			}
+			if zset == nil {
+				continue
+			}
+
+			popped := zpop(ks, key, zset, 1, max)
+			// Replaying a blocking pop must never block, it is logged as the pop it turned into
+			ks.Touch(key)
+			aof.Feed([]byte(popCmd), []byte(key))
+			notifyZPop(key, zset, max)
+			return &resp.Array{Val: []resp.Message{
+				bulkString(key),
+				bulkString(popped[0].Member),
+				doubleReply(conn, popped[0].Score),
+			}}
+		}
+		return nil
+	})
+	if res == nil {
+		conn.W.Write(nullArray(conn))
+		return
+	}
+	conn.W.Write(res.ToBytes())
+}
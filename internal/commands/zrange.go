@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zrangeType is what the bounds of a ZRANGE are compared against
+type zrangeType int
+
+const (
+	zrangeAuto zrangeType = iota // ZRANGE, decided by the BYSCORE and BYLEX options
+	zrangeRank
+	zrangeScore
+	zrangeLex
+)
+
+// zrangeSpec holds the parsed form of every command reading a range of a sorted set
+type zrangeSpec struct {
+	by            zrangeType
+	rev           bool
+	withScores    bool
+	limit         bool
+	offset, count int64
+}
+
+// zrange replies with a range of a sorted set selected by rank, score or member, see
+// zrangeGeneric
+func zrange(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrange", zrangeAuto, false)
+}
+
+// zrevrange is ZRANGE REV for ranks
+func zrevrange(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrevrange", zrangeRank, true)
+}
+
+// zrangebyscore is ZRANGE BYSCORE
+func zrangebyscore(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrangebyscore", zrangeScore, false)
+}
+
+// zrevrangebyscore is ZRANGE BYSCORE REV, it takes max before min
+func zrevrangebyscore(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrevrangebyscore", zrangeScore, true)
+}
+
+// zrangebylex is ZRANGE BYLEX
+func zrangebylex(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrangebylex", zrangeLex, false)
+}
+
+// zrevrangebylex is ZRANGE BYLEX REV, it takes max before min
+func zrevrangebylex(args *resp.Array, conn *pubsub.Connection) {
+	zrangeGeneric(args, conn, "zrevrangebylex", zrangeLex, true)
+}
+
+// zrangeGeneric implements ZRANGE and the older commands it replaces, which fix the type of
+// range and its direction instead of taking them as options. A REV range by score or member
+// takes max before min, LIMIT skips and counts entries in the order they are returned
+func zrangeGeneric(args *resp.Array, conn *pubsub.Connection, name string, by zrangeType, rev bool) {
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key := argv[1]
+
+	spec, err := parseZrangeOptions(argv[4:], by, rev)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	lo, hi := argv[2], argv[3]
+	if spec.rev && spec.by != zrangeRank {
+		lo, hi = hi, lo
+	}
+
+	// Bounds are parsed before the key is looked at, so a malformed range is an error even
+	// when the key doesn't exist
+	selectRange, err := parseRangeBounds(spec, lo, hi)
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(err.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = zsetReply(conn, nil, spec.withScores)
+			return
+		}
+
+		start, stop := selectRange(zset)
+		if spec.limit {
+			start, stop = applyLimit(start, stop, spec.offset, spec.count, spec.rev)
+		}
+		res = zsetReply(conn, zset.Range(start, stop, spec.rev), spec.withScores)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// parseZrangeOptions parses the options following the bounds. BYSCORE, BYLEX and REV are
+// only accepted by ZRANGE itself, which is the only caller passing zrangeAuto
+func parseZrangeOptions(opts []string, by zrangeType, rev bool) (zrangeSpec, error) {
+	spec := zrangeSpec{by: by, rev: rev}
+	fixed := by != zrangeAuto
+	for i := 0; i < len(opts); i++ {
+		switch opt := strings.ToLower(opts[i]); {
+		case opt == "withscores":
+			spec.withScores = true
+		case opt == "limit" && i+2 < len(opts):
+			offset, err1 := strconv.ParseInt(opts[i+1], 10, 64)
+			count, err2 := strconv.ParseInt(opts[i+2], 10, 64)
+			if err1 != nil || err2 != nil {
+				return spec, errNotInteger
+			}
+			spec.limit, spec.offset, spec.count = true, offset, count
+			i += 2
+		case opt == "rev" && !fixed:
+			spec.rev = true
+		case opt == "byscore" && spec.by == zrangeAuto:
+			spec.by = zrangeScore
+		case opt == "bylex" && spec.by == zrangeAuto:
+			spec.by = zrangeLex
+		default:
+			return spec, errSyntax
+		}
+	}
+
+	if spec.by == zrangeAuto {
+		spec.by = zrangeRank
+	}
+	if spec.limit && spec.by == zrangeRank {
+		return spec, errors.New("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if spec.withScores && spec.by == zrangeLex {
+		return spec, errors.New("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	return spec, nil
+}
+
+// parseRangeBounds parses the bounds of a range of the given type and returns a function
+// finding the ascending ranks [start, stop) they select
+func parseRangeBounds(spec zrangeSpec, lo, hi string) (func(zset *ds.SortedSet) (int, int), error) {
+	switch spec.by {
+	case zrangeScore:
+		min, err := parseScoreBound(lo)
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseScoreBound(hi)
+		if err != nil {
+			return nil, err
+		}
+		return func(zset *ds.SortedSet) (int, int) { return scoreRange(zset, min, max) }, nil
+	case zrangeLex:
+		min, err := parseLexBound(lo)
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseLexBound(hi)
+		if err != nil {
+			return nil, err
+		}
+		return func(zset *ds.SortedSet) (int, int) { return lexRange(zset, min, max) }, nil
+	}
+
+	start, err := strconv.ParseInt(lo, 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	stop, err := strconv.ParseInt(hi, 10, 64)
+	if err != nil {
+		return nil, errNotInteger
+	}
+	return func(zset *ds.SortedSet) (int, int) { return rankRange(zset.Len(), start, stop, spec.rev) }, nil
+}
+
+// rankRange turns a start and stop index, inclusive and possibly negative, into the ascending
+// ranks [start, stop). For a reversed range the indexes count from the highest score
+func rankRange(length int, start, stop int64, rev bool) (int, int) {
+	n := int64(length)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	start = max(start, 0)
+	stop = min(stop, n-1)
+	if start > stop {
+		return 0, 0
+	}
+	if rev {
+		return int(n - 1 - stop), int(n - start)
+	}
+	return int(start), int(stop + 1)
+}
+
+// applyLimit narrows the ranks [start, stop) to count entries after skipping offset of them,
+// counting from stop for a reversed range. A negative count returns every remaining entry and
+// a negative offset none
+func applyLimit(start, stop int, offset, count int64, rev bool) (int, int) {
+	n := int64(rangeLen(start, stop))
+	if offset < 0 || offset >= n {
+		return 0, 0
+	}
+	if count < 0 || count > n-offset {
+		count = n - offset
+	}
+	if rev {
+		return stop - int(offset+count), stop - int(offset)
+	}
+	return start + int(offset), start + int(offset+count)
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zrank replies with the 0 based rank of member in ascending order, or null if it doesn't exist
+func zrank(args *resp.Array, conn *pubsub.Connection) {
+	zrankGeneric(args, conn, "zrank", false)
+}
+
+// zrevrank replies with the 0 based rank of member in descending order
+func zrevrank(args *resp.Array, conn *pubsub.Connection) {
+	zrankGeneric(args, conn, "zrevrank", true)
+}
+
+// zrankGeneric implements ZRANK and ZREVRANK. With WITHSCORE the reply is a [rank, score] pair
+func zrankGeneric(args *resp.Array, conn *pubsub.Connection, name string, rev bool) {
+	if len(args.Val) != 3 && len(args.Val) != 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, member := argv[1], argv[2]
+
+	withScore := len(argv) == 4
+	if withScore && !strings.EqualFold(argv[3], "withscore") {
+		msg := resp.SimpleError{Val: []byte("ERR syntax error")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		var rank int
+		found := false
+		if zset != nil {
+			rank, found = zset.Rank(member)
+		}
+		if !found {
+			res = nullReply(conn)
+			return
+		}
+
+		if rev {
+			rank = zset.Len() - 1 - rank
+		}
+		if !withScore {
+			res = &resp.Integer{Val: int64(rank)}
+			return
+		}
+		score, _ := zset.Score(member)
+		res = &resp.Array{Val: []resp.Message{&resp.Integer{Val: int64(rank)}, doubleReply(conn, score)}}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
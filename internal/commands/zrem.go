@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zrem removes members from a sorted set and replies with the number of members that
+// existed. The key is deleted along with its last member
+func zrem(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zrem' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zrem' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, members := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = &resp.Integer{Val: 0}
+			return
+		}
+
+		removed := 0
+		for _, member := range members {
+			if zset.Remove(member) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			if zset.Len() == 0 {
+				ks.Delete(key)
+			}
+			ks.Touch(key)
+			propagate(args)
+			db.Notify(db.NotifyZSet, "zrem", key)
+			if zset.Len() == 0 {
+				db.Notify(db.NotifyGeneric, "del", key)
+			}
+		}
+		res = &resp.Integer{Val: int64(removed)}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
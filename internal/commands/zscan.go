@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zscan iterates over the members of a sorted set and their scores, see ds.ScanTable.Scan for
+// the guarantees of the cursor
+func zscan(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	key, ok := args.Val[1].(*resp.BulkString)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type for the 2nd argument of 'zscan' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	opts, errMsg := parseScanOptions(args, false)
+	if errMsg != nil {
+		conn.W.Write(errMsg.ToBytes())
+		return
+	}
+
+	var res resp.Message
+	do(conn, []string{string(key.Str)}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(string(key.Str))
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset == nil {
+			res = scanReply(0, nil)
+			return
+		}
+
+		members, cursor := zset.Scan(opts.cursor, opts.count)
+		members = scanMatch(members, opts)
+		items := make([]string, 0, 2*len(members))
+		for _, member := range members {
+			score, _ := zset.Score(member)
+			items = append(items, member, formatScore(score))
+		}
+		res = scanReply(cursor, items)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zscore replies with the score of member, or null if the member or the key doesn't exist
+func zscore(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) != 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zscore' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zscore' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, member := argv[1], argv[2]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+		if zset != nil {
+			if score, ok := zset.Score(member); ok {
+				res = doubleReply(conn, score)
+				return
+			}
+		}
+		res = nullReply(conn)
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zmscore replies with the score of every member, null for the ones that don't exist
+func zmscore(args *resp.Array, conn *pubsub.Connection) {
+	if len(args.Val) < 3 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for 'zmscore' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for 'zmscore' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	key, members := argv[1], argv[2:]
+
+	var res resp.Message
+	do(conn, []string{key}, func(ks *db.Keyspace) {
+		zset, err := ks.ZSet(key)
+		if err != nil {
+			res = &resp.SimpleError{Val: []byte(err.Error())}
+			return
+		}
+
+		arr := &resp.Array{Val: make([]resp.Message, 0, len(members))}
+		for _, member := range members {
+			if zset != nil {
+				if score, ok := zset.Score(member); ok {
+					arr.Val = append(arr.Val, doubleReply(conn, score))
+					continue
+				}
+			}
+			arr.Val = append(arr.Val, nullReply(conn))
+		}
+		res = arr
+	})
+
+	conn.W.Write(res.ToBytes())
+}
@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"iter"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/pubsub"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// zaggregate is how the scores of a member found in several inputs are combined
+type zaggregate int
+
+const (
+	zaggSum zaggregate = iota
+	zaggMin
+	zaggMax
+)
+
+// apply combines two scores, a sum of infinities of opposite signs counts as 0
+func (agg zaggregate) apply(a, b float64) float64 {
+	switch agg {
+	case zaggMin:
+		return min(a, b)
+	case zaggMax:
+		return max(a, b)
+	}
+	if sum := a + b; !math.IsNaN(sum) {
+		return sum
+	}
+	return 0
+}
+
+// zstoreInput is one of the keys of ZUNIONSTORE or ZINTERSTORE. Plain sets are accepted and
+// every member has a score of 1
+type zstoreInput struct {
+	zset   *ds.SortedSet
+	set    *db.SetEntry
+	weight float64
+}
+
+func (in zstoreInput) len() int {
+	switch {
+	case in.zset != nil:
+		return in.zset.Len()
+	case in.set != nil:
+		return in.set.Len()
+	}
+	return 0
+}
+
+// score returns the weighted score of member, a weight of 0 applied to an infinite score
+// gives 0
+func (in zstoreInput) score(member string) (float64, bool) {
+	var score float64
+	switch {
+	case in.zset != nil:
+		s, ok := in.zset.Score(member)
+		if !ok {
+			return 0, false
+		}
+		score = s
+	case in.set != nil:
+		if !in.set.Contains(member) {
+			return 0, false
+		}
+		score = 1
+	default:
+		return 0, false
+	}
+	return in.weigh(score), true
+}
+
+func (in zstoreInput) weigh(score float64) float64 {
+	if weighted := score * in.weight; !math.IsNaN(weighted) {
+		return weighted
+	}
+	return 0
+}
+
+// all iterates over the members of the input with their weighted scores
+func (in zstoreInput) all() iter.Seq[ds.ZEntry] {
+	return func(yield func(ds.ZEntry) bool) {
+		switch {
+		case in.zset != nil:
+			for e := range in.zset.All() {
+				if !yield(ds.ZEntry{Member: e.Member, Score: in.weigh(e.Score)}) {
+					return
+				}
+			}
+		case in.set != nil:
+			for member := range in.set.Members() {
+				if !yield(ds.ZEntry{Member: member, Score: in.weigh(1)}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func zunionstore(args *resp.Array, conn *pubsub.Connection) {
+	zsetStoreCommand(args, conn, "zunionstore", setUnion)
+}
+
+func zinterstore(args *resp.Array, conn *pubsub.Connection) {
+	zsetStoreCommand(args, conn, "zinterstore", setInter)
+}
+
+// zsetStoreCommand implements ZUNIONSTORE and ZINTERSTORE. The scores of every input are
+// multiplied by its weight and combined with the aggregate function, the destination is
+// replaced by the result or deleted if the result is empty
+func zsetStoreCommand(args *resp.Array, conn *pubsub.Connection, name string, op setOp) {
+	if len(args.Val) < 4 {
+		msg := resp.SimpleError{Val: []byte("ERR wrong number of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+
+	argv, ok := stringArgs(args)
+	if !ok {
+		msg := resp.SimpleError{Val: []byte("ERR wrong data type of arguments for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	destination := argv[1]
+
+	numKeys, err := strconv.Atoi(argv[2])
+	if err != nil {
+		msg := resp.SimpleError{Val: []byte(errNotInteger.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if numKeys <= 0 {
+		msg := resp.SimpleError{Val: []byte("ERR at least 1 input key is needed for '" + name + "' command")}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	if numKeys > len(argv)-3 {
+		msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+		conn.W.Write(msg.ToBytes())
+		return
+	}
+	keys := argv[3 : 3+numKeys]
+
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	agg := zaggSum
+	for i := 3 + numKeys; i < len(argv); i++ {
+		switch opt := strings.ToLower(argv[i]); {
+		case opt == "weights" && i+numKeys < len(argv):
+			for j := range weights {
+				weights[j], err = strconv.ParseFloat(argv[i+1+j], 64)
+				if err != nil || math.IsNaN(weights[j]) {
+					msg := resp.SimpleError{Val: []byte("ERR weight value is not a float")}
+					conn.W.Write(msg.ToBytes())
+					return
+				}
+			}
+			i += numKeys
+		case opt == "aggregate" && i+1 < len(argv):
+			switch strings.ToLower(argv[i+1]) {
+			case "sum":
+				agg = zaggSum
+			case "min":
+				agg = zaggMin
+			case "max":
+				agg = zaggMax
+			default:
+				msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+				conn.W.Write(msg.ToBytes())
+				return
+			}
+			i++
+		default:
+			msg := resp.SimpleError{Val: []byte(errSyntax.Error())}
+			conn.W.Write(msg.ToBytes())
+			return
+		}
+	}
+
+	var res resp.Message
+	do(conn, append([]string{destination}, keys...), func(ks *db.Keyspace) {
+		inputs := make([]zstoreInput, len(keys))
+		for i, key := range keys {
+			inputs[i].weight = weights[i]
+			e := ks.Lookup(key)
+			switch {
+			case e == nil:
+			case e.Type == db.TypeZSet:
+				inputs[i].zset = e.ZSet
+			case e.Type == db.TypeSet:
+				inputs[i].set = e.Set
+			default:
+				res = &resp.SimpleError{Val: []byte(db.ErrWrongType.Error())}
+				return
+			}
+		}
+
+		result := zsetAlgebra(op, inputs, agg)
+		existed := false
+		if result.Len() == 0 {
+			existed = ks.Delete(destination)
+		} else {
+			ks.Put(destination, &db.Entry{Type: db.TypeZSet, ZSet: result})
+			for range result.Len() {
+				if !ks.Wake(destination) {
+					break
+				}
+			}
+		}
+		ks.Touch(destination)
+		propagate(args)
+		switch {
+		case result.Len() > 0:
+			db.Notify(db.NotifyZSet, name, destination)
+		case existed:
+			db.Notify(db.NotifyGeneric, "del", destination)
+		}
+		res = &resp.Integer{Val: int64(result.Len())}
+	})
+
+	conn.W.Write(res.ToBytes())
+}
+
+// zsetAlgebra computes the union or the intersection of the inputs
+func zsetAlgebra(op setOp, inputs []zstoreInput, agg zaggregate) *ds.SortedSet {
+	result := ds.NewSortedSet()
+	if op == setUnion {
+		scores := make(map[string]float64)
+		for _, in := range inputs {
+			for e := range in.all() {
+				if score, ok := scores[e.Member]; ok {
+					scores[e.Member] = agg.apply(score, e.Score)
+					continue
+				}
+				scores[e.Member] = e.Score
+			}
+		}
+		for member, score := range scores {
+			result.Add(member, score)
+		}
+		return result
+	}
+
+	// Walking the smallest input keeps the number of lookups down, the scores are still
+	// combined in the order the keys were given
+	smallest := slices.MinFunc(inputs, func(a, b zstoreInput) int { return a.len() - b.len() })
+	for e := range smallest.all() {
+		var score float64
+		found := true
+		for i, in := range inputs {
+			s, ok := in.score(e.Member)
+			if !ok {
+				found = false
+				break
+			}
+			if i == 0 {
+				score = s
+				continue
+			}
+			score = agg.apply(score, s)
+		}
+		if found {
+			result.Add(e.Member, score)
+		}
+	}
+	return result
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+)
+
+// waiter is the registration of one client blocked on one key
+type waiter struct {
+	ch    chan struct{} // receives a value when the client is woken
+	woken bool          // signaled and not yet done with the wakeup
+}
+
+// waitQueue holds the clients blocked on a key, oldest first. Clients are served in the
+// order they blocked: Wake signals the oldest one, and wakeups arriving while a woken client
+// hasn't run yet are kept in pending. They are handed to the next client in line once the
+// woken one is served or gives up, so a younger client is never woken ahead of an older one
+type waitQueue struct {
+	waiters ds.Deque[*waiter]
+	woken   int // waiters signaled and not yet done
+	pending int // wakeups waiting for the woken waiters to be done
+}
+
+func (q *waitQueue) find(ch chan struct{}) (int, *waiter) {
+	for i := range q.waiters.Len() {
+		if w := q.waiters.At(i); w.ch == ch {
+			return i, w
+		}
+	}
+	return -1, nil
+}
+
+func (q *waitQueue) signal(w *waiter) {
+	w.woken = true
+	q.woken++
+	w.ch <- struct{}{}
+}
+
+// settle hands a pending wakeup to the oldest waiter once no waiter is woken anymore
+func (q *waitQueue) settle() {
+	if q.waiters.Len() == 0 {
+		q.pending = 0
+		return
+	}
+	if q.woken == 0 && q.pending > 0 {
+		q.pending--
+		w, _ := q.waiters.Front()
+		q.signal(w)
+	}
+}
+
+// Block registers a client waiting for key to become ready. The returned channel receives
+// a value when Wake picks this client. The registration lasts until Unblock, after a wakeup
+// that didn't serve the client Rearm keeps its place in line
+func (ks *Keyspace) Block(key string) chan struct{} {
+	s := ks.shard(key)
+	q, ok := s.blocked[key]
+	if !ok {
+		q = &waitQueue{waiters: *ds.NewDeque[*waiter]()}
+		s.blocked[key] = q
+	}
+	ch := make(chan struct{}, 1)
+	q.waiters.PushBack(&waiter{ch: ch})
+	return ch
+}
+
+// Woken reports whether the registration made with Block has been signaled
+func (ks *Keyspace) Woken(key string, ch chan struct{}) bool {
+	q, ok := ks.shard(key).blocked[key]
+	if !ok {
+		return false
+	}
+	_, w := q.find(ch)
+	return w != nil && w.woken
+}
+
+// Rearm is called by a client that was woken but couldn't be served, for instance because
+// another client took the element first. The client keeps its place in line and a pending
+// wakeup, if any, goes to the oldest waiter
+func (ks *Keyspace) Rearm(key string, ch chan struct{}) {
+	q, ok := ks.shard(key).blocked[key]
+	if !ok {
+		return
+	}
+	_, w := q.find(ch)
+	if w == nil || !w.woken {
+		return
+	}
+	// A client woken on several keys only consumed one of the signals
+	select {
+	case <-ch:
+	default:
+	}
+	w.woken = false
+	q.woken--
+	q.settle()
+}
+
+// Unblock removes a registration made with Block. It returns true if the registration had
+// been woken, in which case the caller decides whether the wakeup must be passed on
+func (ks *Keyspace) Unblock(key string, ch chan struct{}) bool {
+	s := ks.shard(key)
+	q, ok := s.blocked[key]
+	if !ok {
+		return false
+	}
+	i, w := q.find(ch)
+	if w == nil {
+		return false
+	}
+	if i == 0 {
+		q.waiters.PopFront()
+	} else {
+		q.waiters.Remove(w)
+	}
+	if w.woken {
+		q.woken--
+	}
+	q.settle()
+	if q.waiters.Len() == 0 {
+		delete(s.blocked, key)
+	}
+	return w.woken
+}
+
+// Wake signals the client that has been blocked on key the longest. If an older client was
+// woken and hasn't run yet the wakeup is kept for the next one in line. It returns false if
+// no client is left to wake
+func (ks *Keyspace) Wake(key string) bool {
+	q, ok := ks.shard(key).blocked[key]
+	if !ok {
+		return false
+	}
+	if q.woken == 0 {
+		w, _ := q.waiters.Front()
+		q.signal(w)
+		return true
+	}
+	if q.pending < q.waiters.Len()-q.woken {
+		q.pending++
+		return true
+	}
+	return false
+}
+
+// WakeAll signals every client blocked on key, for commands like XREAD whose clients don't
+// consume what they are woken for
+func (ks *Keyspace) WakeAll(key string) {
+	q, ok := ks.shard(key).blocked[key]
+	if !ok {
+		return
+	}
+	for i := range q.waiters.Len() {
+		if w := q.waiters.At(i); !w.woken {
+			q.signal(w)
+		}
+	}
+	q.pending = 0
+}
@@ -0,0 +1,66 @@
+package db
+
+import "testing"
+
+func newTestKeyspace() *Keyspace {
+	ks := &Keyspace{}
+	for i := range ks.shards {
+		ks.shards[i] = &Shard{blocked: make(map[string]*waitQueue)}
+	}
+	return ks
+}
+
+func signaled(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestWakeServesOldestFirst(t *testing.T) {
+	ks := newTestKeyspace()
+	a, b, c := ks.Block("k"), ks.Block("k"), ks.Block("k")
+
+	// Two wakeups: only the oldest client is signaled, the second one waits for it
+	ks.Wake("k")
+	ks.Wake("k")
+	if !signaled(a) || signaled(b) || signaled(c) {
+		t.Fatal("Wake() should signal the oldest client only")
+	}
+
+	// a is served, its pending wakeup goes to b and not c
+	if !ks.Unblock("k", a) {
+		t.Error("Unblock() should report that a was woken")
+	}
+	if !signaled(b) || signaled(c) {
+		t.Fatal("the pending wakeup should go to b")
+	}
+
+	// b finds nothing and keeps its place ahead of c
+	ks.Rearm("k", b)
+	ks.Wake("k")
+	if !signaled(b) || signaled(c) {
+		t.Error("a rearmed client should keep its place in line")
+	}
+
+	// No more than one pending wakeup per waiting client is kept
+	if !ks.Wake("k") || ks.Wake("k") {
+		t.Error("Wake() should only count wakeups that a waiting client can use")
+	}
+}
+
+func TestWakeAll(t *testing.T) {
+	ks := newTestKeyspace()
+	a, b := ks.Block("k"), ks.Block("k")
+	ks.WakeAll("k")
+	if !signaled(a) || !signaled(b) {
+		t.Error("WakeAll() should signal every client")
+	}
+	ks.Unblock("k", a)
+	ks.Unblock("k", b)
+	if ks.Wake("k") {
+		t.Error("Wake() should return false once every client is gone")
+	}
+}
@@ -2,20 +2,138 @@ package db
 
 import (
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
 	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
 )
 
+// ValueType is the kind of value stored under a key, every key has exactly one
+type ValueType int
+
+const (
+	TypeString ValueType = iota
+	TypeList
+	TypeStream
+	TypeHash
+	TypeSet
+	TypeZSet
+)
+
+// String returns the name reported by the TYPE command
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeStream:
+		return "stream"
+	case TypeHash:
+		return "hash"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	}
+	return "none"
+}
+
+// Entry is the value stored under a key, only the field matching Type is set
 type Entry struct {
-	Value     []byte
-	ExpiresAt time.Time
+	Type      ValueType
+	Value     []byte          // TypeString
+	List      *ListEntry      // TypeList
+	Stream    *streams.Stream // TypeStream
+	Hash      *HashEntry      // TypeHash
+	Set       *SetEntry       // TypeSet
+	ZSet      *ds.SortedSet   // TypeZSet
+	ExpiresAt time.Time       // zero when the key has no expiry
+}
+
+// Encoding returns the name OBJECT ENCODING reports for the entry's in-memory representation
+func (e *Entry) Encoding() string {
+	switch e.Type {
+	case TypeString:
+		if v, err := strconv.ParseInt(string(e.Value), 10, 64); err == nil && strconv.FormatInt(v, 10) == string(e.Value) {
+			return "int"
+		}
+		if len(e.Value) <= 44 {
+			return "embstr"
+		}
+		return "raw"
+	case TypeList:
+		return "quicklist"
+	case TypeHash:
+		return "hashtable"
+	case TypeSet:
+		return e.Set.Encoding()
+	case TypeZSet:
+		return "skiplist"
+	}
+	return "stream"
+}
+
+// expired reports whether the key's deadline has passed
+func (e *Entry) expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && now.After(e.ExpiresAt)
 }
 
 type Shard struct {
-	kv map[string]Entry
-	ch chan Command
+	kv      map[string]*Entry
+	expires map[string]*Entry // subset of kv holding the keys with a ttl, sampled by the active expire cycle
+	ch      chan Command
+	blocked map[string]*waitQueue            // clients blocked on a key, oldest first
+	watched map[string]map[*Watcher]struct{} // clients watching a key with WATCH
+	tracked map[string]map[*Tracker]struct{} // clients caching a key they read, see CLIENT TRACKING
+	self    Keyspace                         // view holding only this shard, used by EXEC
+	stale   float64                          // running estimate of the share of expired keys among keys with a ttl
+}
+
+// lookup returns the entry stored at key, deleting it first if it has expired
+func (s *Shard) lookup(key string) *Entry {
+	e, ok := s.kv[key]
+	if !ok {
+		return nil
+	}
+	if e.expired(time.Now()) {
+		s.expire(key)
+		return nil
+	}
+	return e
+}
+
+// put stores e at key, replacing whatever was there
+func (s *Shard) put(key string, e *Entry) {
+	if _, ok := s.kv[key]; !ok {
+		Notify(NotifyNew, "new", key)
+	}
+	s.kv[key] = e
+	if e.ExpiresAt.IsZero() {
+		delete(s.expires, key)
+		return
+	}
+	s.expires[key] = e
+}
+
+// remove deletes key from the shard
+func (s *Shard) remove(key string) {
+	delete(s.kv, key)
+	delete(s.expires, key)
+}
+
+// setExpiry changes the deadline of an existing entry, a zero time removes it
+func (s *Shard) setExpiry(key string, e *Entry, at time.Time) {
+	e.ExpiresAt = at
+	if at.IsZero() {
+		delete(s.expires, key)
+		return
+	}
+	s.expires[key] = e
 }
 
 func NewCommand(key string, value []byte, ttl int64, c chan []byte, op MapCommands) Command {
@@ -33,8 +151,19 @@ type Command struct {
 	ttl   int64       // ttl as a 64 bit signed integer, (negative ttl = infinite)
 	c     chan []byte // channel where we expect the goroutine to push the
 	// return value
-	operation MapCommands // type of command being pushed
-	nx        bool        // NX flag: only set if key does not exist
+	operation MapCommands        // type of command being pushed
+	nx        bool               // NX flag: only set if key does not exist
+	release   chan struct{}      // PAUSE: the shard stays parked until this channel is closed
+	fn        func(ks *Keyspace) // EXEC: function run with exclusive access to the shard
+	caller    int64              // id of the client the command runs for, see Keyspace.For
+	reader    *Tracker           // tracks the key read by the command, nil when it isn't tracked
+}
+
+// For returns the command run on behalf of client id. The key it reads is remembered for
+// reader, which may be nil
+func (cmd Command) For(id int64, reader *Tracker) Command {
+	cmd.caller, cmd.reader = id, reader
+	return cmd
 }
 
 type MapCommands int
@@ -47,6 +176,8 @@ const (
 	CLEANUP
 	DEL
 	EXISTS
+	PAUSE
+	EXEC
 )
 
 var (
@@ -59,11 +190,16 @@ func InitKVStore() {
 		log.Printf("KVStore: Initializing %d shards...This should happen only once", Shards)
 		for i := range Shards {
 			shards[i] = &Shard{
-				kv: make(map[string]Entry),
-				ch: make(chan Command, 4096), // buffered channel
+				kv:      make(map[string]*Entry),
+				expires: make(map[string]*Entry),
+				ch:      make(chan Command, 4096), // buffered channel
+				blocked: make(map[string]*waitQueue),
+				watched: make(map[string]map[*Watcher]struct{}),
+				tracked: make(map[string]map[*Tracker]struct{}),
 			}
+			shards[i].self.shards[i] = shards[i]
 			go shardLoop(shards[i]) // for each shard launch a goroutine which acts as the single thread interacting with that shard, hence we don't lock
-			go deleteExpiredKeysForShard(shards[i])
+			go activeExpireForShard(shards[i])
 		}
 	})
 }
@@ -74,58 +210,60 @@ func GetShardChannel(key string) chan Command {
 	return shards[idx].ch
 }
 
-func shardLoop(s *Shard) {
-	for cmd := range s.ch {
-		// Don't use "default" here as it consumes unnecessary cpu: https://stackoverflow.com/questions/55367231/golang-for-select-loop-consumes-100-of-cpu
-		switch cmd.operation {
-		case GET:
-			handleGetCommand(s, cmd)
-		case SET:
-			handleSetCommand(s, cmd)
-		case TYPE:
-			handleTypeCommand(s, cmd)
-		case CLEANUP:
-			handleCleanupCommand(s)
-		case DEL:
-			handleDelCommand(s, cmd)
-		case EXISTS:
-			handleExistsCommand(s, cmd)
-		}
+// Dispatch routes cmd to the shard owning its key. When ks already holds that shard, as it
+// does while EXEC runs a transaction, the command runs right away on the caller's goroutine
+// since the shard goroutine is parked and would never pick it up
+func Dispatch(ks *Keyspace, cmd Command) {
+	if ks != nil {
+		runCommand(ks.shard(cmd.key), cmd)
+		return
 	}
+	GetShardChannel(cmd.key) <- cmd
 }
 
-// delete expired keys in a shard
-func handleCleanupCommand(s *Shard) {
-	now := time.Now()
-	for key, val := range s.kv {
-		if now.After(val.ExpiresAt) && !val.ExpiresAt.IsZero() {
-			delete(s.kv, key)
-		}
+func shardLoop(s *Shard) {
+	// Don't use "default" here as it consumes unnecessary cpu: https://stackoverflow.com/questions/55367231/golang-for-select-loop-consumes-100-of-cpu
+	for cmd := range s.ch {
+		runCommand(s, cmd)
 	}
 }
 
-func deleteExpiredKeysForShard(s *Shard) {
-	deleteOperationTicker := time.NewTicker(300 * time.Second)
-	defer deleteOperationTicker.Stop()
-	for {
-		<-deleteOperationTicker.C
-		s.ch <- NewCommand("", nil, 0, nil, CLEANUP) // push a cleanup command into the shard channel
-		// this is done so that we don't run into a race condition where this function and the shard
-		// channel access the map at the same time
+// runCommand executes one command on the shard
+func runCommand(s *Shard, cmd Command) {
+	switch cmd.operation {
+	case GET:
+		handleGetCommand(s, cmd)
+	case SET:
+		handleSetCommand(s, cmd)
+	case TYPE:
+		handleTypeCommand(s, cmd)
+	case CLEANUP:
+		handleCleanupCommand(s)
+	case DEL:
+		handleDelCommand(s, cmd)
+	case EXISTS:
+		handleExistsCommand(s, cmd)
+	case PAUSE:
+		handlePauseCommand(cmd)
+	case EXEC:
+		handleExecCommand(s, cmd)
+	}
+	if cmd.reader != nil {
+		s.self.Track(cmd.key, cmd.reader)
 	}
 }
 
 // When we get a GET command
 func handleGetCommand(s *Shard, g Command) {
-	val, ok := s.kv[g.key]
-	if !ok {
+	val := s.lookup(g.key)
+	if val == nil {
+		Notify(NotifyKeyMiss, "keymiss", g.key)
 		g.c <- ([]byte("$-1\r\n")) // use raw byte arrays where we can to reduce conversion cost by CPU
 		return
 	}
 
-	if time.Now().After(val.ExpiresAt) && !val.ExpiresAt.IsZero() {
-		delete(s.kv, g.key)
-		g.c <- ([]byte("$-1\r\n")) // use raw byte arrays where we can to reduce conversion cost by CPU
+	if val.Type != TypeString {
+		g.c <- wrongTypeReply
 		return
 	}
 
@@ -148,75 +286,79 @@ func handleSetCommand(shard *Shard, cmd Command) {
 
 	// NX: only set if key does not exist
 	if cmd.nx {
-		existing, exists := shard.kv[cmd.key]
 		// Check if key exists and is not expired
-		if exists && (existing.ExpiresAt.IsZero() || time.Now().Before(existing.ExpiresAt)) {
+		if shard.lookup(cmd.key) != nil {
 			cmd.c <- []byte("$-1\r\n") // return nil if key already exists
 			return
 		}
 	}
 
 	if cmd.ttl < 0 {
-		shard.kv[cmd.key] = Entry{Value: cmd.value}
+		shard.put(cmd.key, &Entry{Type: TypeString, Value: cmd.value})
+		shard.touch(cmd.key, cmd.caller)
+		aof.Feed([]byte("SET"), []byte(cmd.key), cmd.value)
+		Notify(NotifyString, "set", cmd.key)
 		cmd.c <- []byte("+OK\r\n") // use raw byte arrays where we can to reduce conversion cost by CPU
 		return
 	}
 
 	expiry := time.Now().Add(time.Millisecond * time.Duration(cmd.ttl))
 
-	shard.kv[cmd.key] = Entry{Value: cmd.value, ExpiresAt: expiry}
+	shard.put(cmd.key, &Entry{Type: TypeString, Value: cmd.value, ExpiresAt: expiry})
+	shard.touch(cmd.key, cmd.caller)
+	// The relative ttl is logged as an absolute deadline so replaying the AOF later
+	// doesn't extend the key's lifetime
+	aof.Feed([]byte("SET"), []byte(cmd.key), cmd.value, []byte("PXAT"), []byte(strconv.FormatInt(expiry.UnixMilli(), 10)))
+	Notify(NotifyString, "set", cmd.key)
+	Notify(NotifyGeneric, "expire", cmd.key)
 	cmd.c <- []byte("+OK\r\n") // use raw byte arrays where we can to reduce conversion cost by CPU
 }
 
 func handleTypeCommand(s *Shard, cmd Command) {
-	val, ok := s.kv[cmd.key]
-	if !ok {
+	val := s.lookup(cmd.key)
+	if val == nil {
 		cmd.c <- []byte("+none\r\n") // use raw byte arrays where we can to reduce conversion cost by CPU
 		return
 	}
 
-	if time.Now().After(val.ExpiresAt) && !val.ExpiresAt.IsZero() {
-		delete(s.kv, cmd.key)
-		cmd.c <- []byte("+none\r\n") // use raw byte arrays where we can to reduce conversion cost by CPU
-		return
-	}
-
-	cmd.c <- []byte("+string\r\n") // use raw byte arrays where we can to reduce conversion cost by CPU
+	cmd.c <- []byte("+" + val.Type.String() + "\r\n")
 }
 
 // handleDelCommand deletes a key and returns 1 if it existed, 0 otherwise
 func handleDelCommand(s *Shard, cmd Command) {
-	val, ok := s.kv[cmd.key]
-	if !ok {
+	// Expired keys are treated as not existing
+	if s.lookup(cmd.key) == nil {
 		cmd.c <- []byte(":0\r\n") // key did not exist
 		return
 	}
 
-	// Check if key is expired
-	if !val.ExpiresAt.IsZero() && time.Now().After(val.ExpiresAt) {
-		delete(s.kv, cmd.key)
-		cmd.c <- []byte(":0\r\n") // key was expired, treat as not existing
-		return
-	}
-
-	delete(s.kv, cmd.key)
+	s.remove(cmd.key)
+	s.touch(cmd.key, cmd.caller)
+	aof.Feed([]byte("DEL"), []byte(cmd.key))
+	Notify(NotifyGeneric, "del", cmd.key)
 	cmd.c <- []byte(":1\r\n") // key existed and was deleted
 }
 
 // handleExistsCommand checks if a key exists and returns 1 if it does, 0 otherwise
 func handleExistsCommand(s *Shard, cmd Command) {
-	val, ok := s.kv[cmd.key]
-	if !ok {
+	// Expired keys are treated as not existing
+	if s.lookup(cmd.key) == nil {
 		cmd.c <- []byte(":0\r\n") // key does not exist
 		return
 	}
 
-	// Check if key is expired
-	if !val.ExpiresAt.IsZero() && time.Now().After(val.ExpiresAt) {
-		delete(s.kv, cmd.key)
-		cmd.c <- []byte(":0\r\n") // key was expired, treat as not existing
-		return
-	}
-
 	cmd.c <- []byte(":1\r\n") // key exists
 }
+
+// handlePauseCommand acknowledges the pause and parks the shard goroutine until released,
+// giving the caller exclusive access to the shard for the duration
+func handlePauseCommand(cmd Command) {
+	cmd.c <- nil
+	<-cmd.release
+}
+
+// handleExecCommand runs the function of an EXEC command on the shard goroutine
+func handleExecCommand(s *Shard, cmd Command) {
+	cmd.fn(&s.self)
+	cmd.c <- nil
+}
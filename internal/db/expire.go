@@ -0,0 +1,127 @@
+package db
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/aof"
+)
+
+// Tuning of the active expire cycle, same defaults as Redis
+const (
+	expireCycleKeysPerLoop   = 20 // keys sampled per round
+	expireCycleAcceptable    = 10 // percentage of expired keys in a sample below which the cycle stops
+	expireCycleTimeLimitPerc = 25 // share of each 1/hz period the cycle may run for
+	DefaultHz                = 10
+	MinHz                    = 1
+	MaxHz                    = 500
+)
+
+var (
+	hz atomic.Int64
+
+	expiredKeys             atomic.Int64 // keys deleted because their ttl elapsed, lazily or actively
+	expireTimeCapReached    atomic.Int64 // cycles that stopped because they ran out of time
+	expireCycleMicroseconds atomic.Int64 // total time spent in active expire cycles
+)
+
+func init() {
+	hz.Store(DefaultHz)
+}
+
+// SetHz changes how many times per second every shard runs an active expire cycle, values
+// outside [MinHz, MaxHz] are clamped
+func SetHz(n int64) {
+	hz.Store(min(max(n, MinHz), MaxHz))
+}
+
+// ExpireStats holds the counters reported by INFO
+type ExpireStats struct {
+	ExpiredKeys          int64
+	StalePerc            float64 // estimated percentage of already expired keys among keys with a ttl
+	TimeCapReachedCount  int64
+	CycleCPUMilliseconds int64
+}
+
+// GetExpireStats returns the expiry counters, the stale estimate is averaged over all shards
+func GetExpireStats() ExpireStats {
+	stats := ExpireStats{
+		ExpiredKeys:          expiredKeys.Load(),
+		TimeCapReachedCount:  expireTimeCapReached.Load(),
+		CycleCPUMilliseconds: expireCycleMicroseconds.Load() / 1000,
+	}
+
+	// The estimate is owned by the shard goroutine, so it is read there
+	var total float64
+	for _, s := range shards {
+		done := make(chan []byte, 1)
+		s.ch <- Command{operation: EXEC, c: done, fn: func(*Keyspace) { total += s.stale }}
+		<-done
+	}
+	stats.StalePerc = total / Shards * 100
+	return stats
+}
+
+// expire deletes a key whose deadline has passed. The deletion is logged so that the AOF
+// never depends on the clock of the machine replaying it, and published as an expired event
+func (s *Shard) expire(key string) {
+	s.remove(key)
+	s.touch(key, 0)
+	expiredKeys.Add(1)
+	aof.Feed([]byte("DEL"), []byte(key))
+	Notify(NotifyExpired, "expired", key)
+}
+
+// activeExpireForShard triggers an expire cycle on the shard hz times per second. The cycle
+// itself runs on the shard goroutine so it never races with commands
+func activeExpireForShard(s *Shard) {
+	for {
+		time.Sleep(time.Second / time.Duration(hz.Load()))
+		s.ch <- NewCommand("", nil, 0, nil, CLEANUP)
+	}
+}
+
+// handleCleanupCommand runs one active expire cycle: keys with a ttl are sampled in rounds
+// of expireCycleKeysPerLoop, and a new round starts as long as more than
+// expireCycleAcceptable percent of the sample had expired. Only a slice of the 1/hz period
+// is spent here so the shard keeps serving commands even when many keys expire at once
+func handleCleanupCommand(s *Shard) {
+	start := time.Now()
+	budget := time.Second * expireCycleTimeLimitPerc / 100 / time.Duration(hz.Load())
+
+	sampledTotal, expiredTotal := 0, 0
+	for len(s.expires) > 0 {
+		sampled, expired := 0, 0
+		now := time.Now()
+
+		// Map iteration starts at a random position, so the first keys are a random sample
+		for key, e := range s.expires {
+			if sampled == expireCycleKeysPerLoop {
+				break
+			}
+			sampled++
+			if e.expired(now) {
+				s.expire(key)
+				expired++
+			}
+		}
+		sampledTotal += sampled
+		expiredTotal += expired
+
+		if time.Since(start) > budget {
+			expireTimeCapReached.Add(1)
+			break
+		}
+		if expired*100 <= sampled*expireCycleAcceptable {
+			break
+		}
+	}
+
+	// Smooth the estimate the same way Redis does for expired_stale_perc
+	current := 0.0
+	if sampledTotal > 0 {
+		current = float64(expiredTotal) / float64(sampledTotal)
+	}
+	s.stale = current*0.05 + s.stale*0.95
+	expireCycleMicroseconds.Add(time.Since(start).Microseconds())
+}
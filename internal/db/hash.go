@@ -0,0 +1,117 @@
+package db
+
+import (
+	"iter"
+	"maps"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+)
+
+// HashEntry maps fields to values. The fields are also kept in a ScanTable so HSCAN resumes
+// where it stopped instead of going over the whole hash. The methods reading the hash accept
+// a nil one, which is empty like the hash of a missing key
+type HashEntry struct {
+	fields map[string]string
+	scan   *ds.ScanTable
+}
+
+// NewHashEntry creates a hash holding fields, which it takes ownership of
+func NewHashEntry(fields map[string]string) *HashEntry {
+	h := &HashEntry{fields: fields, scan: ds.NewScanTable()}
+	if h.fields == nil {
+		h.fields = make(map[string]string)
+	}
+	for field := range h.fields {
+		h.scan.Add(field)
+	}
+	return h
+}
+
+func (h *HashEntry) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.fields)
+}
+
+// Get returns the value of field
+func (h *HashEntry) Get(field string) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	value, ok := h.fields[field]
+	return value, ok
+}
+
+// Set stores value in field and reports whether the field is new
+func (h *HashEntry) Set(field, value string) bool {
+	_, exists := h.fields[field]
+	h.fields[field] = value
+	if !exists {
+		h.scan.Add(field)
+	}
+	return !exists
+}
+
+// Delete removes field and reports whether it existed
+func (h *HashEntry) Delete(field string) bool {
+	if _, ok := h.fields[field]; !ok {
+		return false
+	}
+	delete(h.fields, field)
+	h.scan.Remove(field)
+	return true
+}
+
+// All iterates over the fields and their values in no particular order
+func (h *HashEntry) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if h == nil {
+			return
+		}
+		for field, value := range h.fields {
+			if !yield(field, value) {
+				return
+			}
+		}
+	}
+}
+
+// Map returns a copy of the fields and their values
+func (h *HashEntry) Map() map[string]string {
+	return maps.Clone(h.fields)
+}
+
+// Scan returns a batch of about count fields starting at cursor and the cursor of the next
+// batch, see ds.ScanTable.Scan
+func (h *HashEntry) Scan(cursor uint64, count int) ([]string, uint64) {
+	if h == nil {
+		return nil, 0
+	}
+	return h.scan.Scan(cursor, count)
+}
+
+// Hash returns the hash stored at key, nil if the key doesn't exist or ErrWrongType if it
+// holds another type
+func (ks *Keyspace) Hash(key string) (*HashEntry, error) {
+	e := ks.Lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	if e.Type != TypeHash {
+		return nil, ErrWrongType
+	}
+	return e.Hash, nil
+}
+
+// CreateHash returns the hash stored at key, creating an empty one if the key doesn't exist.
+// Hashes are never left empty in the keyspace, callers must set a field before returning
+func (ks *Keyspace) CreateHash(key string) (*HashEntry, error) {
+	hash, err := ks.Hash(key)
+	if hash != nil || err != nil {
+		return hash, err
+	}
+	hash = NewHashEntry(nil)
+	ks.Put(key, &Entry{Type: TypeHash, Hash: hash})
+	return hash, nil
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrWrongType is returned when a command is run against a key holding another type
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+var wrongTypeReply = []byte("-" + ErrWrongType.Error() + "\r\n")
+
+// Keyspace gives exclusive access to a set of shards. It is only valid inside the function
+// passed to Do or DoAll and must not be kept after it returns
+type Keyspace struct {
+	shards [Shards]*Shard // nil for the shards that are not held
+	caller int64          // id of the client the keyspace is used for, see For
+}
+
+// Do runs fn with exclusive access to every shard owning one of keys. When all keys live on
+// the same shard fn runs on that shard's goroutine, otherwise the shards are parked in
+// ascending order (so concurrent callers can't deadlock) and fn runs on the caller's
+// goroutine. Either way no other command can observe the keys while fn runs, so fn must not
+// block and should only compute a reply, not write it to the network
+func Do(keys []string, fn func(ks *Keyspace)) {
+	idx := make([]int, 0, len(keys))
+	seen := [Shards]bool{}
+	for _, key := range keys {
+		i := shardForKey(key)
+		if !seen[i] {
+			seen[i] = true
+			idx = append(idx, i)
+		}
+	}
+
+	if len(idx) == 1 {
+		done := make(chan []byte, 1)
+		shards[idx[0]].ch <- Command{operation: EXEC, fn: fn, c: done}
+		<-done
+		return
+	}
+
+	sort.Ints(idx)
+	ks := &Keyspace{}
+	release := make(chan struct{})
+	defer close(release)
+	for _, i := range idx {
+		ack := make(chan []byte, 1)
+		shards[i].ch <- Command{operation: PAUSE, c: ack, release: release}
+		<-ack
+		ks.shards[i] = shards[i]
+	}
+	fn(ks)
+}
+
+// DoAll runs fn on the caller's goroutine with every shard parked
+func DoAll(fn func(ks *Keyspace)) {
+	resume := pauseShards()
+	defer resume()
+
+	ks := &Keyspace{shards: shards}
+	fn(ks)
+}
+
+// pauseShards parks every shard goroutine and returns a function that resumes them
+func pauseShards() func() {
+	release := make(chan struct{})
+	for _, s := range shards {
+		ack := make(chan []byte, 1)
+		s.ch <- Command{operation: PAUSE, c: ack, release: release}
+		<-ack
+	}
+	return func() { close(release) }
+}
+
+// Size returns the number of keys and the number of keys with a ttl, keys that expired but
+// weren't deleted yet are included
+func Size() (keys int, volatile int) {
+	for _, s := range shards {
+		done := make(chan []byte, 1)
+		s.ch <- Command{operation: EXEC, c: done, fn: func(*Keyspace) {
+			keys += len(s.kv)
+			volatile += len(s.expires)
+		}}
+		<-done
+	}
+	return keys, volatile
+}
+
+// For returns a view of the same shards used on behalf of client id, so that the changes it
+// makes are left out of the client's invalidations with CLIENT TRACKING NOLOOP
+func (ks *Keyspace) For(id int64) *Keyspace {
+	view := *ks
+	view.caller = id
+	return &view
+}
+
+func (ks *Keyspace) shard(key string) *Shard {
+	s := ks.shards[shardForKey(key)]
+	if s == nil {
+		panic("db: key " + key + " is not part of the keyspace held by the caller")
+	}
+	return s
+}
+
+// Lookup returns the entry stored at key, or nil if there is none. Expired keys are
+// deleted on access
+func (ks *Keyspace) Lookup(key string) *Entry {
+	return ks.shard(key).lookup(key)
+}
+
+// Put stores e at key, replacing whatever was there
+func (ks *Keyspace) Put(key string, e *Entry) {
+	ks.shard(key).put(key, e)
+}
+
+// Delete removes key and reports whether it existed
+func (ks *Keyspace) Delete(key string) bool {
+	s := ks.shard(key)
+	if s.lookup(key) == nil {
+		return false
+	}
+	s.remove(key)
+	return true
+}
+
+// SetExpiry changes when an existing key expires, a zero time makes it persistent
+func (ks *Keyspace) SetExpiry(key string, at time.Time) {
+	s := ks.shard(key)
+	if e := s.lookup(key); e != nil {
+		s.setExpiry(key, e, at)
+	}
+}
+
+// Keys calls fn for every live key in the held shards
+func (ks *Keyspace) Keys(fn func(key string, e *Entry)) {
+	now := time.Now()
+	for _, s := range ks.shards {
+		if s == nil {
+			continue
+		}
+		for key, e := range s.kv {
+			if !e.expired(now) {
+				fn(key, e)
+			}
+		}
+	}
+}
@@ -1,72 +1,43 @@
 package db
 
 import (
-	"log"
-	"sync"
-
 	"github.com/codecrafters-io/redis-starter-go/internal/ds"
 )
 
 type ListEntry struct {
-	Mu sync.Mutex
-	Q  ds.Deque[string]
-	B  ds.Deque[chan struct{}]
-}
-
-type ListsMap struct {
-	Mu sync.Mutex
-	L  map[string]*ListEntry
+	Q ds.Deque[string]
 }
 
-var ListOnce sync.Once
-var lists *ListsMap
-
-// Get a list from the global store with a key, if it doesn't exist this function
-// will return nil
-func GetList(key string) *ListEntry {
-	ListOnce.Do(func() {
-		log.Printf("ListsMap: Initializing...This should happen only once")
-		lists = &ListsMap{L: make(map[string]*ListEntry)}
-	})
-
-	lists.Mu.Lock()
-	defer lists.Mu.Unlock()
-
-	val, ok := lists.L[key]
-	if !ok {
-		return nil
+// NewListEntry creates a list holding items, in order
+func NewListEntry(items []string) *ListEntry {
+	list := &ListEntry{Q: *ds.NewDeque[string]()}
+	for _, item := range items {
+		list.Q.PushBack(item)
 	}
-	return val
+	return list
 }
 
-// Get a list from the global store with a key, if it doesn't exist this function
-// will create one and return it
-func CreateOrGetList(key string) *ListEntry {
-	ListOnce.Do(func() {
-		log.Printf("ListsMap: Initializing...This should happen only once")
-		lists = &ListsMap{L: make(map[string]*ListEntry)}
-	})
-
-	lists.Mu.Lock()
-	defer lists.Mu.Unlock()
-
-	val, ok := lists.L[key]
-	if !ok {
-		lists.L[key] = &ListEntry{Q: *ds.NewDeque[string](), B: *ds.NewDeque[chan struct{}]()}
-		return lists.L[key]
+// List returns the list stored at key, nil if the key doesn't exist or ErrWrongType if it
+// holds another type
+func (ks *Keyspace) List(key string) (*ListEntry, error) {
+	e := ks.Lookup(key)
+	if e == nil {
+		return nil, nil
 	}
-	return val
+	if e.Type != TypeList {
+		return nil, ErrWrongType
+	}
+	return e.List, nil
 }
 
-func DeleteList(key string) {
-	lists.Mu.Lock()
-	defer lists.Mu.Unlock()
-
-	_, ok := lists.L[key]
-	if !ok {
-		log.Printf("ListsMap: Trying to delete list :%s, but it doesn't exist", key)
-		return
+// CreateList returns the list stored at key, creating an empty one if the key doesn't exist.
+// Lists are never left empty in the keyspace, callers must push to the list before returning
+func (ks *Keyspace) CreateList(key string) (*ListEntry, error) {
+	list, err := ks.List(key)
+	if list != nil || err != nil {
+		return list, err
 	}
-	log.Printf("ListsMap: Deleting list :%s", key)
-	delete(lists.L, key)
+	list = NewListEntry(nil)
+	ks.Put(key, &Entry{Type: TypeList, List: list})
+	return list, nil
 }
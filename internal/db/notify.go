@@ -0,0 +1,113 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// Classes of keyspace events, each enabled by one flag letter of notify-keyspace-events
+const (
+	NotifyKeyspace int64 = 1 << iota // K: published on __keyspace@0__:<key>
+	NotifyKeyevent                   // E: published on __keyevent@0__:<event>
+	NotifyGeneric                    // g: DEL, EXPIRE, PERSIST and other commands not tied to a type
+	NotifyString                     // $
+	NotifyList                       // l
+	NotifySet                        // s
+	NotifyHash                       // h
+	NotifyZSet                       // z
+	NotifyExpired                    // x: a key was reclaimed because its ttl elapsed
+	NotifyEvicted                    // e: never fired, keys are not evicted
+	NotifyStream                     // t
+	NotifyKeyMiss                    // m: a read found no key
+	NotifyNew                        // n: a key was created
+
+	// NotifyAll is the A alias, it leaves out key miss and new key events like Redis does
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash | NotifyZSet | NotifyExpired | NotifyEvicted | NotifyStream
+)
+
+// notifyClasses maps the flag letters to their class, in the order CONFIG GET prints them
+var notifyClasses = []struct {
+	flag  byte
+	class int64
+}{
+	{'g', NotifyGeneric}, {'$', NotifyString}, {'l', NotifyList}, {'s', NotifySet},
+	{'h', NotifyHash}, {'z', NotifyZSet}, {'x', NotifyExpired}, {'e', NotifyEvicted},
+	{'t', NotifyStream}, {'K', NotifyKeyspace}, {'E', NotifyKeyevent},
+	{'m', NotifyKeyMiss}, {'n', NotifyNew},
+}
+
+var (
+	notifyFlags atomic.Int64
+
+	// notifier publishes a message to a channel, it is set once at startup by the package
+	// owning the pub/sub registry since db can't depend on it
+	notifier func(channel, message string)
+)
+
+// ParseNotifyFlags turns the value of notify-keyspace-events into a set of classes
+func ParseNotifyFlags(s string) (int64, error) {
+	var flags int64
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			flags |= NotifyAll
+			continue
+		}
+		found := false
+		for _, c := range notifyClasses {
+			if c.flag == s[i] {
+				flags |= c.class
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, errors.New("Invalid event class character. Use 'Ag$lshzxeKEtmn'.")
+		}
+	}
+	return flags, nil
+}
+
+// NotifyFlagsString is the normalized form of a set of classes, with A standing for all the
+// classes it covers
+func NotifyFlagsString(flags int64) string {
+	var b strings.Builder
+	if flags&NotifyAll == NotifyAll {
+		b.WriteByte('A')
+	}
+	for _, c := range notifyClasses {
+		if c.class&NotifyAll != 0 && flags&NotifyAll == NotifyAll {
+			continue
+		}
+		if flags&c.class != 0 {
+			b.WriteByte(c.flag)
+		}
+	}
+	return b.String()
+}
+
+// SetNotifyFlags changes the classes of keyspace events that get published
+func SetNotifyFlags(flags int64) {
+	notifyFlags.Store(flags)
+}
+
+// SetNotifier installs the function keyspace events are published with
+func SetNotifier(fn func(channel, message string)) {
+	notifier = fn
+}
+
+// Notify publishes a keyspace event of the given class about key, as long as the class is
+// enabled and at least one of K and E is. It runs on the goroutine that changed the key, so
+// subscribers see events in the order the changes happened
+func Notify(class int64, event, key string) {
+	flags := notifyFlags.Load()
+	if flags&class == 0 || notifier == nil {
+		return
+	}
+	if flags&NotifyKeyspace != 0 {
+		notifier("__keyspace@0__:"+key, event)
+	}
+	if flags&NotifyKeyevent != 0 {
+		notifier("__keyevent@0__:"+event, key)
+	}
+}
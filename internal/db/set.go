@@ -0,0 +1,207 @@
+package db
+
+import (
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+)
+
+// SetMaxIntsetEntries is the largest set kept in the intset encoding, same default as
+// set-max-intset-entries
+const SetMaxIntsetEntries = 512
+
+// SetEntry is an unordered set of unique strings. While every member is an integer and there
+// are at most SetMaxIntsetEntries of them the members are stored in a compact intset, the set
+// is converted to a hash table the first time that stops being true and never goes back
+type SetEntry struct {
+	ints    *ds.IntSet          // intset encoding, nil once converted
+	members map[string]struct{} // hashtable encoding
+	scan    *ds.ScanTable       // hashtable encoding, the members again in SSCAN order
+}
+
+// NewSetEntry creates a set holding members, choosing the encoding that fits them
+func NewSetEntry(members []string) *SetEntry {
+	set := &SetEntry{ints: ds.NewIntSet()}
+	for _, member := range members {
+		set.Add(member)
+	}
+	return set
+}
+
+// parseSetInt returns the integer represented by member if it is in canonical form, "007"
+// or "+7" are stored as strings so the member reads back exactly as it was added
+func parseSetInt(member string) (int64, bool) {
+	v, err := strconv.ParseInt(member, 10, 64)
+	if err != nil || strconv.FormatInt(v, 10) != member {
+		return 0, false
+	}
+	return v, true
+}
+
+// convert moves the members of an intset encoded set into a hash table
+func (s *SetEntry) convert() {
+	s.members = make(map[string]struct{}, s.ints.Len()+1)
+	s.scan = ds.NewScanTable()
+	for v := range s.ints.All() {
+		member := strconv.FormatInt(v, 10)
+		s.members[member] = struct{}{}
+		s.scan.Add(member)
+	}
+	s.ints = nil
+}
+
+// Encoding returns the name OBJECT ENCODING reports for the set
+func (s *SetEntry) Encoding() string {
+	if s.ints != nil {
+		return "intset"
+	}
+	return "hashtable"
+}
+
+// IntSet returns the intset holding the members, nil if the set uses a hash table
+func (s *SetEntry) IntSet() *ds.IntSet {
+	return s.ints
+}
+
+func (s *SetEntry) Len() int {
+	if s.ints != nil {
+		return s.ints.Len()
+	}
+	return len(s.members)
+}
+
+// Add inserts member and reports whether it wasn't already present
+func (s *SetEntry) Add(member string) bool {
+	if s.ints != nil {
+		if v, ok := parseSetInt(member); ok {
+			if s.ints.Contains(v) {
+				return false
+			}
+			if s.ints.Len() < SetMaxIntsetEntries {
+				return s.ints.Add(v)
+			}
+		}
+		s.convert()
+	}
+
+	if _, ok := s.members[member]; ok {
+		return false
+	}
+	s.members[member] = struct{}{}
+	s.scan.Add(member)
+	return true
+}
+
+// Remove deletes member and reports whether it was present
+func (s *SetEntry) Remove(member string) bool {
+	if s.ints != nil {
+		v, ok := parseSetInt(member)
+		return ok && s.ints.Remove(v)
+	}
+
+	if _, ok := s.members[member]; !ok {
+		return false
+	}
+	delete(s.members, member)
+	s.scan.Remove(member)
+	return true
+}
+
+// Contains reports whether member is in the set
+func (s *SetEntry) Contains(member string) bool {
+	if s.ints != nil {
+		v, ok := parseSetInt(member)
+		return ok && s.ints.Contains(v)
+	}
+	_, ok := s.members[member]
+	return ok
+}
+
+// Members iterates over the set, in ascending order for intsets and in no particular order
+// otherwise
+func (s *SetEntry) Members() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if s.ints != nil {
+			for v := range s.ints.All() {
+				if !yield(strconv.FormatInt(v, 10)) {
+					return
+				}
+			}
+			return
+		}
+		for member := range s.members {
+			if !yield(member) {
+				return
+			}
+		}
+	}
+}
+
+// Scan returns a batch of about count members starting at cursor and the cursor of the next
+// batch, see ds.ScanTable.Scan. An intset holds few members and is returned whole, like
+// Redis does for its compact encodings
+func (s *SetEntry) Scan(cursor uint64, count int) ([]string, uint64) {
+	if s.ints != nil {
+		return slices.Collect(s.Members()), 0
+	}
+	return s.scan.Scan(cursor, count)
+}
+
+// Random returns a random member of a non-empty set
+func (s *SetEntry) Random() string {
+	if s.ints != nil {
+		return strconv.FormatInt(s.ints.Get(rand.IntN(s.ints.Len())), 10)
+	}
+
+	// Go maps don't support random access, skipping a random number of members picks
+	// uniformly whatever order the iteration uses
+	skip := rand.IntN(len(s.members))
+	for member := range s.members {
+		if skip == 0 {
+			return member
+		}
+		skip--
+	}
+	return ""
+}
+
+// Clone returns a copy of the set that shares nothing with it
+func (s *SetEntry) Clone() *SetEntry {
+	if s.ints != nil {
+		return &SetEntry{ints: s.ints.Clone()}
+	}
+	clone := &SetEntry{members: make(map[string]struct{}, len(s.members)), scan: ds.NewScanTable()}
+	for member := range s.members {
+		clone.members[member] = struct{}{}
+		clone.scan.Add(member)
+	}
+	return clone
+}
+
+// Set returns the set stored at key, nil if the key doesn't exist or ErrWrongType if it holds
+// another type
+func (ks *Keyspace) Set(key string) (*SetEntry, error) {
+	e := ks.Lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	if e.Type != TypeSet {
+		return nil, ErrWrongType
+	}
+	return e.Set, nil
+}
+
+// CreateSet returns the set stored at key, creating an empty one if the key doesn't exist.
+// Sets are never left empty in the keyspace, callers must add a member before returning
+func (ks *Keyspace) CreateSet(key string) (*SetEntry, error) {
+	set, err := ks.Set(key)
+	if set != nil || err != nil {
+		return set, err
+	}
+	set = NewSetEntry(nil)
+	ks.Put(key, &Entry{Type: TypeSet, Set: set})
+	return set, nil
+}
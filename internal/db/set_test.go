@@ -0,0 +1,55 @@
+package db
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestSetEntryEncoding(t *testing.T) {
+	set := NewSetEntry([]string{"3", "1", "2", "1"})
+	if set.Encoding() != "intset" {
+		t.Fatalf("Encoding() = %s, want intset", set.Encoding())
+	}
+	if got := slices.Collect(set.Members()); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("Members() = %v", got)
+	}
+
+	// Integers that don't read back the same stay strings
+	for _, member := range []string{"007", "+7", " 7", "-0"} {
+		if set.Contains(member) {
+			t.Errorf("Contains(%q) should be false", member)
+		}
+	}
+	set.Add("007")
+	if set.Encoding() != "hashtable" {
+		t.Errorf("Encoding() = %s after adding a non canonical integer, want hashtable", set.Encoding())
+	}
+	if !set.Contains("007") || !set.Contains("2") || set.Len() != 4 {
+		t.Errorf("members lost in the conversion: %v", slices.Collect(set.Members()))
+	}
+}
+
+func TestSetEntryIntsetLimit(t *testing.T) {
+	set := NewSetEntry(nil)
+	for i := range SetMaxIntsetEntries {
+		set.Add(strconv.Itoa(i))
+	}
+	if set.Encoding() != "intset" {
+		t.Fatalf("Encoding() = %s with %d integers, want intset", set.Encoding(), SetMaxIntsetEntries)
+	}
+	if set.Add("0") {
+		t.Error("Add() of an existing member should return false")
+	}
+
+	set.Add(strconv.Itoa(SetMaxIntsetEntries))
+	if set.Encoding() != "hashtable" || set.Len() != SetMaxIntsetEntries+1 {
+		t.Errorf("Encoding() = %s, Len() = %d after exceeding the limit", set.Encoding(), set.Len())
+	}
+
+	clone := set.Clone()
+	clone.Remove("0")
+	if !set.Contains("0") {
+		t.Error("Clone() shares members with the original")
+	}
+}
@@ -22,3 +22,8 @@ func shardForKey(key string) int {
 
 	return int(hash & shardsMask)
 }
+
+// ShardForKey returns the shard index of a key, for registries spread the same way as keys
+func ShardForKey(key string) int {
+	return shardForKey(key)
+}
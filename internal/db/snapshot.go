@@ -0,0 +1,142 @@
+package db
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// StreamSnapshot is a copy of a stream's entries, decoded from its nodes
+type StreamSnapshot struct {
+	Entries      []*streams.StreamEntry
+	LastID       streams.StreamID
+	MaxDeletedID streams.StreamID
+	EntriesAdded uint64
+	Groups       []*streams.ConsumerGroup // copies, sorted by name
+}
+
+// SnapshotEntry is a copy of a single key, only the field matching Type is set
+type SnapshotEntry struct {
+	Type      ValueType
+	Value     []byte            // TypeString
+	List      []string          // TypeList
+	Stream    StreamSnapshot    // TypeStream
+	Hash      map[string]string // TypeHash
+	Set       *SetEntry         // TypeSet
+	ZSet      []ds.ZEntry       // TypeZSet, in ascending order
+	ExpiresAt time.Time
+}
+
+// Snapshot is a point-in-time copy of the whole dataset, used by persistence
+type Snapshot struct {
+	Keys map[string]*SnapshotEntry
+}
+
+// TakeSnapshot stops every shard and copies the dataset. Nothing can be modified while the
+// copy is being taken, so the result is consistent across keys of every type. frozen, when
+// not nil, runs while writers are still stopped
+func TakeSnapshot(frozen func()) *Snapshot {
+	snap := &Snapshot{Keys: make(map[string]*SnapshotEntry)}
+
+	DoAll(func(ks *Keyspace) {
+		if frozen != nil {
+			frozen()
+		}
+
+		// Values are never mutated in place so sharing the string byte slices is safe,
+		// lists, hashes, sets, sorted sets and consumer groups are modified in place and have to
+		// be copied
+		minID := &streams.StreamID{}
+		maxID := &streams.StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+		ks.Keys(func(key string, e *Entry) {
+			se := &SnapshotEntry{Type: e.Type, ExpiresAt: e.ExpiresAt}
+			switch e.Type {
+			case TypeString:
+				se.Value = e.Value
+			case TypeList:
+				se.List = e.List.Q.GetSlice(0, int64(e.List.Q.Len()-1))
+			case TypeStream:
+				se.Stream.Entries = e.Stream.Range(minID, maxID, 0)
+				se.Stream.LastID = e.Stream.LastID()
+				se.Stream.MaxDeletedID = e.Stream.MaxDeletedID()
+				se.Stream.EntriesAdded = e.Stream.EntriesAdded()
+				se.Stream.Groups = e.Stream.CloneGroups()
+			case TypeHash:
+				se.Hash = e.Hash.Map()
+			case TypeSet:
+				se.Set = e.Set.Clone()
+			case TypeZSet:
+				se.ZSet = e.ZSet.Range(0, e.ZSet.Len(), false)
+			}
+			snap.Keys[key] = se
+		})
+	})
+
+	return snap
+}
+
+// restore installs an entry loaded from disk, keys whose deadline has already passed are
+// skipped
+func restore(key string, e *Entry) {
+	if e.expired(time.Now()) {
+		return
+	}
+	Do([]string{key}, func(ks *Keyspace) {
+		ks.Put(key, e)
+	})
+}
+
+// RestoreString installs a string key loaded from disk
+func RestoreString(key string, value []byte, expiresAt time.Time) {
+	restore(key, &Entry{Type: TypeString, Value: value, ExpiresAt: expiresAt})
+}
+
+// RestoreList installs a list loaded from disk
+func RestoreList(key string, items []string, expiresAt time.Time) {
+	if len(items) == 0 {
+		return
+	}
+	restore(key, &Entry{Type: TypeList, List: NewListEntry(items), ExpiresAt: expiresAt})
+}
+
+// RestoreStream installs a stream loaded from disk
+func RestoreStream(key string, snap StreamSnapshot, expiresAt time.Time) {
+	stream := streams.NewEmptyStream()
+	for _, entry := range snap.Entries {
+		stream.Insert(entry)
+	}
+	stream.RestoreMetadata(snap.LastID, snap.MaxDeletedID, snap.EntriesAdded)
+	for _, g := range snap.Groups {
+		stream.RestoreGroup(g)
+	}
+	restore(key, &Entry{Type: TypeStream, Stream: stream, ExpiresAt: expiresAt})
+}
+
+// RestoreHash installs a hash loaded from disk
+func RestoreHash(key string, fields map[string]string, expiresAt time.Time) {
+	if len(fields) == 0 {
+		return
+	}
+	restore(key, &Entry{Type: TypeHash, Hash: NewHashEntry(fields), ExpiresAt: expiresAt})
+}
+
+// RestoreSet installs a set loaded from disk
+func RestoreSet(key string, members []string, expiresAt time.Time) {
+	if len(members) == 0 {
+		return
+	}
+	restore(key, &Entry{Type: TypeSet, Set: NewSetEntry(members), ExpiresAt: expiresAt})
+}
+
+// RestoreZSet installs a sorted set loaded from disk
+func RestoreZSet(key string, entries []ds.ZEntry, expiresAt time.Time) {
+	if len(entries) == 0 {
+		return
+	}
+	zset := ds.NewSortedSet()
+	for _, e := range entries {
+		zset.Add(e.Member, e.Score)
+	}
+	restore(key, &Entry{Type: TypeZSet, ZSet: zset, ExpiresAt: expiresAt})
+}
@@ -0,0 +1,27 @@
+package db
+
+import "github.com/codecrafters-io/redis-starter-go/internal/streams"
+
+// Stream returns the stream stored at key, nil if the key doesn't exist or ErrWrongType if
+// it holds another type
+func (ks *Keyspace) Stream(key string) (*streams.Stream, error) {
+	e := ks.Lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	if e.Type != TypeStream {
+		return nil, ErrWrongType
+	}
+	return e.Stream, nil
+}
+
+// CreateStream returns the stream stored at key, creating an empty one if the key doesn't exist
+func (ks *Keyspace) CreateStream(key string) (*streams.Stream, error) {
+	stream, err := ks.Stream(key)
+	if stream != nil || err != nil {
+		return stream, err
+	}
+	stream = streams.NewEmptyStream()
+	ks.Put(key, &Entry{Type: TypeStream, Stream: stream})
+	return stream, nil
+}
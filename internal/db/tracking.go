@@ -0,0 +1,157 @@
+package db
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Tracker is the client side caching state of a client with CLIENT TRACKING on. In the
+// default mode the keys the client reads are remembered by their shard, and the first change
+// to one of them invalidates it and forgets it until it is read again. In BCAST mode nothing
+// is remembered, every change to a key matching one of the prefixes invalidates it
+type Tracker struct {
+	ID       int64        // id of the tracking client
+	Redirect atomic.Int64 // id of the client invalidations are sent to, 0 for the tracking client
+	NoLoop   atomic.Bool  // changes made by the tracking client itself aren't sent to it
+	BCAST    bool
+
+	invalidate func(key string, caller int64) // sends an invalidation for key, run by the shard that changed it
+	off        atomic.Bool                    // set by Stop, the keys Untrack didn't drop yet send nothing
+
+	// keys registered on their shard, so Untrack can find them. The shards owning them add
+	// and remove keys in parallel
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// bcast holds the prefixes of the trackers in BCAST mode. Every shard reads it on every
+// change, active tells them whether there is anything to look at without locking
+var bcast = struct {
+	sync.RWMutex
+	prefixes map[string]map[*Tracker]struct{}
+	active   atomic.Int64
+}{prefixes: make(map[string]map[*Tracker]struct{})}
+
+// NewTracker creates the tracking state of client id, invalidate is called with every key
+// to invalidate and the id of the client that changed it. It must not block since it runs on
+// the shard that changed the key
+func NewTracker(id int64, broadcast bool, invalidate func(key string, caller int64)) *Tracker {
+	return &Tracker{ID: id, BCAST: broadcast, invalidate: invalidate, keys: make(map[string]struct{})}
+}
+
+// Keys returns the keys the tracker is registered on
+func (t *Tracker) Keys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Collect(maps.Keys(t.keys))
+}
+
+// Broadcast registers prefixes of a tracker in BCAST mode, the empty prefix matches every key
+func (t *Tracker) Broadcast(prefixes []string) {
+	bcast.Lock()
+	defer bcast.Unlock()
+	for _, prefix := range prefixes {
+		trackers, ok := bcast.prefixes[prefix]
+		if !ok {
+			trackers = make(map[*Tracker]struct{})
+			bcast.prefixes[prefix] = trackers
+		}
+		if _, ok := trackers[t]; !ok {
+			trackers[t] = struct{}{}
+			bcast.active.Add(1)
+		}
+	}
+}
+
+// Stop turns tracking off, the tracker never sends an invalidation again. The keys it is
+// registered on are dropped with Untrack
+func (t *Tracker) Stop() {
+	t.off.Store(true)
+	if !t.BCAST {
+		return
+	}
+	bcast.Lock()
+	defer bcast.Unlock()
+	for prefix, trackers := range bcast.prefixes {
+		if _, ok := trackers[t]; !ok {
+			continue
+		}
+		delete(trackers, t)
+		bcast.active.Add(-1)
+		if len(trackers) == 0 {
+			delete(bcast.prefixes, prefix)
+		}
+	}
+}
+
+// send invalidates key for the tracker unless caller, the client that changed it, is the
+// tracking client and asked to be left out with NOLOOP
+func (t *Tracker) send(key string, caller int64) {
+	if t.off.Load() || (caller == t.ID && t.NoLoop.Load()) {
+		return
+	}
+	t.invalidate(key, caller)
+}
+
+// Track remembers that t read key, the next change to it is sent to t
+func (ks *Keyspace) Track(key string, t *Tracker) {
+	s := ks.shard(key)
+	trackers, ok := s.tracked[key]
+	if !ok {
+		trackers = make(map[*Tracker]struct{})
+		s.tracked[key] = trackers
+	}
+	trackers[t] = struct{}{}
+
+	t.mu.Lock()
+	t.keys[key] = struct{}{}
+	t.mu.Unlock()
+}
+
+// Untrack drops every registration of t, the caller must hold all of its keys. Without it
+// the keys of a client that stopped tracking would stay registered until they change
+func (ks *Keyspace) Untrack(t *Tracker) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.keys {
+		s := ks.shard(key)
+		delete(s.tracked[key], t)
+		if len(s.tracked[key]) == 0 {
+			delete(s.tracked, key)
+		}
+	}
+	clear(t.keys)
+}
+
+// invalidate sends key to the trackers that read it since it last changed and to the
+// trackers in BCAST mode with a matching prefix. caller is the id of the client that changed
+// it, 0 for the server itself
+func (s *Shard) invalidate(key string, caller int64) {
+	for t := range s.tracked[key] {
+		t.send(key, caller)
+		t.mu.Lock()
+		delete(t.keys, key)
+		t.mu.Unlock()
+	}
+	delete(s.tracked, key)
+
+	if bcast.active.Load() == 0 {
+		return
+	}
+	matched := make(map[*Tracker]struct{})
+	bcast.RLock()
+	for prefix, trackers := range bcast.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			for t := range trackers {
+				matched[t] = struct{}{}
+			}
+		}
+	}
+	bcast.RUnlock()
+	for t := range matched {
+		t.send(key, caller)
+	}
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func newTrackingKeyspace() *Keyspace {
+	ks := &Keyspace{}
+	for i := range ks.shards {
+		ks.shards[i] = &Shard{tracked: make(map[string]map[*Tracker]struct{})}
+	}
+	return ks
+}
+
+func TestTrackerInvalidatesOnce(t *testing.T) {
+	ks := newTrackingKeyspace()
+	var got []string
+	tr := NewTracker(1, false, func(key string, _ int64) { got = append(got, key) })
+
+	ks.Track("a", tr)
+	ks.Track("b", tr)
+	ks.Touch("a")
+	ks.Touch("a")
+	if !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("Touch() should invalidate a tracked key once, got %v", got)
+	}
+
+	// NOLOOP leaves out the changes of the tracking client, the key is forgotten all the same
+	tr.NoLoop.Store(true)
+	ks.For(1).Touch("b")
+	ks.Track("b", tr)
+	ks.For(2).Touch("b")
+	if !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("Touch() by the tracking client should be left out with NOLOOP, got %v", got)
+	}
+
+	tr.Stop()
+	ks.Track("c", tr)
+	ks.Touch("c")
+	if len(got) != 2 {
+		t.Fatalf("A stopped tracker should send nothing, got %v", got)
+	}
+}
+
+func TestTrackerUntrack(t *testing.T) {
+	ks := newTrackingKeyspace()
+	var got []string
+	tr := NewTracker(1, false, func(key string, _ int64) { got = append(got, key) })
+
+	ks.Track("a", tr)
+	ks.Track("b", tr)
+	ks.Touch("a")
+	if keys := tr.Keys(); !slices.Equal(keys, []string{"b"}) {
+		t.Fatalf("Keys() should drop invalidated keys, got %v", keys)
+	}
+
+	tr.Stop()
+	ks.Untrack(tr)
+	for _, s := range ks.shards {
+		if len(s.tracked) != 0 {
+			t.Fatalf("Untrack() should drop every registration, left %v", s.tracked)
+		}
+	}
+	if len(tr.Keys()) != 0 {
+		t.Fatalf("Untrack() should forget the keys, got %v", tr.Keys())
+	}
+}
+
+func TestTrackerBroadcast(t *testing.T) {
+	ks := newTrackingKeyspace()
+	var got []string
+	tr := NewTracker(1, true, func(key string, _ int64) { got = append(got, key) })
+	tr.Broadcast([]string{"user:", "us"})
+	defer tr.Stop()
+
+	ks.Touch("user:1")
+	ks.Touch("order:1")
+	if !slices.Equal(got, []string{"user:1"}) {
+		t.Fatalf("Touch() should invalidate keys matching a prefix once, got %v", got)
+	}
+
+	tr.Stop()
+	ks.Touch("user:2")
+	if len(got) != 1 || bcast.active.Load() != 0 {
+		t.Fatalf("Stop() should drop the prefixes, got %v", got)
+	}
+}
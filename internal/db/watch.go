@@ -0,0 +1,86 @@
+package db
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher is the set of keys a client watches with WATCH. It is marked dirty as soon as one
+// of them is modified, which makes the next EXEC of the client fail
+type Watcher struct {
+	keys  map[string]time.Time // watched keys and their deadline when WATCH ran, zero if none
+	dirty atomic.Bool          // set by the shards owning the keys, which may run in parallel
+}
+
+func NewWatcher() *Watcher {
+	return &Watcher{keys: make(map[string]time.Time)}
+}
+
+// Keys returns the watched keys
+func (w *Watcher) Keys() []string {
+	return slices.Collect(maps.Keys(w.keys))
+}
+
+// Watch registers w on key. Watching a key twice keeps the first registration
+func (ks *Keyspace) Watch(key string, w *Watcher) {
+	if _, ok := w.keys[key]; ok {
+		return
+	}
+	s := ks.shard(key)
+	var deadline time.Time
+	if e := s.lookup(key); e != nil {
+		deadline = e.ExpiresAt
+	}
+	w.keys[key] = deadline
+
+	watchers, ok := s.watched[key]
+	if !ok {
+		watchers = make(map[*Watcher]struct{})
+		s.watched[key] = watchers
+	}
+	watchers[w] = struct{}{}
+}
+
+// Unwatch drops every registration of w, the caller must hold all of its keys
+func (ks *Keyspace) Unwatch(w *Watcher) {
+	for key := range w.keys {
+		s := ks.shard(key)
+		delete(s.watched[key], w)
+		if len(s.watched[key]) == 0 {
+			delete(s.watched, key)
+		}
+	}
+	clear(w.keys)
+}
+
+// Dirty reports whether one of the watched keys was modified since it was watched. A key
+// that had a ttl and has expired since counts as modified even if it wasn't deleted yet. The
+// caller must hold all of the keys
+func (w *Watcher) Dirty() bool {
+	if w.dirty.Load() {
+		return true
+	}
+	now := time.Now()
+	for _, deadline := range w.keys {
+		if !deadline.IsZero() && now.After(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// Touch signals that key was modified. Every write command calls it for the keys it changes
+func (ks *Keyspace) Touch(key string) {
+	ks.shard(key).touch(key, ks.caller)
+}
+
+// touch marks every client watching key dirty and invalidates it for the clients tracking it,
+// caller is the id of the client that modified it
+func (s *Shard) touch(key string, caller int64) {
+	for w := range s.watched[key] {
+		w.dirty.Store(true)
+	}
+	s.invalidate(key, caller)
+}
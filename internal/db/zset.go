@@ -0,0 +1,29 @@
+package db
+
+import "github.com/codecrafters-io/redis-starter-go/internal/ds"
+
+// ZSet returns the sorted set stored at key, nil if the key doesn't exist or ErrWrongType if
+// it holds another type
+func (ks *Keyspace) ZSet(key string) (*ds.SortedSet, error) {
+	e := ks.Lookup(key)
+	if e == nil {
+		return nil, nil
+	}
+	if e.Type != TypeZSet {
+		return nil, ErrWrongType
+	}
+	return e.ZSet, nil
+}
+
+// CreateZSet returns the sorted set stored at key, creating an empty one if the key doesn't
+// exist. Sorted sets are never left empty in the keyspace, callers must add a member before
+// returning
+func (ks *Keyspace) CreateZSet(key string) (*ds.SortedSet, error) {
+	zset, err := ks.ZSet(key)
+	if zset != nil || err != nil {
+		return zset, err
+	}
+	zset = ds.NewSortedSet()
+	ks.Put(key, &Entry{Type: TypeZSet, ZSet: zset})
+	return zset, nil
+}
@@ -1,51 +1,115 @@
 package ds
 
-// Deque is a generic ring-buffer-based double-ended queue.
+// dequeMinCapacity is the smallest buffer a deque allocates, it never shrinks below it
+const dequeMinCapacity = 8
+
+// Deque is a generic ring-buffer-based double-ended queue. Pushes and pops at both ends are
+// amortized O(1): the buffer doubles when it is full and halves once it is a quarter full,
+// so a drained deque gives its memory back.
 type Deque[T comparable] struct {
-	Buf []T
+	buf  []T // circular buffer, its length is zero or a power of two
+	head int // index in buf of the front element
+	n    int // number of elements
 }
 
 // NewDeque creates a deque with an initial capacity.
 func NewDeque[T comparable]() *Deque[T] {
 	return &Deque[T]{
-		Buf: make([]T, 0),
+		buf: make([]T, dequeMinCapacity),
 	}
 }
 
 func (d *Deque[T]) Len() int {
-	return len(d.Buf)
+	return d.n
+}
+
+// Cap returns the number of elements the deque can hold before it has to grow.
+func (d *Deque[T]) Cap() int {
+	return len(d.buf)
 }
 
 func (d *Deque[T]) empty() bool {
-	return len(d.Buf) == 0
+	return d.n == 0
+}
+
+// index maps the position of an element in the deque to its index in buf.
+func (d *Deque[T]) index(i int) int {
+	return (d.head + i) & (len(d.buf) - 1)
+}
+
+// resize moves the elements to a buffer of the given capacity, front element first.
+func (d *Deque[T]) resize(capacity int) {
+	buf := make([]T, capacity)
+	if d.n > 0 {
+		if end := d.head + d.n; end <= len(d.buf) {
+			copy(buf, d.buf[d.head:end])
+		} else {
+			k := copy(buf, d.buf[d.head:])
+			copy(buf[k:], d.buf[:end-len(d.buf)])
+		}
+	}
+	d.buf = buf
+	d.head = 0
+}
+
+// grow makes room for one more element.
+func (d *Deque[T]) grow() {
+	if d.n < len(d.buf) {
+		return
+	}
+	d.resize(max(2*len(d.buf), dequeMinCapacity))
+}
+
+// shrink halves the buffer once it is at most a quarter full, which keeps the cost of
+// resizing amortized O(1) when pushes and pops alternate around the threshold.
+func (d *Deque[T]) shrink() {
+	capacity := len(d.buf)
+	for capacity > dequeMinCapacity && d.n <= capacity/4 {
+		capacity /= 2
+	}
+	if capacity != len(d.buf) {
+		d.resize(capacity)
+	}
 }
 
 func (d *Deque[T]) PushBack(v T) {
-	d.Buf = append(d.Buf, v)
+	d.grow()
+	d.buf[d.index(d.n)] = v
+	d.n++
 }
 
 func (d *Deque[T]) PushFront(v T) {
-	d.Buf = append([]T{v}, d.Buf...)
+	d.grow()
+	d.head = d.index(len(d.buf) - 1)
+	d.buf[d.head] = v
+	d.n++
 }
 
 func (d *Deque[T]) PopFront() (T, bool) {
+	var zero T
 	if d.empty() {
-		var noop T
-		return noop, false
+		return zero, false
 	}
-	val := d.Buf[0]
-	d.Buf = d.Buf[1:]
+	val := d.buf[d.head]
+	// The slot is cleared so the buffer doesn't keep the popped value alive
+	d.buf[d.head] = zero
+	d.head = d.index(1)
+	d.n--
+	d.shrink()
 	return val, true
 }
 
 func (d *Deque[T]) PopBack() (T, bool) {
+	var zero T
 	if d.empty() {
-		var zero T
 		return zero, false
 	}
 
-	v := d.Buf[len(d.Buf)-1]
-	d.Buf = d.Buf[:len(d.Buf)-1]
+	i := d.index(d.n - 1)
+	v := d.buf[i]
+	d.buf[i] = zero
+	d.n--
+	d.shrink()
 	return v, true
 }
 
@@ -54,7 +118,7 @@ func (d *Deque[T]) Front() (T, bool) {
 		var zero T
 		return zero, false
 	}
-	return d.Buf[0], true
+	return d.buf[d.head], true
 }
 
 func (d *Deque[T]) Back() (T, bool) {
@@ -62,23 +126,128 @@ func (d *Deque[T]) Back() (T, bool) {
 		var zero T
 		return zero, false
 	}
-	return d.Buf[len(d.Buf)-1], true
+	return d.buf[d.index(d.n-1)], true
+}
+
+// At returns the element at index i, which must be in range
+func (d *Deque[T]) At(i int) T {
+	return d.buf[d.index(i)]
+}
+
+// Set replaces the element at index i, which must be in range
+func (d *Deque[T]) Set(i int, v T) {
+	d.buf[d.index(i)] = v
+}
+
+// Insert adds v at index i, shifting the elements from i on back by one. i must be in
+// [0, Len()]. Only the elements on the shorter side of i are moved
+func (d *Deque[T]) Insert(i int, v T) {
+	d.grow()
+	if i < d.n/2 {
+		d.head = d.index(len(d.buf) - 1)
+		for j := 0; j < i; j++ {
+			d.Set(j, d.At(j+1))
+		}
+	} else {
+		for j := d.n; j > i; j-- {
+			d.Set(j, d.At(j-1))
+		}
+	}
+	d.Set(i, v)
+	d.n++
+}
+
+// Trim keeps only the elements whose index is in [start, stop), which must be in range
+func (d *Deque[T]) Trim(start, stop int) {
+	d.clearRange(stop, d.n)
+	d.clearRange(0, start)
+	d.head = d.index(start)
+	d.n = stop - start
+	d.shrink()
+}
+
+// clearRange zeroes the slots of the elements whose index is in [start, stop).
+func (d *Deque[T]) clearRange(start, stop int) {
+	var zero T
+	for i := start; i < stop; i++ {
+		d.Set(i, zero)
+	}
+}
+
+// RemoveN deletes the first count occurrences of v, the last -count ones when count is
+// negative or all of them when it is zero, and returns how many were removed
+func (d *Deque[T]) RemoveN(v T, count int) int {
+	removed := 0
+	if count < 0 {
+		// Walk from the back, kept elements are moved towards the end
+		j := d.n
+		for i := d.n - 1; i >= 0; i-- {
+			if d.At(i) == v && removed < -count {
+				removed++
+				continue
+			}
+			j--
+			d.Set(j, d.At(i))
+		}
+		d.clearRange(0, j)
+		d.head = d.index(j)
+		d.n -= j
+		d.shrink()
+		return removed
+	}
+
+	j := 0
+	for i := range d.n {
+		if d.At(i) == v && (count == 0 || removed < count) {
+			removed++
+			continue
+		}
+		d.Set(j, d.At(i))
+		j++
+	}
+	d.clearRange(j, d.n)
+	d.n = j
+	d.shrink()
+	return removed
 }
 
-// Note that this expects that the start and stop is already validated
+// GetSlice returns a copy of the elements from start to stop, both included. Note that this
+// expects that the start and stop is already validated
 func (d *Deque[T]) GetSlice(start int64, stop int64) []T {
-	return d.Buf[start : stop+1]
+	if stop < start {
+		return []T{}
+	}
+	out := make([]T, 0, stop-start+1)
+	for i := int(start); i <= int(stop); i++ {
+		out = append(out, d.At(i))
+	}
+	return out
 }
 
+// Remove deletes the first occurrence of el and reports whether it was found. The elements
+// on the shorter side of it are shifted to close the gap
 func (d *Deque[T]) Remove(el T) bool {
-	for i, ch := range d.Buf {
-		if ch == el {
-			// Found the element to remove.
-			// Return a new slice that concatenates the part before the index
-			// and the part after the index.
-			d.Buf = append(d.Buf[:i], d.Buf[i+1:]...)
-			return true
+	for i := range d.n {
+		if d.At(i) != el {
+			continue
+		}
+
+		var zero T
+		if i < d.n/2 {
+			for j := i; j > 0; j-- {
+				d.Set(j, d.At(j-1))
+			}
+			d.Set(0, zero)
+			d.head = d.index(1)
+		} else {
+			for j := i; j < d.n-1; j++ {
+				d.Set(j, d.At(j+1))
+			}
+			d.Set(d.n-1, zero)
 		}
+		d.n--
+		d.shrink()
+		return true
 	}
 	// If the element was not found, return false.
 	return false
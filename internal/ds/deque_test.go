@@ -0,0 +1,197 @@
+package ds
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func dequeOf(items ...string) *Deque[string] {
+	d := NewDeque[string]()
+	for _, item := range items {
+		d.PushBack(item)
+	}
+	return d
+}
+
+func TestDequeInsertAndSet(t *testing.T) {
+	d := dequeOf("b", "d")
+	d.Insert(0, "a")
+	d.Insert(2, "c")
+	d.Insert(d.Len(), "e")
+	d.Set(4, "E")
+	if got := d.GetSlice(0, int64(d.Len()-1)); !slices.Equal(got, []string{"a", "b", "c", "d", "E"}) {
+		t.Errorf("elements = %v", got)
+	}
+	if d.At(2) != "c" {
+		t.Errorf("At(2) = %s, want c", d.At(2))
+	}
+
+	d.Trim(1, 3)
+	if got := d.GetSlice(0, int64(d.Len()-1)); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("elements after Trim(1, 3) = %v", got)
+	}
+}
+
+func TestDequeRemoveN(t *testing.T) {
+	tests := []struct {
+		count   int
+		removed int
+		want    []string
+	}{
+		{0, 3, []string{"b", "c"}},
+		{2, 2, []string{"b", "c", "a"}},
+		{-2, 2, []string{"a", "b", "c"}},
+		{10, 3, []string{"b", "c"}},
+		{-10, 3, []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		d := dequeOf("a", "b", "a", "c", "a")
+		if removed := d.RemoveN("a", tt.count); removed != tt.removed {
+			t.Errorf("RemoveN(a, %d) = %d, want %d", tt.count, removed, tt.removed)
+		}
+		if got := d.GetSlice(0, int64(d.Len()-1)); !slices.Equal(got, tt.want) {
+			t.Errorf("RemoveN(a, %d) left %v, want %v", tt.count, got, tt.want)
+		}
+	}
+}
+
+// TestDequeRandomized checks the deque against a slice after many random operations, which
+// exercises every position of the head in the ring and every grow and shrink
+func TestDequeRandomized(t *testing.T) {
+	d := NewDeque[int]()
+	var model []int
+	for i := range 20000 {
+		v := rand.IntN(50)
+		switch op := rand.IntN(10); {
+		case op < 3:
+			d.PushBack(v)
+			model = append(model, v)
+		case op < 6:
+			d.PushFront(v)
+			model = slices.Insert(model, 0, v)
+		case op == 6:
+			if got, ok := d.PopFront(); ok != (len(model) > 0) || (ok && got != model[0]) {
+				t.Fatalf("PopFront() = %d, %v, want the head of %v", got, ok, model)
+			}
+			if len(model) > 0 {
+				model = model[1:]
+			}
+		case op == 7:
+			if got, ok := d.PopBack(); ok != (len(model) > 0) || (ok && got != model[len(model)-1]) {
+				t.Fatalf("PopBack() = %d, %v, want the tail of %v", got, ok, model)
+			}
+			if len(model) > 0 {
+				model = model[:len(model)-1]
+			}
+		case op == 8:
+			pos := rand.IntN(len(model) + 1)
+			d.Insert(pos, v)
+			model = slices.Insert(model, pos, v)
+		case op == 9:
+			pos := slices.Index(model, v)
+			if d.Remove(v) != (pos >= 0) {
+				t.Fatalf("Remove(%d) = %v on %v", v, pos < 0, model)
+			}
+			if pos >= 0 {
+				model = slices.Delete(model, pos, pos+1)
+			}
+		}
+
+		if d.Len() != len(model) {
+			t.Fatalf("after %d operations Len() = %d, want %d", i, d.Len(), len(model))
+		}
+		if i%100 == 0 {
+			if got := d.GetSlice(0, int64(d.Len()-1)); !slices.Equal(got, model) {
+				t.Fatalf("after %d operations elements = %v, want %v", i, got, model)
+			}
+		}
+	}
+}
+
+func TestDequeShrinksAfterDrain(t *testing.T) {
+	d := NewDeque[string]()
+	for i := range 100000 {
+		d.PushFront(strconv.Itoa(i))
+	}
+	if d.Cap() < d.Len() {
+		t.Fatalf("Cap() = %d is below Len() = %d", d.Cap(), d.Len())
+	}
+
+	for d.Len() > 10 {
+		d.PopBack()
+	}
+	if d.Cap() > 64 {
+		t.Errorf("Cap() = %d with %d elements left, the buffer wasn't shrunk", d.Cap(), d.Len())
+	}
+	if got := d.GetSlice(0, 0); got[0] != "99999" {
+		t.Errorf("front element = %s, want 99999", got[0])
+	}
+
+	d.Trim(0, 1)
+	d.PopFront()
+	if d.Cap() != dequeMinCapacity || d.Len() != 0 {
+		t.Errorf("Cap() = %d, Len() = %d after draining, want %d and 0", d.Cap(), d.Len(), dequeMinCapacity)
+	}
+}
+
+// benchmarkSizes are the lengths the deque is filled to before measuring, the cost per
+// operation must not depend on them
+var benchmarkSizes = []int{1_000, 100_000, 1_000_000}
+
+func filledDeque(n int) *Deque[string] {
+	d := NewDeque[string]()
+	for range n {
+		d.PushBack("x")
+	}
+	return d
+}
+
+func BenchmarkDequePushFront(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			d := filledDeque(n)
+			b.ReportAllocs()
+			for b.Loop() {
+				d.PushFront("x")
+				d.PopBack()
+			}
+		})
+	}
+}
+
+func BenchmarkDequePushBack(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			d := filledDeque(n)
+			b.ReportAllocs()
+			for b.Loop() {
+				d.PushBack("x")
+				d.PopFront()
+			}
+		})
+	}
+}
+
+// BenchmarkDequeFillAndDrain pushes n elements and pops them all, reporting the capacity
+// left behind, which must be back to the minimum
+func BenchmarkDequeFillAndDrain(b *testing.B) {
+	for _, n := range benchmarkSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			d := NewDeque[string]()
+			b.ReportAllocs()
+			for b.Loop() {
+				for range n {
+					d.PushFront("x")
+				}
+				for d.Len() > 0 {
+					d.PopFront()
+				}
+			}
+			b.ReportMetric(float64(n), "elements/op")
+			b.ReportMetric(float64(d.Cap()), "cap-after-drain")
+		})
+	}
+}
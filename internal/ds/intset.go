@@ -0,0 +1,186 @@
+package ds
+
+import (
+	"encoding/binary"
+	"errors"
+	"iter"
+	"math"
+	"sort"
+)
+
+// IntSet is a sorted set of integers laid out the way Redis stores it: an 8 byte header
+// holding the width of every element and the number of elements, followed by the elements
+// in ascending order, little endian. All elements share the smallest width (2, 4 or 8 bytes)
+// that fits the largest of them, so small sets of small numbers take very little memory.
+type IntSet struct {
+	buf []byte
+}
+
+const intsetHeaderSize = 8
+
+// NewIntSet creates an empty set using 2 byte elements.
+func NewIntSet() *IntSet {
+	s := &IntSet{buf: make([]byte, intsetHeaderSize)}
+	binary.LittleEndian.PutUint32(s.buf, 2)
+	return s
+}
+
+// IntSetFromBytes parses the serialized form returned by Bytes.
+func IntSetFromBytes(b []byte) (*IntSet, error) {
+	if len(b) < intsetHeaderSize {
+		return nil, errors.New("intset: truncated header")
+	}
+	width := binary.LittleEndian.Uint32(b)
+	if width != 2 && width != 4 && width != 8 {
+		return nil, errors.New("intset: invalid encoding")
+	}
+	n := binary.LittleEndian.Uint32(b[4:])
+	if uint64(len(b)) != intsetHeaderSize+uint64(n)*uint64(width) {
+		return nil, errors.New("intset: length doesn't match the header")
+	}
+
+	s := &IntSet{buf: append([]byte(nil), b...)}
+	for i := 1; i < int(n); i++ {
+		if s.Get(i-1) >= s.Get(i) {
+			return nil, errors.New("intset: elements are not sorted")
+		}
+	}
+	return s, nil
+}
+
+// Bytes returns a copy of the serialized set.
+func (s *IntSet) Bytes() []byte {
+	return append([]byte(nil), s.buf...)
+}
+
+// Clone returns a copy of the set.
+func (s *IntSet) Clone() *IntSet {
+	return &IntSet{buf: s.Bytes()}
+}
+
+func (s *IntSet) width() int {
+	return int(binary.LittleEndian.Uint32(s.buf))
+}
+
+func (s *IntSet) Len() int {
+	return int(binary.LittleEndian.Uint32(s.buf[4:]))
+}
+
+func (s *IntSet) setLen(n int) {
+	binary.LittleEndian.PutUint32(s.buf[4:], uint32(n))
+}
+
+// Get returns the i-th smallest element.
+func (s *IntSet) Get(i int) int64 {
+	w := s.width()
+	p := s.buf[intsetHeaderSize+i*w:]
+	switch w {
+	case 2:
+		return int64(int16(binary.LittleEndian.Uint16(p)))
+	case 4:
+		return int64(int32(binary.LittleEndian.Uint32(p)))
+	}
+	return int64(binary.LittleEndian.Uint64(p))
+}
+
+func (s *IntSet) set(i int, v int64) {
+	w := s.width()
+	p := s.buf[intsetHeaderSize+i*w:]
+	switch w {
+	case 2:
+		binary.LittleEndian.PutUint16(p, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(p, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(p, uint64(v))
+	}
+}
+
+// search returns the position of v, or the position it would be inserted at.
+func (s *IntSet) search(v int64) (int, bool) {
+	n := s.Len()
+	i := sort.Search(n, func(i int) bool { return s.Get(i) >= v })
+	return i, i < n && s.Get(i) == v
+}
+
+func (s *IntSet) Contains(v int64) bool {
+	_, found := s.search(v)
+	return found
+}
+
+// Add inserts v and reports whether it wasn't already present.
+func (s *IntSet) Add(v int64) bool {
+	if widthFor(v) > s.width() {
+		s.upgrade(v)
+		return true
+	}
+
+	i, found := s.search(v)
+	if found {
+		return false
+	}
+	w, n := s.width(), s.Len()
+	s.buf = append(s.buf, make([]byte, w)...)
+	start := intsetHeaderSize + i*w
+	copy(s.buf[start+w:], s.buf[start:intsetHeaderSize+n*w])
+	s.setLen(n + 1)
+	s.set(i, v)
+	return true
+}
+
+// upgrade widens every element to fit v and adds it. v doesn't fit the current width so it
+// is either smaller or larger than every element
+func (s *IntSet) upgrade(v int64) {
+	old := *s
+	n := old.Len()
+	s.buf = make([]byte, intsetHeaderSize+(n+1)*widthFor(v))
+	binary.LittleEndian.PutUint32(s.buf, uint32(widthFor(v)))
+	s.setLen(n + 1)
+
+	offset := 0
+	if v < 0 {
+		s.set(0, v)
+		offset = 1
+	} else {
+		s.set(n, v)
+	}
+	for i := range n {
+		s.set(i+offset, old.Get(i))
+	}
+}
+
+// Remove deletes v and reports whether it was present.
+func (s *IntSet) Remove(v int64) bool {
+	i, found := s.search(v)
+	if !found {
+		return false
+	}
+	w, n := s.width(), s.Len()
+	start := intsetHeaderSize + i*w
+	copy(s.buf[start:], s.buf[start+w:])
+	s.buf = s.buf[:len(s.buf)-w]
+	s.setLen(n - 1)
+	return true
+}
+
+// All iterates over the elements in ascending order.
+func (s *IntSet) All() iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		for i := range s.Len() {
+			if !yield(s.Get(i)) {
+				return
+			}
+		}
+	}
+}
+
+// widthFor returns the number of bytes needed to store v.
+func widthFor(v int64) int {
+	switch {
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4
+	}
+	return 8
+}
@@ -0,0 +1,83 @@
+package ds
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestIntSet(t *testing.T) {
+	s := NewIntSet()
+	for _, v := range []int64{5, 1, 3, 1, -2} {
+		s.Add(v)
+	}
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int64{-2, 1, 3, 5}) {
+		t.Fatalf("All() = %v", got)
+	}
+	if s.width() != 2 {
+		t.Errorf("width() = %d, want 2", s.width())
+	}
+
+	if s.Add(3) {
+		t.Error("Add() of an existing element should return false")
+	}
+	if !s.Contains(-2) || s.Contains(4) {
+		t.Error("Contains() returned a wrong answer")
+	}
+	if !s.Remove(1) || s.Remove(1) {
+		t.Error("Remove() should only succeed once")
+	}
+	if got := slices.Collect(s.All()); !slices.Equal(got, []int64{-2, 3, 5}) {
+		t.Errorf("All() after Remove = %v", got)
+	}
+}
+
+func TestIntSetUpgrade(t *testing.T) {
+	s := NewIntSet()
+	s.Add(10)
+	s.Add(-10)
+
+	s.Add(100000)
+	if s.width() != 4 {
+		t.Errorf("width() = %d after adding a 32 bit value, want 4", s.width())
+	}
+	s.Add(math.MinInt64)
+	if s.width() != 8 {
+		t.Errorf("width() = %d after adding a 64 bit value, want 8", s.width())
+	}
+
+	want := []int64{math.MinInt64, -10, 10, 100000}
+	if got := slices.Collect(s.All()); !slices.Equal(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+}
+
+func TestIntSetBytes(t *testing.T) {
+	s := NewIntSet()
+	s.Add(1)
+	s.Add(2)
+	want := []byte{2, 0, 0, 0, 2, 0, 0, 0, 1, 0, 2, 0}
+	if !bytes.Equal(s.Bytes(), want) {
+		t.Errorf("Bytes() = %v, want %v", s.Bytes(), want)
+	}
+
+	parsed, err := IntSetFromBytes(want)
+	if err != nil {
+		t.Fatalf("IntSetFromBytes() error = %v", err)
+	}
+	if got := slices.Collect(parsed.All()); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("IntSetFromBytes() = %v", got)
+	}
+
+	for _, bad := range [][]byte{
+		{2, 0, 0},
+		{3, 0, 0, 0, 0, 0, 0, 0},
+		{2, 0, 0, 0, 2, 0, 0, 0, 1, 0},
+		{2, 0, 0, 0, 2, 0, 0, 0, 2, 0, 1, 0},
+	} {
+		if _, err := IntSetFromBytes(bad); err == nil {
+			t.Errorf("IntSetFromBytes(%v) should fail", bad)
+		}
+	}
+}
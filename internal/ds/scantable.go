@@ -0,0 +1,116 @@
+package ds
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// scanTableMinSize is the number of buckets of an empty table, always a power of two.
+const scanTableMinSize = 4
+
+// ScanTable spreads the members of a collection over a power of two number of buckets picked
+// by their hash, the layout Redis walks with the reverse binary cursor of SCAN. It only
+// serves iteration: membership is answered by the map holding the collection, the table is
+// kept next to it so that resuming an iteration costs O(1) instead of a pass over every
+// member. The table doubles once it holds as many members as buckets and halves once it is
+// less than 1/8 full.
+type ScanTable struct {
+	buckets [][]string
+	n       int
+}
+
+// NewScanTable creates an empty table.
+func NewScanTable() *ScanTable {
+	return &ScanTable{buckets: make([][]string, scanTableMinSize)}
+}
+
+// scanSeed is picked per process so clients cannot craft members piling up in one bucket.
+var scanSeed = maphash.MakeSeed()
+
+func scanHash(member string) uint64 {
+	return maphash.String(scanSeed, member)
+}
+
+func (t *ScanTable) bucket(member string) int {
+	return int(scanHash(member) & uint64(len(t.buckets)-1))
+}
+
+func (t *ScanTable) Len() int {
+	return t.n
+}
+
+// Add inserts member, which must not be in the table already.
+func (t *ScanTable) Add(member string) {
+	if t.n >= len(t.buckets) {
+		t.resize(2 * len(t.buckets))
+	}
+	b := t.bucket(member)
+	t.buckets[b] = append(t.buckets[b], member)
+	t.n++
+}
+
+// Remove deletes member and reports whether it was present.
+func (t *ScanTable) Remove(member string) bool {
+	b := t.bucket(member)
+	bucket := t.buckets[b]
+	for i, m := range bucket {
+		if m != member {
+			continue
+		}
+		last := len(bucket) - 1
+		bucket[i] = bucket[last]
+		bucket[last] = ""
+		if last == 0 {
+			t.buckets[b] = nil
+		} else {
+			t.buckets[b] = bucket[:last]
+		}
+		t.n--
+		if len(t.buckets) > scanTableMinSize && t.n < len(t.buckets)/8 {
+			t.resize(len(t.buckets) / 2)
+		}
+		return true
+	}
+	return false
+}
+
+func (t *ScanTable) resize(size int) {
+	old := t.buckets
+	t.buckets = make([][]string, size)
+	for _, bucket := range old {
+		for _, member := range bucket {
+			b := t.bucket(member)
+			t.buckets[b] = append(t.buckets[b], member)
+		}
+	}
+}
+
+// Scan returns the members of the buckets visited from cursor on, stopping once count
+// members were collected or 10*count buckets were visited, and the cursor to continue from,
+// 0 once the iteration is complete.
+//
+// The cursor is incremented with its bits reversed, so the buckets a resize splits or merges
+// are always the ones left to visit. A member present for the whole iteration is returned at
+// least once however the table changes between calls, and only a table shrinking in the
+// middle of an iteration can return a member twice.
+func (t *ScanTable) Scan(cursor uint64, count int) ([]string, uint64) {
+	mask := uint64(len(t.buckets) - 1)
+	var members []string
+	// A huge count visits every bucket rather than overflowing into none
+	visits := math.MaxInt
+	if count <= math.MaxInt/10 {
+		visits = 10 * count
+	}
+	for ; visits > 0; visits-- {
+		members = append(members, t.buckets[cursor&mask]...)
+
+		// Set the bits above the mask so the increment carries into the next bucket
+		cursor |= ^mask
+		cursor = bits.Reverse64(bits.Reverse64(cursor) + 1)
+		if cursor == 0 || len(members) >= count {
+			break
+		}
+	}
+	return members, cursor
+}
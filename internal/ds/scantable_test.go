@@ -0,0 +1,90 @@
+package ds
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// scanAll runs a full iteration of t with the given count, calling between after every call
+func scanAll(t *ScanTable, count int, between func()) map[string]int {
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		var batch []string
+		batch, cursor = t.Scan(cursor, count)
+		for _, member := range batch {
+			seen[member]++
+		}
+		if cursor == 0 {
+			return seen
+		}
+		between()
+	}
+}
+
+func TestScanTableFullIteration(t *testing.T) {
+	table := NewScanTable()
+	if batch, cursor := table.Scan(0, 10); len(batch) != 0 || cursor != 0 {
+		t.Fatalf("Scan() of an empty table = %v, %d", batch, cursor)
+	}
+
+	for i := range 1000 {
+		table.Add("m:" + strconv.Itoa(i))
+	}
+	seen := scanAll(table, 10, func() {})
+	if batch, cursor := table.Scan(0, math.MaxInt); len(batch) != 1000 || cursor != 0 {
+		t.Errorf("Scan() with a huge count returned %d members and cursor %d", len(batch), cursor)
+	}
+	if len(seen) != 1000 {
+		t.Errorf("Scan() returned %d members, want 1000", len(seen))
+	}
+	for member, n := range seen {
+		if n != 1 {
+			t.Errorf("Scan() returned %s %d times", member, n)
+		}
+	}
+}
+
+func TestScanTableResize(t *testing.T) {
+	table := NewScanTable()
+	for i := range 100 {
+		table.Add("m:" + strconv.Itoa(i))
+	}
+
+	// The table grows several times during the iteration, the members that were there from
+	// the start are still returned exactly once
+	next := 100
+	seen := scanAll(table, 5, func() {
+		for end := min(next+50, 2000); next < end; next++ {
+			table.Add("m:" + strconv.Itoa(next))
+		}
+	})
+	for i := range 100 {
+		if n := seen["m:"+strconv.Itoa(i)]; n != 1 {
+			t.Errorf("m:%d returned %d times while the table grew", i, n)
+		}
+	}
+
+	// Shrinking may return a member twice, but never skips one
+	for i := 100; i < next; i++ {
+		table.Remove("m:" + strconv.Itoa(i))
+	}
+	for i := range 2000 {
+		table.Add("x:" + strconv.Itoa(i))
+	}
+	removed := 0
+	seen = scanAll(table, 5, func() {
+		for end := min(removed+200, 2000); removed < end; removed++ {
+			table.Remove("x:" + strconv.Itoa(removed))
+		}
+	})
+	for i := range 100 {
+		if seen["m:"+strconv.Itoa(i)] == 0 {
+			t.Errorf("m:%d was skipped while the table shrank", i)
+		}
+	}
+	if table.Len() != 100 || len(table.buckets) > 8*table.Len() || table.Remove("x:10") {
+		t.Errorf("Len() = %d with %d buckets after removing every x member", table.Len(), len(table.buckets))
+	}
+}
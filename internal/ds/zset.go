@@ -0,0 +1,285 @@
+package ds
+
+import (
+	"iter"
+	"math/rand/v2"
+)
+
+const (
+	zsetMaxLevel = 32   // enough for 2^64 elements with p = 1/4
+	zsetP        = 0.25 // probability of a node reaching the next level
+)
+
+// ZEntry is a member of a sorted set together with its score.
+type ZEntry struct {
+	Member string
+	Score  float64
+}
+
+// less orders entries by score, then by member.
+func (e ZEntry) less(score float64, member string) bool {
+	return e.Score < score || (e.Score == score && e.Member < member)
+}
+
+type zsetLevel struct {
+	forward *zsetNode
+	span    int // number of nodes between this node and forward, forward included
+}
+
+type zsetNode struct {
+	ZEntry
+	backward *zsetNode
+	level    []zsetLevel
+}
+
+// SortedSet is a set of unique members ordered by score, with ties ordered by member. It is
+// the skiplist plus dict pair Redis uses: the skiplist keeps the order and, thanks to the
+// span stored on every link, answers rank queries in O(log n); the map gives O(1) score
+// lookups by member. The members are also kept in a ScanTable for ZSCAN.
+type SortedSet struct {
+	header *zsetNode
+	level  int
+	length int // nodes in the skiplist, differs from len(dict) while a score is being updated
+	dict   map[string]float64
+	scan   *ScanTable
+}
+
+// NewSortedSet creates an empty sorted set.
+func NewSortedSet() *SortedSet {
+	return &SortedSet{
+		header: &zsetNode{level: make([]zsetLevel, zsetMaxLevel)},
+		level:  1,
+		dict:   make(map[string]float64),
+		scan:   NewScanTable(),
+	}
+}
+
+func (z *SortedSet) Len() int {
+	return len(z.dict)
+}
+
+// Score returns the score of member.
+func (z *SortedSet) Score(member string) (float64, bool) {
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// Add sets the score of member, inserting it if needed. It reports whether the member is new.
+func (z *SortedSet) Add(member string, score float64) bool {
+	old, exists := z.dict[member]
+	if exists {
+		if old == score {
+			return false
+		}
+		z.delete(member, old)
+	}
+	z.insert(member, score)
+	z.dict[member] = score
+	if !exists {
+		z.scan.Add(member)
+	}
+	return !exists
+}
+
+// Remove deletes member and reports whether it was present.
+func (z *SortedSet) Remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.delete(member, score)
+	delete(z.dict, member)
+	z.scan.Remove(member)
+	return true
+}
+
+func randomLevel() int {
+	level := 1
+	for level < zsetMaxLevel && rand.Float64() < zsetP {
+		level++
+	}
+	return level
+}
+
+func (z *SortedSet) insert(member string, score float64) {
+	var update [zsetMaxLevel]*zsetNode
+	var rank [zsetMaxLevel]int
+
+	x := z.header
+	for i := z.level - 1; i >= 0; i-- {
+		if i < z.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			rank[i] += x.level[i].span
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	level := randomLevel()
+	if level > z.level {
+		for i := z.level; i < level; i++ {
+			rank[i] = 0
+			update[i] = z.header
+			update[i].level[i].span = z.length
+		}
+		z.level = level
+	}
+
+	x = &zsetNode{ZEntry: ZEntry{Member: member, Score: score}, level: make([]zsetLevel, level)}
+	for i := range level {
+		x.level[i].forward = update[i].level[i].forward
+		update[i].level[i].forward = x
+		x.level[i].span = update[i].level[i].span - (rank[0] - rank[i])
+		update[i].level[i].span = rank[0] - rank[i] + 1
+	}
+	for i := level; i < z.level; i++ {
+		update[i].level[i].span++
+	}
+
+	if update[0] != z.header {
+		x.backward = update[0]
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x
+	}
+	z.length++
+}
+
+func (z *SortedSet) delete(member string, score float64) {
+	var update [zsetMaxLevel]*zsetNode
+
+	x := z.header
+	for i := z.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && x.level[i].forward.less(score, member) {
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+	x = x.level[0].forward
+
+	for i := range z.level {
+		if update[i].level[i].forward == x {
+			update[i].level[i].span += x.level[i].span - 1
+			update[i].level[i].forward = x.level[i].forward
+		} else {
+			update[i].level[i].span--
+		}
+	}
+	if x.level[0].forward != nil {
+		x.level[0].forward.backward = x.backward
+	}
+	for z.level > 1 && z.header.level[z.level-1].forward == nil {
+		z.level--
+	}
+	z.length--
+}
+
+// Rank returns the 0 based position of member in ascending order.
+func (z *SortedSet) Rank(member string) (int, bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false
+	}
+	// Every entry ordered before member is counted, member itself is the next one
+	return z.countWhile(func(e ZEntry) bool { return e.less(score, member) }), true
+}
+
+// countWhile returns the number of leading entries for which before holds. before must hold
+// for a prefix of the set and fail for the rest.
+func (z *SortedSet) countWhile(before func(ZEntry) bool) int {
+	n := 0
+	x := z.header
+	for i := z.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && before(x.level[i].forward.ZEntry) {
+			n += x.level[i].span
+			x = x.level[i].forward
+		}
+	}
+	return n
+}
+
+// ScoreRank returns the number of entries with a score below bound, or at most bound when
+// exclusive is set: the rank of the first entry of a range starting at bound.
+func (z *SortedSet) ScoreRank(bound float64, exclusive bool) int {
+	if exclusive {
+		return z.countWhile(func(e ZEntry) bool { return e.Score <= bound })
+	}
+	return z.countWhile(func(e ZEntry) bool { return e.Score < bound })
+}
+
+// LexRank returns the number of entries whose member sorts before bound, or is at most bound
+// when exclusive is set. It is only meaningful when every entry has the same score.
+func (z *SortedSet) LexRank(bound string, exclusive bool) int {
+	if exclusive {
+		return z.countWhile(func(e ZEntry) bool { return e.Member <= bound })
+	}
+	return z.countWhile(func(e ZEntry) bool { return e.Member < bound })
+}
+
+// nodeAt returns the node at the 0 based rank, which must be in range.
+func (z *SortedSet) nodeAt(rank int) *zsetNode {
+	traversed := -1
+	x := z.header
+	for i := z.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil && traversed+x.level[i].span <= rank {
+			traversed += x.level[i].span
+			x = x.level[i].forward
+		}
+		if traversed == rank {
+			return x
+		}
+	}
+	return nil
+}
+
+// Range returns the entries whose rank is in [start, stop), in ascending order, or in
+// descending order when rev is set. Out of range bounds are clamped.
+func (z *SortedSet) Range(start, stop int, rev bool) []ZEntry {
+	start, stop = max(start, 0), min(stop, z.Len())
+	if start >= stop {
+		return nil
+	}
+
+	entries := make([]ZEntry, 0, stop-start)
+	if rev {
+		for x := z.nodeAt(stop - 1); len(entries) < stop-start; x = x.backward {
+			entries = append(entries, x.ZEntry)
+		}
+		return entries
+	}
+	for x := z.nodeAt(start); len(entries) < stop-start; x = x.level[0].forward {
+		entries = append(entries, x.ZEntry)
+	}
+	return entries
+}
+
+// All iterates over the entries in ascending order.
+func (z *SortedSet) All() iter.Seq[ZEntry] {
+	return func(yield func(ZEntry) bool) {
+		for x := z.header.level[0].forward; x != nil; x = x.level[0].forward {
+			if !yield(x.ZEntry) {
+				return
+			}
+		}
+	}
+}
+
+// Scan returns a batch of about count members starting at cursor and the cursor of the next
+// batch, see ScanTable.Scan.
+func (z *SortedSet) Scan(cursor uint64, count int) ([]string, uint64) {
+	return z.scan.Scan(cursor, count)
+}
+
+// Members iterates over the members in no particular order, it is cheaper than All when the
+// order doesn't matter.
+func (z *SortedSet) Members() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for member := range z.dict {
+			if !yield(member) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ds
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// sortedEntries returns the entries of m in the order a sorted set must keep them
+func sortedEntries(m map[string]float64) []ZEntry {
+	entries := make([]ZEntry, 0, len(m))
+	for member, score := range m {
+		entries = append(entries, ZEntry{member, score})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].less(entries[j].Score, entries[j].Member) })
+	return entries
+}
+
+func TestSortedSetOrder(t *testing.T) {
+	z := NewSortedSet()
+	z.Add("c", 2)
+	z.Add("a", 2)
+	z.Add("b", 1)
+	if !z.Add("d", 0) || z.Add("d", 3) {
+		t.Error("Add() should only report new members")
+	}
+
+	want := []ZEntry{{"b", 1}, {"a", 2}, {"c", 2}, {"d", 3}}
+	if got := slices.Collect(z.All()); !slices.Equal(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+	if got := z.Range(1, 3, false); !slices.Equal(got, want[1:3]) {
+		t.Errorf("Range(1, 3) = %v", got)
+	}
+	if got := z.Range(0, 10, true); !slices.Equal(got, []ZEntry{{"d", 3}, {"c", 2}, {"a", 2}, {"b", 1}}) {
+		t.Errorf("Range(0, 10, rev) = %v", got)
+	}
+	if got := z.Range(3, 2, false); got != nil {
+		t.Errorf("Range(3, 2) = %v, want nil", got)
+	}
+
+	if rank, ok := z.Rank("c"); !ok || rank != 2 {
+		t.Errorf("Rank(c) = %d, %v", rank, ok)
+	}
+	if z.ScoreRank(2, false) != 1 || z.ScoreRank(2, true) != 3 || z.ScoreRank(10, false) != 4 {
+		t.Errorf("ScoreRank() returned wrong ranks")
+	}
+
+	if !z.Remove("a") || z.Remove("a") {
+		t.Error("Remove() should only succeed once")
+	}
+	if _, ok := z.Score("a"); ok || z.Len() != 3 {
+		t.Error("removed member is still present")
+	}
+}
+
+func TestSortedSetLexRank(t *testing.T) {
+	z := NewSortedSet()
+	for _, m := range []string{"a", "b", "c", "d"} {
+		z.Add(m, 0)
+	}
+	if z.LexRank("b", false) != 1 || z.LexRank("b", true) != 2 || z.LexRank("bb", false) != 2 {
+		t.Errorf("LexRank() returned wrong ranks")
+	}
+}
+
+// TestSortedSetRandomized checks ranks and ranges against a sorted slice after many random
+// inserts, updates and removals
+func TestSortedSetRandomized(t *testing.T) {
+	z := NewSortedSet()
+	model := make(map[string]float64)
+	for i := range 5000 {
+		member := "m" + strconv.Itoa(rand.IntN(500))
+		switch rand.IntN(3) {
+		case 0, 1:
+			score := float64(rand.IntN(100))
+			z.Add(member, score)
+			model[member] = score
+		case 2:
+			z.Remove(member)
+			delete(model, member)
+		}
+
+		if i%500 != 0 {
+			continue
+		}
+		want := sortedEntries(model)
+		if got := z.Range(0, z.Len(), false); !slices.Equal(got, want) {
+			t.Fatalf("after %d operations Range() doesn't match the model", i)
+		}
+		for rank, e := range want {
+			if r, ok := z.Rank(e.Member); !ok || r != rank {
+				t.Fatalf("Rank(%s) = %d, want %d", e.Member, r, rank)
+			}
+			if got := z.Range(rank, rank+1, false); len(got) != 1 || got[0] != e {
+				t.Fatalf("Range(%d, %d) = %v, want %v", rank, rank+1, got, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package parser
+
+import (
+	"bufio"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// handleAttribute reads the attributes and the reply they describe, which always follows
+func handleAttribute(r *bufio.Reader) (resp.Message, error) {
+	entries, err := readEntries(r, "attribute")
+	if err != nil {
+		return nil, err
+	}
+	reply, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Attribute{Val: entries, Reply: reply}, nil
+}
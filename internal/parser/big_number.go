@@ -0,0 +1,22 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func handleBigNumber(r *bufio.Reader) (resp.Message, error) {
+	line, err := readLine(r, "big number")
+	if err != nil {
+		return nil, err
+	}
+
+	n, ok := new(big.Int).SetString(string(line), 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid big number %q", line)
+	}
+	return &resp.BigNumber{Val: n}, nil
+}
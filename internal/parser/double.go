@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func handleDouble(r *bufio.Reader) (resp.Message, error) {
+	line, err := readLine(r, "double")
+	if err != nil {
+		return nil, err
+	}
+
+	switch string(line) {
+	case "inf":
+		return &resp.Double{Val: math.Inf(1)}, nil
+	case "-inf":
+		return &resp.Double{Val: math.Inf(-1)}, nil
+	case "nan":
+		return &resp.Double{Val: math.NaN()}, nil
+	}
+
+	// ParseFloat alone would also accept "Inf", "0x1p-2" or "1_000"
+	for _, c := range line {
+		if !(c >= '0' && c <= '9') && c != '.' && c != '-' && c != '+' && c != 'e' && c != 'E' {
+			return nil, fmt.Errorf("Invalid character %q in double type", c)
+		}
+	}
+	f, err := strconv.ParseFloat(string(line), 64)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Double{Val: f}, nil
+}
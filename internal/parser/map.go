@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func handleMap(r *bufio.Reader) (resp.Message, error) {
+	entries, err := readEntries(r, "map")
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Map{Val: entries}, nil
+}
+
+// readEntries reads the number of key value pairs of a map or an attribute and the pairs
+func readEntries(r *bufio.Reader, kind string) ([]resp.MapEntry, error) {
+	n, err := readCount(r, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]resp.MapEntry, n)
+	for i := range entries {
+		if entries[i].Key, err = Parse(r); err != nil {
+			return nil, err
+		}
+		if entries[i].Val, err = Parse(r); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// readElements reads the number of elements of a set or a push and the elements
+func readElements(r *bufio.Reader, kind string) ([]resp.Message, error) {
+	n, err := readCount(r, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]resp.Message, n)
+	for i := range elements {
+		if elements[i], err = Parse(r); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// readCount reads the CRLF terminated, non negative size of an aggregate type
+func readCount(r *bufio.Reader, kind string) (int64, error) {
+	line, err := readLine(r, kind)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseInt(string(line), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("Number of elements in a %s cannot be negative", kind)
+	}
+	return n, nil
+}
+
+// readLine reads up to the next CRLF and returns what came before it
+func readLine(r *bufio.Reader, kind string) ([]byte, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("Invalid CRLF in %s type", kind)
+	}
+	return line[:len(line)-2], nil
+}
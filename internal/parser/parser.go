@@ -29,6 +29,20 @@ func Parse(r *bufio.Reader) (resp.Message, error) {
 		return handleBoolean(r)
 	case '*':
 		return handleArray(r)
+	case '%':
+		return handleMap(r)
+	case '~':
+		return handleSet(r)
+	case '>':
+		return handlePush(r)
+	case '|':
+		return handleAttribute(r)
+	case ',':
+		return handleDouble(r)
+	case '(':
+		return handleBigNumber(r)
+	case '=':
+		return handleVerbatimString(r)
 	default:
 		// Check if this might be an inline command (plain text format)
 		// Inline commands start with printable ASCII characters
@@ -3,6 +3,8 @@ package parser
 import (
 	"bufio"
 	"bytes"
+	"math"
+	"math/big"
 	"reflect"
 	"testing"
 
@@ -443,6 +445,369 @@ func TestParseArray(t *testing.T) {
 	}
 }
 
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "empty map",
+			input: "%0\r\n",
+			want:  &resp.Map{Val: []resp.MapEntry{}},
+		},
+		{
+			name:  "map with mixed values",
+			input: "%2\r\n+first\r\n:1\r\n$6\r\nsecond\r\n#f\r\n",
+			want: &resp.Map{Val: []resp.MapEntry{
+				{Key: &resp.SimpleString{Val: []byte("first")}, Val: &resp.Integer{Val: 1}},
+				{Key: &resp.BulkString{Size: 6, Str: []byte("second")}, Val: &resp.Boolean{Val: false}},
+			}},
+		},
+		{
+			name:    "negative count",
+			input:   "%-1\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			input:   "%1\r\n+key\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '%' prefix
+			got, err := handleMap(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleMap() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "empty set",
+			input: "~0\r\n",
+			want:  &resp.Set{Val: []resp.Message{}},
+		},
+		{
+			name:  "set with elements",
+			input: "~2\r\n:1\r\n+two\r\n",
+			want: &resp.Set{Val: []resp.Message{
+				&resp.Integer{Val: 1},
+				&resp.SimpleString{Val: []byte("two")},
+			}},
+		},
+		{
+			name:    "invalid count format",
+			input:   "~x\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "incomplete set elements",
+			input:   "~2\r\n:1\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '~' prefix
+			got, err := handleSet(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleSet() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePush(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "pub/sub message",
+			input: ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$2\r\nhi\r\n",
+			want: &resp.Push{Val: []resp.Message{
+				&resp.BulkString{Size: 7, Str: []byte("message")},
+				&resp.BulkString{Size: 2, Str: []byte("ch")},
+				&resp.BulkString{Size: 2, Str: []byte("hi")},
+			}},
+		},
+		{
+			name:    "missing CRLF",
+			input:   ">1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '>' prefix
+			got, err := handlePush(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handlePush() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handlePush() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDouble(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "integral double",
+			input: ",10\r\n",
+			want:  &resp.Double{Val: 10},
+		},
+		{
+			name:  "fractional double",
+			input: ",-1.5\r\n",
+			want:  &resp.Double{Val: -1.5},
+		},
+		{
+			name:  "exponent",
+			input: ",1.5e3\r\n",
+			want:  &resp.Double{Val: 1500},
+		},
+		{
+			name:  "positive infinity",
+			input: ",inf\r\n",
+			want:  &resp.Double{Val: math.Inf(1)},
+		},
+		{
+			name:  "negative infinity",
+			input: ",-inf\r\n",
+			want:  &resp.Double{Val: math.Inf(-1)},
+		},
+		{
+			name:    "empty double",
+			input:   ",\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "hexadecimal double",
+			input:   ",0x10\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			input:   ",abc\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the ',' prefix
+			got, err := handleDouble(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleDouble() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleDouble() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBigNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "big number",
+			input: "(3492890328409238509324850943850943825024385\r\n",
+			want:  &resp.BigNumber{Val: bigInt("3492890328409238509324850943850943825024385")},
+		},
+		{
+			name:  "negative big number",
+			input: "(-12\r\n",
+			want:  &resp.BigNumber{Val: bigInt("-12")},
+		},
+		{
+			name:    "invalid digits",
+			input:   "(12a\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing CRLF",
+			input:   "(12\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '(' prefix
+			got, err := handleBigNumber(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleBigNumber() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleBigNumber() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVerbatimString(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "text",
+			input: "=15\r\ntxt:Some string\r\n",
+			want:  &resp.VerbatimString{Format: "txt", Str: []byte("Some string")},
+		},
+		{
+			name:  "empty text",
+			input: "=4\r\nmkd:\r\n",
+			want:  &resp.VerbatimString{Format: "mkd", Str: []byte{}},
+		},
+		{
+			name:    "missing format",
+			input:   "=3\r\ntxt\r\n",
+			wantErr: true,
+		},
+		{
+			name:    "size mismatch",
+			input:   "=10\r\ntxt:short\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '=' prefix
+			got, err := handleVerbatimString(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleVerbatimString() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleVerbatimString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    resp.Message
+		wantErr bool
+	}{
+		{
+			name:  "attribute before a reply",
+			input: "|1\r\n+ttl\r\n:3600\r\n$5\r\nvalue\r\n",
+			want: &resp.Attribute{
+				Val:   []resp.MapEntry{{Key: &resp.SimpleString{Val: []byte("ttl")}, Val: &resp.Integer{Val: 3600}}},
+				Reply: &resp.BulkString{Size: 5, Str: []byte("value")},
+			},
+		},
+		{
+			name:    "missing reply",
+			input:   "|1\r\n+ttl\r\n:3600\r\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewBufferString(tt.input))
+			r.ReadByte() // skip the '|' prefix
+			got, err := handleAttribute(r)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handleAttribute() error = %v, wantErr %v",
+					err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handleAttribute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func bigInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 10)
+	return n
+}
+
+// TestRESP3RoundTrip checks that every RESP3 type parses back to what was serialized
+func TestRESP3RoundTrip(t *testing.T) {
+	messages := []resp.Message{
+		&resp.Map{Val: []resp.MapEntry{{Key: &resp.BulkString{Size: 1, Str: []byte("k")}, Val: &resp.Double{Val: 0.1}}}},
+		&resp.Set{Val: []resp.Message{&resp.Null{}, &resp.Boolean{Val: true}}},
+		&resp.Push{Val: []resp.Message{&resp.Double{Val: math.Inf(-1)}, &resp.Double{Val: 1e300}}},
+		&resp.BigNumber{Val: bigInt("-3492890328409238509324850943850943825024385")},
+		&resp.VerbatimString{Format: "txt", Str: []byte("line\r\nbreak")},
+		&resp.Attribute{Val: []resp.MapEntry{}, Reply: &resp.Integer{Val: 1}},
+	}
+
+	for _, msg := range messages {
+		got, err := Parse(bufio.NewReader(bytes.NewReader(msg.ToBytes())))
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", msg.ToBytes(), err)
+			continue
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("Parse(%q) = %v, want %v", msg.ToBytes(), got, msg)
+		}
+	}
+}
+
 func TestParse(t *testing.T) {
 	tests := []struct {
 		name    string
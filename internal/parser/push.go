@@ -0,0 +1,15 @@
+package parser
+
+import (
+	"bufio"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func handlePush(r *bufio.Reader) (resp.Message, error) {
+	elements, err := readElements(r, "push")
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Push{Val: elements}, nil
+}
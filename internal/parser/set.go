@@ -0,0 +1,15 @@
+package parser
+
+import (
+	"bufio"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+func handleSet(r *bufio.Reader) (resp.Message, error) {
+	elements, err := readElements(r, "set")
+	if err != nil {
+		return nil, err
+	}
+	return &resp.Set{Val: elements}, nil
+}
@@ -0,0 +1,23 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
+)
+
+// handleVerbatimString reads a verbatim string, laid out like a bulk string whose content
+// starts with a three letter format and a colon
+func handleVerbatimString(r *bufio.Reader) (resp.Message, error) {
+	msg, err := handleBulkString(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := msg.(*resp.BulkString)
+	if bs.Size < 4 || bs.Str[3] != ':' {
+		return nil, fmt.Errorf("Invalid format prefix in verbatim string")
+	}
+	return &resp.VerbatimString{Format: string(bs.Str[:3]), Str: bs.Str[4:]}, nil
+}
@@ -2,23 +2,107 @@ package pubsub
 
 import (
 	"bufio"
+	"bytes"
+	"net"
 	"sync"
+	"sync/atomic"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 )
 
-// The global pub sub instance is represented by this struct which contains a mapping of each client to
-// an array of channels it is subscribed to + channels mapped to an array of connections
+// The global pub sub instance is represented by this struct which contains channels mapped to the
+// connections subscribed to them + glob patterns mapped to the connections subscribed to them.
+// Shard channels live in slots of their own, each with its own lock, so SPUBLISH scales
 type Global struct {
 	ChannelToClient map[string]map[*Connection]struct{}
+	PatternToClient map[string]map[*Connection]struct{}
 	Mu              sync.RWMutex
+
+	ShardChannels [db.Shards]*ShardSlot
 }
 
 // A "connection" with a client is represented as this struct, this is done to
-// keep track of subcribed/unsubscribed modes and number of subscribed channels
+// keep track of subcribed/unsubscribed modes and number of subscribed channels and patterns
 type Connection struct {
-	W        *bufio.Writer
-	Channels map[string]struct{}
-	Name     string // connection name set by CLIENT SETNAME
-	Mu       sync.Mutex // protects W for concurrent writes
+	ID            int64 // unique id assigned when the client connects, see CLIENT ID
+	W             *bufio.Writer
+	Channels      map[string]struct{}
+	Patterns      map[string]struct{} // glob patterns subscribed to with PSUBSCRIBE
+	ShardChannels map[string]struct{} // shard channels subscribed to with SSUBSCRIBE
+	Name          string              // connection name set by CLIENT SETNAME
+	Proto         atomic.Int32        // RESP version spoken by the client, read by the goroutines delivering to it
+
+	// Outbound state: W buffers the replies of the client's own commands, Flush queues them
+	// and Push queues messages from other clients, see NewConnection
+	nc    net.Conn
+	reply bytes.Buffer
+	out   outbound
+
+	// Transaction state, see MULTI, EXEC and WATCH
+	InMulti     bool          // commands are queued instead of run
+	Queued      []*resp.Array // commands queued since MULTI
+	MultiFailed bool          // a command could not be queued, EXEC will abort
+	Watcher     *db.Watcher   // keys watched with WATCH, nil when none are
+	Held        *db.Keyspace  // every shard, held while EXEC runs the queued commands
+
+	// Client side caching state, see CLIENT TRACKING
+	Tracker *db.Tracker // nil while tracking is off
+	OptIn   bool        // only the reads of the command following CLIENT CACHING yes are tracked
+	OptOut  bool        // the reads of the command following CLIENT CACHING no aren't tracked
+	Caching bool        // CLIENT CACHING was called for the next command or transaction
+	Reader  *db.Tracker // tracks the keys read by the running command, nil when they aren't
+
+	// Blocking state, see CLIENT UNBLOCK
+	blockMu sync.Mutex
+	unblock chan bool // set while the client waits in a blocking command
+}
+
+// Subscriptions returns the number of channels and patterns the client is subscribed to
+func (c *Connection) Subscriptions() int {
+	return len(c.Channels) + len(c.Patterns)
+}
+
+// Subscribed reports whether the client is in subscribed mode: subscribed to a channel, a
+// pattern or a shard channel
+func (c *Connection) Subscribed() bool {
+	return c.Subscriptions() > 0 || len(c.ShardChannels) > 0
+}
+
+// RESP3 reports whether the client switched to RESP3 with HELLO 3
+func (c *Connection) RESP3() bool {
+	return c.Proto.Load() == 3
+}
+
+// BeginBlocking marks the client as waiting in a blocking command. The returned channel
+// receives a value if CLIENT UNBLOCK releases the client, true when it asked for an error
+// reply. EndBlocking must be called once the command stops waiting
+func (c *Connection) BeginBlocking() <-chan bool {
+	c.blockMu.Lock()
+	defer c.blockMu.Unlock()
+	c.unblock = make(chan bool, 1)
+	return c.unblock
+}
+
+// EndBlocking marks the client as no longer waiting
+func (c *Connection) EndBlocking() {
+	c.blockMu.Lock()
+	defer c.blockMu.Unlock()
+	c.unblock = nil
+}
+
+// Unblock releases the client from the blocking command it waits in, with an error reply
+// if withError is set. It reports whether the client was waiting
+func (c *Connection) Unblock(withError bool) bool {
+	c.blockMu.Lock()
+	defer c.blockMu.Unlock()
+	if c.unblock == nil {
+		return false
+	}
+	c.unblock <- withError
+	// A second CLIENT UNBLOCK before the client notices the first one finds it released
+	c.unblock = nil
+	return true
 }
 
 var PubSubOnce sync.Once
@@ -28,6 +112,10 @@ func InitPubSub() {
 	PubSubOnce.Do(func() {
 		Instance = Global{
 			ChannelToClient: make(map[string]map[*Connection]struct{}),
+			PatternToClient: make(map[string]map[*Connection]struct{}),
+		}
+		for i := range Instance.ShardChannels {
+			Instance.ShardChannels[i] = &ShardSlot{Channels: make(map[string]map[*Connection]struct{})}
 		}
 	})
 }
@@ -0,0 +1,197 @@
+package pubsub
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OutputBufferLimit caps how many bytes may wait to be written to a client: a client with
+// more than Hard bytes queued, or more than Soft bytes for SoftSeconds in a row, is
+// disconnected. A limit of 0 disables it
+type OutputBufferLimit struct {
+	Hard        int64
+	Soft        int64
+	SoftSeconds int64
+}
+
+// pubsubLimit is the limit applied to the messages published to clients, see
+// SetPubSubLimit. It defaults to the Redis one: 32mb hard, 8mb soft for 60 seconds
+var pubsubLimit atomic.Pointer[OutputBufferLimit]
+
+// limitDisconnections counts the clients disconnected for going over an output buffer limit
+var limitDisconnections atomic.Int64
+
+func init() {
+	pubsubLimit.Store(&OutputBufferLimit{Hard: 32 << 20, Soft: 8 << 20, SoftSeconds: 60})
+}
+
+// SetPubSubLimit changes the output buffer limit of clients receiving published messages
+func SetPubSubLimit(limit OutputBufferLimit) {
+	pubsubLimit.Store(&limit)
+}
+
+// LimitDisconnections returns the number of clients disconnected for going over an output
+// buffer limit
+func LimitDisconnections() int64 {
+	return limitDisconnections.Load()
+}
+
+// outbound is the queue of what has to be written to a client. A single goroutine writes it
+// out, so replies and messages pushed by other clients never interleave and a slow client
+// doesn't hold up the clients publishing to it
+type outbound struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  [][]byte
+	after  [][]byte  // messages queued by the next Flush, after the reply
+	size   int64     // bytes queued or being written
+	softAt time.Time // when size went over the soft limit, zero while it is under
+	closed bool
+	done   chan struct{} // closed once the writer goroutine returns
+}
+
+// NewConnection returns the connection of a client connected through nc and starts the
+// goroutine writing to it. Replies are written to W and sent with Flush, Close must be called
+// once the client goes away
+func NewConnection(nc net.Conn) *Connection {
+	c := &Connection{
+		Channels: make(map[string]struct{}),
+		Patterns: make(map[string]struct{}),
+		nc:       nc,
+
+		ShardChannels: make(map[string]struct{}),
+	}
+	c.W = bufio.NewWriter(&c.reply)
+	c.Proto.Store(2)
+	c.out.cond = sync.NewCond(&c.out.mu)
+	c.out.done = make(chan struct{})
+	go c.writeLoop()
+	return c
+}
+
+// Flush queues the replies written to W since the last call as a single write, so messages
+// pushed from other goroutines never land in the middle of a reply. The messages given to
+// PushAfterReply meanwhile follow it
+func (c *Connection) Flush() {
+	c.W.Flush()
+	if c.reply.Len() > 0 {
+		c.enqueue(bytes.Clone(c.reply.Bytes()), nil)
+		c.reply.Reset()
+	}
+
+	c.out.mu.Lock()
+	after := c.out.after
+	c.out.after = nil
+	c.out.mu.Unlock()
+	for _, payload := range after {
+		c.Push(payload)
+	}
+}
+
+// Push queues a message published to the client. It is subject to the pubsub output buffer
+// limit: a client that falls too far behind is disconnected
+func (c *Connection) Push(payload []byte) {
+	c.enqueue(payload, pubsubLimit.Load())
+}
+
+// PushAfterReply is Push for a message caused by the command the client is running, it is
+// held back until the command's reply is flushed
+func (c *Connection) PushAfterReply(payload []byte) {
+	c.out.mu.Lock()
+	defer c.out.mu.Unlock()
+	c.out.after = append(c.out.after, payload)
+}
+
+// enqueue queues b to be written, checking limit when it isn't nil. Nothing is queued once
+// the connection is closed
+func (c *Connection) enqueue(b []byte, limit *OutputBufferLimit) {
+	o := &c.out
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.queue = append(o.queue, b)
+	o.size += int64(len(b))
+	o.cond.Signal()
+
+	if limit == nil {
+		return
+	}
+	if limit.Hard > 0 && o.size > limit.Hard {
+		c.kill("hard")
+		return
+	}
+	switch {
+	case limit.Soft == 0 || o.size <= limit.Soft:
+		o.softAt = time.Time{}
+	case o.softAt.IsZero():
+		o.softAt = time.Now()
+	case time.Since(o.softAt) >= time.Duration(limit.SoftSeconds)*time.Second:
+		c.kill("soft")
+	}
+}
+
+// kill drops what is queued and closes the network connection, the client's read loop then
+// fails and tears the connection down. o.mu must be held
+func (c *Connection) kill(which string) {
+	log.Printf("Client id=%d closed for overcoming of output buffer limits (%s limit, %d bytes queued)", c.ID, which, c.out.size)
+	limitDisconnections.Add(1)
+	c.out.closed = true
+	c.out.queue = nil
+	c.out.cond.Signal()
+	c.nc.Close()
+}
+
+// Close writes out what is still queued and closes the network connection
+func (c *Connection) Close() {
+	o := &c.out
+	o.mu.Lock()
+	o.closed = true
+	o.cond.Signal()
+	o.mu.Unlock()
+	<-o.done
+	c.nc.Close()
+}
+
+// writeLoop writes the queue to the network until the connection is closed
+func (c *Connection) writeLoop() {
+	defer close(c.out.done)
+	w := bufio.NewWriter(c.nc)
+	o := &c.out
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for {
+		for len(o.queue) == 0 && !o.closed {
+			o.cond.Wait()
+		}
+		if len(o.queue) == 0 {
+			return // closed and drained
+		}
+
+		// Write everything queued so far in one go, without holding the lock
+		batch := o.queue
+		o.queue = nil
+		o.mu.Unlock()
+		var written int64
+		for _, b := range batch {
+			w.Write(b)
+			written += int64(len(b))
+		}
+		err := w.Flush()
+		o.mu.Lock()
+
+		o.size -= written
+		if err != nil {
+			// The client went away, its read loop will notice
+			o.closed = true
+			o.queue = nil
+			return
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package pubsub
 
+import "github.com/codecrafters-io/redis-starter-go/internal/resp"
+
 func (g *Global) GetMap(channel string) map[*Connection]struct{} {
 	g.Mu.RLock()
 	defer g.Mu.RUnlock()
@@ -10,7 +12,118 @@ func (g *Global) GetMap(channel string) map[*Connection]struct{} {
 	return nil
 }
 
-func (g *Global) DeliverMessage(cons map[*Connection]struct{}, payload []byte) {
+// Forget removes a connection that went away from every channel, pattern and shard channel
+// it is subscribed to
+func (g *Global) Forget(c *Connection) {
+	for channel := range c.ShardChannels {
+		g.ShardUnsubscribe(c, channel)
+	}
+
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	for channel := range c.Channels {
+		g.unsubscribe(c, channel)
+	}
+	for pattern := range c.Patterns {
+		delete(g.PatternToClient[pattern], c)
+		if len(g.PatternToClient[pattern]) == 0 {
+			delete(g.PatternToClient, pattern)
+		}
+	}
+}
+
+// Unsubscribe removes a connection from the subscribers of a channel
+func (g *Global) Unsubscribe(c *Connection, channel string) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	g.unsubscribe(c, channel)
+}
+
+// unsubscribe removes a connection from the subscribers of a channel and drops the channel
+// once nobody is subscribed to it, so short lived channel names don't pile up. g.Mu must be
+// held
+func (g *Global) unsubscribe(c *Connection, channel string) {
+	if cons, ok := g.ChannelToClient[channel]; ok {
+		delete(cons, c)
+		if len(cons) == 0 {
+			delete(g.ChannelToClient, channel)
+		}
+	}
+}
+
+// ChannelNames returns the channels with at least one subscriber that match reports as
+// matching
+func (g *Global) ChannelNames(match func(channel string) bool) []string {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	var names []string
+	for channel, cons := range g.ChannelToClient {
+		if len(cons) > 0 && match(channel) {
+			names = append(names, channel)
+		}
+	}
+	return names
+}
+
+// NumSub returns the number of connections subscribed to a channel, patterns aside
+func (g *Global) NumSub(channel string) int {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return len(g.ChannelToClient[channel])
+}
+
+// IsSubscribed reports whether c is subscribed to channel, it is safe to call from any
+// goroutine unlike reading c.Channels
+func (g *Global) IsSubscribed(c *Connection, channel string) bool {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	_, ok := g.ChannelToClient[channel][c]
+	return ok
+}
+
+// NumPat returns the number of distinct patterns clients are subscribed to
+func (g *Global) NumPat() int {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	return len(g.PatternToClient)
+}
+
+// GetPatterns returns the connections subscribed to each pattern that match reports as
+// matching, by pattern
+func (g *Global) GetPatterns(match func(pattern string) bool) map[string]map[*Connection]struct{} {
+	g.Mu.RLock()
+	defer g.Mu.RUnlock()
+	var matched map[string]map[*Connection]struct{}
+	for pattern, cons := range g.PatternToClient {
+		if len(cons) == 0 || !match(pattern) {
+			continue
+		}
+		if matched == nil {
+			matched = make(map[string]map[*Connection]struct{})
+		}
+		matched[pattern] = cons
+	}
+	return matched
+}
+
+// DeliverTo queues a message to the given connections, see DeliverMessage
+func DeliverTo(conns []*Connection, message []resp.Message) {
+	array := (&resp.Array{Val: message}).ToBytes()
+	push := (&resp.Push{Val: message}).ToBytes()
+
+	for _, conn := range conns {
+		payload := array
+		if conn.RESP3() {
+			payload = push
+		}
+		conn.Push(payload)
+	}
+}
+
+// DeliverMessage queues a message to every connection in cons, as a push to RESP3 clients
+// and as an array to RESP2 ones. Queueing doesn't wait for the clients to read, and messages
+// delivered one after the other reach each client in that order
+func (g *Global) DeliverMessage(cons map[*Connection]struct{}, message []resp.Message) {
 	if cons == nil {
 		return
 	}
@@ -24,11 +137,5 @@ func (g *Global) DeliverMessage(cons map[*Connection]struct{}, payload []byte) {
 	}
 	g.Mu.RUnlock()
 
-	// Write to connections without holding the global lock
-	for _, conn := range conns {
-		conn.Mu.Lock()
-		conn.W.Write(payload)
-		conn.W.Flush()
-		conn.Mu.Unlock()
-	}
+	DeliverTo(conns, message)
 }
@@ -0,0 +1,83 @@
+package pubsub
+
+import (
+	"sync"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+)
+
+// ShardSlot holds the shard channels that hash to one slot, see Global.ShardChannels
+type ShardSlot struct {
+	Mu       sync.RWMutex
+	Channels map[string]map[*Connection]struct{}
+}
+
+// shardSlot returns the slot of a shard channel, picked by the hash that spreads keys over
+// the shards of the keyspace
+func (g *Global) shardSlot(channel string) *ShardSlot {
+	return g.ShardChannels[db.ShardForKey(channel)]
+}
+
+// ShardSubscribe subscribes a connection to a shard channel
+func (g *Global) ShardSubscribe(c *Connection, channel string) {
+	slot := g.shardSlot(channel)
+	slot.Mu.Lock()
+	defer slot.Mu.Unlock()
+	c.ShardChannels[channel] = struct{}{}
+	if cons, ok := slot.Channels[channel]; ok {
+		cons[c] = struct{}{}
+	} else {
+		slot.Channels[channel] = map[*Connection]struct{}{c: {}}
+	}
+}
+
+// ShardUnsubscribe unsubscribes a connection from a shard channel, dropping the channel once
+// nobody is subscribed to it
+func (g *Global) ShardUnsubscribe(c *Connection, channel string) {
+	slot := g.shardSlot(channel)
+	slot.Mu.Lock()
+	defer slot.Mu.Unlock()
+	delete(c.ShardChannels, channel)
+	if cons, ok := slot.Channels[channel]; ok {
+		delete(cons, c)
+		if len(cons) == 0 {
+			delete(slot.Channels, channel)
+		}
+	}
+}
+
+// ShardSubscribers returns the connections subscribed to a shard channel
+func (g *Global) ShardSubscribers(channel string) []*Connection {
+	slot := g.shardSlot(channel)
+	slot.Mu.RLock()
+	defer slot.Mu.RUnlock()
+	cons := make([]*Connection, 0, len(slot.Channels[channel]))
+	for c := range slot.Channels[channel] {
+		cons = append(cons, c)
+	}
+	return cons
+}
+
+// ShardChannelNames returns the shard channels with at least one subscriber that match
+// reports as matching
+func (g *Global) ShardChannelNames(match func(channel string) bool) []string {
+	var names []string
+	for _, slot := range g.ShardChannels {
+		slot.Mu.RLock()
+		for channel := range slot.Channels {
+			if match(channel) {
+				names = append(names, channel)
+			}
+		}
+		slot.Mu.RUnlock()
+	}
+	return names
+}
+
+// ShardNumSub returns the number of connections subscribed to a shard channel
+func (g *Global) ShardNumSub(channel string) int {
+	slot := g.shardSlot(channel)
+	slot.Mu.RLock()
+	defer slot.Mu.RUnlock()
+	return len(slot.Channels[channel])
+}
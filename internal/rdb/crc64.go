@@ -0,0 +1,15 @@
+package rdb
+
+import "hash/crc64"
+
+// Redis checksums RDB files with the Jones CRC-64 variant (reflected, no initial or final XOR)
+// https://github.com/redis/redis/blob/unstable/src/crc64.c
+const jonesPoly = 0x95ac9329ac4bc9b5
+
+var jonesTable = crc64.MakeTable(jonesPoly)
+
+// crc64Jones extends the running checksum with p. The standard library inverts the value
+// on the way in and out, so the inversions are undone here to match Redis
+func crc64Jones(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, jonesTable, p)
+}
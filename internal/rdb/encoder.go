@@ -0,0 +1,78 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"math"
+)
+
+// encoder writes the RDB primitives while keeping a running checksum of everything written
+type encoder struct {
+	w   *bufio.Writer
+	crc uint64
+	err error
+}
+
+func (e *encoder) write(p []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc = crc64Jones(e.crc, p)
+	_, e.err = e.w.Write(p)
+}
+
+func (e *encoder) writeByte(b byte) {
+	e.write([]byte{b})
+}
+
+// writeBinaryDouble stores a float64 as 8 little endian bytes, the way ZSET_2 scores are saved
+func (e *encoder) writeBinaryDouble(f float64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+	e.write(b[:])
+}
+
+// writeLength uses the variable length encoding Redis uses for sizes and counts
+func (e *encoder) writeLength(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.writeByte(byte(n))
+	case n < 1<<14:
+		e.write([]byte{0x40 | byte(n>>8), byte(n)})
+	case n <= 0xFFFFFFFF:
+		buf := []byte{0x80, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(buf[1:], uint32(n))
+		e.write(buf)
+	default:
+		buf := make([]byte, 9)
+		buf[0] = 0x81
+		binary.BigEndian.PutUint64(buf[1:], n)
+		e.write(buf)
+	}
+}
+
+func (e *encoder) writeString(s string) {
+	e.writeLength(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.writeLength(uint64(len(b)))
+	e.write(b)
+}
+
+// writeMillis writes a millisecond unix timestamp as 8 little endian bytes
+func (e *encoder) writeMillis(ms int64) {
+	e.write(binary.LittleEndian.AppendUint64(nil, uint64(ms)))
+}
+
+// finish appends the checksum and flushes everything to the underlying writer
+func (e *encoder) finish() error {
+	if e.err != nil {
+		return e.err
+	}
+	if _, err := e.w.Write(binary.LittleEndian.AppendUint64(nil, e.crc)); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
@@ -0,0 +1,209 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+)
+
+// Listpacks are the compact serialized lists Redis uses inside quicklist and stream nodes
+// https://github.com/antirez/listpack/blob/master/listpack.md
+const (
+	lpHeaderSize = 6
+	lpEOF        = 0xFF
+)
+
+// listpackWriter builds a listpack one element at a time
+type listpackWriter struct {
+	buf   []byte
+	count int
+}
+
+func newListpackWriter() *listpackWriter {
+	return &listpackWriter{buf: make([]byte, lpHeaderSize, 256)}
+}
+
+// AppendString appends a string element, integers are stored as strings so that the
+// original representation round trips exactly
+func (lp *listpackWriter) AppendString(s string) {
+	start := len(lp.buf)
+	n := len(s)
+	switch {
+	case n < 64:
+		lp.buf = append(lp.buf, 0x80|byte(n))
+	case n < 4096:
+		lp.buf = append(lp.buf, 0xE0|byte(n>>8), byte(n))
+	default:
+		lp.buf = append(lp.buf, 0xF0)
+		lp.buf = binary.LittleEndian.AppendUint32(lp.buf, uint32(n))
+	}
+	lp.buf = append(lp.buf, s...)
+	lp.appendBacklen(len(lp.buf) - start)
+}
+
+// AppendInt appends an integer element using the smallest encoding that fits
+func (lp *listpackWriter) AppendInt(v int64) {
+	start := len(lp.buf)
+	switch {
+	case v >= 0 && v <= 127:
+		lp.buf = append(lp.buf, byte(v))
+	case v >= -4096 && v <= 4095:
+		u := uint64(v) & 0x1FFF
+		lp.buf = append(lp.buf, 0xC0|byte(u>>8), byte(u))
+	case v >= -32768 && v <= 32767:
+		lp.buf = append(lp.buf, 0xF1)
+		lp.buf = binary.LittleEndian.AppendUint16(lp.buf, uint16(v))
+	case v >= -8388608 && v <= 8388607:
+		u := uint32(v)
+		lp.buf = append(lp.buf, 0xF2, byte(u), byte(u>>8), byte(u>>16))
+	case v >= -2147483648 && v <= 2147483647:
+		lp.buf = append(lp.buf, 0xF3)
+		lp.buf = binary.LittleEndian.AppendUint32(lp.buf, uint32(v))
+	default:
+		lp.buf = append(lp.buf, 0xF4)
+		lp.buf = binary.LittleEndian.AppendUint64(lp.buf, uint64(v))
+	}
+	lp.appendBacklen(len(lp.buf) - start)
+}
+
+// appendBacklen stores the element size so the listpack can be walked backwards
+func (lp *listpackWriter) appendBacklen(l int) {
+	switch {
+	case l <= 127:
+		lp.buf = append(lp.buf, byte(l))
+	case l < 16383:
+		lp.buf = append(lp.buf, byte(l>>7), byte(l&127)|128)
+	case l < 2097151:
+		lp.buf = append(lp.buf, byte(l>>14), byte((l>>7)&127)|128, byte(l&127)|128)
+	case l < 268435455:
+		lp.buf = append(lp.buf, byte(l>>21), byte((l>>14)&127)|128, byte((l>>7)&127)|128, byte(l&127)|128)
+	default:
+		lp.buf = append(lp.buf, byte(l>>28), byte((l>>21)&127)|128, byte((l>>14)&127)|128, byte((l>>7)&127)|128, byte(l&127)|128)
+	}
+	lp.count++
+}
+
+// Bytes terminates the listpack and fills in the header
+func (lp *listpackWriter) Bytes() []byte {
+	lp.buf = append(lp.buf, lpEOF)
+	binary.LittleEndian.PutUint32(lp.buf[0:4], uint32(len(lp.buf)))
+	count := lp.count
+	if count > 65535 {
+		count = 65535 // the header count saturates, readers walk the elements instead
+	}
+	binary.LittleEndian.PutUint16(lp.buf[4:6], uint16(count))
+	return lp.buf
+}
+
+func backlenSize(l int) int {
+	switch {
+	case l <= 127:
+		return 1
+	case l < 16383:
+		return 2
+	case l < 2097151:
+		return 3
+	case l < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// signExtend interprets the low bits of v as a two's complement number
+func signExtend(v uint64, bits uint) int64 {
+	shift := 64 - bits
+	return int64(v<<shift) >> shift
+}
+
+// parseListpack decodes every element of a listpack as a string, integers are rendered
+// in decimal the same way Redis does when replying to clients
+func parseListpack(b []byte) ([]string, error) {
+	if len(b) < lpHeaderSize+1 {
+		return nil, fmt.Errorf("listpack: too short")
+	}
+	if int(binary.LittleEndian.Uint32(b[0:4])) != len(b) {
+		return nil, fmt.Errorf("listpack: header size mismatch")
+	}
+
+	var out []string
+	i := lpHeaderSize
+	for {
+		if i >= len(b) {
+			return nil, fmt.Errorf("listpack: missing terminator")
+		}
+		enc := b[i]
+		if enc == lpEOF {
+			return out, nil
+		}
+
+		start := i
+		var val string
+		switch {
+		case enc&0x80 == 0: // 7 bit unsigned integer
+			val = strconv.FormatUint(uint64(enc&0x7F), 10)
+			i++
+		case enc&0xC0 == 0x80: // 6 bit string length
+			n := int(enc & 0x3F)
+			if i+1+n > len(b) {
+				return nil, fmt.Errorf("listpack: truncated element")
+			}
+			val = string(b[i+1 : i+1+n])
+			i += 1 + n
+		case enc&0xE0 == 0xC0: // 13 bit signed integer
+			if i+2 > len(b) {
+				return nil, fmt.Errorf("listpack: truncated element")
+			}
+			u := uint64(enc&0x1F)<<8 | uint64(b[i+1])
+			val = strconv.FormatInt(signExtend(u, 13), 10)
+			i += 2
+		case enc&0xF0 == 0xE0: // 12 bit string length
+			if i+2 > len(b) {
+				return nil, fmt.Errorf("listpack: truncated element")
+			}
+			n := int(enc&0x0F)<<8 | int(b[i+1])
+			if i+2+n > len(b) {
+				return nil, fmt.Errorf("listpack: truncated element")
+			}
+			val = string(b[i+2 : i+2+n])
+			i += 2 + n
+		default:
+			var size int
+			switch enc {
+			case 0xF0:
+				size = 4
+			case 0xF1:
+				size = 2
+			case 0xF2:
+				size = 3
+			case 0xF3:
+				size = 4
+			case 0xF4:
+				size = 8
+			default:
+				return nil, fmt.Errorf("listpack: unknown encoding 0x%02x", enc)
+			}
+			if i+1+size > len(b) {
+				return nil, fmt.Errorf("listpack: truncated element")
+			}
+			var u uint64
+			for j := size - 1; j >= 0; j-- {
+				u = u<<8 | uint64(b[i+1+j])
+			}
+			if enc == 0xF0 { // 32 bit string length
+				n := int(u)
+				if i+5+n > len(b) {
+					return nil, fmt.Errorf("listpack: truncated element")
+				}
+				val = string(b[i+5 : i+5+n])
+				i += 5 + n
+			} else {
+				val = strconv.FormatInt(signExtend(u, uint(size*8)), 10)
+				i += 1 + size
+			}
+		}
+
+		i += backlenSize(i - start)
+		out = append(out, val)
+	}
+}
@@ -0,0 +1,55 @@
+package rdb
+
+import "fmt"
+
+// lzfDecompress expands an LZF compressed buffer, Redis compresses large strings with it
+// when rdbcompression is enabled
+// http://oldhome.schmorp.de/marc/liblzf.html
+func lzfDecompress(in []byte, outLen int) ([]byte, error) {
+	out := make([]byte, 0, outLen)
+	i := 0
+
+	for i < len(in) {
+		ctrl := int(in[i])
+		i++
+
+		// Literal run of ctrl+1 bytes
+		if ctrl < 32 {
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, fmt.Errorf("lzf: literal run past end of input")
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+
+		// Back reference: the top 3 bits hold the length, the rest the offset
+		length := ctrl >> 5
+		if length == 7 {
+			if i >= len(in) {
+				return nil, fmt.Errorf("lzf: truncated back reference")
+			}
+			length += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, fmt.Errorf("lzf: truncated back reference")
+		}
+		ref := len(out) - ((ctrl & 0x1f) << 8) - int(in[i]) - 1
+		i++
+		if ref < 0 {
+			return nil, fmt.Errorf("lzf: back reference before start of output")
+		}
+
+		// The source and destination may overlap, so copy byte by byte
+		for j := 0; j < length+2; j++ {
+			out = append(out, out[ref+j])
+		}
+	}
+
+	if len(out) != outLen {
+		return nil, fmt.Errorf("lzf: expected %d bytes, got %d", outLen, len(out))
+	}
+	return out, nil
+}
@@ -0,0 +1,930 @@
+package rdb
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/internal/db"
+	"github.com/codecrafters-io/redis-starter-go/internal/ds"
+	"github.com/codecrafters-io/redis-starter-go/internal/streams"
+)
+
+// File format reference: https://rdb.fnordig.de/file_format.html
+const (
+	version = 11
+
+	opAux          = 0xFA
+	opResizeDB     = 0xFB
+	opExpireTimeMs = 0xFC
+	opExpireTime   = 0xFD
+	opSelectDB     = 0xFE
+	opEOF          = 0xFF
+	opIdle         = 0xF8
+	opFreq         = 0xF9
+
+	typeString           = 0
+	typeList             = 1
+	typeSet              = 2
+	typeHash             = 4
+	typeZSet             = 3
+	typeZSet2            = 5
+	typeSetIntset        = 11
+	typeStreamListpacks  = 15
+	typeHashListpack     = 16
+	typeZSetListpack     = 17
+	typeListQuicklist2   = 18
+	typeSetListpack      = 20
+	typeStreamListpacks2 = 19
+	typeStreamListpacks3 = 21
+
+	quicklistNodePlain  = 1
+	quicklistNodePacked = 2
+
+	// Same defaults as list-max-listpack-size and stream-node-max-entries
+	listNodeMaxEntries   = 128
+	streamNodeMaxEntries = 100
+
+	streamItemFlagDeleted    = 1
+	streamItemFlagSameFields = 2
+)
+
+// Save writes the snapshot to path. The file is written next to the destination and
+// renamed over it once complete, so a crash mid-save never leaves a truncated dump behind
+func Save(path string, snap *db.Snapshot) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("temp-%d.rdb", os.Getpid()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f, snap); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func write(f *os.File, snap *db.Snapshot) error {
+	e := &encoder{w: bufio.NewWriter(f)}
+	e.write([]byte(fmt.Sprintf("REDIS%04d", version)))
+
+	aux := [][2]string{
+		{"redis-ver", "7.2.0"},
+		{"redis-bits", "64"},
+		{"ctime", strconv.FormatInt(time.Now().Unix(), 10)},
+		{"aof-base", "0"},
+	}
+	for _, kv := range aux {
+		e.writeByte(opAux)
+		e.writeString(kv[0])
+		e.writeString(kv[1])
+	}
+
+	expires := 0
+	for _, entry := range snap.Keys {
+		if !entry.ExpiresAt.IsZero() {
+			expires++
+		}
+	}
+
+	e.writeByte(opSelectDB)
+	e.writeLength(0)
+	e.writeByte(opResizeDB)
+	e.writeLength(uint64(len(snap.Keys)))
+	e.writeLength(uint64(expires))
+
+	for key, entry := range snap.Keys {
+		if !entry.ExpiresAt.IsZero() {
+			e.writeByte(opExpireTimeMs)
+			e.writeMillis(entry.ExpiresAt.UnixMilli())
+		}
+		switch entry.Type {
+		case db.TypeString:
+			e.writeByte(typeString)
+			e.writeString(key)
+			e.writeBytes(entry.Value)
+		case db.TypeList:
+			e.writeByte(typeListQuicklist2)
+			e.writeString(key)
+			writeList(e, entry.List)
+		case db.TypeSet:
+			writeSet(e, key, entry.Set)
+		case db.TypeHash:
+			e.writeByte(typeHash)
+			e.writeString(key)
+			writeHash(e, entry.Hash)
+		case db.TypeZSet:
+			e.writeByte(typeZSet2)
+			e.writeString(key)
+			writeZSet(e, entry.ZSet)
+		case db.TypeStream:
+			e.writeByte(typeStreamListpacks3)
+			e.writeString(key)
+			writeStream(e, entry.Stream)
+		}
+	}
+
+	e.writeByte(opEOF)
+	return e.finish()
+}
+
+// writeList stores a list as a quicklist of packed listpack nodes
+func writeList(e *encoder, items []string) {
+	nodes := (len(items) + listNodeMaxEntries - 1) / listNodeMaxEntries
+	e.writeLength(uint64(nodes))
+	for start := 0; start < len(items); start += listNodeMaxEntries {
+		end := min(start+listNodeMaxEntries, len(items))
+		lp := newListpackWriter()
+		for _, item := range items[start:end] {
+			lp.AppendString(item)
+		}
+		e.writeLength(quicklistNodePacked)
+		e.writeBytes(lp.Bytes())
+	}
+}
+
+// writeSet stores an intset encoded set as its serialized intset and any other set as its
+// number of members followed by every member
+func writeSet(e *encoder, key string, set *db.SetEntry) {
+	if ints := set.IntSet(); ints != nil {
+		e.writeByte(typeSetIntset)
+		e.writeString(key)
+		e.writeBytes(ints.Bytes())
+		return
+	}
+
+	e.writeByte(typeSet)
+	e.writeString(key)
+	e.writeLength(uint64(set.Len()))
+	for member := range set.Members() {
+		e.writeString(member)
+	}
+}
+
+// writeHash stores a hash as its number of fields followed by every field and value
+func writeHash(e *encoder, hash map[string]string) {
+	e.writeLength(uint64(len(hash)))
+	for field, value := range hash {
+		e.writeString(field)
+		e.writeString(value)
+	}
+}
+
+// writeZSet stores a sorted set as its number of members followed by every member and its
+// binary score. Like Redis the highest scores come first, so a loader inserting every member
+// at the head of a list builds it in order
+func writeZSet(e *encoder, entries []ds.ZEntry) {
+	e.writeLength(uint64(len(entries)))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e.writeString(entries[i].Member)
+		e.writeBinaryDouble(entries[i].Score)
+	}
+}
+
+// writeStream stores a stream the way Redis lays it out in memory: a radix tree keyed by
+// the master ID of each node, where every node is a listpack of delta encoded entries
+func writeStream(e *encoder, stream db.StreamSnapshot) {
+	entries := stream.Entries
+	nodes := (len(entries) + streamNodeMaxEntries - 1) / streamNodeMaxEntries
+	e.writeLength(uint64(nodes))
+
+	for start := 0; start < len(entries); start += streamNodeMaxEntries {
+		end := min(start+streamNodeMaxEntries, len(entries))
+		chunk := entries[start:end]
+		master := chunk[0].ID
+		masterFields := fieldNames(chunk[0].Fields)
+
+		lp := newListpackWriter()
+		lp.AppendInt(int64(len(chunk))) // valid entries
+		lp.AppendInt(0)                 // deleted entries
+		lp.AppendInt(int64(len(masterFields)))
+		for _, field := range masterFields {
+			lp.AppendString(field)
+		}
+		lp.AppendInt(0) // master entry terminator
+
+		for _, entry := range chunk {
+			fields := fieldNames(entry.Fields)
+			sameFields := slices.Equal(fields, masterFields)
+
+			flags := int64(0)
+			if sameFields {
+				flags |= streamItemFlagSameFields
+			}
+			lp.AppendInt(flags)
+			lp.AppendInt(int64(entry.ID.Ms - master.Ms))
+			lp.AppendInt(int64(entry.ID.Seq - master.Seq))
+			if sameFields {
+				for i := 1; i < len(entry.Fields); i += 2 {
+					lp.AppendString(entry.Fields[i])
+				}
+				lp.AppendInt(int64(len(fields) + 3))
+			} else {
+				lp.AppendInt(int64(len(fields)))
+				for _, s := range entry.Fields {
+					lp.AppendString(s)
+				}
+				lp.AppendInt(int64(2*len(fields) + 4))
+			}
+		}
+
+		e.writeBytes(streamIDBytes(master.Ms, master.Seq))
+		e.writeBytes(lp.Bytes())
+	}
+
+	var first streams.StreamID
+	if len(entries) > 0 {
+		first = *entries[0].ID
+	}
+	e.writeLength(uint64(len(entries)))
+	e.writeLength(stream.LastID.Ms)
+	e.writeLength(stream.LastID.Seq)
+	e.writeLength(first.Ms)
+	e.writeLength(first.Seq)
+	e.writeLength(stream.MaxDeletedID.Ms)
+	e.writeLength(stream.MaxDeletedID.Seq)
+	e.writeLength(stream.EntriesAdded)
+
+	e.writeLength(uint64(len(stream.Groups)))
+	for _, g := range stream.Groups {
+		writeConsumerGroup(e, g)
+	}
+}
+
+// activeMillis is the active time of a consumer as Redis stores it, -1 if it was never active
+func activeMillis(c *streams.Consumer) int64 {
+	if c.ActiveTime.IsZero() {
+		return -1
+	}
+	return c.ActiveTime.UnixMilli()
+}
+
+// writeConsumerGroup stores a group the way Redis does: its last delivered ID, the group PEL
+// with delivery times and counts, then every consumer with the IDs of the entries it owns
+func writeConsumerGroup(e *encoder, g *streams.ConsumerGroup) {
+	e.writeString(g.Name)
+	e.writeLength(g.LastID.Ms)
+	e.writeLength(g.LastID.Seq)
+	// An unknown count is the -1 Redis stores as an unsigned length
+	e.writeLength(uint64(g.EntriesRead))
+
+	pel := g.PendingFrom(streams.StreamID{})
+	e.writeLength(uint64(len(pel)))
+	for _, pe := range pel {
+		e.write(streamIDBytes(pe.ID.Ms, pe.ID.Seq))
+		e.writeMillis(pe.DeliveryTime.UnixMilli())
+		e.writeLength(uint64(pe.DeliveryCount))
+	}
+
+	names := make([]string, 0, len(g.Consumers))
+	for name := range g.Consumers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	e.writeLength(uint64(len(names)))
+	for _, name := range names {
+		c := g.Consumers[name]
+		e.writeString(name)
+		e.writeMillis(c.SeenTime.UnixMilli())
+		e.writeMillis(activeMillis(c))
+		e.writeLength(uint64(c.Pending))
+		for _, pe := range pel {
+			if pe.Consumer == c {
+				e.write(streamIDBytes(pe.ID.Ms, pe.ID.Seq))
+			}
+		}
+	}
+}
+
+// fieldNames returns the field names of an entry, in order
+func fieldNames(fields []string) []string {
+	names := make([]string, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		names = append(names, fields[i])
+	}
+	return names
+}
+
+// Load reads the dump at path into the keyspace, a missing file simply means there is
+// nothing to load
+func Load(path string) error {
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keys, err := read(buf)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	log.Printf("RDB: Loaded %d keys from %s", keys, path)
+	return nil
+}
+
+func read(buf []byte) (int, error) {
+	d := &decoder{buf: buf}
+
+	magic, err := d.readN(9)
+	if err != nil || string(magic[:5]) != "REDIS" {
+		return 0, fmt.Errorf("not an RDB file")
+	}
+	ver, err := strconv.Atoi(string(magic[5:]))
+	if err != nil || ver < 1 || ver > 12 {
+		return 0, fmt.Errorf("unsupported RDB version %q", magic[5:])
+	}
+
+	loaded := 0
+	dbIndex := 0
+	var expiresAt time.Time
+
+	for {
+		op, err := d.readByte()
+		if err != nil {
+			return loaded, err
+		}
+
+		switch op {
+		case opAux:
+			if _, err := d.readString(); err != nil {
+				return loaded, err
+			}
+			if _, err := d.readString(); err != nil {
+				return loaded, err
+			}
+			continue
+		case opSelectDB:
+			if dbIndex, err = d.readLen(); err != nil {
+				return loaded, err
+			}
+			continue
+		case opResizeDB:
+			if _, err := d.readUint(); err != nil {
+				return loaded, err
+			}
+			if _, err := d.readUint(); err != nil {
+				return loaded, err
+			}
+			continue
+		case opExpireTimeMs:
+			ms, err := d.readMillis()
+			if err != nil {
+				return loaded, err
+			}
+			expiresAt = time.UnixMilli(ms)
+			continue
+		case opExpireTime:
+			b, err := d.readN(4)
+			if err != nil {
+				return loaded, err
+			}
+			secs := int64(b[0]) | int64(b[1])<<8 | int64(b[2])<<16 | int64(b[3])<<24
+			expiresAt = time.Unix(secs, 0)
+			continue
+		case opFreq:
+			if _, err := d.readByte(); err != nil {
+				return loaded, err
+			}
+			continue
+		case opIdle:
+			if _, err := d.readUint(); err != nil {
+				return loaded, err
+			}
+			continue
+		case opEOF:
+			return loaded, verifyChecksum(buf, d.pos, ver)
+		}
+
+		key, err := d.readString()
+		if err != nil {
+			return loaded, err
+		}
+		restore, err := readObject(d, op)
+		if err != nil {
+			return loaded, fmt.Errorf("key %q: %w", key, err)
+		}
+
+		// Only database 0 exists in this server
+		if dbIndex == 0 {
+			restore(string(key), expiresAt)
+			loaded++
+		}
+		expiresAt = time.Time{}
+	}
+}
+
+func verifyChecksum(buf []byte, pos int, ver int) error {
+	// Checksums were introduced in version 5, and a zero checksum means it was disabled
+	if ver < 5 || pos+8 > len(buf) {
+		return nil
+	}
+	stored := uint64(buf[pos]) | uint64(buf[pos+1])<<8 | uint64(buf[pos+2])<<16 | uint64(buf[pos+3])<<24 |
+		uint64(buf[pos+4])<<32 | uint64(buf[pos+5])<<40 | uint64(buf[pos+6])<<48 | uint64(buf[pos+7])<<56
+	if stored != 0 && stored != crc64Jones(0, buf[:pos]) {
+		return fmt.Errorf("checksum mismatch")
+	}
+	return nil
+}
+
+// readObject decodes a value of the given type and returns a function that installs it
+// into the keyspace
+func readObject(d *decoder, typ byte) (func(key string, expiresAt time.Time), error) {
+	switch typ {
+	case typeString:
+		val, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreString(key, val, expiresAt)
+		}, nil
+	case typeList, typeListQuicklist2:
+		items, err := readList(d, typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreList(key, items, expiresAt)
+		}, nil
+	case typeSet, typeSetIntset, typeSetListpack:
+		members, err := readSet(d, typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreSet(key, members, expiresAt)
+		}, nil
+	case typeHash, typeHashListpack:
+		fields, err := readHash(d, typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreHash(key, fields, expiresAt)
+		}, nil
+	case typeZSet, typeZSet2, typeZSetListpack:
+		entries, err := readZSet(d, typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreZSet(key, entries, expiresAt)
+		}, nil
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		stream, err := readStream(d, typ)
+		if err != nil {
+			return nil, err
+		}
+		return func(key string, expiresAt time.Time) {
+			db.RestoreStream(key, stream, expiresAt)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %d", typ)
+}
+
+func readList(d *decoder, typ byte) ([]string, error) {
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []string
+	for range n {
+		if typ == typeList {
+			item, err := d.readString()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, string(item))
+			continue
+		}
+
+		container, err := d.readUint()
+		if err != nil {
+			return nil, err
+		}
+		node, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if container == quicklistNodePlain {
+			items = append(items, string(node))
+			continue
+		}
+		elements, err := parseListpack(node)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, elements...)
+	}
+	return items, nil
+}
+
+// readSet decodes a set stored as a list of members, an intset or a listpack
+func readSet(d *decoder, typ byte) ([]string, error) {
+	if typ == typeSetIntset || typ == typeSetListpack {
+		blob, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if typ == typeSetListpack {
+			return parseListpack(blob)
+		}
+		ints, err := ds.IntSetFromBytes(blob)
+		if err != nil {
+			return nil, err
+		}
+		members := make([]string, 0, ints.Len())
+		for v := range ints.All() {
+			members = append(members, strconv.FormatInt(v, 10))
+		}
+		return members, nil
+	}
+
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	for range n {
+		member, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, string(member))
+	}
+	return members, nil
+}
+
+// readHash decodes a hash stored either as a list of field-value strings or as a single
+// listpack alternating fields and values
+func readHash(d *decoder, typ byte) (map[string]string, error) {
+	if typ == typeHashListpack {
+		lp, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		elements, err := parseListpack(lp)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements)%2 != 0 {
+			return nil, fmt.Errorf("hash listpack has an odd number of elements")
+		}
+		hash := make(map[string]string, len(elements)/2)
+		for i := 0; i < len(elements); i += 2 {
+			hash[elements[i]] = elements[i+1]
+		}
+		return hash, nil
+	}
+
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	hash := make(map[string]string)
+	for range n {
+		field, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		hash[string(field)] = string(value)
+	}
+	return hash, nil
+}
+
+// readZSet decodes a sorted set stored as members followed by a string or binary score, or
+// as a single listpack alternating members and scores
+func readZSet(d *decoder, typ byte) ([]ds.ZEntry, error) {
+	if typ == typeZSetListpack {
+		lp, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		elements, err := parseListpack(lp)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements)%2 != 0 {
+			return nil, fmt.Errorf("sorted set listpack has an odd number of elements")
+		}
+		entries := make([]ds.ZEntry, 0, len(elements)/2)
+		for i := 0; i < len(elements); i += 2 {
+			score, err := strconv.ParseFloat(elements[i+1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid sorted set score %q", elements[i+1])
+			}
+			entries = append(entries, ds.ZEntry{Member: elements[i], Score: score})
+		}
+		return entries, nil
+	}
+
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	var entries []ds.ZEntry
+	for range n {
+		member, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if typ == typeZSet2 {
+			score, err = d.readBinaryDouble()
+		} else {
+			score, err = d.readDouble()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(score) {
+			return nil, fmt.Errorf("sorted set member %q has a NaN score", member)
+		}
+		entries = append(entries, ds.ZEntry{Member: string(member), Score: score})
+	}
+	return entries, nil
+}
+
+func readStream(d *decoder, typ byte) (db.StreamSnapshot, error) {
+	var stream db.StreamSnapshot
+	nodes, err := d.readLen()
+	if err != nil {
+		return stream, err
+	}
+
+	for range nodes {
+		key, err := d.readString()
+		if err != nil {
+			return stream, err
+		}
+		if len(key) != 16 {
+			return stream, fmt.Errorf("stream node key has %d bytes, expected 16", len(key))
+		}
+		lp, err := d.readString()
+		if err != nil {
+			return stream, err
+		}
+		nodeEntries, err := parseStreamNode(key, lp)
+		if err != nil {
+			return stream, err
+		}
+		stream.Entries = append(stream.Entries, nodeEntries...)
+	}
+
+	// length, last ID and, for the newer encodings, first ID, max deleted ID and entries
+	// added. The length and first ID follow from the entries
+	var metadata [8]uint64
+	n := 3
+	if typ >= typeStreamListpacks2 {
+		n = len(metadata)
+	}
+	for i := range n {
+		if metadata[i], err = d.readUint(); err != nil {
+			return stream, err
+		}
+	}
+	stream.LastID = streams.StreamID{Ms: metadata[1], Seq: metadata[2]}
+	stream.MaxDeletedID = streams.StreamID{Ms: metadata[5], Seq: metadata[6]}
+	stream.EntriesAdded = metadata[7]
+	if typ < typeStreamListpacks2 {
+		stream.EntriesAdded = metadata[0]
+	}
+
+	count, err := d.readLen()
+	if err != nil {
+		return stream, err
+	}
+	stream.Groups = make([]*streams.ConsumerGroup, 0, count)
+	for range count {
+		g, err := readConsumerGroup(d, typ)
+		if err != nil {
+			return stream, err
+		}
+		stream.Groups = append(stream.Groups, g)
+	}
+	return stream, nil
+}
+
+// readConsumerGroup reads a group stored by writeConsumerGroup. The group PEL comes first,
+// the consumers that follow say which of its entries they own
+func readConsumerGroup(d *decoder, typ byte) (*streams.ConsumerGroup, error) {
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	var lastID streams.StreamID
+	if lastID.Ms, err = d.readUint(); err != nil {
+		return nil, err
+	}
+	if lastID.Seq, err = d.readUint(); err != nil {
+		return nil, err
+	}
+	g := streams.NewConsumerGroup(string(name), lastID)
+	if typ >= typeStreamListpacks2 {
+		read, err := d.readUint()
+		if err != nil {
+			return nil, err
+		}
+		g.EntriesRead = int64(read)
+	}
+
+	n, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	pel := make([]*streams.PendingEntry, 0, n)
+	byID := make(map[streams.StreamID]*streams.PendingEntry, n)
+	for range n {
+		raw, err := d.readN(16)
+		if err != nil {
+			return nil, err
+		}
+		deliveryTime, err := d.readMillis()
+		if err != nil {
+			return nil, err
+		}
+		deliveryCount, err := d.readUint()
+		if err != nil {
+			return nil, err
+		}
+		ms, seq := streamIDFromBytes(raw)
+		pe := &streams.PendingEntry{
+			ID:            streams.StreamID{Ms: ms, Seq: seq},
+			DeliveryTime:  time.UnixMilli(deliveryTime),
+			DeliveryCount: int64(deliveryCount),
+		}
+		pel = append(pel, pe)
+		byID[pe.ID] = pe
+	}
+
+	consumers, err := d.readLen()
+	if err != nil {
+		return nil, err
+	}
+	for range consumers {
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		seenTime, err := d.readMillis()
+		if err != nil {
+			return nil, err
+		}
+		// Older encodings don't have the active time, the seen time is the best estimate
+		activeTime := seenTime
+		if typ >= typeStreamListpacks3 {
+			if activeTime, err = d.readMillis(); err != nil {
+				return nil, err
+			}
+		}
+		c, _ := g.Consumer(string(name), true, time.UnixMilli(seenTime))
+		if activeTime >= 0 {
+			c.ActiveTime = time.UnixMilli(activeTime)
+		}
+		owned, err := d.readLen()
+		if err != nil {
+			return nil, err
+		}
+		for range owned {
+			raw, err := d.readN(16)
+			if err != nil {
+				return nil, err
+			}
+			ms, seq := streamIDFromBytes(raw)
+			pe, ok := byID[streams.StreamID{Ms: ms, Seq: seq}]
+			if !ok {
+				return nil, fmt.Errorf("consumer %s owns %d-%d which isn't in the group PEL", name, ms, seq)
+			}
+			pe.Consumer = c
+		}
+	}
+	for _, pe := range pel {
+		if pe.Consumer == nil {
+			return nil, fmt.Errorf("pending entry %s of group %s has no consumer", pe.ID.String(), name)
+		}
+	}
+	g.RestorePending(pel)
+	return g, nil
+}
+
+// parseStreamNode expands a stream listpack node back into entries, see writeStream for the layout
+func parseStreamNode(key []byte, lp []byte) ([]*streams.StreamEntry, error) {
+	elements, err := parseListpack(lp)
+	if err != nil {
+		return nil, err
+	}
+	c := &listpackCursor{elements: elements}
+
+	masterMs, masterSeq := streamIDFromBytes(key)
+	count := c.nextInt()
+	deleted := c.nextInt()
+	numFields := c.nextInt()
+	masterFields := make([]string, 0, numFields)
+	for range numFields {
+		masterFields = append(masterFields, c.next())
+	}
+	c.next() // master entry terminator
+
+	var entries []*streams.StreamEntry
+	for range count + deleted {
+		flags := c.nextInt()
+		id := &streams.StreamID{
+			Ms:  masterMs + uint64(c.nextInt()),
+			Seq: masterSeq + uint64(c.nextInt()),
+		}
+		var fields []string
+		if flags&streamItemFlagSameFields != 0 {
+			fields = make([]string, 0, 2*len(masterFields))
+			for _, field := range masterFields {
+				fields = append(fields, field, c.next())
+			}
+		} else {
+			n := c.nextInt()
+			fields = make([]string, 0, 2*n)
+			for range n {
+				fields = append(fields, c.next(), c.next())
+			}
+		}
+		c.next() // lp-count, only needed to walk the node backwards
+
+		if flags&streamItemFlagDeleted == 0 {
+			entries = append(entries, &streams.StreamEntry{ID: id, Fields: fields})
+		}
+	}
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	return entries, nil
+}
+
+// listpackCursor walks decoded listpack elements, remembering the first error
+type listpackCursor struct {
+	elements []string
+	pos      int
+	err      error
+}
+
+func (c *listpackCursor) next() string {
+	if c.pos >= len(c.elements) {
+		if c.err == nil {
+			c.err = fmt.Errorf("stream node ended unexpectedly")
+		}
+		return ""
+	}
+	c.pos++
+	return c.elements[c.pos-1]
+}
+
+func (c *listpackCursor) nextInt() int64 {
+	s := c.next()
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("stream node: expected an integer, got %q", s)
+	}
+	return n
+}
+
+func streamIDBytes(ms, seq uint64) []byte {
+	b := make([]byte, 16)
+	for i := range 8 {
+		b[i] = byte(ms >> (56 - 8*i))
+		b[8+i] = byte(seq >> (56 - 8*i))
+	}
+	return b
+}
+
+func streamIDFromBytes(b []byte) (ms, seq uint64) {
+	for i := range 8 {
+		ms = ms<<8 | uint64(b[i])
+		seq = seq<<8 | uint64(b[8+i])
+	}
+	return ms, seq
+}
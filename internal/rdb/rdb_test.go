@@ -0,0 +1,61 @@
+package rdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCRC64Jones(t *testing.T) {
+	// Check value from the test in Redis' crc64.c
+	got := crc64Jones(0, []byte("123456789"))
+	if got != 0xe9c6d914c4b8d9ca {
+		t.Errorf("crc64Jones() = %#x, want %#x", got, uint64(0xe9c6d914c4b8d9ca))
+	}
+
+	// The checksum can be computed incrementally
+	split := crc64Jones(crc64Jones(0, []byte("1234")), []byte("56789"))
+	if split != got {
+		t.Errorf("incremental crc64Jones() = %#x, want %#x", split, got)
+	}
+}
+
+func TestListpackRoundTrip(t *testing.T) {
+	long := string(make([]byte, 5000))
+	lp := newListpackWriter()
+	lp.AppendString("")
+	lp.AppendString("hello")
+	lp.AppendString(string(make([]byte, 100)))
+	lp.AppendString(long)
+	for _, v := range []int64{0, 127, 128, -1, 4095, -4096, 32767, -32768, 8388607, -8388608, 2147483647, -2147483648, 1 << 40, -1 << 40} {
+		lp.AppendInt(v)
+	}
+
+	got, err := parseListpack(lp.Bytes())
+	if err != nil {
+		t.Fatalf("parseListpack() error = %v", err)
+	}
+	want := []string{
+		"", "hello", string(make([]byte, 100)), long,
+		"0", "127", "128", "-1", "4095", "-4096", "32767", "-32768", "8388607", "-8388608",
+		"2147483647", "-2147483648", "1099511627776", "-1099511627776",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseListpack() = %q, want %q", got, want)
+	}
+}
+
+func TestLZFDecompress(t *testing.T) {
+	// Thirteen 'a's: a one byte literal run followed by a 12 byte back reference to it
+	compressed := []byte{0x00, 'a', 0xe0, 0x03, 0x00}
+	got, err := lzfDecompress(compressed, 13)
+	if err != nil {
+		t.Fatalf("lzfDecompress() error = %v", err)
+	}
+	if string(got) != "aaaaaaaaaaaaa" {
+		t.Errorf("lzfDecompress() = %q", got)
+	}
+
+	if _, err := lzfDecompress([]byte{0xe0, 0x04, 0x00}, 13); err == nil {
+		t.Error("lzfDecompress() should reject references before the start of the output")
+	}
+}
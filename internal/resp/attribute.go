@@ -0,0 +1,15 @@
+package resp
+
+import "strconv"
+
+// Attribute is the RESP3 type carrying auxiliary data about a reply, like a map sent just
+// before it. Reply is the reply the attributes describe
+type Attribute struct {
+	Val   []MapEntry
+	Reply Message
+}
+
+func (a *Attribute) ToBytes() []byte {
+	res := appendEntries([]byte("|"+strconv.Itoa(len(a.Val))+"\r\n"), a.Val)
+	return append(res, a.Reply.ToBytes()...)
+}
@@ -0,0 +1,12 @@
+package resp
+
+import "math/big"
+
+// BigNumber is the RESP3 type for integers outside of the signed 64 bit range
+type BigNumber struct {
+	Val *big.Int
+}
+
+func (b *BigNumber) ToBytes() []byte {
+	return []byte("(" + b.Val.String() + "\r\n")
+}
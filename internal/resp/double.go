@@ -0,0 +1,31 @@
+package resp
+
+import (
+	"math"
+	"strconv"
+)
+
+// Double is the RESP3 floating point type
+type Double struct {
+	Val float64
+}
+
+func (d *Double) ToBytes() []byte {
+	return []byte("," + FormatDouble(d.Val) + "\r\n")
+}
+
+// FormatDouble renders f the way Redis replies with it: the shortest representation that
+// reads back as the same number, without an exponent for everyday magnitudes
+func FormatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == 0 || (math.Abs(f) >= 1e-6 && math.Abs(f) < 1e21):
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,26 @@
+package resp
+
+import "strconv"
+
+// MapEntry is one key value pair of a Map or an Attribute
+type MapEntry struct {
+	Key Message
+	Val Message
+}
+
+// Map is the RESP3 map type, entries keep the order they were added in
+type Map struct {
+	Val []MapEntry
+}
+
+func (m *Map) ToBytes() []byte {
+	return appendEntries([]byte("%"+strconv.Itoa(len(m.Val))+"\r\n"), m.Val)
+}
+
+func appendEntries(res []byte, entries []MapEntry) []byte {
+	for _, e := range entries {
+		res = append(res, e.Key.ToBytes()...)
+		res = append(res, e.Val.ToBytes()...)
+	}
+	return res
+}
@@ -0,0 +1,13 @@
+package resp
+
+import "strconv"
+
+// Push is the RESP3 out of band data type, used for pub/sub messages. Clients tell pushes
+// apart from the replies to their commands by the type alone
+type Push struct {
+	Val []Message
+}
+
+func (p *Push) ToBytes() []byte {
+	return appendElements([]byte(">"+strconv.Itoa(len(p.Val))+"\r\n"), p.Val)
+}
@@ -0,0 +1,19 @@
+package resp
+
+import "strconv"
+
+// Set is the RESP3 set type, an unordered collection of unique elements
+type Set struct {
+	Val []Message
+}
+
+func (s *Set) ToBytes() []byte {
+	return appendElements([]byte("~"+strconv.Itoa(len(s.Val))+"\r\n"), s.Val)
+}
+
+func appendElements(res []byte, elements []Message) []byte {
+	for _, el := range elements {
+		res = append(res, el.ToBytes()...)
+	}
+	return res
+}
@@ -0,0 +1,17 @@
+package resp
+
+import "strconv"
+
+// VerbatimString is the RESP3 type for text meant to be shown as is, Format is the three
+// letter encoding of Str: "txt" for plain text or "mkd" for markdown
+type VerbatimString struct {
+	Format string
+	Str    []byte
+}
+
+func (v *VerbatimString) ToBytes() []byte {
+	res := []byte("=" + strconv.Itoa(len(v.Format)+1+len(v.Str)) + "\r\n" + v.Format + ":")
+	res = append(res, v.Str...)
+	res = append(res, "\r\n"...)
+	return res
+}
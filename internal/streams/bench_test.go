@@ -0,0 +1,254 @@
+package streams
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// legacyNode and legacyRax are the layout streams used before nodes: one entry per key of
+// the trie, keys being the ID as a zero-padded decimal string and children being a map.
+// They are kept here only for the benchmarks to compare against
+type legacyNode struct {
+	isEnd    bool
+	children map[byte]*legacyNode
+	prefix   []byte
+	entry    *StreamEntry
+}
+
+type legacyRax struct {
+	root *legacyNode
+}
+
+type legacyEdge struct {
+	parent *legacyNode
+	edge   byte
+}
+
+func legacyKey(id *StreamID) []byte {
+	return []byte(fmt.Sprintf("%020d-%020d", id.Ms, id.Seq))
+}
+
+func newLegacyRax() *legacyRax {
+	return &legacyRax{root: &legacyNode{children: make(map[byte]*legacyNode)}}
+}
+
+func (r *legacyRax) insert(s []byte, entry *StreamEntry) {
+	node := r.root
+	i := 0
+	for i < len(s) {
+		child, ok := node.children[s[i]]
+		if !ok {
+			node.children[s[i]] = &legacyNode{isEnd: true, prefix: s[i:], entry: entry}
+			return
+		}
+		node = child
+		prefixLen := MaxCommonStringLen(s[i:], node.prefix)
+		i += prefixLen
+		if prefixLen < len(node.prefix) {
+			old := &legacyNode{isEnd: node.isEnd, children: node.children, prefix: node.prefix[prefixLen:], entry: node.entry}
+			node.prefix = node.prefix[:prefixLen]
+			node.children = map[byte]*legacyNode{old.prefix[0]: old}
+			node.isEnd, node.entry = false, nil
+			if i == len(s) {
+				node.isEnd, node.entry = true, entry
+			} else {
+				node.children[s[i]] = &legacyNode{isEnd: true, prefix: s[i:], entry: entry}
+			}
+			return
+		}
+	}
+}
+
+func legacyLeftmost(n *legacyNode) *legacyNode {
+	for n != nil && !n.isEnd {
+		var next *legacyNode
+		minKey := 256
+		for k, c := range n.children {
+			if int(k) < minKey {
+				minKey, next = int(k), c
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// next returns the smallest child of parent whose edge is greater than edge
+func legacyNext(parent *legacyNode, edge int) *legacyNode {
+	var next *legacyNode
+	minKey := 256
+	for k, c := range parent.children {
+		if int(k) > edge && int(k) < minKey {
+			minKey, next = int(k), c
+		}
+	}
+	return next
+}
+
+func legacyAfter(stack []legacyEdge) *legacyNode {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if next := legacyNext(stack[i].parent, int(stack[i].edge)); next != nil {
+			return legacyLeftmost(next)
+		}
+	}
+	return nil
+}
+
+func (r *legacyRax) seekGE(s []byte) *legacyNode {
+	var stack []legacyEdge
+	node := r.root
+	i := 0
+	for i < len(s) {
+		child, ok := node.children[s[i]]
+		if !ok {
+			if next := legacyNext(node, int(s[i])); next != nil {
+				return legacyLeftmost(next)
+			}
+			return legacyAfter(stack)
+		}
+		stack = append(stack, legacyEdge{parent: node, edge: s[i]})
+		prefixLen := MaxCommonStringLen(s[i:], child.prefix)
+		if prefixLen < len(child.prefix) {
+			if child.prefix[prefixLen] > s[i+prefixLen] {
+				return legacyLeftmost(child)
+			}
+			return legacyAfter(stack)
+		}
+		node = child
+		i += prefixLen
+	}
+	if node.isEnd {
+		return node
+	}
+	return legacyLeftmost(node)
+}
+
+func (r *legacyRax) successor(s []byte) *legacyNode {
+	var stack []legacyEdge
+	node := r.root
+	for i := 0; i < len(s); {
+		stack = append(stack, legacyEdge{parent: node, edge: s[i]})
+		node = node.children[s[i]]
+		i += len(node.prefix)
+	}
+	if len(node.children) > 0 {
+		return legacyLeftmost(legacyNext(node, -1))
+	}
+	return legacyAfter(stack)
+}
+
+func (r *legacyRax) rangeN(start *StreamID, count int) []*StreamEntry {
+	result := make([]*StreamEntry, 0, count)
+	for node := r.seekGE(legacyKey(start)); node != nil && len(result) < count; node = r.successor(legacyKey(node.entry.ID)) {
+		result = append(result, node.entry)
+	}
+	return result
+}
+
+// benchEntry returns the i'th entry of the benchmarks: IDs a few per millisecond and the
+// same three fields every time, like most streams
+func benchEntry(i int) *StreamEntry {
+	return &StreamEntry{
+		ID:     &StreamID{Ms: 1700000000000 + uint64(i/4), Seq: uint64(i % 4)},
+		Fields: []string{"sensor", "s" + strconv.Itoa(i%16), "temperature", strconv.Itoa(20 + i%10), "unit", "celsius"},
+	}
+}
+
+// heapBytes returns the bytes in use on the heap after a collection
+func heapBytes() uint64 {
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// BenchmarkStreamMemory reports the heap used per entry of a stream of 100000 entries. The
+// entries are built up front, as XADD builds them from its arguments
+func BenchmarkStreamMemory(b *testing.B) {
+	const n = 100000
+	entries := make([]*StreamEntry, n)
+	for i := range entries {
+		entries[i] = benchEntry(i)
+	}
+
+	b.Run("nodes", func(b *testing.B) {
+		for range b.N {
+			before := heapBytes()
+			s := NewEmptyStream()
+			for _, e := range entries {
+				s.Insert(e)
+			}
+			b.ReportMetric(float64(heapBytes()-before)/n, "B/entry")
+			runtime.KeepAlive(s)
+		}
+	})
+	b.Run("legacy", func(b *testing.B) {
+		for range b.N {
+			before := heapBytes()
+			r := newLegacyRax()
+			for _, e := range entries {
+				// The legacy layout keeps the entry itself, it has to be counted
+				kept := &StreamEntry{ID: &StreamID{Ms: e.ID.Ms, Seq: e.ID.Seq}, Fields: make([]string, len(e.Fields))}
+				for j, f := range e.Fields {
+					kept.Fields[j] = string([]byte(f))
+				}
+				r.insert(legacyKey(kept.ID), kept)
+			}
+			b.ReportMetric(float64(heapBytes()-before)/n, "B/entry")
+			runtime.KeepAlive(r)
+		}
+	})
+}
+
+// BenchmarkXAdd measures appending an entry to a stream
+func BenchmarkXAdd(b *testing.B) {
+	b.Run("nodes", func(b *testing.B) {
+		s := NewEmptyStream()
+		for i := range b.N {
+			s.Insert(benchEntry(i))
+		}
+	})
+	b.Run("legacy", func(b *testing.B) {
+		r := newLegacyRax()
+		for i := range b.N {
+			e := benchEntry(i)
+			r.insert(legacyKey(e.ID), e)
+		}
+	})
+}
+
+// BenchmarkXRange measures reading 100 entries from a random point of a stream of 100000
+func BenchmarkXRange(b *testing.B) {
+	const n = 100000
+	maxID := &StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+
+	b.Run("nodes", func(b *testing.B) {
+		s := NewEmptyStream()
+		for i := range n {
+			s.Insert(benchEntry(i))
+		}
+		b.ResetTimer()
+		for i := range b.N {
+			start := benchEntry(i * 7919 % (n - 100)).ID
+			if got := s.Range(start, maxID, 100); len(got) != 100 {
+				b.Fatalf("Range() returned %d entries", len(got))
+			}
+		}
+	})
+	b.Run("legacy", func(b *testing.B) {
+		r := newLegacyRax()
+		for i := range n {
+			e := benchEntry(i)
+			r.insert(legacyKey(e.ID), e)
+		}
+		b.ResetTimer()
+		for i := range b.N {
+			start := benchEntry(i * 7919 % (n - 100)).ID
+			if got := r.rangeN(start, 100); len(got) != 100 {
+				b.Fatalf("rangeN() returned %d entries", len(got))
+			}
+		}
+	})
+}
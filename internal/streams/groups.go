@@ -0,0 +1,253 @@
+package streams
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// ConsumerGroup delivers the entries of a stream to a set of consumers, each entry to only
+// one of them. Delivered entries stay in the pending entries list (PEL) until they are
+// acknowledged, so an entry whose consumer died can be claimed by another one
+type ConsumerGroup struct {
+	Name        string
+	LastID      StreamID             // last entry delivered to the group, new reads start after it
+	EntriesRead int64                // entries delivered to the group, UnknownEntriesRead if not known
+	Consumers   map[string]*Consumer // consumers by name
+	pel         []*PendingEntry      // delivered and not yet acknowledged entries, by ascending ID
+}
+
+// UnknownEntriesRead is the EntriesRead of a group when the number of entries it has read
+// isn't known, like the -1 of Redis
+const UnknownEntriesRead = -1
+
+// Consumer is a member of a consumer group
+type Consumer struct {
+	Name       string
+	SeenTime   time.Time // last time the consumer tried to read or claim entries
+	ActiveTime time.Time // last time the consumer was actually given entries, zero if never
+	Pending    int       // number of entries of the PEL owned by the consumer
+}
+
+// PendingEntry is an entry delivered to a consumer and not yet acknowledged
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      *Consumer
+	DeliveryTime  time.Time // last time the entry was delivered
+	DeliveryCount int64     // number of times the entry was delivered
+}
+
+// Idle returns how long ago the entry was last delivered
+func (pe *PendingEntry) Idle(now time.Time) time.Duration {
+	return max(now.Sub(pe.DeliveryTime), 0)
+}
+
+// NewConsumerGroup creates a consumer group with no consumers that delivers the entries
+// after lastID, with an unknown number of entries read
+func NewConsumerGroup(name string, lastID StreamID) *ConsumerGroup {
+	return &ConsumerGroup{
+		Name:        name,
+		LastID:      lastID,
+		EntriesRead: UnknownEntriesRead,
+		Consumers:   make(map[string]*Consumer),
+	}
+}
+
+// Contains reports whether the stream holds an entry with the given ID
+func (s *Stream) Contains(id StreamID) bool {
+	node := s.Radix.SeekLE(id.InternalKey())
+	if node == nil {
+		return false
+	}
+	_, ok := node.Node.find(id)
+	return ok
+}
+
+// Entry returns the entry with the given ID, nil if there is none
+func (s *Stream) Entry(id StreamID) *StreamEntry {
+	node := s.Radix.SeekLE(id.InternalKey())
+	if node == nil {
+		return nil
+	}
+	e, ok := node.Node.find(id)
+	if !ok {
+		return nil
+	}
+	return node.Node.entry(e)
+}
+
+// Group returns the consumer group with the given name, nil if there is none
+func (s *Stream) Group(name string) *ConsumerGroup {
+	return s.groups[name]
+}
+
+// Groups returns the consumer groups of the stream sorted by name
+func (s *Stream) Groups() []*ConsumerGroup {
+	groups := slices.Collect(maps.Values(s.groups))
+	slices.SortFunc(groups, func(a, b *ConsumerGroup) int {
+		switch {
+		case a.Name < b.Name:
+			return -1
+		case a.Name > b.Name:
+			return 1
+		}
+		return 0
+	})
+	return groups
+}
+
+// CreateGroup adds a consumer group that delivers the entries after lastID and has read
+// entriesRead entries. It returns nil if a group with that name already exists
+func (s *Stream) CreateGroup(name string, lastID StreamID, entriesRead int64) *ConsumerGroup {
+	if _, ok := s.groups[name]; ok {
+		return nil
+	}
+	if s.groups == nil {
+		s.groups = make(map[string]*ConsumerGroup)
+	}
+	g := NewConsumerGroup(name, lastID)
+	g.EntriesRead = entriesRead
+	s.groups[name] = g
+	return g
+}
+
+// DestroyGroup deletes a consumer group along with its consumers and PEL, it reports
+// whether the group existed
+func (s *Stream) DestroyGroup(name string) bool {
+	if _, ok := s.groups[name]; !ok {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// CloneGroups returns a deep copy of the consumer groups, sorted by name
+func (s *Stream) CloneGroups() []*ConsumerGroup {
+	groups := s.Groups()
+	clones := make([]*ConsumerGroup, len(groups))
+	for i, g := range groups {
+		clone := NewConsumerGroup(g.Name, g.LastID)
+		clone.EntriesRead = g.EntriesRead
+		for name, c := range g.Consumers {
+			copied := *c
+			clone.Consumers[name] = &copied
+		}
+		clone.pel = make([]*PendingEntry, len(g.pel))
+		for j, pe := range g.pel {
+			clone.pel[j] = &PendingEntry{
+				ID:            pe.ID,
+				Consumer:      clone.Consumers[pe.Consumer.Name],
+				DeliveryTime:  pe.DeliveryTime,
+				DeliveryCount: pe.DeliveryCount,
+			}
+		}
+		clones[i] = clone
+	}
+	return clones
+}
+
+// RestoreGroup installs a consumer group loaded from disk
+func (s *Stream) RestoreGroup(g *ConsumerGroup) {
+	if s.groups == nil {
+		s.groups = make(map[string]*ConsumerGroup)
+	}
+	s.groups[g.Name] = g
+}
+
+// RestorePending sets the PEL of a group loaded from disk, pel must be sorted by ID and
+// refer to consumers of the group
+func (g *ConsumerGroup) RestorePending(pel []*PendingEntry) {
+	for _, pe := range pel {
+		pe.Consumer.Pending++
+	}
+	g.pel = pel
+}
+
+// Consumer returns the consumer with the given name, creating it when create is set. The
+// second result reports whether the consumer was created
+func (g *ConsumerGroup) Consumer(name string, create bool, now time.Time) (*Consumer, bool) {
+	if c, ok := g.Consumers[name]; ok {
+		return c, false
+	}
+	if !create {
+		return nil, false
+	}
+	c := &Consumer{Name: name, SeenTime: now}
+	g.Consumers[name] = c
+	return c, true
+}
+
+// DeleteConsumer removes a consumer and the entries it owns from the PEL. It returns the
+// number of pending entries the consumer had, and false if it didn't exist
+func (g *ConsumerGroup) DeleteConsumer(name string) (int, bool) {
+	c, ok := g.Consumers[name]
+	if !ok {
+		return 0, false
+	}
+	pending := c.Pending
+	g.pel = slices.DeleteFunc(g.pel, func(pe *PendingEntry) bool { return pe.Consumer == c })
+	delete(g.Consumers, name)
+	return pending, true
+}
+
+// search returns the position of id in the PEL, or where it would be inserted
+func (g *ConsumerGroup) search(id StreamID) (int, bool) {
+	return slices.BinarySearchFunc(g.pel, &id, func(pe *PendingEntry, target *StreamID) int {
+		return pe.ID.Compare(target)
+	})
+}
+
+// Pending returns the PEL entry with the given ID, nil if the entry isn't pending
+func (g *ConsumerGroup) Pending(id StreamID) *PendingEntry {
+	if i, ok := g.search(id); ok {
+		return g.pel[i]
+	}
+	return nil
+}
+
+// PendingLen returns the number of entries in the PEL
+func (g *ConsumerGroup) PendingLen() int {
+	return len(g.pel)
+}
+
+// PendingFrom returns the PEL entries from the first one whose ID is at least start, in
+// ascending order. The slice must not be kept across changes to the group
+func (g *ConsumerGroup) PendingFrom(start StreamID) []*PendingEntry {
+	i, _ := g.search(start)
+	return g.pel[i:]
+}
+
+// Deliver records that the entry was delivered to c. A new entry is added to the PEL, an
+// entry that was already pending changes owner. The delivery count is incremented
+func (g *ConsumerGroup) Deliver(id StreamID, c *Consumer, now time.Time) *PendingEntry {
+	i, ok := g.search(id)
+	if ok {
+		pe := g.pel[i]
+		g.Transfer(pe, c)
+		pe.DeliveryTime = now
+		pe.DeliveryCount++
+		return pe
+	}
+	pe := &PendingEntry{ID: id, Consumer: c, DeliveryTime: now, DeliveryCount: 1}
+	g.pel = slices.Insert(g.pel, i, pe)
+	c.Pending++
+	return pe
+}
+
+// Transfer makes c the owner of a pending entry
+func (g *ConsumerGroup) Transfer(pe *PendingEntry, c *Consumer) {
+	pe.Consumer.Pending--
+	pe.Consumer = c
+	c.Pending++
+}
+
+// Ack removes an entry from the PEL and reports whether it was pending
+func (g *ConsumerGroup) Ack(id StreamID) bool {
+	i, ok := g.search(id)
+	if !ok {
+		return false
+	}
+	g.pel[i].Consumer.Pending--
+	g.pel = slices.Delete(g.pel, i, i+1)
+	return true
+}
@@ -0,0 +1,217 @@
+package streams
+
+import "encoding/binary"
+
+// NodeMaxEntries and NodeMaxBytes bound the size of a stream node, they are the defaults of
+// stream-node-max-entries and stream-node-max-bytes. A new node is started once the last
+// one reaches either
+const (
+	NodeMaxEntries = 100
+	NodeMaxBytes   = 4096
+)
+
+// Flags of a listpack entry
+const (
+	flagDeleted    byte = 1 << iota // the entry was deleted, it stays in the buffer until its node goes
+	flagSameFields                  // the entry has the field names of the master entry, only its values are stored
+)
+
+// listpack is a stream node: consecutive entries packed in one buffer and stored in the rax
+// under the ID of the first one, the master entry. As in the listpacks of Redis, entry IDs
+// are deltas from the master ID and an entry with the same field names as the master entry
+// only stores its values. An entry is laid out as
+//
+//	flags ms-delta seq-delta value...                    with flagSameFields
+//	flags ms-delta seq-delta count field value...        otherwise
+//
+// every number being a varint and every string its length as a varint followed by its bytes.
+// Deleting an entry only flags it, the node is freed once all of its entries are deleted
+type listpack struct {
+	master  StreamID
+	fields  []string // field names of the master entry
+	count   int      // number of live entries
+	deleted int      // number of entries flagged as deleted
+	buf     []byte
+}
+
+// lpEntry is the decoded header of an entry of a listpack
+type lpEntry struct {
+	id    StreamID
+	flags byte
+	pos   int // offset of the flags
+	body  int // offset of the values, or of the field count
+	next  int // offset of the next entry
+}
+
+// newListpack returns a node whose master entry is se
+func newListpack(se *StreamEntry) *listpack {
+	lp := &listpack{master: StreamID{Ms: se.ID.Ms, Seq: se.ID.Seq}}
+	lp.fields = make([]string, 0, len(se.Fields)/2)
+	for i := 0; i < len(se.Fields); i += 2 {
+		lp.fields = append(lp.fields, se.Fields[i])
+	}
+	lp.append(se)
+	return lp
+}
+
+// full reports whether the node can't take more entries
+func (lp *listpack) full() bool {
+	return lp.count+lp.deleted >= NodeMaxEntries || len(lp.buf) >= NodeMaxBytes
+}
+
+// sameFields reports whether fields has the field names of the master entry
+func (lp *listpack) sameFields(fields []string) bool {
+	if len(fields) != 2*len(lp.fields) {
+		return false
+	}
+	for i, name := range lp.fields {
+		if fields[2*i] != name {
+			return false
+		}
+	}
+	return true
+}
+
+// append adds an entry at the end of the node, its ID must be greater than the others
+func (lp *listpack) append(se *StreamEntry) {
+	same := lp.sameFields(se.Fields)
+	var flags byte
+	if same {
+		flags = flagSameFields
+	}
+	lp.buf = append(lp.buf, flags)
+	lp.buf = binary.AppendUvarint(lp.buf, se.ID.Ms-lp.master.Ms)
+	// The sequence number goes back when the ms part moves on, the delta may be negative
+	lp.buf = binary.AppendVarint(lp.buf, int64(se.ID.Seq-lp.master.Seq))
+	if same {
+		for i := 1; i < len(se.Fields); i += 2 {
+			lp.buf = appendString(lp.buf, se.Fields[i])
+		}
+	} else {
+		lp.buf = binary.AppendUvarint(lp.buf, uint64(len(se.Fields)/2))
+		for _, s := range se.Fields {
+			lp.buf = appendString(lp.buf, s)
+		}
+	}
+	lp.count++
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// at decodes the header of the entry at offset pos
+func (lp *listpack) at(pos int) lpEntry {
+	e := lpEntry{flags: lp.buf[pos], pos: pos}
+	p := pos + 1
+	ms, n := binary.Uvarint(lp.buf[p:])
+	p += n
+	seq, n := binary.Varint(lp.buf[p:])
+	p += n
+	e.id = StreamID{Ms: lp.master.Ms + ms, Seq: lp.master.Seq + uint64(seq)}
+	e.body = p
+
+	// Skip the strings to find the next entry
+	strs := len(lp.fields)
+	if e.flags&flagSameFields == 0 {
+		k, n := binary.Uvarint(lp.buf[p:])
+		p += n
+		strs = 2 * int(k)
+	}
+	for range strs {
+		l, n := binary.Uvarint(lp.buf[p:])
+		p += n + int(l)
+	}
+	e.next = p
+	return e
+}
+
+// all decodes the headers of every entry, deleted ones included
+func (lp *listpack) all() []lpEntry {
+	entries := make([]lpEntry, 0, lp.count+lp.deleted)
+	for pos := 0; pos < len(lp.buf); {
+		e := lp.at(pos)
+		entries = append(entries, e)
+		pos = e.next
+	}
+	return entries
+}
+
+// entry decodes the entry e
+func (lp *listpack) entry(e lpEntry) *StreamEntry {
+	p := e.body
+	var fields []string
+	if e.flags&flagSameFields != 0 {
+		fields = make([]string, 0, 2*len(lp.fields))
+		for _, name := range lp.fields {
+			var value string
+			value, p = lp.str(p)
+			fields = append(fields, name, value)
+		}
+	} else {
+		k, n := binary.Uvarint(lp.buf[p:])
+		p += n
+		fields = make([]string, 2*k)
+		for i := range fields {
+			fields[i], p = lp.str(p)
+		}
+	}
+	id := e.id
+	return &StreamEntry{ID: &id, Fields: fields}
+}
+
+// str decodes the string at offset p and returns it along with the offset after it
+func (lp *listpack) str(p int) (string, int) {
+	l, n := binary.Uvarint(lp.buf[p:])
+	p += n
+	return string(lp.buf[p : p+int(l)]), p + int(l)
+}
+
+// first returns the first live entry, false if there is none
+func (lp *listpack) first() (lpEntry, bool) {
+	for pos := 0; pos < len(lp.buf); {
+		e := lp.at(pos)
+		if e.flags&flagDeleted == 0 {
+			return e, true
+		}
+		pos = e.next
+	}
+	return lpEntry{}, false
+}
+
+// last returns the last live entry, false if there is none
+func (lp *listpack) last() (lpEntry, bool) {
+	var last lpEntry
+	found := false
+	for pos := 0; pos < len(lp.buf); {
+		e := lp.at(pos)
+		if e.flags&flagDeleted == 0 {
+			last, found = e, true
+		}
+		pos = e.next
+	}
+	return last, found
+}
+
+// find returns the live entry with the given ID, false if there is none
+func (lp *listpack) find(id StreamID) (lpEntry, bool) {
+	for pos := 0; pos < len(lp.buf); {
+		e := lp.at(pos)
+		switch e.id.Compare(&id) {
+		case 0:
+			return e, e.flags&flagDeleted == 0
+		case 1:
+			return lpEntry{}, false
+		}
+		pos = e.next
+	}
+	return lpEntry{}, false
+}
+
+// markDeleted flags the live entry e as deleted
+func (lp *listpack) markDeleted(e lpEntry) {
+	lp.buf[e.pos] |= flagDeleted
+	lp.count--
+	lp.deleted++
+}
@@ -0,0 +1,103 @@
+package streams
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// TestStreamNodes checks the node layout against a slice of the same entries: IDs whose
+// sequence goes back when the ms part moves on, entries with and without the field names of
+// their master entry, and random deletions that free some nodes
+func TestStreamNodes(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	s := NewEmptyStream()
+	var model []*StreamEntry
+	id := StreamID{Ms: 1000, Seq: 5}
+	for i := range 1000 {
+		if rng.IntN(3) == 0 {
+			id = StreamID{Ms: id.Ms + rng.Uint64N(5) + 1}
+		} else {
+			id.Seq++
+		}
+		fields := []string{"name", "n" + strconv.Itoa(i), "value", strconv.Itoa(i)}
+		switch rng.IntN(4) {
+		case 0:
+			fields = []string{"other", strconv.Itoa(i)}
+		case 1:
+			fields = append(fields, "name", "again")
+		}
+		entry := &StreamEntry{ID: &StreamID{Ms: id.Ms, Seq: id.Seq}, Fields: fields}
+		s.Insert(entry)
+		model = append(model, entry)
+	}
+	for i := len(model) - 1; i >= 0; i-- {
+		if rng.IntN(3) == 0 || i >= 200 && i < 420 {
+			if !s.Delete(*model[i].ID) {
+				t.Fatalf("Delete(%v) = false", model[i].ID)
+			}
+			model = slices.Delete(model, i, i+1)
+		}
+	}
+
+	if s.Len() != len(model) {
+		t.Fatalf("Len() = %d, want %d", s.Len(), len(model))
+	}
+	equal := func(got, want []*StreamEntry) bool {
+		return slices.EqualFunc(got, want, func(a, b *StreamEntry) bool {
+			return *a.ID == *b.ID && slices.Equal(a.Fields, b.Fields)
+		})
+	}
+
+	minID, maxID := &StreamID{}, &StreamID{Ms: ^uint64(0), Seq: ^uint64(0)}
+	if got := s.Range(minID, maxID, 0); !equal(got, model) {
+		t.Errorf("Range() returned %d entries, want %d", len(got), len(model))
+	}
+	reversed := slices.Clone(model)
+	slices.Reverse(reversed)
+	if got := s.RevRange(minID, maxID, 0); !equal(got, reversed) {
+		t.Errorf("RevRange() returned %d entries, want %d", len(got), len(reversed))
+	}
+
+	for range 200 {
+		i, j := rng.IntN(len(model)), rng.IntN(len(model))
+		if i > j {
+			i, j = j, i
+		}
+		count := rng.IntN(10)
+		want := model[i : j+1]
+		if count > 0 && len(want) > count {
+			want = want[:count]
+		}
+		if got := s.Range(model[i].ID, model[j].ID, count); !equal(got, want) {
+			t.Errorf("Range(%v, %v, %d) = %d entries, want %d", model[i].ID, model[j].ID, count, len(got), len(want))
+		}
+
+		want = slices.Clone(model[i : j+1])
+		slices.Reverse(want)
+		if count > 0 && len(want) > count {
+			want = want[:count]
+		}
+		if got := s.RevRange(model[i].ID, model[j].ID, count); !equal(got, want) {
+			t.Errorf("RevRange(%v, %v, %d) = %d entries, want %d", model[i].ID, model[j].ID, count, len(got), len(want))
+		}
+	}
+
+	for _, entry := range model {
+		if got := s.Entry(*entry.ID); got == nil || !equal([]*StreamEntry{got}, []*StreamEntry{entry}) {
+			t.Fatalf("Entry(%v) = %v, want %v", entry.ID, got, entry.Fields)
+		}
+	}
+	if s.Contains(StreamID{Ms: 1000, Seq: 5}) || s.Entry(StreamID{Ms: 1000, Seq: 5}) != nil {
+		t.Error("Contains(1000-5) = true for an ID never added")
+	}
+	if first, last := s.FirstEntry(), s.LastEntry(); *first.ID != *model[0].ID || *last.ID != *model[len(model)-1].ID {
+		t.Errorf("FirstEntry() = %v, LastEntry() = %v", first.ID, last.ID)
+	}
+
+	// Every node holds at most NodeMaxEntries entries and the deleted range freed some
+	if nodes := s.Radix.Len(); nodes >= 1000/NodeMaxEntries || nodes < len(model)/NodeMaxEntries {
+		t.Errorf("Radix.Len() = %d nodes for %d entries", nodes, len(model))
+	}
+}
@@ -1,13 +1,10 @@
 package streams
 
-import (
-	"fmt"
-	"sync"
-)
+import "encoding/binary"
 
 type StreamEntry struct {
-	ID    *StreamID
-	Entry map[string]string
+	ID     *StreamID
+	Fields []string // field, value, field, value... in the order they were added, names may repeat
 }
 
 type StreamID struct {
@@ -36,9 +33,41 @@ func (sid *StreamID) Compare(other *StreamID) int {
 	return 0
 }
 
-// InternalKey returns a zero-padded string representation for lexicographical ordering in the trie
+// InternalKey returns the ID as 16 big endian bytes, the ms part first, so that keys in the
+// trie sort like the IDs
 func (sid *StreamID) InternalKey() []byte {
-	return []byte(fmt.Sprintf("%020d-%020d", sid.Ms, sid.Seq))
+	key := make([]byte, 0, 16)
+	key = binary.BigEndian.AppendUint64(key, sid.Ms)
+	return binary.BigEndian.AppendUint64(key, sid.Seq)
+}
+
+// Incr sets the ID to the next possible one and reports false if it is already the
+// greatest ID
+func (sid *StreamID) Incr() bool {
+	if sid.Seq < ^uint64(0) {
+		sid.Seq++
+		return true
+	}
+	if sid.Ms < ^uint64(0) {
+		sid.Ms++
+		sid.Seq = 0
+		return true
+	}
+	return false
+}
+
+// Decr sets the ID to the previous possible one and reports false if it is 0-0
+func (sid *StreamID) Decr() bool {
+	if sid.Seq > 0 {
+		sid.Seq--
+		return true
+	}
+	if sid.Ms > 0 {
+		sid.Ms--
+		sid.Seq = ^uint64(0)
+		return true
+	}
+	return false
 }
 
 // IsZero returns true if the StreamID is 0-0
@@ -46,43 +75,242 @@ func (sid *StreamID) IsZero() bool {
 	return sid.Ms == 0 && sid.Seq == 0
 }
 
-type BlockingListener struct {
-	Channel   chan struct{}
-	WaitingID *StreamID
+type Stream struct {
+	Radix        *Rax                      // nodes of up to NodeMaxEntries entries keyed by their first ID
+	length       int                       // number of entries
+	lastID       StreamID                  // greatest ID ever added, it stays when that entry is deleted
+	maxDeletedID StreamID                  // greatest ID removed by XDEL
+	entriesAdded uint64                    // number of entries ever added
+	groups       map[string]*ConsumerGroup // consumer groups by name, nil until one is created
 }
 
-type Stream struct {
-	LastEntry         *StreamEntry
-	Radix             *Rax
-	BlockingListeners []*BlockingListener
+// Insert adds an entry, its ID must be greater than LastID. It goes at the end of the last
+// node unless that one is full
+func (s *Stream) Insert(se *StreamEntry) {
+	if last := s.Radix.Last(); last != nil && !last.Node.full() {
+		last.Node.append(se)
+	} else {
+		lp := newListpack(se)
+		s.Radix.Insert(lp.master.InternalKey(), lp)
+	}
+	s.length++
+	s.entriesAdded++
+	s.lastID = StreamID{Ms: se.ID.Ms, Seq: se.ID.Seq}
+}
+
+// Delete removes the entry with the given ID and reports whether it existed. Explicit
+// deletions are tracked by MaxDeletedID, trimming goes through trim instead
+func (s *Stream) Delete(id StreamID) bool {
+	if !s.remove(id) {
+		return false
+	}
+	if id.Compare(&s.maxDeletedID) > 0 {
+		s.maxDeletedID = id
+	}
+	return true
 }
 
-func (s *Stream) Insert(se *StreamEntry, prefix []byte) {
-	s.Radix.Insert(prefix, se)
-	s.LastEntry = se
+func (s *Stream) remove(id StreamID) bool {
+	node := s.Radix.SeekLE(id.InternalKey())
+	if node == nil {
+		return false
+	}
+	e, ok := node.Node.find(id)
+	if !ok {
+		return false
+	}
+	s.drop(node.Node, e)
+	return true
 }
 
-// Range returns all entries with IDs in the range [start, end] (inclusive)
-func (s *Stream) Range(start, end *StreamID) []*StreamEntry {
+// drop deletes the live entry e of the node lp, freeing the node once it has no live entry
+func (s *Stream) drop(lp *listpack, e lpEntry) {
+	lp.markDeleted(e)
+	if lp.count == 0 {
+		s.Radix.Delete(lp.master.InternalKey())
+	}
+	s.length--
+}
+
+// Len returns the number of entries
+func (s *Stream) Len() int {
+	return s.length
+}
+
+// LastID returns the greatest ID ever added to the stream, 0-0 if nothing was added. New
+// entries must have a greater ID, even if that entry has been deleted since
+func (s *Stream) LastID() StreamID {
+	return s.lastID
+}
+
+// MaxDeletedID returns the greatest ID deleted with Delete, 0-0 if none was
+func (s *Stream) MaxDeletedID() StreamID {
+	return s.maxDeletedID
+}
+
+// EntriesAdded returns the number of entries ever added to the stream, deleted ones included
+func (s *Stream) EntriesAdded() uint64 {
+	return s.entriesAdded
+}
+
+// RestoreMetadata sets the bookkeeping of a stream loaded from disk or recreated by XSETID
+func (s *Stream) RestoreMetadata(lastID, maxDeletedID StreamID, entriesAdded uint64) {
+	s.lastID = lastID
+	s.maxDeletedID = maxDeletedID
+	s.entriesAdded = entriesAdded
+}
+
+// EntriesRead returns the number of entries added up to and including id, which is how many
+// entries a consumer group whose last delivered ID is id has read. Like Redis it is only
+// known when id is the last ID, or when no entry was deleted past the first one and id isn't
+// after the first entry; the second value reports whether it is known
+func (s *Stream) EntriesRead(id StreamID) (uint64, bool) {
+	if s.entriesAdded == 0 {
+		return 0, true
+	}
+	cmpLast := id.Compare(&s.lastID)
+	if s.length == 0 && cmpLast <= 0 || cmpLast == 0 {
+		return s.entriesAdded, true
+	}
+	if cmpLast > 0 {
+		return 0, false
+	}
+
+	// Nothing was deleted after the first entry, so every entry before it is gone and every
+	// entry from it onwards is still there
+	first := s.FirstEntry().ID
+	if s.maxDeletedID.IsZero() || s.maxDeletedID.Compare(first) < 0 {
+		switch id.Compare(first) {
+		case -1:
+			return s.entriesAdded - uint64(s.length), true
+		case 0:
+			return s.entriesAdded - uint64(s.length) + 1, true
+		}
+	}
+	return 0, false
+}
+
+// hasTombstonesFrom reports whether an entry deleted with Delete may have had an ID at or
+// after id, in which case counting the entries from there is off
+func (s *Stream) hasTombstonesFrom(id StreamID) bool {
+	return s.length > 0 && !s.maxDeletedID.IsZero() && s.maxDeletedID.Compare(&id) >= 0
+}
+
+// AdvanceGroup moves the last delivered ID of g to id, the next entry it reads, and counts
+// that entry as read. Like Redis the count is estimated again from id when deletions may
+// have made it wrong
+func (s *Stream) AdvanceGroup(g *ConsumerGroup, id StreamID) {
+	if g.EntriesRead != UnknownEntriesRead && !s.hasTombstonesFrom(id) {
+		g.EntriesRead++
+	} else if read, ok := s.EntriesRead(id); ok {
+		g.EntriesRead = int64(read)
+	} else {
+		g.EntriesRead = UnknownEntriesRead
+	}
+	g.LastID = id
+}
+
+// GroupLag returns the number of entries added to the stream that g has yet to read. It
+// comes from the group's count of entries read while no deletion or trimming after its last
+// ID makes that count wrong, and is estimated from the last ID otherwise; the second value
+// reports whether it is known
+func (s *Stream) GroupLag(g *ConsumerGroup) (uint64, bool) {
+	if s.entriesAdded == 0 {
+		return 0, true
+	}
+	trimmed := s.length > 0 && g.LastID.Compare(s.FirstEntry().ID) < 0
+	if g.EntriesRead != UnknownEntriesRead && !s.hasTombstonesFrom(g.LastID) && !trimmed {
+		return s.entriesAdded - min(uint64(g.EntriesRead), s.entriesAdded), true
+	}
+	read, ok := s.EntriesRead(g.LastID)
+	if !ok {
+		return 0, false
+	}
+	return s.entriesAdded - read, true
+}
+
+// FirstEntry returns the entry with the smallest ID, nil if the stream is empty
+func (s *Stream) FirstEntry() *StreamEntry {
+	if node := s.Radix.First(); node != nil {
+		if e, ok := node.Node.first(); ok {
+			return node.Node.entry(e)
+		}
+	}
+	return nil
+}
+
+// LastEntry returns the entry with the greatest ID, nil if the stream is empty
+func (s *Stream) LastEntry() *StreamEntry {
+	if node := s.Radix.Last(); node != nil {
+		if e, ok := node.Node.last(); ok {
+			return node.Node.entry(e)
+		}
+	}
+	return nil
+}
+
+// Range returns up to count entries with IDs in the range [start, end] (inclusive) in
+// ascending order, all of them when count is 0. The entries are decoded copies, they can be
+// used after the stream changes
+func (s *Stream) Range(start, end *StreamID, count int) []*StreamEntry {
 	var result []*StreamEntry
 
-	startKey := start.InternalKey()
-	node := s.Radix.SeekGE(startKey)
+	// The node holding start is the last one whose master ID isn't greater
+	node := s.Radix.SeekLE(start.InternalKey())
+	if node == nil {
+		node = s.Radix.First()
+	}
 	for node != nil {
-		// Check if the entry is within the range
-		if node.Entry.ID.Compare(end) > 0 {
-			break
+		lp := node.Node
+		for pos := 0; pos < len(lp.buf); {
+			e := lp.at(pos)
+			pos = e.next
+			if e.flags&flagDeleted != 0 || e.id.Compare(start) < 0 {
+				continue
+			}
+			// Check if the entry is within the range
+			if e.id.Compare(end) > 0 {
+				return result
+			}
+			result = append(result, lp.entry(e))
+			if count > 0 && len(result) == count {
+				return result
+			}
 		}
-		result = append(result, node.Entry)
 
-		// Get next entry
-		node = s.Radix.Successor(node.Entry.ID.InternalKey())
+		// Get next node
+		node = s.Radix.Successor(lp.master.InternalKey())
 	}
 
 	return result
 }
 
-type GlobalInstance struct {
-	Mu sync.Mutex
-	KV map[string]*Stream
+// RevRange returns up to count entries with IDs in the range [start, end] (inclusive) in
+// descending order, all of them when count is 0
+func (s *Stream) RevRange(start, end *StreamID, count int) []*StreamEntry {
+	var result []*StreamEntry
+
+	node := s.Radix.SeekLE(end.InternalKey())
+	for node != nil {
+		// Entries can only be decoded forwards, walk the headers of the node backwards
+		lp := node.Node
+		entries := lp.all()
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if e.flags&flagDeleted != 0 || e.id.Compare(end) > 0 {
+				continue
+			}
+			if e.id.Compare(start) < 0 {
+				return result
+			}
+			result = append(result, lp.entry(e))
+			if count > 0 && len(result) == count {
+				return result
+			}
+		}
+
+		node = s.Radix.Predecessor(lp.master.InternalKey())
+	}
+
+	return result
 }
@@ -1,87 +1,130 @@
 package streams
 
+import "sort"
+
 // Struct representing the node of a radix trie
 type RaxNode struct {
-	IsEndOfEntry bool              // Is the current node the end of an entry or is it just a connecting node
-	Children     map[byte]*RaxNode // To store the edges in the trie
-	Prefix       []byte            // The common prefix of all children of this node
-	Entry        *StreamEntry      // nil if this is not end of entry, else pointer to struct
+	IsEndOfEntry bool       // Is the current node the end of a key or is it just a connecting node
+	Children     []*RaxNode // The edges of the trie, sorted by the first byte of each child's prefix
+	Prefix       []byte     // The part of the key this node adds to its parent's
+	Node         *listpack  // nil if this is not the end of a key, else the stream node stored under it
 }
 
-// Struct representing a radix trie
+// Struct representing a radix trie. Its keys are the master IDs of the stream nodes as 16
+// big endian bytes, so keys sort like the IDs they encode
 type Rax struct {
-	Root *RaxNode // Root node of trie
+	Root  *RaxNode // Root node of trie, its prefix is always empty
+	keys  int      // number of keys
+	nodes int      // number of nodes, the root included
 }
 
 // Struct representing the edge of a radix trie (used to backtrack over trie)
 type TrieEdge struct {
 	parent *RaxNode
-	node   *RaxNode
-	edge   byte
+	index  int // position of the edge in parent.Children
+}
+
+// NewRax returns an empty trie
+func NewRax() *Rax {
+	return &Rax{Root: &RaxNode{}, nodes: 1}
+}
+
+// child returns the index of the child whose prefix starts with b and whether there is one.
+// If there isn't, the index is where such a child would go
+func (n *RaxNode) child(b byte) (int, bool) {
+	i := sort.Search(len(n.Children), func(i int) bool { return n.Children[i].Prefix[0] >= b })
+	return i, i < len(n.Children) && n.Children[i].Prefix[0] == b
+}
+
+// addChild inserts c at index i of the children
+func (n *RaxNode) addChild(i int, c *RaxNode) {
+	n.Children = append(n.Children, nil)
+	copy(n.Children[i+1:], n.Children[i:])
+	n.Children[i] = c
+}
+
+// Len returns the number of keys
+func (r *Rax) Len() int {
+	return r.keys
 }
 
-func (r *Rax) Insert(s []byte, entry *StreamEntry) {
+// Nodes returns the number of nodes of the trie, the root included
+func (r *Rax) Nodes() int {
+	return r.nodes
+}
+
+// Insert stores lp under the key s, replacing what was stored there
+func (r *Rax) Insert(s []byte, lp *listpack) {
 	node := r.Root
 	i := 0
 
 	// Start traversing over the string
 	for i < len(s) {
 		// Check if current node has a child with the i'th char
-		child, ok := node.Children[s[i]]
+		idx, ok := node.child(s[i])
 		if !ok {
-			// If child doesn't Exist, create a new child node
-			newNode := &RaxNode{
-				IsEndOfEntry: true,  // This node does represent the end of an entry
-				Children:     nil,   // Child node has no children
-				Prefix:       s[i:], // Prefix of the child node is the remaining string that we haven't traversed
-				Entry:        entry,
-			}
-			// Make an edge b/w the current node and child node
-			node.Children[s[i]] = newNode
-			// We have inserted the element, break
-			break
+			// If child doesn't Exist, create a new child node holding the remaining string
+			// that we haven't traversed. It gets its own copy, the caller may reuse s
+			node.addChild(idx, &RaxNode{
+				IsEndOfEntry: true,
+				Prefix:       append([]byte(nil), s[i:]...),
+				Node:         lp,
+			})
+			r.keys++
+			r.nodes++
+			return
 		}
 
 		// Child Exists!!
-		node = child // Descend the trie
-		prefixLen := MaxCommonStringLen(s[i:], node.Prefix)
-		i += prefixLen // Increment pointer over string
+		child := node.Children[idx]
+		prefixLen := MaxCommonStringLen(s[i:], child.Prefix)
 
-		// New entry has a common prefix with current node, Split:
-		if prefixLen < len(node.Prefix) {
-			// oldNode stores the entries AFTER the common prefix (from the existing node)
+		// New key has a common prefix with the child, Split:
+		if prefixLen < len(child.Prefix) {
+			// oldNode stores the keys AFTER the common prefix (from the existing node)
 			oldNode := &RaxNode{
-				IsEndOfEntry: node.IsEndOfEntry,
-				Children:     node.Children,
-				Prefix:       node.Prefix[prefixLen:], // Remaining part of OLD prefix
-				Entry:        node.Entry,
+				IsEndOfEntry: child.IsEndOfEntry,
+				Children:     child.Children,
+				Prefix:       child.Prefix[prefixLen:], // Remaining part of OLD prefix
+				Node:         child.Node,
 			}
 
-			// Update current node to be the split point
-			node.Prefix = node.Prefix[:prefixLen]
-			node.Children = make(map[byte]*RaxNode)
-			node.Children[oldNode.Prefix[0]] = oldNode
+			// Update the child to be the split point
+			child.Prefix = child.Prefix[:prefixLen]
+			child.Children = []*RaxNode{oldNode}
+			child.IsEndOfEntry = false
+			child.Node = nil
+			r.nodes++
+			i += prefixLen
 
-			// Determine if current split point is end of entry
 			if i == len(s) {
 				// The input string ends exactly at the split point
-				node.IsEndOfEntry = true
-				node.Entry = entry
+				child.IsEndOfEntry = true
+				child.Node = lp
 			} else {
 				// Input string continues beyond split point - create new node for it
-				node.IsEndOfEntry = false
-				node.Entry = nil
-				newNode := &RaxNode{
+				pos, _ := child.child(s[i])
+				child.addChild(pos, &RaxNode{
 					IsEndOfEntry: true,
-					Children:     nil,
-					Prefix:       s[i:],
-					Entry:        entry,
-				}
-				node.Children[s[i]] = newNode
+					Prefix:       append([]byte(nil), s[i:]...),
+					Node:         lp,
+				})
+				r.nodes++
 			}
-			break
+			r.keys++
+			return
 		}
+
+		node = child // Descend the trie
+		i += prefixLen
+	}
+
+	// The key ends at an existing node
+	if !node.IsEndOfEntry {
+		r.keys++
 	}
+	node.IsEndOfEntry = true
+	node.Node = lp
 }
 
 func (r *Rax) SearchExact(s []byte) *RaxNode {
@@ -91,34 +134,28 @@ func (r *Rax) SearchExact(s []byte) *RaxNode {
 	// Start traversing over the string
 	for i < len(s) {
 		// Check if current node has a child with the i'th char
-		child, ok := node.Children[s[i]]
+		idx, ok := node.child(s[i])
 		if !ok {
-			// The entry doesn't exist, returning false
+			// The key doesn't exist
 			return nil
 		}
 
-		node = child // Descend the trie
+		node = node.Children[idx] // Descend the trie
 		prefixLen := MaxCommonStringLen(s[i:], node.Prefix)
 
 		// If at any point the common prefix bw the input and the node differs:
-		// the input doesn't exist in the trie, hence return false
+		// the input doesn't exist in the trie
 		if prefixLen != len(node.Prefix) {
 			return nil
 		}
 
 		// All checks are done, increment to the next node
 		i += prefixLen
+	}
 
-		// Node found after satisfying all conditions
-		if i == len(s) {
-			if node.IsEndOfEntry {
-				return node
-			} else {
-				return nil
-			}
-		}
+	if node.IsEndOfEntry {
+		return node
 	}
-	// Node not found
 	return nil
 }
 
@@ -132,31 +169,28 @@ func (r *Rax) Delete(s []byte) bool {
 	// Start traversing over the string
 	for i < len(s) {
 		// See if child exists for the current node with the edge of the i'th char
-		child, ok := node.Children[s[i]]
+		idx, ok := node.child(s[i])
 		if !ok {
 			// Child doesn't exist, we didn't delete anything...return
 			return false
 		}
 
+		child := node.Children[idx]
 		prefixLen := MaxCommonStringLen(s[i:], child.Prefix)
 		if prefixLen != len(child.Prefix) {
 			// the length of common prefix b/w the remaining string and the stored prefix
-			// didn't match, hence the entry doesn't exist...returning false
+			// didn't match, hence the key doesn't exist...returning false
 			return false
 		}
 
 		// All good so far, add current edge to stack
-		stack = append(stack, TrieEdge{
-			parent: node,
-			node:   child,
-			edge:   s[i],
-		})
+		stack = append(stack, TrieEdge{parent: node, index: idx})
 
 		node = child // Descend the trie
 		i += prefixLen
 	}
 
-	// After the loop, if the node isn't the end of an entry...
+	// After the loop, if the node isn't the end of a key...
 	// then we didn't delete anything, return false
 	if !node.IsEndOfEntry {
 		return false
@@ -164,16 +198,18 @@ func (r *Rax) Delete(s []byte) bool {
 
 	// Unmark terminal
 	node.IsEndOfEntry = false
+	node.Node = nil
+	r.keys--
 
-	// Backtrack on the traversed nodes for cleanup
+	// Backtrack on the traversed nodes for cleanup, the root is never on the stack
 	for len(stack) > 0 {
 		f := stack[len(stack)-1]     // Stack.top()
 		stack = stack[:len(stack)-1] // Stack.pop()
 
-		n := f.node
+		n := f.parent.Children[f.index]
 
 		// Case 1 - node still needed:
-		// Current node is the end of an entry OR parent of another node
+		// Current node is the end of a key OR parent of another node
 		if n.IsEndOfEntry || len(n.Children) > 1 {
 			break
 		}
@@ -181,176 +217,217 @@ func (r *Rax) Delete(s []byte) bool {
 		// Case 2 - merge with single child
 		// We can compress the space by merging the single child with the current node
 		if len(n.Children) == 1 {
-			// For the single child of the node
-			for _, child := range n.Children {
-				// In the prefix of the current node, add the prefix of the child
-				n.Prefix = append(n.Prefix, child.Prefix...)
-				// Determine if the current node is the end of an entry based on child
-				n.IsEndOfEntry = child.IsEndOfEntry
-				// Transfer children
-				n.Children = child.Children
-			}
+			child := n.Children[0]
+			// In the prefix of the current node, add the prefix of the child. The prefix
+			// may share its array with a sibling's, so the result gets its own
+			n.Prefix = append(append([]byte(nil), n.Prefix...), child.Prefix...)
+			// Determine if the current node is the end of a key based on child
+			n.IsEndOfEntry = child.IsEndOfEntry
+			n.Node = child.Node
+			// Transfer children
+			n.Children = child.Children
+			r.nodes--
 			break
 		}
 
 		// Case 3 - remove empty node
-		delete(f.parent.Children, f.edge)
+		f.parent.Children = append(f.parent.Children[:f.index], f.parent.Children[f.index+1:]...)
+		r.nodes--
 	}
 
 	return true
 }
 
 func leftmost(n *RaxNode) *RaxNode {
-	if n == nil {
-		return nil
+	for n != nil && !n.IsEndOfEntry {
+		if len(n.Children) == 0 {
+			return nil
+		}
+		// pick smallest edge
+		n = n.Children[0]
 	}
-	if n.IsEndOfEntry {
-		return n
+	return n
+}
+
+// First returns the node with the smallest key, nil if the trie is empty
+func (r *Rax) First() *RaxNode {
+	return leftmost(r.Root)
+}
+
+// Last returns the node with the greatest key, nil if the trie is empty
+func (r *Rax) Last() *RaxNode {
+	return rightmost(r.Root)
+}
+
+func rightmost(n *RaxNode) *RaxNode {
+	// pick largest edge, a key ending at n is smaller than everything below it
+	for n != nil && len(n.Children) > 0 {
+		n = n.Children[len(n.Children)-1]
 	}
-	if len(n.Children) == 0 {
+	if n == nil || !n.IsEndOfEntry {
 		return nil
 	}
-
-	// pick smallest edge
-	var minKey int = 256
-	var next *RaxNode
-	for k, c := range n.Children {
-		if int(k) < minKey {
-			minKey = int(k)
-			next = c
-		}
-	}
-	return leftmost(next)
+	return n
 }
 
+// SeekGE returns the node with the smallest key that is greater than or equal to s, nil if
+// there is none
 func (r *Rax) SeekGE(s []byte) *RaxNode {
 	var stack []TrieEdge
 	node := r.Root
 	i := 0
 
 	for i < len(s) {
-		child, ok := node.Children[s[i]]
+		idx, ok := node.child(s[i])
 		if !ok {
-			// No exact match for this character.
-			// Find the first child of 'node' that is > s[i]
-			var minKey int = 256
-			var nextNode *RaxNode
-			for k, c := range node.Children {
-				if int(k) > int(s[i]) && int(k) < minKey {
-					minKey = int(k)
-					nextNode = c
-				}
+			// No exact match for this character, the first child of 'node' after it has
+			// the answer. If there is none, backtrack
+			if idx < len(node.Children) {
+				return leftmost(node.Children[idx])
 			}
-			if nextNode != nil {
-				return leftmost(nextNode)
-			}
-			// No larger child, backtrack
-			break
+			return after(stack)
 		}
 
+		child := node.Children[idx]
 		prefixLen := MaxCommonStringLen(s[i:], child.Prefix)
 		if prefixLen < len(child.Prefix) {
-			// Prefix mismatch.
-			// If the first differing byte in child.Prefix is > s[i+prefixLen], then this child is GE.
-			if i+prefixLen < len(s) && child.Prefix[prefixLen] > s[i+prefixLen] {
+			// Prefix mismatch. If s ends inside child.Prefix or the first differing byte in
+			// child.Prefix is > s[i+prefixLen], then every key below this child is GE
+			if i+prefixLen == len(s) || child.Prefix[prefixLen] > s[i+prefixLen] {
 				return leftmost(child)
 			}
-			// Otherwise, this child is smaller than s, so we need a larger sibling of this child.
-			// We'll break and let the backtracking handle it.
-			stack = append(stack, TrieEdge{parent: node, node: child, edge: s[i]})
-			break
+			// Otherwise, this child is smaller than s, so we need a larger sibling of it
+			stack = append(stack, TrieEdge{parent: node, index: idx})
+			return after(stack)
 		}
 
-		stack = append(stack, TrieEdge{parent: node, node: child, edge: s[i]})
+		stack = append(stack, TrieEdge{parent: node, index: idx})
 		node = child
 		i += prefixLen
 	}
 
-	if i == len(s) {
-		if node.IsEndOfEntry {
-			return node
-		}
-		// Not an entry, find leftmost in subtree
-		if len(node.Children) > 0 {
-			return leftmost(node)
-		}
+	if node.IsEndOfEntry {
+		return node
 	}
+	// Not a key, find leftmost in subtree
+	if len(node.Children) > 0 {
+		return leftmost(node)
+	}
+	return after(stack)
+}
 
-	// Backtrack to find larger sibling
-	for len(stack) > 0 {
-		top := stack[len(stack)-1]
-		stack = stack[:len(stack)-1]
-
-		parent := top.parent
-		currEdge := top.edge
+// SeekLE returns the node with the greatest key that is less than or equal to s, nil if
+// there is none. It mirrors SeekGE for reverse iteration
+func (r *Rax) SeekLE(s []byte) *RaxNode {
+	var stack []TrieEdge
+	node := r.Root
+	i := 0
 
-		var minKey int = 256
-		var nextNode *RaxNode
+	for i < len(s) {
+		idx, ok := node.child(s[i])
+		if !ok {
+			// No exact match for this character, everything smaller than s lives under a
+			// smaller edge of node, in node itself or before node
+			stack = append(stack, TrieEdge{parent: node, index: idx})
+			return before(stack)
+		}
 
-		for k, c := range parent.Children {
-			if int(k) > int(currEdge) && int(k) < minKey {
-				minKey = int(k)
-				nextNode = c
+		child := node.Children[idx]
+		prefixLen := MaxCommonStringLen(s[i:], child.Prefix)
+		if prefixLen < len(child.Prefix) {
+			// Prefix mismatch. If the first differing byte in child.Prefix is < s[i+prefixLen],
+			// the whole child subtree is smaller than s and its greatest key is the answer
+			if i+prefixLen < len(s) && child.Prefix[prefixLen] < s[i+prefixLen] {
+				return rightmost(child)
 			}
+			// Otherwise every key below child is greater than s
+			stack = append(stack, TrieEdge{parent: node, index: idx})
+			return before(stack)
 		}
 
-		if nextNode != nil {
-			return leftmost(nextNode)
-		}
+		stack = append(stack, TrieEdge{parent: node, index: idx})
+		node = child
+		i += prefixLen
 	}
 
-	return nil
+	if node.IsEndOfEntry {
+		return node
+	}
+	// Every key below node is greater than s
+	return before(stack)
 }
 
+// Successor returns the node right after the existing key s, nil if s is the greatest key
 func (r *Rax) Successor(s []byte) *RaxNode {
+	stack, node := r.descend(s)
+	if node == nil {
+		return nil // Should not happen if s is an existing key
+	}
+
+	// If node has children, the successor is the leftmost key in the children's subtrees
+	if len(node.Children) > 0 {
+		return leftmost(node.Children[0])
+	}
+	// Otherwise, backtrack to find a larger sibling
+	return after(stack)
+}
+
+// Predecessor returns the node right before the existing key s, nil if s is the smallest
+// key
+func (r *Rax) Predecessor(s []byte) *RaxNode {
+	stack, node := r.descend(s)
+	if node == nil {
+		return nil // Should not happen if s is an existing key
+	}
+	return before(stack)
+}
+
+// descend walks down to the node of the existing key s, returning the edges it took
+func (r *Rax) descend(s []byte) ([]TrieEdge, *RaxNode) {
 	var stack []TrieEdge
 	node := r.Root
 	i := 0
 
-	// Descend to the node
 	for i < len(s) {
-		child, ok := node.Children[s[i]]
+		idx, ok := node.child(s[i])
 		if !ok {
-			return nil // Should not happen if s is an existing key
+			return nil, nil
 		}
-		stack = append(stack, TrieEdge{parent: node, node: child, edge: s[i]})
-		node = child
-		i += len(child.Prefix)
+		stack = append(stack, TrieEdge{parent: node, index: idx})
+		node = node.Children[idx]
+		i += len(node.Prefix)
 	}
+	return stack, node
+}
 
-	// If node has children, the successor is the leftmost entry in the children's subtrees
-	if len(node.Children) > 0 {
-		var minKey int = 256
-		var nextNode *RaxNode
-		for k, c := range node.Children {
-			if int(k) < minKey {
-				minKey = int(k)
-				nextNode = c
-			}
+// after returns the smallest key greater than everything below the last edge of the stack:
+// the leftmost key under the next sibling edge, backtracking up the stack
+func after(stack []TrieEdge) *RaxNode {
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if top.index+1 < len(top.parent.Children) {
+			return leftmost(top.parent.Children[top.index+1])
 		}
-		return leftmost(nextNode)
 	}
 
-	// Otherwise, backtrack to find a larger sibling
+	return nil
+}
+
+// before returns the greatest key smaller than everything below the last edge of the
+// stack: the rightmost key under the previous sibling edge, or else the parent itself when
+// it is a key, which sorts before all its children, backtracking up the stack
+func before(stack []TrieEdge) *RaxNode {
 	for len(stack) > 0 {
 		top := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
 
-		parent := top.parent
-		currEdge := top.edge
-
-		var minKey int = 256
-		var nextNode *RaxNode
-
-		for k, c := range parent.Children {
-			if int(k) > int(currEdge) && int(k) < minKey {
-				minKey = int(k)
-				nextNode = c
-			}
+		if top.index > 0 {
+			return rightmost(top.parent.Children[top.index-1])
 		}
-
-		if nextNode != nil {
-			return leftmost(nextNode)
+		if top.parent.IsEndOfEntry {
+			return top.parent
 		}
 	}
 
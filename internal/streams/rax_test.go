@@ -0,0 +1,88 @@
+package streams
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// TestRaxSeek checks SeekGE, SeekLE, Successor and Predecessor against a sorted slice of
+// the same keys, with random deletions so nodes get split and merged, along with the key
+// and node counts
+func TestRaxSeek(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	s := NewEmptyStream()
+	var keys [][]byte
+	for range 500 {
+		id := &StreamID{Ms: rng.Uint64N(50), Seq: rng.Uint64N(20)}
+		key := id.InternalKey()
+		if s.Radix.SearchExact(key) != nil {
+			continue
+		}
+		s.Radix.Insert(key, &listpack{master: *id})
+		keys = append(keys, key)
+	}
+	for i := len(keys) - 1; i >= 0; i -= 3 {
+		if !s.Radix.Delete(keys[i]) {
+			t.Fatalf("Delete(%q) = false", keys[i])
+		}
+		keys = slices.Delete(keys, i, i+1)
+	}
+	slices.SortFunc(keys, bytes.Compare)
+
+	var countNodes func(n *RaxNode) int
+	countNodes = func(n *RaxNode) int {
+		total := 1
+		for _, c := range n.Children {
+			total += countNodes(c)
+		}
+		return total
+	}
+	if s.Radix.Len() != len(keys) || s.Radix.Nodes() != countNodes(s.Radix.Root) {
+		t.Errorf("Len() = %d, Nodes() = %d, want %d and %d", s.Radix.Len(), s.Radix.Nodes(), len(keys), countNodes(s.Radix.Root))
+	}
+
+	nodeKey := func(n *RaxNode) string {
+		if n == nil {
+			return "<nil>"
+		}
+		return string(n.Node.master.InternalKey())
+	}
+	keyAt := func(i int) string {
+		if i < 0 || i >= len(keys) {
+			return "<nil>"
+		}
+		return string(keys[i])
+	}
+
+	for i, key := range keys {
+		if got := nodeKey(s.Radix.Successor(key)); got != keyAt(i+1) {
+			t.Errorf("Successor(%q) = %q, want %q", key, got, keyAt(i+1))
+		}
+		if got := nodeKey(s.Radix.Predecessor(key)); got != keyAt(i-1) {
+			t.Errorf("Predecessor(%q) = %q, want %q", key, got, keyAt(i-1))
+		}
+	}
+
+	for ms := range uint64(52) {
+		for seq := range uint64(22) {
+			probe := (&StreamID{Ms: ms, Seq: seq}).InternalKey()
+			i, found := slices.BinarySearchFunc(keys, probe, bytes.Compare)
+			if got := nodeKey(s.Radix.SeekGE(probe)); got != keyAt(i) {
+				t.Errorf("SeekGE(%q) = %q, want %q", probe, got, keyAt(i))
+			}
+			want := keyAt(i - 1)
+			if found {
+				want = keyAt(i)
+			}
+			if got := nodeKey(s.Radix.SeekLE(probe)); got != want {
+				t.Errorf("SeekLE(%q) = %q, want %q", probe, got, want)
+			}
+		}
+	}
+
+	if first, last := nodeKey(s.Radix.First()), nodeKey(s.Radix.Last()); first != keyAt(0) || last != keyAt(len(keys)-1) {
+		t.Errorf("First() = %q, Last() = %q", first, last)
+	}
+}
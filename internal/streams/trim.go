@@ -0,0 +1,55 @@
+package streams
+
+// TrimMaxLen deletes the oldest entries until at most maxLen are left and returns how many
+// were deleted. See trim for approx and limit
+func (s *Stream) TrimMaxLen(maxLen int, approx bool, limit int) int {
+	return s.trim(func(kept int, _ StreamID) bool { return kept >= maxLen }, approx, limit)
+}
+
+// TrimMinID deletes the entries whose ID is smaller than minID and returns how many were
+// deleted. See trim for approx and limit
+func (s *Stream) TrimMinID(minID StreamID, approx bool, limit int) int {
+	return s.trim(func(_ int, last StreamID) bool { return last.Compare(&minID) < 0 }, approx, limit)
+}
+
+// trim deletes entries from the head of the stream. canDrop reports whether the entries up
+// to the one with ID last can go, kept being the number of entries that would be left.
+// Whole nodes go first. An approximate trim stops there, the way Redis only frees whole
+// nodes, so it is cheap but may keep a few entries too many; limit, when not zero, caps the
+// number of entries it deletes. An exact trim goes on deleting the entries of the first
+// node left one at a time
+func (s *Stream) trim(canDrop func(kept int, last StreamID) bool, approx bool, limit int) int {
+	deleted := 0
+	for node := s.Radix.First(); node != nil; node = s.Radix.First() {
+		lp := node.Node
+		last, _ := lp.last()
+		if limit > 0 && deleted+lp.count > limit || !canDrop(s.length-lp.count, last.id) {
+			break
+		}
+		s.Radix.Delete(lp.master.InternalKey())
+		s.length -= lp.count
+		deleted += lp.count
+	}
+	if approx {
+		return deleted
+	}
+
+	node := s.Radix.First()
+	if node == nil {
+		return deleted
+	}
+	lp := node.Node
+	for pos := 0; pos < len(lp.buf); {
+		e := lp.at(pos)
+		pos = e.next
+		if e.flags&flagDeleted != 0 {
+			continue
+		}
+		if !canDrop(s.length-1, e.id) {
+			break
+		}
+		s.drop(lp, e)
+		deleted++
+	}
+	return deleted
+}
@@ -0,0 +1,89 @@
+package streams
+
+import "testing"
+
+// newTestStream returns a stream with the entries 1-0 to n-0
+func newTestStream(n int) *Stream {
+	s := NewEmptyStream()
+	for i := 1; i <= n; i++ {
+		id := &StreamID{Ms: uint64(i)}
+		s.Insert(&StreamEntry{ID: id, Fields: []string{"f", "v"}})
+	}
+	return s
+}
+
+func TestStreamDelete(t *testing.T) {
+	s := newTestStream(5)
+	if !s.Delete(StreamID{Ms: 5}) || !s.Delete(StreamID{Ms: 1}) || !s.Delete(StreamID{Ms: 3}) {
+		t.Fatal("Delete() should find existing entries")
+	}
+	if s.Delete(StreamID{Ms: 3}) {
+		t.Error("Delete() of a deleted entry should report false")
+	}
+
+	if s.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", s.Len())
+	}
+	if first := s.FirstEntry(); first == nil || first.ID.Ms != 2 {
+		t.Errorf("FirstEntry() = %v, want 2-0", first)
+	}
+	if last := s.LastEntry(); last == nil || last.ID.Ms != 4 {
+		t.Errorf("LastEntry() = %v, want 4-0", last)
+	}
+	if got := s.LastID(); got != (StreamID{Ms: 5}) {
+		t.Errorf("LastID() = %v, want 5-0", got)
+	}
+	if got := s.MaxDeletedID(); got != (StreamID{Ms: 5}) {
+		t.Errorf("MaxDeletedID() = %v, want 5-0", got)
+	}
+	if s.EntriesAdded() != 5 {
+		t.Errorf("EntriesAdded() = %d, want 5", s.EntriesAdded())
+	}
+
+	s.Delete(StreamID{Ms: 2})
+	s.Delete(StreamID{Ms: 4})
+	if s.Len() != 0 || s.FirstEntry() != nil || s.LastEntry() != nil {
+		t.Errorf("stream should be empty, Len() = %d", s.Len())
+	}
+}
+
+func TestStreamTrim(t *testing.T) {
+	tests := []struct {
+		name      string
+		trim      func(s *Stream) int
+		wantDel   int
+		wantFirst uint64
+	}{
+		{"maxlen exact", func(s *Stream) int { return s.TrimMaxLen(10, false, 0) }, 240, 241},
+		{"maxlen zero", func(s *Stream) int { return s.TrimMaxLen(0, false, 0) }, 250, 0},
+		{"maxlen above length", func(s *Stream) int { return s.TrimMaxLen(300, false, 0) }, 0, 1},
+		{"maxlen approx", func(s *Stream) int { return s.TrimMaxLen(10, true, 0) }, 200, 201},
+		{"maxlen approx limit", func(s *Stream) int { return s.TrimMaxLen(10, true, 150) }, 100, 101},
+		{"minid exact", func(s *Stream) int { return s.TrimMinID(StreamID{Ms: 120}, false, 0) }, 119, 120},
+		{"minid approx", func(s *Stream) int { return s.TrimMinID(StreamID{Ms: 120}, true, 0) }, 100, 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStream(250)
+			if got := tt.trim(s); got != tt.wantDel {
+				t.Errorf("deleted %d entries, want %d", got, tt.wantDel)
+			}
+			if s.Len() != 250-tt.wantDel {
+				t.Errorf("Len() = %d, want %d", s.Len(), 250-tt.wantDel)
+			}
+			first := s.FirstEntry()
+			if tt.wantFirst == 0 {
+				if first != nil {
+					t.Errorf("FirstEntry() = %v, want none", first.ID)
+				}
+			} else if first == nil || first.ID.Ms != tt.wantFirst {
+				t.Errorf("FirstEntry() = %v, want %d-0", first, tt.wantFirst)
+			}
+			// Trimming isn't a deletion, it leaves the max deleted ID alone
+			if s.MaxDeletedID() != (StreamID{}) || s.LastID() != (StreamID{Ms: 250}) {
+				t.Errorf("MaxDeletedID() = %v, LastID() = %v", s.MaxDeletedID(), s.LastID())
+			}
+		})
+	}
+}
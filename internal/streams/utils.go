@@ -112,33 +112,14 @@ func (sid *StreamID) String() string {
 	return fmt.Sprintf("%d-%d", sid.Ms, sid.Seq)
 }
 
-func newRaxNode(isEnd bool, prefix []byte, entry *StreamEntry) *RaxNode {
-	return &RaxNode{
-		Entry:        entry,
-		IsEndOfEntry: isEnd,
-		Prefix:       prefix,
-		Children:     make(map[byte]*RaxNode),
-	}
-}
-
-func NewRadixTrie(prefix []byte, entry *StreamEntry) *Rax {
-	return &Rax{
-		Root: newRaxNode(true, prefix, entry),
-	}
-}
-
 func NewStream(entry *StreamEntry) *Stream {
 	s := NewEmptyStream()
-	s.Insert(entry, entry.ID.InternalKey())
+	s.Insert(entry)
 	return s
 }
 
 func NewEmptyStream() *Stream {
 	return &Stream{
-		Radix: &Rax{
-			Root: &RaxNode{
-				Children: make(map[byte]*RaxNode),
-			},
-		},
+		Radix: NewRax(),
 	}
 }
@@ -0,0 +1,100 @@
+package utils
+
+// MatchPattern reports whether s matches the glob-style pattern, with the same rules as
+// Redis: `*` matches any run of characters, `?` any single character, `[abc]`, `[^abc]`
+// and `[a-z]` a character class, and `\` escapes the next character
+func MatchPattern(pattern, s string) bool {
+	// When the pattern stops matching, the last star seen swallows one more character and
+	// matching resumes right after it. Earlier stars never need to be retried since the last
+	// one can absorb anything they could, which keeps matching O(len(pattern)*len(s))
+	star := false
+	var afterStar, starS string
+	for len(pattern) > 0 || len(s) > 0 {
+		if len(pattern) > 0 {
+			switch pattern[0] {
+			case '*':
+				// Collapse consecutive stars, a trailing one matches everything left
+				for len(pattern) > 1 && pattern[1] == '*' {
+					pattern = pattern[1:]
+				}
+				if len(pattern) == 1 {
+					return true
+				}
+				pattern = pattern[1:]
+				star, afterStar, starS = true, pattern, s
+				continue
+			case '?':
+				if len(s) > 0 {
+					s = s[1:]
+					pattern = pattern[1:]
+					continue
+				}
+			case '[':
+				if len(s) > 0 {
+					if matched, rest := matchClass(pattern[1:], s[0]); matched {
+						s = s[1:]
+						pattern = rest
+						continue
+					}
+				}
+			default:
+				p := pattern
+				if p[0] == '\\' && len(p) >= 2 {
+					p = p[1:]
+				}
+				if len(s) > 0 && p[0] == s[0] {
+					s = s[1:]
+					pattern = p[1:]
+					continue
+				}
+			}
+		}
+
+		if !star || len(starS) == 0 {
+			return false
+		}
+		starS = starS[1:]
+		pattern, s = afterStar, starS
+	}
+	return true
+}
+
+// matchClass matches c against the character class starting right after `[` and returns
+// the pattern following the closing `]`. An unterminated class extends to the end of the
+// pattern
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := false
+	if len(pattern) > 0 && pattern[0] == '^' {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	matched := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) >= 2:
+			if pattern[1] == c {
+				matched = true
+			}
+			pattern = pattern[2:]
+		case len(pattern) >= 3 && pattern[1] == '-':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			pattern = pattern[3:]
+		default:
+			if pattern[0] == c {
+				matched = true
+			}
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // closing ]
+	}
+	return matched != negate, pattern
+}
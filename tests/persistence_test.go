@@ -0,0 +1,508 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	buildOnce sync.Once
+	serverBin string
+	buildErr  error
+)
+
+// buildServer compiles the server once per test run so tests can start and restart it
+func buildServer(t *testing.T) string {
+	t.Helper()
+	buildOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "keyforge-bin")
+		if err != nil {
+			buildErr = err
+			return
+		}
+		serverBin = filepath.Join(dir, "keyforge")
+		out, err := exec.Command("go", "build", "-o", serverBin, "../app").CombinedOutput()
+		if err != nil {
+			buildErr = err
+			serverBin = string(out)
+		}
+	})
+	if buildErr != nil {
+		t.Fatalf("Failed to build server: %v\n%s", buildErr, serverBin)
+	}
+	return serverBin
+}
+
+// startServer runs a dedicated server process on port and returns a client for it together
+// with a function that kills the process, simulating a crash
+func startServer(t *testing.T, port int, args ...string) (*redis.Client, func()) {
+	t.Helper()
+	bin := buildServer(t)
+
+	cmd := exec.Command(bin, append([]string{"-port", strconv.Itoa(port)}, args...)...)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{Addr: "localhost:" + strconv.Itoa(port)})
+	stop := func() {
+		client.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+
+	ctx := context.Background()
+	deadline := time.Now().Add(5 * time.Second)
+	for client.Ping(ctx).Err() != nil {
+		if time.Now().After(deadline) {
+			stop()
+			t.Fatalf("Server on port %d did not become ready", port)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return client, stop
+}
+
+// =============================================================================
+// RDB Persistence Tests
+// =============================================================================
+
+// TestRDBRoundTrip writes keys of every type, saves, restarts the server and checks that
+// the same data is loaded back
+func TestRDBRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-dir", dir, "-dbfilename", "roundtrip.rdb"}
+	ctx := context.Background()
+
+	client, stop := startServer(t, 6390, args...)
+
+	client.Set(ctx, "rdb:string", "hello", 0)
+	client.Set(ctx, "rdb:number", "12345", 0)
+	client.Set(ctx, "rdb:ttl", "expiring", time.Hour)
+	client.Set(ctx, "rdb:gone", "short", 100*time.Millisecond)
+
+	items := make([]interface{}, 0, 300)
+	for i := 0; i < 300; i++ {
+		items = append(items, "item-"+strconv.Itoa(i))
+	}
+	client.RPush(ctx, "rdb:list", items...)
+	client.HSet(ctx, "rdb:hash", "name", "alice", "age", "30", "empty", "")
+	client.SAdd(ctx, "rdb:intset", 1, 2, 70000, -5)
+	client.SAdd(ctx, "rdb:set", "red", "green", 7)
+	client.ZAdd(ctx, "rdb:zset", redis.Z{Score: 2.5, Member: "b"}, redis.Z{Score: -1, Member: "a"},
+		redis.Z{Score: math.Inf(1), Member: "top"}, redis.Z{Score: 0.1, Member: "tenth"})
+
+	for i := 1; i <= 250; i++ {
+		values := map[string]interface{}{"n": i}
+		if i%3 == 0 {
+			values["extra"] = "field"
+		}
+		client.XAdd(ctx, &redis.XAddArgs{Stream: "rdb:stream", ID: "1000-" + strconv.Itoa(i), Values: values})
+	}
+
+	client.XGroupCreate(ctx, "rdb:stream", "workers", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "workers", Consumer: "alice", Count: 2, Streams: []string{"rdb:stream", ">"}, Block: -1})
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "workers", Consumer: "bob", Count: 1, Streams: []string{"rdb:stream", ">"}, Block: -1})
+	client.XGroupCreateConsumer(ctx, "rdb:stream", "workers", "idle")
+	client.XGroupCreateMkStream(ctx, "rdb:empty", "g", "$")
+	for i := 1; i <= 3; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: "rdb:trimmed", ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XDel(ctx, "rdb:trimmed", "1-3")
+	client.XTrimMaxLen(ctx, "rdb:trimmed", 1)
+
+	time.Sleep(150 * time.Millisecond) // let rdb:gone expire before saving
+	if err := client.Do(ctx, "SAVE").Err(); err != nil {
+		t.Fatalf("SAVE failed: %v", err)
+	}
+	stop()
+
+	if _, err := os.Stat(filepath.Join(dir, "roundtrip.rdb")); err != nil {
+		t.Fatalf("RDB file was not written: %v", err)
+	}
+
+	client, stop = startServer(t, 6390, args...)
+	defer stop()
+
+	if val, err := client.Get(ctx, "rdb:string").Result(); err != nil || val != "hello" {
+		t.Errorf("Expected rdb:string = hello, got %q (%v)", val, err)
+	}
+	if val, err := client.Get(ctx, "rdb:number").Result(); err != nil || val != "12345" {
+		t.Errorf("Expected rdb:number = 12345, got %q (%v)", val, err)
+	}
+	if val, err := client.Get(ctx, "rdb:ttl").Result(); err != nil || val != "expiring" {
+		t.Errorf("Expected rdb:ttl = expiring, got %q (%v)", val, err)
+	}
+	if _, err := client.Get(ctx, "rdb:gone").Result(); err != redis.Nil {
+		t.Errorf("Expected rdb:gone to have expired, got %v", err)
+	}
+
+	list, err := client.LRange(ctx, "rdb:list", 0, -1).Result()
+	if err != nil {
+		t.Fatalf("LRANGE failed: %v", err)
+	}
+	if len(list) != 300 || list[0] != "item-0" || list[299] != "item-299" {
+		t.Errorf("List was not restored correctly: %d items", len(list))
+	}
+
+	hash, err := client.HGetAll(ctx, "rdb:hash").Result()
+	if err != nil || len(hash) != 3 || hash["name"] != "alice" || hash["age"] != "30" || hash["empty"] != "" {
+		t.Errorf("Hash was not restored correctly: %v (%v)", hash, err)
+	}
+
+	for key, want := range map[string]string{"rdb:intset": "-5,1,2,70000", "rdb:set": "7,green,red"} {
+		members, err := client.SMembers(ctx, key).Result()
+		sort.Strings(members)
+		if err != nil || strings.Join(members, ",") != want {
+			t.Errorf("Set %s was not restored correctly: %v (%v)", key, members, err)
+		}
+	}
+	if enc, _ := client.ObjectEncoding(ctx, "rdb:intset").Result(); enc != "intset" {
+		t.Errorf("Expected rdb:intset to keep the intset encoding, got %s", enc)
+	}
+
+	zset, err := client.ZRangeWithScores(ctx, "rdb:zset", 0, -1).Result()
+	if err != nil || len(zset) != 4 || zset[0].Member != "a" || zset[1].Score != 0.1 || zset[2].Score != 2.5 || !math.IsInf(zset[3].Score, 1) {
+		t.Errorf("Sorted set was not restored correctly: %v (%v)", zset, err)
+	}
+
+	entries, err := client.XRange(ctx, "rdb:stream", "-", "+").Result()
+	if err != nil {
+		t.Fatalf("XRANGE failed: %v", err)
+	}
+	if len(entries) != 250 {
+		t.Fatalf("Expected 250 stream entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ID != "1000-"+strconv.Itoa(i+1) {
+			t.Errorf("Entry %d: expected ID 1000-%d, got %s", i, i+1, entry.ID)
+		}
+		if entry.Values["n"] != strconv.Itoa(i+1) {
+			t.Errorf("Entry %s: expected n=%d, got %v", entry.ID, i+1, entry.Values["n"])
+		}
+		if _, ok := entry.Values["extra"]; ok != ((i+1)%3 == 0) {
+			t.Errorf("Entry %s: unexpected fields %v", entry.ID, entry.Values)
+		}
+	}
+
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: "rdb:stream", Group: "workers", Start: "-", End: "+", Count: 10}).Result()
+	if err != nil || len(pending) != 3 || pending[0].Consumer != "alice" || pending[2].ID != "1000-3" || pending[2].Consumer != "bob" || pending[0].Idle < 150*time.Millisecond {
+		t.Errorf("Consumer group PEL was not restored correctly: %v (%v)", pending, err)
+	}
+	res, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "workers", Consumer: "idle", Count: 1, Streams: []string{"rdb:stream", ">"}, Block: -1}).Result()
+	if err != nil || res[0].Messages[0].ID != "1000-4" {
+		t.Errorf("Expected the group to resume after 1000-3, got %v (%v)", res, err)
+	}
+	if summary, err := client.XPending(ctx, "rdb:empty", "g").Result(); err != nil || summary.Count != 0 {
+		t.Errorf("Expected the empty stream and its group to be restored, got %v (%v)", summary, err)
+	}
+
+	// New entries must still be ordered after the restored ones
+	if _, err := client.XAdd(ctx, &redis.XAddArgs{Stream: "rdb:stream", ID: "1000-1", Values: map[string]interface{}{"a": "b"}}).Result(); err == nil {
+		t.Error("XADD with an ID smaller than the restored top item should fail")
+	}
+	if n, _ := client.XLen(ctx, "rdb:trimmed").Result(); n != 1 {
+		t.Errorf("Expected 1 entry left in rdb:trimmed, got %d", n)
+	}
+	if id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: "rdb:trimmed", ID: "1-*", Values: []interface{}{"n", 4}}).Result(); err != nil || id != "1-4" {
+		t.Errorf("Expected the deleted top item to keep its ID, got %s (%v)", id, err)
+	}
+}
+
+// TestBGSaveAndLastSave tests BGSAVE and that LASTSAVE advances once it completes
+func TestBGSaveAndLastSave(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	client, stop := startServer(t, 6391, "-dir", dir, "-dbfilename", "bg.rdb")
+	defer stop()
+
+	before, err := client.LastSave(ctx).Result()
+	if err != nil {
+		t.Fatalf("LASTSAVE failed: %v", err)
+	}
+
+	client.Set(ctx, "bg:key", "value", 0)
+	time.Sleep(1100 * time.Millisecond) // LASTSAVE has a resolution of one second
+
+	status, err := client.BgSave(ctx).Result()
+	if err != nil {
+		t.Fatalf("BGSAVE failed: %v", err)
+	}
+	if status != "Background saving started" {
+		t.Errorf("Unexpected BGSAVE reply: %s", status)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after, err := client.LastSave(ctx).Result()
+		if err != nil {
+			t.Fatalf("LASTSAVE failed: %v", err)
+		}
+		if after > before {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("LASTSAVE did not advance after BGSAVE")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "bg.rdb")); err != nil {
+		t.Errorf("RDB file was not written: %v", err)
+	}
+}
+
+// =============================================================================
+// AOF Persistence Tests
+// =============================================================================
+
+// TestAOFReplay checks that writes are logged and replayed after a crash
+func TestAOFReplay(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-dir", dir, "-appendonly", "yes", "-appendfsync", "always"}
+	ctx := context.Background()
+
+	client, stop := startServer(t, 6392, args...)
+
+	client.Set(ctx, "aof:string", "hello", 0)
+	client.Set(ctx, "aof:ttl", "expiring", time.Hour)
+	client.Set(ctx, "aof:gone", "short", 100*time.Millisecond)
+	client.Set(ctx, "aof:deleted", "value", 0)
+	client.Del(ctx, "aof:deleted")
+	client.RPush(ctx, "aof:list", "a", "b", "c", "d")
+	client.LPush(ctx, "aof:list", "z")
+	client.LPop(ctx, "aof:list")
+	client.LPopCount(ctx, "aof:list", 2)
+	client.Expire(ctx, "aof:list", time.Hour)
+	client.RPush(ctx, "aof:queue", "1", "2", "3")
+	client.BLMove(ctx, "aof:queue", "aof:processing", "LEFT", "LEFT", time.Second)
+	client.LMPop(ctx, "RIGHT", 1, "aof:queue")
+	id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:stream", Values: []interface{}{"field", "value"}}).Result()
+	if err != nil {
+		t.Fatalf("XADD failed: %v", err)
+	}
+	id2 := client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:stream", Values: []interface{}{"field", "other"}}).Val()
+	client.XGroupCreate(ctx, "aof:stream", "g", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Streams: []string{"aof:stream", ">"}, Block: -1})
+	client.XAck(ctx, "aof:stream", "g", id)
+	client.XClaim(ctx, &redis.XClaimArgs{Stream: "aof:stream", Group: "g", Consumer: "bob", Messages: []string{id2}})
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:trimmed", MaxLen: 3, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XDel(ctx, "aof:trimmed", "1-5")
+	client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "aof:tx:string", "both", 0)
+		pipe.SAdd(ctx, "aof:tx:set", "or", "neither")
+		return nil
+	})
+	stop()
+
+	time.Sleep(150 * time.Millisecond) // aof:gone must not come back after the restart
+	client, stop = startServer(t, 6392, args...)
+	defer stop()
+
+	if val, err := client.Get(ctx, "aof:string").Result(); err != nil || val != "hello" {
+		t.Errorf("Expected aof:string = hello, got %q (%v)", val, err)
+	}
+	if val, err := client.Get(ctx, "aof:ttl").Result(); err != nil || val != "expiring" {
+		t.Errorf("Expected aof:ttl = expiring, got %q (%v)", val, err)
+	}
+	if _, err := client.Get(ctx, "aof:gone").Result(); err != redis.Nil {
+		t.Errorf("Expected aof:gone to have expired, got %v", err)
+	}
+	if _, err := client.Get(ctx, "aof:deleted").Result(); err != redis.Nil {
+		t.Errorf("Expected aof:deleted to stay deleted, got %v", err)
+	}
+
+	list, err := client.LRange(ctx, "aof:list", 0, -1).Result()
+	if err != nil || len(list) != 2 || list[0] != "c" || list[1] != "d" {
+		t.Errorf("Expected aof:list = [c d], got %v (%v)", list, err)
+	}
+	if ttl, err := client.TTL(ctx, "aof:list").Result(); err != nil || ttl <= 59*time.Minute {
+		t.Errorf("Expected aof:list to keep its ttl, got %v (%v)", ttl, err)
+	}
+
+	queue, _ := client.LRange(ctx, "aof:queue", 0, -1).Result()
+	processing, _ := client.LRange(ctx, "aof:processing", 0, -1).Result()
+	if fmt.Sprint(queue, processing) != "[2] [1]" {
+		t.Errorf("Expected aof:queue = [2] and aof:processing = [1], got %v and %v", queue, processing)
+	}
+
+	entries, err := client.XRange(ctx, "aof:stream", "-", "+").Result()
+	if err != nil || len(entries) != 2 || entries[0].ID != id || entries[1].ID != id2 {
+		t.Errorf("Expected entries %s and %s, got %v (%v)", id, id2, entries, err)
+	}
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: "aof:stream", Group: "g", Start: "-", End: "+", Count: 10}).Result()
+	if err != nil || len(pending) != 1 || pending[0].ID != id2 || pending[0].Consumer != "bob" || pending[0].RetryCount != 2 {
+		t.Errorf("Expected %s to be pending for bob with 2 deliveries, got %v (%v)", id2, pending, err)
+	}
+	trimmed, err := client.XRange(ctx, "aof:trimmed", "-", "+").Result()
+	if err != nil || len(trimmed) != 2 || trimmed[0].ID != "1-3" || trimmed[1].ID != "1-4" {
+		t.Errorf("Expected aof:trimmed to hold 1-3 and 1-4, got %v (%v)", trimmed, err)
+	}
+
+	if val, _ := client.Get(ctx, "aof:tx:string").Result(); val != "both" || client.SCard(ctx, "aof:tx:set").Val() != 2 {
+		t.Error("Expected the writes of the transaction to be replayed")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "appendonly.aof"))
+	if err != nil || !strings.Contains(string(data), "MULTI") || !strings.Contains(string(data), "EXEC") {
+		t.Errorf("Expected the transaction to be logged inside MULTI and EXEC (%v)", err)
+	}
+}
+
+// TestAOFTruncatedTail checks that a command cut off by a crash is dropped on load
+func TestAOFTruncatedTail(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-dir", dir, "-appendonly", "yes", "-appendfsync", "always"}
+	ctx := context.Background()
+
+	client, stop := startServer(t, 6393, args...)
+	client.Set(ctx, "aof:complete", "yes", 0)
+	stop()
+
+	path := filepath.Join(dir, "appendonly.aof")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open the AOF: %v", err)
+	}
+	f.WriteString("*3\r\n$3\r\nSET\r\n$7\r\naof:par")
+	f.Close()
+
+	client, stop = startServer(t, 6393, args...)
+	defer stop()
+
+	if val, err := client.Get(ctx, "aof:complete").Result(); err != nil || val != "yes" {
+		t.Errorf("Expected aof:complete = yes, got %q (%v)", val, err)
+	}
+
+	// New writes must be appended after the last complete command, not after the garbage
+	client.Set(ctx, "aof:after", "ok", 0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read the AOF: %v", err)
+	}
+	if strings.Contains(string(data), "aof:par") {
+		t.Error("Truncated command was not removed from the AOF")
+	}
+}
+
+// TestBGRewriteAOF checks that the rewritten file is smaller and still holds every write,
+// including the ones made after the rewrite started
+func TestBGRewriteAOF(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{"-dir", dir, "-appendonly", "yes"}
+	ctx := context.Background()
+	path := filepath.Join(dir, "appendonly.aof")
+
+	client, stop := startServer(t, 6394, args...)
+
+	for i := 0; i < 200; i++ {
+		client.Set(ctx, "aof:counter", strconv.Itoa(i), 0)
+		client.RPush(ctx, "aof:queue", "job-"+strconv.Itoa(i))
+		client.LPop(ctx, "aof:queue")
+	}
+	client.RPush(ctx, "aof:queue", "last")
+	for i := 0; i < 100; i++ {
+		client.HSet(ctx, "aof:hash", "field-"+strconv.Itoa(i), i)
+		client.HIncrByFloat(ctx, "aof:hash", "total", 0.1)
+		client.SAdd(ctx, "aof:set", "member-"+strconv.Itoa(i))
+		client.ZIncrBy(ctx, "aof:zset", 0.5, "member-"+strconv.Itoa(i%10))
+	}
+	client.SPopN(ctx, "aof:set", 40)
+	for i := 1; i <= 3; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:stream", ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, "aof:stream", "g", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Count: 2, Streams: []string{"aof:stream", ">"}, Block: -1})
+	client.XGroupCreateConsumer(ctx, "aof:stream", "g", "bob")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:emptied", ID: "1-1", Values: []interface{}{"n", 1}})
+	client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:emptied", ID: "1-2", Values: []interface{}{"n", 2}})
+	client.XDel(ctx, "aof:emptied", "1-1", "1-2")
+	client.ZPopMin(ctx, "aof:zset", 2)
+	time.Sleep(1100 * time.Millisecond) // let everysec flush the file
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("AOF was not written: %v", err)
+	}
+
+	status, err := client.BgRewriteAOF(ctx).Result()
+	if err != nil {
+		t.Fatalf("BGREWRITEAOF failed: %v", err)
+	}
+	if status != "Background append only file rewriting started" {
+		t.Errorf("Unexpected BGREWRITEAOF reply: %s", status)
+	}
+	client.Set(ctx, "aof:during", "rewrite", 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after, err := os.Stat(path)
+		if err == nil && after.Size() < before.Size() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("AOF was not rewritten")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	client.Set(ctx, "aof:after", "rewrite", 0)
+	time.Sleep(1100 * time.Millisecond)
+	stop()
+
+	client, stop = startServer(t, 6394, args...)
+	defer stop()
+
+	if val, err := client.Get(ctx, "aof:counter").Result(); err != nil || val != "199" {
+		t.Errorf("Expected aof:counter = 199, got %q (%v)", val, err)
+	}
+	for _, key := range []string{"aof:during", "aof:after"} {
+		if val, err := client.Get(ctx, key).Result(); err != nil || val != "rewrite" {
+			t.Errorf("Expected %s = rewrite, got %q (%v)", key, val, err)
+		}
+	}
+	list, err := client.LRange(ctx, "aof:queue", 0, -1).Result()
+	if err != nil || len(list) != 1 || list[0] != "last" {
+		t.Errorf("Expected aof:queue = [last], got %v (%v)", list, err)
+	}
+	hash, err := client.HGetAll(ctx, "aof:hash").Result()
+	if err != nil || len(hash) != 101 || hash["field-99"] != "99" {
+		t.Errorf("Expected aof:hash to have 101 fields, got %d (%v)", len(hash), err)
+	}
+	if total, _ := strconv.ParseFloat(hash["total"], 64); total < 9.99 || total > 10.01 {
+		t.Errorf("Expected aof:hash total to be about 10, got %s", hash["total"])
+	}
+	if n, err := client.SCard(ctx, "aof:set").Result(); err != nil || n != 60 {
+		t.Errorf("Expected aof:set to have 60 members, got %d (%v)", n, err)
+	}
+	zset, err := client.ZRangeWithScores(ctx, "aof:zset", 0, -1).Result()
+	if err != nil || len(zset) != 8 || zset[0].Member != "member-2" || zset[0].Score != 5 {
+		t.Errorf("Expected aof:zset to have 8 members scored 5, got %v (%v)", zset, err)
+	}
+	summary, err := client.XPending(ctx, "aof:stream", "g").Result()
+	if err != nil || summary.Count != 2 || summary.Higher != "1-2" || summary.Consumers["alice"] != 2 {
+		t.Errorf("Expected 1-1 and 1-2 to be pending for alice, got %+v (%v)", summary, err)
+	}
+	if n, _ := client.XGroupCreateConsumer(ctx, "aof:stream", "g", "bob").Result(); n != 0 {
+		t.Error("Expected the consumer bob to be rewritten")
+	}
+	if n, err := client.XLen(ctx, "aof:emptied").Result(); err != nil || n != 0 {
+		t.Errorf("Expected aof:emptied to be rewritten empty, got %d (%v)", n, err)
+	}
+	if id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: "aof:emptied", ID: "1-*", Values: []interface{}{"n", 3}}).Result(); err != nil || id != "1-3" {
+		t.Errorf("Expected aof:emptied to keep its last ID, got %s (%v)", id, err)
+	}
+}
@@ -1,11 +1,21 @@
 package tests
 
 import (
+	"bufio"
 	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/internal/parser"
+	"github.com/codecrafters-io/redis-starter-go/internal/resp"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -778,6 +788,14 @@ func TestBLPopTimeout(t *testing.T) {
 	if elapsed < 900*time.Millisecond {
 		t.Errorf("BLPOP returned too quickly: %v", elapsed)
 	}
+
+	// A negative or malformed timeout is rejected instead of blocking forever
+	for _, timeout := range []string{"-1", "soon"} {
+		err := client.Do(ctx, "BLPOP", "non:existent:blpop:key", timeout).Err()
+		if err == nil || !strings.HasPrefix(err.Error(), "ERR timeout is") {
+			t.Errorf("Expected BLPOP with timeout %s to fail, got %v", timeout, err)
+		}
+	}
 }
 
 // TestBLPopBlocking tests BLPOP blocking behavior with concurrent push
@@ -1198,291 +1216,4169 @@ func TestListAsStack(t *testing.T) {
 	client.Del(ctx, key)
 }
 
-// =============================================================================
-// Pub/Sub Tests
-// =============================================================================
-
-// TestPubSubBasic tests basic publish/subscribe functionality
-func TestPubSubBasic(t *testing.T) {
-	publisher := newTestClient()
-	subscriber := newTestClient()
-	defer publisher.Close()
-	defer subscriber.Close()
+// TestRPop tests popping from the tail, with and without a count
+func TestRPop(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
 	ctx := context.Background()
 
-	channel := "test:pubsub:basic"
-	message := "Hello, PubSub!"
-
-	// Subscribe to channel
-	pubsub := subscriber.Subscribe(ctx, channel)
-	defer pubsub.Close()
+	key := "test:list:rpop"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
 
-	// Wait for subscription confirmation
-	_, err := pubsub.Receive(ctx)
-	if err != nil {
-		t.Fatalf("Failed to receive subscription confirmation: %v", err)
+	client.RPush(ctx, key, "a", "b", "c", "d")
+	if val, err := client.RPop(ctx, key).Result(); err != nil || val != "d" {
+		t.Errorf("Expected d, got %q (%v)", val, err)
+	}
+	if vals, _ := client.RPopCount(ctx, key, 2).Result(); fmt.Sprint(vals) != "[c b]" {
+		t.Errorf("Expected [c b], got %v", vals)
+	}
+	client.RPop(ctx, key)
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected the list to be deleted once empty")
 	}
+	if _, err := client.RPop(ctx, key).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing list, got %v", err)
+	}
+}
 
-	// Publish message
-	receiversCount, err := publisher.Publish(ctx, channel, message).Result()
-	if err != nil {
-		t.Fatalf("PUBLISH failed: %v", err)
+// TestLIndexAndLSet tests reading and replacing elements by index
+func TestLIndexAndLSet(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:list:lindex"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	client.RPush(ctx, key, "a", "b", "c")
+	if val, _ := client.LIndex(ctx, key, -1).Result(); val != "c" {
+		t.Errorf("Expected LINDEX -1 = c, got %s", val)
 	}
-	if receiversCount != 1 {
-		t.Errorf("Expected 1 receiver, got %d", receiversCount)
+	if _, err := client.LIndex(ctx, key, 3).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for an index out of range, got %v", err)
 	}
 
-	// Receive the message
-	msg, err := pubsub.ReceiveMessage(ctx)
-	if err != nil {
-		t.Fatalf("Failed to receive message: %v", err)
+	if err := client.LSet(ctx, key, -2, "B").Err(); err != nil {
+		t.Errorf("LSET failed: %v", err)
 	}
-	if msg.Channel != channel {
-		t.Errorf("Expected channel %s, got %s", channel, msg.Channel)
+	if val, _ := client.LIndex(ctx, key, 1).Result(); val != "B" {
+		t.Errorf("Expected LINDEX 1 = B after LSET, got %s", val)
 	}
-	if msg.Payload != message {
-		t.Errorf("Expected message %s, got %s", message, msg.Payload)
+	if err := client.LSet(ctx, key, 5, "x").Err(); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Errorf("Expected an out of range error, got %v", err)
+	}
+	if err := client.LSet(ctx, "test:list:missing", 0, "x").Err(); err == nil || !strings.Contains(err.Error(), "no such key") {
+		t.Errorf("Expected a no such key error, got %v", err)
 	}
 }
 
-// TestPubSubMultipleSubscribers tests publishing to multiple subscribers
-func TestPubSubMultipleSubscribers(t *testing.T) {
-	publisher := newTestClient()
-	sub1 := newTestClient()
-	sub2 := newTestClient()
-	defer publisher.Close()
-	defer sub1.Close()
-	defer sub2.Close()
+// TestLInsert tests inserting around a pivot
+func TestLInsert(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
 	ctx := context.Background()
 
-	channel := "test:pubsub:multi"
-
-	// Subscribe both clients
-	pubsub1 := sub1.Subscribe(ctx, channel)
-	pubsub2 := sub2.Subscribe(ctx, channel)
-	defer pubsub1.Close()
-	defer pubsub2.Close()
-
-	// Wait for subscription confirmations
-	pubsub1.Receive(ctx)
-	pubsub2.Receive(ctx)
+	key := "test:list:linsert"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
 
-	// Publish
-	receiversCount, err := publisher.Publish(ctx, channel, "broadcast").Result()
-	if err != nil {
-		t.Fatalf("PUBLISH failed: %v", err)
+	client.RPush(ctx, key, "a", "c", "c")
+	if n, _ := client.LInsertBefore(ctx, key, "c", "b").Result(); n != 4 {
+		t.Errorf("Expected length 4, got %d", n)
 	}
-	if receiversCount != 2 {
-		t.Errorf("Expected 2 receivers, got %d", receiversCount)
+	if n, _ := client.LInsertAfter(ctx, key, "c", "d").Result(); n != 5 {
+		t.Errorf("Expected length 5, got %d", n)
 	}
-
-	// Both should receive
-	msg1, _ := pubsub1.ReceiveMessage(ctx)
-	msg2, _ := pubsub2.ReceiveMessage(ctx)
-
-	if msg1.Payload != "broadcast" {
-		t.Errorf("Subscriber 1: expected 'broadcast', got '%s'", msg1.Payload)
+	if vals, _ := client.LRange(ctx, key, 0, -1).Result(); fmt.Sprint(vals) != "[a b c d c]" {
+		t.Errorf("Expected [a b c d c], got %v", vals)
 	}
-	if msg2.Payload != "broadcast" {
-		t.Errorf("Subscriber 2: expected 'broadcast', got '%s'", msg2.Payload)
+	if n, _ := client.LInsertAfter(ctx, key, "x", "y").Result(); n != -1 {
+		t.Errorf("Expected -1 for a missing pivot, got %d", n)
+	}
+	if n, _ := client.LInsertAfter(ctx, "test:list:missing", "x", "y").Result(); n != 0 {
+		t.Errorf("Expected 0 for a missing key, got %d", n)
 	}
 }
 
-// TestPubSubMultipleChannels tests subscribing to multiple channels
-func TestPubSubMultipleChannels(t *testing.T) {
-	publisher := newTestClient()
-	subscriber := newTestClient()
-	defer publisher.Close()
-	defer subscriber.Close()
+// TestLRem tests removing occurrences from the head, the tail or everywhere
+func TestLRem(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
 	ctx := context.Background()
 
-	ch1 := "test:pubsub:ch1"
-	ch2 := "test:pubsub:ch2"
-
-	// Subscribe to both channels
-	pubsub := subscriber.Subscribe(ctx, ch1, ch2)
-	defer pubsub.Close()
-
-	// Wait for both subscription confirmations
-	pubsub.Receive(ctx)
-	pubsub.Receive(ctx)
+	key := "test:list:lrem"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
 
-	// Publish to channel 1
-	publisher.Publish(ctx, ch1, "message1")
-	msg, _ := pubsub.ReceiveMessage(ctx)
-	if msg.Channel != ch1 || msg.Payload != "message1" {
-		t.Errorf("Expected message1 on %s, got %s on %s", ch1, msg.Payload, msg.Channel)
+	client.RPush(ctx, key, "x", "a", "x", "b", "x", "x")
+	if n, _ := client.LRem(ctx, key, 1, "x").Result(); n != 1 {
+		t.Errorf("Expected 1 removed, got %d", n)
+	}
+	if n, _ := client.LRem(ctx, key, -2, "x").Result(); n != 2 {
+		t.Errorf("Expected 2 removed, got %d", n)
+	}
+	if vals, _ := client.LRange(ctx, key, 0, -1).Result(); fmt.Sprint(vals) != "[a x b]" {
+		t.Errorf("Expected [a x b], got %v", vals)
 	}
 
-	// Publish to channel 2
-	publisher.Publish(ctx, ch2, "message2")
-	msg, _ = pubsub.ReceiveMessage(ctx)
-	if msg.Channel != ch2 || msg.Payload != "message2" {
-		t.Errorf("Expected message2 on %s, got %s on %s", ch2, msg.Payload, msg.Channel)
+	client.LRem(ctx, key, 0, "a")
+	client.LRem(ctx, key, 0, "b")
+	if n, _ := client.LRem(ctx, key, 0, "x").Result(); n != 1 {
+		t.Errorf("Expected 1 removed, got %d", n)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected the list to be deleted once empty")
 	}
 }
 
-// TestPubSubUnsubscribe tests unsubscribing from channels
-func TestPubSubUnsubscribe(t *testing.T) {
-	publisher := newTestClient()
-	subscriber := newTestClient()
-	defer publisher.Close()
-	defer subscriber.Close()
+// TestLTrim tests trimming with positive, negative and out of range indexes
+func TestLTrim(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
 	ctx := context.Background()
 
-	channel := "test:pubsub:unsub"
+	key := "test:list:ltrim"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
 
-	// Subscribe
-	pubsub := subscriber.Subscribe(ctx, channel)
-	defer pubsub.Close()
-	pubsub.Receive(ctx)
+	tests := []struct {
+		start, stop int64
+		want        string
+	}{
+		{1, -2, "[b c d]"},
+		{-100, 100, "[a b c d e]"},
+		{-2, -1, "[d e]"},
+		{0, 0, "[a]"},
+	}
+	for _, tt := range tests {
+		client.Del(ctx, key)
+		client.RPush(ctx, key, "a", "b", "c", "d", "e")
+		if err := client.LTrim(ctx, key, tt.start, tt.stop).Err(); err != nil {
+			t.Errorf("LTRIM %d %d failed: %v", tt.start, tt.stop, err)
+		}
+		if vals, _ := client.LRange(ctx, key, 0, -1).Result(); fmt.Sprint(vals) != tt.want {
+			t.Errorf("LTRIM %d %d left %v, want %s", tt.start, tt.stop, vals, tt.want)
+		}
+	}
 
-	// Initial publish should reach subscriber
-	receiversCount, _ := publisher.Publish(ctx, channel, "before unsub").Result()
-	if receiversCount != 1 {
-		t.Errorf("Expected 1 receiver before unsub, got %d", receiversCount)
+	client.LTrim(ctx, key, 5, 10)
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected an empty range to delete the list")
 	}
+}
 
-	// Consume the message
-	pubsub.ReceiveMessage(ctx)
+// TestLPos tests finding elements with RANK, COUNT and MAXLEN
+func TestLPos(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
 
-	// Unsubscribe
-	err := pubsub.Unsubscribe(ctx, channel)
-	if err != nil {
-		t.Fatalf("UNSUBSCRIBE failed: %v", err)
+	key := "test:list:lpos"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	client.RPush(ctx, key, "a", "b", "c", "1", "2", "3", "c", "c")
+	if pos, _ := client.LPos(ctx, key, "c", redis.LPosArgs{}).Result(); pos != 2 {
+		t.Errorf("Expected 2, got %d", pos)
+	}
+	if pos, _ := client.LPos(ctx, key, "c", redis.LPosArgs{Rank: -1}).Result(); pos != 7 {
+		t.Errorf("Expected 7 with RANK -1, got %d", pos)
+	}
+	if pos, _ := client.LPos(ctx, key, "c", redis.LPosArgs{Rank: 2}).Result(); pos != 6 {
+		t.Errorf("Expected 6 with RANK 2, got %d", pos)
+	}
+	if _, err := client.LPos(ctx, key, "x", redis.LPosArgs{}).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing element, got %v", err)
 	}
 
-	// Wait a bit for unsubscribe to process
-	time.Sleep(100 * time.Millisecond)
+	if all, _ := client.LPosCount(ctx, key, "c", 0, redis.LPosArgs{}).Result(); fmt.Sprint(all) != "[2 6 7]" {
+		t.Errorf("Expected [2 6 7] with COUNT 0, got %v", all)
+	}
+	if some, _ := client.LPosCount(ctx, key, "c", 2, redis.LPosArgs{Rank: -1}).Result(); fmt.Sprint(some) != "[7 6]" {
+		t.Errorf("Expected [7 6] with COUNT 2 RANK -1, got %v", some)
+	}
+	if some, _ := client.LPosCount(ctx, key, "c", 0, redis.LPosArgs{MaxLen: 5}).Result(); fmt.Sprint(some) != "[2]" {
+		t.Errorf("Expected [2] with MAXLEN 5, got %v", some)
+	}
 
-	// Publish after unsubscribe should reach 0 subscribers
-	receiversCount, _ = publisher.Publish(ctx, channel, "after unsub").Result()
-	if receiversCount != 0 {
-		t.Errorf("Expected 0 receivers after unsub, got %d", receiversCount)
+	for _, args := range [][]interface{}{
+		{"LPOS", key, "c", "RANK", 0},
+		{"LPOS", key, "c", "COUNT", -1},
+		{"LPOS", key, "c", "MAXLEN", -1},
+		{"LPOS", key, "c", "COUNT"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+	if err := client.Do(ctx, "LPOS", key, "c", "RANK", "-9223372036854775808").Err(); err == nil || !strings.HasPrefix(err.Error(), "ERR value is out of range") {
+		t.Errorf("Expected the smallest RANK to be out of range, got %v", err)
 	}
 }
 
-// TestPubSubPublishNoSubscribers tests publishing with no subscribers
-func TestPubSubPublishNoSubscribers(t *testing.T) {
+// TestPushX tests that LPUSHX and RPUSHX only push to existing lists
+func TestPushX(t *testing.T) {
 	client := newTestClient()
 	defer client.Close()
 	ctx := context.Background()
 
-	receiversCount, err := client.Publish(ctx, "no:subscribers:channel", "message").Result()
-	if err != nil {
-		t.Fatalf("PUBLISH failed: %v", err)
+	key := "test:list:pushx"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if n, _ := client.LPushX(ctx, key, "a").Result(); n != 0 {
+		t.Errorf("Expected LPUSHX on a missing key to return 0, got %d", n)
 	}
-	if receiversCount != 0 {
-		t.Errorf("Expected 0 receivers, got %d", receiversCount)
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected LPUSHX not to create the list")
+	}
+
+	client.RPush(ctx, key, "b")
+	client.LPushX(ctx, key, "a")
+	if n, _ := client.RPushX(ctx, key, "c", "d").Result(); n != 4 {
+		t.Errorf("Expected length 4, got %d", n)
+	}
+	if vals, _ := client.LRange(ctx, key, 0, -1).Result(); fmt.Sprint(vals) != "[a b c d]" {
+		t.Errorf("Expected [a b c d], got %v", vals)
 	}
 }
 
-// TestPubSubChannel tests using the channel interface
-func TestPubSubChannel(t *testing.T) {
-	publisher := newTestClient()
-	subscriber := newTestClient()
-	defer publisher.Close()
-	defer subscriber.Close()
+// TestLMove tests moving elements between the ends of two lists
+func TestLMove(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
 	ctx := context.Background()
 
-	channel := "test:pubsub:channel"
-	numMessages := 5
+	src, dst := "test:list:lmove:src", "test:list:lmove:dst"
+	client.Del(ctx, src, dst)
+	defer client.Del(ctx, src, dst)
 
-	pubsub := subscriber.Subscribe(ctx, channel)
-	defer pubsub.Close()
+	client.RPush(ctx, src, "a", "b", "c")
+	if val, _ := client.LMove(ctx, src, dst, "LEFT", "RIGHT").Result(); val != "a" {
+		t.Errorf("Expected a, got %s", val)
+	}
+	if val, _ := client.RPopLPush(ctx, src, dst).Result(); val != "c" {
+		t.Errorf("Expected c, got %s", val)
+	}
+	if vals, _ := client.LRange(ctx, dst, 0, -1).Result(); fmt.Sprint(vals) != "[c a]" {
+		t.Errorf("Expected destination [c a], got %v", vals)
+	}
 
-	// Get channel for messages
-	ch := pubsub.Channel()
+	// Moving the last element deletes the source
+	client.LMove(ctx, src, dst, "RIGHT", "RIGHT")
+	if n, _ := client.Exists(ctx, src).Result(); n != 0 {
+		t.Error("Expected the source to be deleted once empty")
+	}
+	if _, err := client.LMove(ctx, src, dst, "LEFT", "LEFT").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing source, got %v", err)
+	}
 
-	// Wait for subscription
-	time.Sleep(100 * time.Millisecond)
+	// The same list as source and destination rotates it
+	if val, _ := client.LMove(ctx, dst, dst, "LEFT", "RIGHT").Result(); val != "c" {
+		t.Errorf("Expected c, got %s", val)
+	}
+	if vals, _ := client.LRange(ctx, dst, 0, -1).Result(); fmt.Sprint(vals) != "[a b c]" {
+		t.Errorf("Expected the rotated list [a b c], got %v", vals)
+	}
 
-	// Publish messages
-	for i := 0; i < numMessages; i++ {
-		publisher.Publish(ctx, channel, i)
+	if err := client.Do(ctx, "LMOVE", dst, src, "UP", "LEFT").Err(); err == nil || !strings.Contains(err.Error(), "syntax") {
+		t.Errorf("Expected a syntax error, got %v", err)
 	}
 
-	// Receive messages
-	received := 0
-	timeout := time.After(2 * time.Second)
-	for received < numMessages {
-		select {
-		case msg := <-ch:
-			if msg.Channel != channel {
-				t.Errorf("Expected channel %s, got %s", channel, msg.Channel)
-			}
-			received++
-		case <-timeout:
-			t.Fatalf("Timeout: only received %d of %d messages", received, numMessages)
-		}
+	// A destination of the wrong type leaves the source untouched
+	client.Set(ctx, src, "string", 0)
+	if err := client.LMove(ctx, dst, src, "LEFT", "LEFT").Err(); err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE, got %v", err)
+	}
+	if n, _ := client.LLen(ctx, dst).Result(); n != 3 {
+		t.Errorf("Expected the source to keep 3 elements, got %d", n)
 	}
 }
 
-// TestPubSubPingInSubscribedMode tests PING while subscribed
-func TestPubSubPingInSubscribedMode(t *testing.T) {
-	subscriber := newTestClient()
-	defer subscriber.Close()
+// TestBLMove tests that BLMOVE and BRPOPLPUSH wait for the source to be pushed to
+func TestBLMove(t *testing.T) {
+	client := newTestClient()
+	pusherClient := newTestClient()
+	defer client.Close()
+	defer pusherClient.Close()
 	ctx := context.Background()
 
-	pubsub := subscriber.Subscribe(ctx, "test:pubsub:ping")
-	defer pubsub.Close()
+	src, dst := "test:list:blmove:src", "test:list:blmove:dst"
+	client.Del(ctx, src, dst)
+	defer client.Del(ctx, src, dst)
 
-	// Wait for subscription
-	pubsub.Receive(ctx)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		pusherClient.RPush(ctx, src, "job")
+	}()
+	val, err := client.BLMove(ctx, src, dst, "LEFT", "LEFT", 5*time.Second).Result()
+	if err != nil || val != "job" {
+		t.Fatalf("Expected job, got %q (%v)", val, err)
+	}
+	if vals, _ := client.LRange(ctx, dst, 0, -1).Result(); fmt.Sprint(vals) != "[job]" {
+		t.Errorf("Expected destination [job], got %v", vals)
+	}
 
-	// Ping should still work in subscribed mode
-	err := pubsub.Ping(ctx)
-	if err != nil {
-		t.Fatalf("PING in subscribed mode failed: %v", err)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		pusherClient.RPush(ctx, src, "second")
+	}()
+	if val, _ := client.BRPopLPush(ctx, src, dst, 5*time.Second).Result(); val != "second" {
+		t.Errorf("Expected second, got %s", val)
+	}
+
+	start := time.Now()
+	if _, err := client.BLMove(ctx, src, dst, "LEFT", "LEFT", 200*time.Millisecond).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil on timeout, got %v", err)
+	}
+	if time.Since(start) < 150*time.Millisecond {
+		t.Error("BLMOVE returned before the timeout")
 	}
 }
 
-// TestPubSubConcurrentPublish tests concurrent publishing
-func TestPubSubConcurrentPublish(t *testing.T) {
-	subscriber := newTestClient()
-	defer subscriber.Close()
+// TestBLMoveChain tests that an element moved by BLMOVE wakes a client blocked on the
+// destination
+func TestBLMoveChain(t *testing.T) {
+	mover := newTestClient()
+	popper := newTestClient()
+	pusher := newTestClient()
+	defer mover.Close()
+	defer popper.Close()
+	defer pusher.Close()
 	ctx := context.Background()
 
-	channel := "test:pubsub:concurrent"
-	numPublishers := 5
-	messagesPerPublisher := 10
+	src, dst := "test:list:chain:src", "test:list:chain:dst"
+	pusher.Del(ctx, src, dst)
+	defer pusher.Del(ctx, src, dst)
 
-	pubsub := subscriber.Subscribe(ctx, channel)
-	defer pubsub.Close()
-	pubsub.Receive(ctx)
+	var wg sync.WaitGroup
+	var popped []string
+	var popErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		mover.BLMove(ctx, src, dst, "LEFT", "LEFT", 5*time.Second)
+	}()
+	go func() {
+		defer wg.Done()
+		popped, popErr = popper.BLPop(ctx, 5*time.Second, dst).Result()
+	}()
 
-	// Get channel
-	ch := pubsub.Channel()
+	time.Sleep(300 * time.Millisecond)
+	pusher.RPush(ctx, src, "item")
+	wg.Wait()
 
-	// Start concurrent publishers
+	if popErr != nil || fmt.Sprint(popped) != "["+dst+" item]" {
+		t.Errorf("Expected [%s item], got %v (%v)", dst, popped, popErr)
+	}
+}
+
+// TestReliableQueue moves items to a processing list with several blocked consumers while a
+// producer pushes. Every item must end up in the processing list exactly once
+func TestReliableQueue(t *testing.T) {
+	producer := newTestClient()
+	defer producer.Close()
+	ctx := context.Background()
+
+	queue, processing := "test:list:queue", "test:list:processing"
+	producer.Del(ctx, queue, processing)
+	defer producer.Del(ctx, queue, processing)
+
+	const consumers, items = 4, 200
 	var wg sync.WaitGroup
-	for i := 0; i < numPublishers; i++ {
+	var moved atomic.Int64
+	for range consumers {
 		wg.Add(1)
-		go func(publisherID int) {
+		go func() {
 			defer wg.Done()
-			pub := newTestClient()
-			defer pub.Close()
-			for j := 0; j < messagesPerPublisher; j++ {
-				pub.Publish(ctx, channel, publisherID*100+j)
+			client := newTestClient()
+			defer client.Close()
+			for {
+				if _, err := client.BLMove(ctx, queue, processing, "RIGHT", "LEFT", 500*time.Millisecond).Result(); err != nil {
+					return
+				}
+				moved.Add(1)
 			}
-		}(i)
+		}()
 	}
 
-	// Receive all messages
-	totalExpected := numPublishers * messagesPerPublisher
-	received := 0
-	timeout := time.After(5 * time.Second)
+	for i := range items {
+		producer.LPush(ctx, queue, strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	if moved.Load() != items {
+		t.Errorf("Expected %d moves, got %d", items, moved.Load())
+	}
+	vals, _ := producer.LRange(ctx, processing, 0, -1).Result()
+	seen := make(map[string]bool)
+	for _, v := range vals {
+		if seen[v] {
+			t.Errorf("Item %s was moved twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != items {
+		t.Errorf("Expected %d distinct items in the processing list, got %d", items, len(seen))
+	}
+	if n, _ := producer.Exists(ctx, queue).Result(); n != 0 {
+		t.Error("Expected the queue to be drained")
+	}
+}
+
+// TestBRPop tests the blocking pop from the tail
+func TestBRPop(t *testing.T) {
+	client := newTestClient()
+	pusherClient := newTestClient()
+	defer client.Close()
+	defer pusherClient.Close()
+	ctx := context.Background()
+
+	key := "test:list:brpop"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	client.RPush(ctx, key, "a", "b")
+	if vals, _ := client.BRPop(ctx, time.Second, "test:list:brpop:empty", key).Result(); fmt.Sprint(vals) != "["+key+" b]" {
+		t.Errorf("Expected [%s b], got %v", key, vals)
+	}
+	client.Del(ctx, key)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		pusherClient.RPush(ctx, key, "x", "y")
+	}()
+	if vals, _ := client.BRPop(ctx, 5*time.Second, key).Result(); fmt.Sprint(vals) != "["+key+" y]" {
+		t.Errorf("Expected [%s y], got %v", key, vals)
+	}
+}
+
+// TestLMPop tests popping several elements from the first non-empty list
+func TestLMPop(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	a, b := "test:list:lmpop:a", "test:list:lmpop:b"
+	client.Del(ctx, a, b)
+	defer client.Del(ctx, a, b)
+
+	client.RPush(ctx, b, "1", "2", "3")
+	key, vals, err := client.LMPop(ctx, "LEFT", 2, a, b).Result()
+	if err != nil || key != b || fmt.Sprint(vals) != "[1 2]" {
+		t.Errorf("Expected %s [1 2], got %s %v (%v)", b, key, vals, err)
+	}
+	key, vals, _ = client.LMPop(ctx, "RIGHT", 10, a, b).Result()
+	if key != b || fmt.Sprint(vals) != "[3]" {
+		t.Errorf("Expected %s [3], got %s %v", b, key, vals)
+	}
+	if _, _, err := client.LMPop(ctx, "LEFT", 1, a, b).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil when every list is empty, got %v", err)
+	}
+
+	for _, args := range [][]interface{}{
+		{"LMPOP", 0, a, "LEFT"},
+		{"LMPOP", 2, a, "LEFT"},
+		{"LMPOP", 1, a, "UP"},
+		{"LMPOP", 1, a, "LEFT", "COUNT", 0},
+		{"LMPOP", 1, a, "LEFT", "COUNT"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+// TestBLMPop tests that BLMPOP waits for one of the lists to be pushed to
+func TestBLMPop(t *testing.T) {
+	client := newTestClient()
+	pusherClient := newTestClient()
+	defer client.Close()
+	defer pusherClient.Close()
+	ctx := context.Background()
+
+	a, b := "test:list:blmpop:a", "test:list:blmpop:b"
+	client.Del(ctx, a, b)
+	defer client.Del(ctx, a, b)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		pusherClient.RPush(ctx, b, "x", "y", "z")
+	}()
+	key, vals, err := client.BLMPop(ctx, 5*time.Second, "RIGHT", 2, a, b).Result()
+	if err != nil || key != b || fmt.Sprint(vals) != "[z y]" {
+		t.Errorf("Expected %s [z y], got %s %v (%v)", b, key, vals, err)
+	}
+
+	if _, _, err := client.BLMPop(ctx, 200*time.Millisecond, "LEFT", 1, a).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil on timeout, got %v", err)
+	}
+}
+
+// TestBlockedClientsFIFO tests that clients blocked on a list are served in the order they
+// blocked, whether the elements arrive in one push or one at a time
+func TestBlockedClientsFIFO(t *testing.T) {
+	pusher := newTestClient()
+	defer pusher.Close()
+	ctx := context.Background()
+
+	key := "test:list:fifo"
+	pusher.Del(ctx, key)
+	defer pusher.Del(ctx, key)
+
+	const waiters = 5
+	for _, oneByOne := range []bool{false, true} {
+		results := make([]string, waiters)
+		var wg sync.WaitGroup
+		for i := range waiters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client := newTestClient()
+				defer client.Close()
+				if res, err := client.BLPop(ctx, 5*time.Second, key).Result(); err == nil {
+					results[i] = res[1]
+				}
+			}()
+			// Give each client the time to block before the next one does
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		elements := []interface{}{"0", "1", "2", "3", "4"}
+		if oneByOne {
+			for _, el := range elements {
+				pusher.RPush(ctx, key, el)
+			}
+		} else {
+			pusher.RPush(ctx, key, elements...)
+		}
+		wg.Wait()
+
+		if fmt.Sprint(results) != "[0 1 2 3 4]" {
+			t.Errorf("Expected the clients to be served in the order they blocked (one by one: %v), got %v", oneByOne, results)
+		}
+	}
+}
+
+// TestClientUnblock tests releasing a blocked client with CLIENT UNBLOCK
+func TestClientUnblock(t *testing.T) {
+	client := newTestClient()
+	admin := newTestClient()
+	defer client.Close()
+	defer admin.Close()
+	ctx := context.Background()
+
+	key := "test:list:unblock"
+	admin.Del(ctx, key)
+	defer admin.Del(ctx, key)
+
+	conn := client.Conn()
+	defer conn.Close()
+	id, err := conn.ClientID(ctx).Result()
+	if err != nil {
+		t.Fatalf("CLIENT ID failed: %v", err)
+	}
+
+	if n, _ := admin.ClientUnblock(ctx, id).Result(); n != 0 {
+		t.Errorf("Expected 0 for a client that isn't blocked, got %d", n)
+	}
+
+	for _, withError := range []bool{false, true} {
+		done := make(chan error, 1)
+		go func() {
+			done <- conn.BLPop(ctx, 0, key).Err()
+		}()
+		time.Sleep(200 * time.Millisecond)
+
+		var n int64
+		if withError {
+			n, _ = admin.ClientUnblockWithError(ctx, id).Result()
+		} else {
+			n, _ = admin.ClientUnblock(ctx, id).Result()
+		}
+		if n != 1 {
+			t.Errorf("Expected 1 for a blocked client, got %d", n)
+		}
 
-	for received < totalExpected {
 		select {
-		case <-ch:
-			received++
-		case <-timeout:
-			t.Fatalf("Timeout: received %d of %d messages", received, totalExpected)
+		case err := <-done:
+			if withError && (err == nil || !strings.HasPrefix(err.Error(), "UNBLOCKED")) {
+				t.Errorf("Expected an UNBLOCKED error, got %v", err)
+			}
+			if !withError && err != redis.Nil {
+				t.Errorf("Expected redis.Nil as if the timeout expired, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("The client wasn't released by CLIENT UNBLOCK")
 		}
 	}
 
-	wg.Wait()
+	if n, _ := admin.ClientUnblock(ctx, 1<<40).Result(); n != 0 {
+		t.Errorf("Expected 0 for an unknown client, got %d", n)
+	}
+	if err := admin.Do(ctx, "CLIENT", "UNBLOCK", id, "LATER").Err(); err == nil {
+		t.Error("Expected an error for an invalid reason")
+	}
+
+	// The released client no longer holds a place in line
+	pusher := newTestClient()
+	defer pusher.Close()
+	other := newTestClient()
+	defer other.Close()
+	result := make(chan []string, 1)
+	go func() {
+		res, _ := other.BLPop(ctx, 5*time.Second, key).Result()
+		result <- res
+	}()
+	time.Sleep(200 * time.Millisecond)
+	pusher.RPush(ctx, key, "after")
+	if res := <-result; fmt.Sprint(res) != "["+key+" after]" {
+		t.Errorf("Expected the next blocked client to be served, got %v", res)
+	}
+}
+
+// TestClientUnblockXRead tests that CLIENT UNBLOCK releases every kind of blocking command
+func TestClientUnblockXRead(t *testing.T) {
+	client := newTestClient()
+	admin := newTestClient()
+	defer client.Close()
+	defer admin.Close()
+	ctx := context.Background()
+
+	conn := client.Conn()
+	defer conn.Close()
+	id, _ := conn.ClientID(ctx).Result()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.XRead(ctx, &redis.XReadArgs{Streams: []string{"test:stream:unblock", "$"}, Block: 0}).Err()
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	if n, _ := admin.ClientUnblock(ctx, id).Result(); n != 1 {
+		t.Errorf("Expected 1 for a blocked client, got %d", n)
+	}
+	select {
+	case err := <-done:
+		if err != redis.Nil {
+			t.Errorf("Expected redis.Nil, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("XREAD wasn't released by CLIENT UNBLOCK")
+	}
+}
+
+// =============================================================================
+// Hash Tests
+// =============================================================================
+
+// TestHSetAndHGet tests HSET, HGET, HMGET, HLEN and HSTRLEN
+func TestHSetAndHGet(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:basic"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	added, err := client.HSet(ctx, key, "name", "alice", "city", "paris").Result()
+	if err != nil {
+		t.Fatalf("HSET failed: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("Expected HSET to add 2 fields, got %d", added)
+	}
+	if added, _ := client.HSet(ctx, key, "name", "bob", "age", "30").Result(); added != 1 {
+		t.Errorf("Expected HSET to add 1 new field, got %d", added)
+	}
+
+	if val, err := client.HGet(ctx, key, "name").Result(); err != nil || val != "bob" {
+		t.Errorf("Expected name = bob, got %q (%v)", val, err)
+	}
+	if _, err := client.HGet(ctx, key, "missing").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing field, got %v", err)
+	}
+
+	vals, err := client.HMGet(ctx, key, "name", "missing", "city").Result()
+	if err != nil {
+		t.Fatalf("HMGET failed: %v", err)
+	}
+	if len(vals) != 3 || vals[0] != "bob" || vals[1] != nil || vals[2] != "paris" {
+		t.Errorf("Unexpected HMGET reply: %v", vals)
+	}
+
+	if n, _ := client.HLen(ctx, key).Result(); n != 3 {
+		t.Errorf("Expected HLEN 3, got %d", n)
+	}
+	if n, _ := client.HStrLen(ctx, key, "city").Result(); n != 5 {
+		t.Errorf("Expected HSTRLEN 5, got %d", n)
+	}
+	if n, _ := client.HStrLen(ctx, key, "missing").Result(); n != 0 {
+		t.Errorf("Expected HSTRLEN 0 for a missing field, got %d", n)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "hash" {
+		t.Errorf("Expected type 'hash', got '%s'", typ)
+	}
+}
+
+// TestHGetAllKeysVals tests HGETALL, HKEYS and HVALS
+func TestHGetAllKeysVals(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:getall"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.HSet(ctx, key, "a", "1", "b", "2", "c", "3")
+
+	all, err := client.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("HGETALL failed: %v", err)
+	}
+	if len(all) != 3 || all["a"] != "1" || all["b"] != "2" || all["c"] != "3" {
+		t.Errorf("Unexpected HGETALL reply: %v", all)
+	}
+
+	keys, _ := client.HKeys(ctx, key).Result()
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "a,b,c" {
+		t.Errorf("Unexpected HKEYS reply: %v", keys)
+	}
+	vals, _ := client.HVals(ctx, key).Result()
+	sort.Strings(vals)
+	if strings.Join(vals, ",") != "1,2,3" {
+		t.Errorf("Unexpected HVALS reply: %v", vals)
+	}
+
+	if all, _ := client.HGetAll(ctx, "test:hash:missing").Result(); len(all) != 0 {
+		t.Errorf("Expected an empty HGETALL for a missing key, got %v", all)
+	}
+}
+
+// TestHDelAndHExists tests HDEL, HEXISTS and that the key disappears with its last field
+func TestHDelAndHExists(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:del"
+	client.Del(ctx, key)
+	client.HSet(ctx, key, "a", "1", "b", "2")
+
+	if ok, _ := client.HExists(ctx, key, "a").Result(); !ok {
+		t.Error("Expected HEXISTS a to be true")
+	}
+	if n, _ := client.HDel(ctx, key, "a", "missing").Result(); n != 1 {
+		t.Errorf("Expected HDEL to remove 1 field, got %d", n)
+	}
+	if ok, _ := client.HExists(ctx, key, "a").Result(); ok {
+		t.Error("Expected HEXISTS a to be false after HDEL")
+	}
+	if n, _ := client.HDel(ctx, key, "b").Result(); n != 1 {
+		t.Errorf("Expected HDEL to remove 1 field, got %d", n)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Errorf("Expected the empty hash to be deleted, EXISTS returned %d", n)
+	}
+}
+
+// TestHSetNX tests that HSETNX only sets missing fields
+func TestHSetNX(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:setnx"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if ok, _ := client.HSetNX(ctx, key, "f", "first").Result(); !ok {
+		t.Error("Expected HSETNX on a missing field to succeed")
+	}
+	if ok, _ := client.HSetNX(ctx, key, "f", "second").Result(); ok {
+		t.Error("Expected HSETNX on an existing field to fail")
+	}
+	if val, _ := client.HGet(ctx, key, "f").Result(); val != "first" {
+		t.Errorf("Expected f = first, got %q", val)
+	}
+}
+
+// TestHIncrBy tests HINCRBY and HINCRBYFLOAT including their error cases
+func TestHIncrBy(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:incr"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if n, err := client.HIncrBy(ctx, key, "count", 5).Result(); err != nil || n != 5 {
+		t.Errorf("Expected 5, got %d (%v)", n, err)
+	}
+	if n, err := client.HIncrBy(ctx, key, "count", -7).Result(); err != nil || n != -2 {
+		t.Errorf("Expected -2, got %d (%v)", n, err)
+	}
+
+	client.HSet(ctx, key, "max", "9223372036854775807", "text", "abc")
+	if err := client.HIncrBy(ctx, key, "max", 1).Err(); err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Errorf("Expected an overflow error, got %v", err)
+	}
+	if err := client.HIncrBy(ctx, key, "text", 1).Err(); err == nil || !strings.Contains(err.Error(), "not an integer") {
+		t.Errorf("Expected a not an integer error, got %v", err)
+	}
+
+	if f, err := client.HIncrByFloat(ctx, key, "price", 10.5).Result(); err != nil || f != 10.5 {
+		t.Errorf("Expected 10.5, got %v (%v)", f, err)
+	}
+	if f, err := client.HIncrByFloat(ctx, key, "price", 0.1).Result(); err != nil || f != 10.6 {
+		t.Errorf("Expected 10.6, got %v (%v)", f, err)
+	}
+	if val, _ := client.HGet(ctx, key, "price").Result(); val != "10.6" {
+		t.Errorf("Expected price = 10.6, got %q", val)
+	}
+	if err := client.HIncrByFloat(ctx, key, "text", 1).Err(); err == nil || !strings.Contains(err.Error(), "not a float") {
+		t.Errorf("Expected a not a float error, got %v", err)
+	}
+
+	// A failed increment must not create the key
+	client.Set(ctx, key+":s", "x", 0)
+	defer client.Del(ctx, key+":s")
+	if err := client.HIncrBy(ctx, key+":s", "f", 1).Err(); err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE, got %v", err)
+	}
+}
+
+// TestHRandField tests the count and WITHVALUES forms of HRANDFIELD
+func TestHRandField(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:rand"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.HSet(ctx, key, "a", "1", "b", "2", "c", "3")
+
+	field, err := client.Do(ctx, "HRANDFIELD", key).Text()
+	if err != nil || (field != "a" && field != "b" && field != "c") {
+		t.Errorf("Unexpected HRANDFIELD reply %q (%v)", field, err)
+	}
+
+	fields, _ := client.HRandField(ctx, key, 10).Result()
+	sort.Strings(fields)
+	if strings.Join(fields, ",") != "a,b,c" {
+		t.Errorf("Expected every field once, got %v", fields)
+	}
+
+	fields, _ = client.HRandField(ctx, key, -10).Result()
+	if len(fields) != 10 {
+		t.Errorf("Expected 10 fields for a negative count, got %d", len(fields))
+	}
+
+	pairs, err := client.HRandFieldWithValues(ctx, key, 2).Result()
+	if err != nil || len(pairs) != 2 {
+		t.Fatalf("Expected 2 pairs, got %v (%v)", pairs, err)
+	}
+	for _, pair := range pairs {
+		if want, _ := client.HGet(ctx, key, pair.Key).Result(); want != pair.Value {
+			t.Errorf("Pair %s=%s doesn't match the hash", pair.Key, pair.Value)
+		}
+	}
+
+	if _, err := client.Do(ctx, "HRANDFIELD", "test:hash:missing").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing key, got %v", err)
+	}
+}
+
+// TestHScan tests that HSCAN visits every field exactly once and honours MATCH and NOVALUES
+func TestHScan(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:hash:scan"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	values := make([]interface{}, 0, 1000)
+	for i := 0; i < 500; i++ {
+		values = append(values, "field:"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+	client.HSet(ctx, key, values...)
+
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		items, next, err := client.HScan(ctx, key, cursor, "", 50).Result()
+		if err != nil {
+			t.Fatalf("HSCAN failed: %v", err)
+		}
+		for i := 0; i < len(items); i += 2 {
+			seen[items[i]]++
+			if items[i] != "field:"+items[i+1] {
+				t.Errorf("Field %s returned with value %s", items[i], items[i+1])
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 500 {
+		t.Errorf("Expected 500 fields, got %d", len(seen))
+	}
+	for field, n := range seen {
+		if n != 1 {
+			t.Errorf("Field %s returned %d times", field, n)
+		}
+	}
+
+	var matched []string
+	cursor = 0
+	for {
+		items, next, err := client.HScanNoValues(ctx, key, cursor, "field:1?", 100).Result()
+		if err != nil {
+			t.Fatalf("HSCAN NOVALUES failed: %v", err)
+		}
+		matched = append(matched, items...)
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	if len(matched) != 10 {
+		t.Errorf("Expected 10 fields matching field:1?, got %v", matched)
+	}
+
+	if items, next, err := client.HScan(ctx, "test:hash:missing", 0, "", 10).Result(); err != nil || next != 0 || len(items) != 0 {
+		t.Errorf("Expected an empty scan of a missing key, got %v %d (%v)", items, next, err)
+	}
+}
+
+// =============================================================================
+// Set Tests
+// =============================================================================
+
+// TestSAddAndMembers tests SADD, SREM, SMEMBERS, SISMEMBER, SMISMEMBER and SCARD
+func TestSAddAndMembers(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:set:basic"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if n, err := client.SAdd(ctx, key, "a", "b", "c", "a").Result(); err != nil || n != 3 {
+		t.Errorf("Expected SADD to add 3 members, got %d (%v)", n, err)
+	}
+	if n, _ := client.SAdd(ctx, key, "c", "d").Result(); n != 1 {
+		t.Errorf("Expected SADD to add 1 new member, got %d", n)
+	}
+
+	members, _ := client.SMembers(ctx, key).Result()
+	sort.Strings(members)
+	if strings.Join(members, ",") != "a,b,c,d" {
+		t.Errorf("Unexpected SMEMBERS reply: %v", members)
+	}
+	if ok, _ := client.SIsMember(ctx, key, "b").Result(); !ok {
+		t.Error("Expected b to be a member")
+	}
+	if flags, _ := client.SMIsMember(ctx, key, "a", "x", "d").Result(); len(flags) != 3 || !flags[0] || flags[1] || !flags[2] {
+		t.Errorf("Unexpected SMISMEMBER reply: %v", flags)
+	}
+	if n, _ := client.SCard(ctx, key).Result(); n != 4 {
+		t.Errorf("Expected SCARD 4, got %d", n)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "set" {
+		t.Errorf("Expected type 'set', got '%s'", typ)
+	}
+
+	if n, _ := client.SRem(ctx, key, "a", "x").Result(); n != 1 {
+		t.Errorf("Expected SREM to remove 1 member, got %d", n)
+	}
+	client.SRem(ctx, key, "b", "c", "d")
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Errorf("Expected the empty set to be deleted, EXISTS returned %d", n)
+	}
+}
+
+// TestSetIntsetEncoding tests that small integer sets use the intset encoding until a
+// string member or too many members are added
+func TestSetIntsetEncoding(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:set:intset"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	client.SAdd(ctx, key, 3, 1, 2, -100000)
+	if enc, _ := client.ObjectEncoding(ctx, key).Result(); enc != "intset" {
+		t.Errorf("Expected intset encoding, got %s", enc)
+	}
+	if ok, _ := client.SIsMember(ctx, key, "-100000").Result(); !ok {
+		t.Error("Expected -100000 to be a member")
+	}
+	members, _ := client.SMembers(ctx, key).Result()
+	if strings.Join(members, ",") != "-100000,1,2,3" {
+		t.Errorf("Expected sorted intset members, got %v", members)
+	}
+
+	client.SAdd(ctx, key, "tag")
+	if enc, _ := client.ObjectEncoding(ctx, key).Result(); enc != "hashtable" {
+		t.Errorf("Expected hashtable encoding after adding a string, got %s", enc)
+	}
+	if n, _ := client.SCard(ctx, key).Result(); n != 5 {
+		t.Errorf("Expected 5 members after the conversion, got %d", n)
+	}
+
+	big := "test:set:intset:big"
+	client.Del(ctx, big)
+	defer client.Del(ctx, big)
+	values := make([]interface{}, 0, 513)
+	for i := 0; i < 513; i++ {
+		values = append(values, i)
+	}
+	client.SAdd(ctx, big, values[:512]...)
+	if enc, _ := client.ObjectEncoding(ctx, big).Result(); enc != "intset" {
+		t.Errorf("Expected intset encoding with 512 members, got %s", enc)
+	}
+	client.SAdd(ctx, big, values[512])
+	if enc, _ := client.ObjectEncoding(ctx, big).Result(); enc != "hashtable" {
+		t.Errorf("Expected hashtable encoding with 513 members, got %s", enc)
+	}
+}
+
+// TestSetAlgebra tests SINTER, SUNION, SDIFF, SINTERCARD and the STORE variants over keys
+// spread across shards
+func TestSetAlgebra(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	keys := []string{"test:set:algebra:a", "test:set:algebra:b", "test:set:algebra:c", "test:set:algebra:dest"}
+	client.Del(ctx, keys...)
+	defer client.Del(ctx, keys...)
+	a, b, c, dest := keys[0], keys[1], keys[2], keys[3]
+
+	client.SAdd(ctx, a, "1", "2", "3", "x")
+	client.SAdd(ctx, b, "2", "3", "4", "x")
+	client.SAdd(ctx, c, "3", "x", "y")
+
+	sorted := func(members []string, err error) string {
+		if err != nil {
+			t.Fatalf("Set command failed: %v", err)
+		}
+		sort.Strings(members)
+		return strings.Join(members, ",")
+	}
+
+	if got := sorted(client.SInter(ctx, a, b, c).Result()); got != "3,x" {
+		t.Errorf("SINTER = %s, want 3,x", got)
+	}
+	if got := sorted(client.SUnion(ctx, a, b, c).Result()); got != "1,2,3,4,x,y" {
+		t.Errorf("SUNION = %s, want 1,2,3,4,x,y", got)
+	}
+	if got := sorted(client.SDiff(ctx, a, b).Result()); got != "1" {
+		t.Errorf("SDIFF = %s, want 1", got)
+	}
+	if got := sorted(client.SInter(ctx, a, "test:set:algebra:missing").Result()); got != "" {
+		t.Errorf("SINTER with a missing key = %s, want empty", got)
+	}
+	if n, _ := client.SInterCard(ctx, 0, a, b).Result(); n != 3 {
+		t.Errorf("SINTERCARD = %d, want 3", n)
+	}
+	if n, _ := client.SInterCard(ctx, 2, a, b).Result(); n != 2 {
+		t.Errorf("SINTERCARD LIMIT 2 = %d, want 2", n)
+	}
+
+	if n, _ := client.SUnionStore(ctx, dest, a, b).Result(); n != 5 {
+		t.Errorf("SUNIONSTORE = %d, want 5", n)
+	}
+	if n, _ := client.SInterStore(ctx, dest, dest, c).Result(); n != 2 {
+		t.Errorf("SINTERSTORE with the destination as a source = %d, want 2", n)
+	}
+	if got := sorted(client.SMembers(ctx, dest).Result()); got != "3,x" {
+		t.Errorf("Destination = %s, want 3,x", got)
+	}
+	if n, _ := client.SDiffStore(ctx, dest, c, a, b).Result(); n != 1 {
+		t.Errorf("SDIFFSTORE = %d, want 1", n)
+	}
+	if n, _ := client.SDiffStore(ctx, dest, a, a).Result(); n != 0 {
+		t.Errorf("SDIFFSTORE of a set with itself = %d, want 0", n)
+	}
+	if n, _ := client.Exists(ctx, dest).Result(); n != 0 {
+		t.Error("Expected an empty STORE result to delete the destination")
+	}
+
+	client.Set(ctx, dest, "string", 0)
+	if err := client.SUnion(ctx, a, dest).Err(); err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Errorf("Expected WRONGTYPE from SUNION, got %v", err)
+	}
+	if n, err := client.SUnionStore(ctx, dest, a).Result(); err != nil || n != 4 {
+		t.Errorf("Expected SUNIONSTORE to overwrite a string, got %d (%v)", n, err)
+	}
+}
+
+// TestSetAlgebraConcurrentMoves tests that SMOVE and SUNION across shards never observe a
+// member in both or neither set
+func TestSetAlgebraConcurrentMoves(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	src, dst := "test:set:move:src", "test:set:move:dst"
+	client.Del(ctx, src, dst)
+	defer client.Del(ctx, src, dst)
+
+	members := make([]interface{}, 0, 200)
+	for i := 0; i < 200; i++ {
+		members = append(members, "m"+strconv.Itoa(i))
+	}
+	client.SAdd(ctx, src, members...)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mover := newTestClient()
+		defer mover.Close()
+		for _, m := range members {
+			mover.SMove(ctx, src, dst, m)
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		union, err := client.SUnion(ctx, src, dst).Result()
+		if err != nil {
+			t.Fatalf("SUNION failed: %v", err)
+		}
+		if len(union) != 200 {
+			t.Fatalf("Expected 200 members across both sets, got %d", len(union))
+		}
+		n1, _ := client.SCard(ctx, src).Result()
+		n2, _ := client.SCard(ctx, dst).Result()
+		if n1+n2 < 200 {
+			t.Fatalf("Members lost during SMOVE: %d + %d", n1, n2)
+		}
+	}
+
+	if ok, _ := client.SMove(ctx, src, dst, "m0").Result(); ok {
+		t.Error("Expected SMOVE of a member that is no longer in the source to return false")
+	}
+	if n, _ := client.SCard(ctx, dst).Result(); n != 200 {
+		t.Errorf("Expected 200 members in the destination, got %d", n)
+	}
+}
+
+// TestSPopAndSRandMember tests SPOP and SRANDMEMBER with and without a count
+func TestSPopAndSRandMember(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:set:pop"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.SAdd(ctx, key, "a", "b", "c", "d", "e")
+
+	if m, err := client.SRandMember(ctx, key).Result(); err != nil || m == "" {
+		t.Errorf("Unexpected SRANDMEMBER reply %q (%v)", m, err)
+	}
+	if members, _ := client.SRandMemberN(ctx, key, 10).Result(); len(members) != 5 {
+		t.Errorf("Expected 5 distinct members, got %v", members)
+	}
+	if members, _ := client.SRandMemberN(ctx, key, -8).Result(); len(members) != 8 {
+		t.Errorf("Expected 8 members for a negative count, got %v", members)
+	}
+
+	popped, err := client.SPop(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("SPOP failed: %v", err)
+	}
+	if ok, _ := client.SIsMember(ctx, key, popped).Result(); ok {
+		t.Errorf("Popped member %s is still in the set", popped)
+	}
+	if members, _ := client.SPopN(ctx, key, 3).Result(); len(members) != 3 {
+		t.Errorf("Expected SPOP to pop 3 members, got %v", members)
+	}
+	if members, _ := client.SPopN(ctx, key, 3).Result(); len(members) != 1 {
+		t.Errorf("Expected SPOP to pop the last member, got %v", members)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected the set to be deleted once empty")
+	}
+	if _, err := client.SPop(ctx, key).Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil from SPOP on a missing key, got %v", err)
+	}
+}
+
+// TestSScan tests that SSCAN visits every member exactly once
+func TestSScan(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:set:scan"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	members := make([]interface{}, 0, 300)
+	for i := 0; i < 300; i++ {
+		members = append(members, "tag:"+strconv.Itoa(i))
+	}
+	client.SAdd(ctx, key, members...)
+
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		items, next, err := client.SScan(ctx, key, cursor, "tag:*", 25).Result()
+		if err != nil {
+			t.Fatalf("SSCAN failed: %v", err)
+		}
+		for _, item := range items {
+			seen[item]++
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 300 {
+		t.Errorf("Expected 300 members, got %d", len(seen))
+	}
+	for member, n := range seen {
+		if n != 1 {
+			t.Errorf("Member %s returned %d times", member, n)
+		}
+	}
+}
+
+// =============================================================================
+// Sorted Set Tests
+// =============================================================================
+
+// TestZAddAndScore tests ZADD with its NX, XX, GT, LT, CH and INCR options, along with
+// ZSCORE, ZMSCORE, ZINCRBY, ZCARD and ZREM
+func TestZAddAndScore(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:zset:basic"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if n, err := client.ZAdd(ctx, key, redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}).Result(); err != nil || n != 2 {
+		t.Errorf("Expected ZADD to add 2 members, got %d (%v)", n, err)
+	}
+	if n, _ := client.ZAdd(ctx, key, redis.Z{Score: 5, Member: "a"}, redis.Z{Score: 3, Member: "c"}).Result(); n != 1 {
+		t.Errorf("Expected ZADD to count only the new member, got %d", n)
+	}
+	if n, _ := client.ZAddArgs(ctx, key, redis.ZAddArgs{Ch: true, Members: []redis.Z{{Score: 6, Member: "a"}, {Score: 2, Member: "b"}}}).Result(); n != 1 {
+		t.Errorf("Expected ZADD CH to count the changed member, got %d", n)
+	}
+	if n, _ := client.ZAddNX(ctx, key, redis.Z{Score: 0, Member: "a"}, redis.Z{Score: 4, Member: "d"}).Result(); n != 1 {
+		t.Errorf("Expected ZADD NX to add only d, got %d", n)
+	}
+	if n, _ := client.ZAddXX(ctx, key, redis.Z{Score: 0, Member: "x"}).Result(); n != 0 {
+		t.Errorf("Expected ZADD XX to skip missing members, got %d", n)
+	}
+	client.ZAddGT(ctx, key, redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 10, Member: "b"})
+	client.ZAddLT(ctx, key, redis.Z{Score: 9, Member: "c"})
+	scores, _ := client.ZMScore(ctx, key, "a", "b", "c", "x").Result()
+	if len(scores) != 4 || scores[0] != 6 || scores[1] != 10 || scores[2] != 3 || scores[3] != 0 {
+		t.Errorf("Unexpected scores after GT and LT: %v", scores)
+	}
+
+	if score, err := client.ZAddArgsIncr(ctx, key, redis.ZAddArgs{Members: []redis.Z{{Score: 1.5, Member: "a"}}}).Result(); err != nil || score != 7.5 {
+		t.Errorf("Expected ZADD INCR to return 7.5, got %v (%v)", score, err)
+	}
+	if _, err := client.ZAddArgsIncr(ctx, key, redis.ZAddArgs{NX: true, Members: []redis.Z{{Score: 1, Member: "a"}}}).Result(); err != redis.Nil {
+		t.Errorf("Expected a null reply from a skipped ZADD INCR, got %v", err)
+	}
+	if score, _ := client.ZIncrBy(ctx, key, -0.25, "new").Result(); score != -0.25 {
+		t.Errorf("Expected ZINCRBY on a missing member to start at 0, got %v", score)
+	}
+	if score, _ := client.ZScore(ctx, key, "new").Result(); score != -0.25 {
+		t.Errorf("Expected ZSCORE -0.25, got %v", score)
+	}
+	if _, err := client.ZScore(ctx, key, "missing").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing member, got %v", err)
+	}
+
+	client.ZAdd(ctx, key, redis.Z{Score: math.Inf(1), Member: "top"})
+	if err := client.ZIncrBy(ctx, key, math.Inf(-1), "top").Err(); err == nil || !strings.Contains(err.Error(), "NaN") {
+		t.Errorf("Expected a NaN error, got %v", err)
+	}
+	for _, args := range [][]interface{}{
+		{"ZADD", key, "NX", "XX", 1, "a"},
+		{"ZADD", key, "GT", "LT", 1, "a"},
+		{"ZADD", key, "INCR", 1, "a", 2, "b"},
+		{"ZADD", key, 1, "a", 2},
+		{"ZADD", key, "nan", "a"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+
+	if n, _ := client.ZCard(ctx, key).Result(); n != 6 {
+		t.Errorf("Expected ZCARD 6, got %d", n)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "zset" {
+		t.Errorf("Expected type 'zset', got '%s'", typ)
+	}
+	if enc, _ := client.ObjectEncoding(ctx, key).Result(); enc != "skiplist" {
+		t.Errorf("Expected skiplist encoding, got %s", enc)
+	}
+	if n, _ := client.ZRem(ctx, key, "a", "b", "x").Result(); n != 2 {
+		t.Errorf("Expected ZREM to remove 2 members, got %d", n)
+	}
+	client.ZRem(ctx, key, "c", "d", "new", "top")
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected the sorted set to be deleted once empty")
+	}
+}
+
+// TestZRange tests ZRANGE by rank, score and member, in both directions and with LIMIT, as
+// well as the legacy range commands
+func TestZRange(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:zset:range"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.ZAdd(ctx, key,
+		redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 2, Member: "c"},
+		redis.Z{Score: 3, Member: "d"}, redis.Z{Score: 4.5, Member: "e"})
+
+	joined := func(members []string, err error) string {
+		if err != nil {
+			t.Fatalf("Range command failed: %v", err)
+		}
+		return strings.Join(members, ",")
+	}
+
+	cases := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"rank", joined(client.ZRange(ctx, key, 1, -2).Result()), "b,c,d"},
+		{"rank out of range", joined(client.ZRange(ctx, key, 3, 100).Result()), "d,e"},
+		{"rank empty", joined(client.ZRange(ctx, key, 4, 1).Result()), ""},
+		{"rank rev", joined(client.ZRevRange(ctx, key, 0, 1).Result()), "e,d"},
+		{"byscore", joined(client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "2", Max: "(4.5"}).Result()), "b,c,d"},
+		{"byscore inf", joined(client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "(1", Max: "+inf"}).Result()), "b,c,d,e"},
+		{"byscore limit", joined(client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: "+inf", Offset: 1, Count: 2}).Result()), "b,c"},
+		{"byscore rev", joined(client.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Min: "2", Max: "3"}).Result()), "d,c,b"},
+		{"byscore rev limit", joined(client.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Min: "-inf", Max: "+inf", Offset: 1, Count: 2}).Result()), "d,c"},
+		{"zrange byscore rev", joined(client.ZRangeArgs(ctx, redis.ZRangeArgs{Key: key, Start: "(1", Stop: "3", ByScore: true, Rev: true}).Result()), "d,c,b"},
+		{"zrange bylex", joined(client.ZRangeArgs(ctx, redis.ZRangeArgs{Key: key, Start: "[b", Stop: "(d", ByLex: true}).Result()), "b,c"},
+		{"zrange rev rank", joined(client.ZRangeArgs(ctx, redis.ZRangeArgs{Key: key, Start: 1, Stop: 2, Rev: true}).Result()), "d,c"},
+		{"missing key", joined(client.ZRange(ctx, "test:zset:range:missing", 0, -1).Result()), ""},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, c.got, c.want)
+		}
+	}
+
+	withScores, _ := client.ZRangeWithScores(ctx, key, -2, -1).Result()
+	if len(withScores) != 2 || withScores[0].Member != "d" || withScores[0].Score != 3 || withScores[1].Score != 4.5 {
+		t.Errorf("Unexpected ZRANGE WITHSCORES reply: %v", withScores)
+	}
+
+	lex := "test:zset:range:lex"
+	client.Del(ctx, lex)
+	defer client.Del(ctx, lex)
+	for _, m := range []string{"apple", "banana", "cherry", "date"} {
+		client.ZAdd(ctx, lex, redis.Z{Score: 0, Member: m})
+	}
+	if got := joined(client.ZRangeByLex(ctx, lex, &redis.ZRangeBy{Min: "[b", Max: "+"}).Result()); got != "banana,cherry,date" {
+		t.Errorf("ZRANGEBYLEX = %s", got)
+	}
+	if got := joined(client.ZRevRangeByLex(ctx, lex, &redis.ZRangeBy{Min: "-", Max: "(cherry", Count: 1}).Result()); got != "banana" {
+		t.Errorf("ZREVRANGEBYLEX LIMIT = %s", got)
+	}
+	if n, _ := client.ZLexCount(ctx, lex, "(apple", "[cherry").Result(); n != 2 {
+		t.Errorf("Expected ZLEXCOUNT 2, got %d", n)
+	}
+
+	for _, args := range [][]interface{}{
+		{"ZRANGE", key, 0, -1, "LIMIT", 0, 1},
+		{"ZRANGE", key, "[a", "[b", "BYLEX", "WITHSCORES"},
+		{"ZRANGEBYSCORE", key, "x", "1"},
+		{"ZRANGEBYLEX", key, "a", "[b"},
+		{"ZREVRANGE", key, 0, -1, "BYSCORE"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+// TestZRankAndCount tests ZRANK, ZREVRANK and ZCOUNT
+func TestZRankAndCount(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:zset:rank"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	members := make([]redis.Z, 0, 200)
+	for i := 0; i < 200; i++ {
+		members = append(members, redis.Z{Score: float64(i / 2), Member: fmt.Sprintf("m%03d", i)})
+	}
+	client.ZAdd(ctx, key, members...)
+
+	if rank, _ := client.ZRank(ctx, key, "m150").Result(); rank != 150 {
+		t.Errorf("Expected ZRANK 150, got %d", rank)
+	}
+	if rank, _ := client.ZRevRank(ctx, key, "m150").Result(); rank != 49 {
+		t.Errorf("Expected ZREVRANK 49, got %d", rank)
+	}
+	if rs, _ := client.ZRankWithScore(ctx, key, "m011").Result(); rs.Rank != 11 || rs.Score != 5 {
+		t.Errorf("Unexpected ZRANK WITHSCORE reply: %+v", rs)
+	}
+	if _, err := client.ZRank(ctx, key, "missing").Result(); err != redis.Nil {
+		t.Errorf("Expected redis.Nil for a missing member, got %v", err)
+	}
+
+	counts := []struct {
+		min, max string
+		want     int64
+	}{
+		{"-inf", "+inf", 200},
+		{"10", "19", 20},
+		{"(10", "19", 18},
+		{"(10", "(19", 16},
+		{"50", "40", 0},
+		{"99", "1000", 2},
+	}
+	for _, c := range counts {
+		if n, _ := client.ZCount(ctx, key, c.min, c.max).Result(); n != c.want {
+			t.Errorf("ZCOUNT %s %s = %d, want %d", c.min, c.max, n, c.want)
+		}
+	}
+}
+
+// TestZPop tests ZPOPMIN and ZPOPMAX, and BZPOPMIN being served by a ZADD from another client
+func TestZPop(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:zset:pop"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.ZAdd(ctx, key, redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 3, Member: "c"})
+
+	if popped, _ := client.ZPopMin(ctx, key).Result(); len(popped) != 1 || popped[0].Member != "a" || popped[0].Score != 1 {
+		t.Errorf("Unexpected ZPOPMIN reply: %v", popped)
+	}
+	if popped, _ := client.ZPopMax(ctx, key, 5).Result(); len(popped) != 2 || popped[0].Member != "c" || popped[1].Member != "b" {
+		t.Errorf("Unexpected ZPOPMAX reply: %v", popped)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected the sorted set to be deleted once empty")
+	}
+	if popped, err := client.ZPopMin(ctx, key).Result(); err != nil || len(popped) != 0 {
+		t.Errorf("Expected an empty ZPOPMIN reply on a missing key, got %v (%v)", popped, err)
+	}
+	if err := client.Do(ctx, "ZPOPMIN", key, -1).Err(); err == nil {
+		t.Error("Expected ZPOPMIN with a negative count to fail")
+	}
+
+	if _, err := client.BZPopMin(ctx, 100*time.Millisecond, key).Result(); err != redis.Nil {
+		t.Errorf("Expected BZPOPMIN to time out, got %v", err)
+	}
+	if err := client.Do(ctx, "BZPOPMIN", key, -1).Err(); err == nil {
+		t.Error("Expected BZPOPMIN with a negative timeout to fail")
+	}
+
+	done := make(chan *redis.ZWithKey, 1)
+	go func() {
+		c := newTestClient()
+		defer c.Close()
+		res, err := c.BZPopMax(ctx, 2*time.Second, "test:zset:pop:other", key).Result()
+		if err != nil {
+			t.Errorf("BZPOPMAX failed: %v", err)
+		}
+		done <- res
+	}()
+	time.Sleep(100 * time.Millisecond)
+	client.ZAdd(ctx, key, redis.Z{Score: 7, Member: "x"}, redis.Z{Score: 9, Member: "y"})
+
+	select {
+	case res := <-done:
+		if res == nil || res.Key != key || res.Member != "y" || res.Score != 9 {
+			t.Errorf("Unexpected BZPOPMAX reply: %+v", res)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("BZPOPMAX was not served")
+	}
+}
+
+// TestZUnionInterStore tests ZUNIONSTORE and ZINTERSTORE with weights, aggregates and a
+// plain set as an input
+func TestZUnionInterStore(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	keys := []string{"test:zset:store:a", "test:zset:store:b", "test:zset:store:set", "test:zset:store:dest"}
+	client.Del(ctx, keys...)
+	defer client.Del(ctx, keys...)
+	a, b, set, dest := keys[0], keys[1], keys[2], keys[3]
+
+	client.ZAdd(ctx, a, redis.Z{Score: 1, Member: "x"}, redis.Z{Score: 2, Member: "y"}, redis.Z{Score: 3, Member: "z"})
+	client.ZAdd(ctx, b, redis.Z{Score: 10, Member: "y"}, redis.Z{Score: 20, Member: "z"}, redis.Z{Score: 30, Member: "w"})
+	client.SAdd(ctx, set, "z", "w")
+
+	if n, err := client.ZUnionStore(ctx, dest, &redis.ZStore{Keys: []string{a, b}}).Result(); err != nil || n != 4 {
+		t.Errorf("Expected ZUNIONSTORE to store 4 members, got %d (%v)", n, err)
+	}
+	if got, _ := client.ZRangeWithScores(ctx, dest, 0, -1).Result(); len(got) != 4 || got[0].Member != "x" || got[1].Score != 12 || got[3].Score != 30 {
+		t.Errorf("Unexpected union: %v", got)
+	}
+
+	if n, _ := client.ZInterStore(ctx, dest, &redis.ZStore{Keys: []string{a, b}, Weights: []float64{2, 0.5}, Aggregate: "MAX"}).Result(); n != 2 {
+		t.Errorf("Expected ZINTERSTORE to store 2 members, got %d", n)
+	}
+	if scores, _ := client.ZMScore(ctx, dest, "y", "z").Result(); len(scores) != 2 || scores[0] != 5 || scores[1] != 10 {
+		t.Errorf("Unexpected weighted MAX intersection: %v", scores)
+	}
+
+	if n, _ := client.ZInterStore(ctx, dest, &redis.ZStore{Keys: []string{dest, set}, Aggregate: "MIN"}).Result(); n != 1 {
+		t.Errorf("Expected the intersection with a set to hold 1 member, got %d", n)
+	}
+	if score, _ := client.ZScore(ctx, dest, "z").Result(); score != 1 {
+		t.Errorf("Expected set members to count as score 1, got %v", score)
+	}
+
+	if n, _ := client.ZInterStore(ctx, dest, &redis.ZStore{Keys: []string{a, "test:zset:store:missing"}}).Result(); n != 0 {
+		t.Errorf("Expected an empty intersection, got %d", n)
+	}
+	if n, _ := client.Exists(ctx, dest).Result(); n != 0 {
+		t.Error("Expected an empty result to delete the destination")
+	}
+
+	for _, args := range [][]interface{}{
+		{"ZUNIONSTORE", dest, 0, a},
+		{"ZUNIONSTORE", dest, 3, a, b},
+		{"ZUNIONSTORE", dest, 1, a, "WEIGHTS", "x"},
+		{"ZUNIONSTORE", dest, 1, a, "AGGREGATE", "AVG"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+// TestZScan tests that ZSCAN visits every member exactly once and returns its score
+func TestZScan(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:zset:scan"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	members := make([]redis.Z, 0, 300)
+	for i := 0; i < 300; i++ {
+		members = append(members, redis.Z{Score: float64(i), Member: "m:" + strconv.Itoa(i)})
+	}
+	client.ZAdd(ctx, key, members...)
+
+	seen := make(map[string]int)
+	var cursor uint64
+	for {
+		items, next, err := client.ZScan(ctx, key, cursor, "m:*", 25).Result()
+		if err != nil {
+			t.Fatalf("ZSCAN failed: %v", err)
+		}
+		if len(items)%2 != 0 {
+			t.Fatalf("Expected member-score pairs, got %v", items)
+		}
+		for i := 0; i < len(items); i += 2 {
+			seen[items[i]]++
+			if "m:"+items[i+1] != items[i] {
+				t.Errorf("Member %s returned with score %s", items[i], items[i+1])
+			}
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(seen) != 300 {
+		t.Errorf("Expected 300 members, got %d", len(seen))
+	}
+	for member, n := range seen {
+		if n != 1 {
+			t.Errorf("Member %s returned %d times", member, n)
+		}
+	}
+}
+
+// =============================================================================
+// Stream Tests
+// =============================================================================
+
+// TestXGroup tests the XGROUP subcommands
+func TestXGroup(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xgroup"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	if err := client.XGroupCreate(ctx, key, "g", "$").Err(); err == nil || !strings.Contains(err.Error(), "MKSTREAM") {
+		t.Errorf("Expected XGROUP CREATE on a missing key to fail, got %v", err)
+	}
+	if err := client.XGroupCreateMkStream(ctx, key, "g", "$").Err(); err != nil {
+		t.Fatalf("XGROUP CREATE MKSTREAM failed: %v", err)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "stream" {
+		t.Errorf("Expected MKSTREAM to create an empty stream, TYPE returned %s", typ)
+	}
+	if err := client.XGroupCreate(ctx, key, "g", "0").Err(); err == nil || !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		t.Errorf("Expected BUSYGROUP, got %v", err)
+	}
+
+	if n, err := client.XGroupCreateConsumer(ctx, key, "g", "alice").Result(); err != nil || n != 1 {
+		t.Errorf("Expected XGROUP CREATECONSUMER to return 1, got %d (%v)", n, err)
+	}
+	if n, _ := client.XGroupCreateConsumer(ctx, key, "g", "alice").Result(); n != 0 {
+		t.Errorf("Expected XGROUP CREATECONSUMER of an existing consumer to return 0, got %d", n)
+	}
+	if err := client.XGroupCreateConsumer(ctx, key, "missing", "alice").Err(); err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Errorf("Expected NOGROUP, got %v", err)
+	}
+
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-1", Values: []interface{}{"f", "1"}})
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-2", Values: []interface{}{"f", "2"}})
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Streams: []string{key, ">"}, Block: -1})
+	if n, err := client.XGroupDelConsumer(ctx, key, "g", "alice").Result(); err != nil || n != 2 {
+		t.Errorf("Expected XGROUP DELCONSUMER to return the 2 pending entries, got %d (%v)", n, err)
+	}
+	if pending, _ := client.XPending(ctx, key, "g").Result(); pending.Count != 0 {
+		t.Errorf("Expected the entries of a deleted consumer to leave the PEL, got %d", pending.Count)
+	}
+
+	// SETID rewinds the group, the entries are delivered again
+	if err := client.XGroupSetID(ctx, key, "g", "0").Err(); err != nil {
+		t.Fatalf("XGROUP SETID failed: %v", err)
+	}
+	res, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "bob", Streams: []string{key, ">"}, Block: -1}).Result()
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 2 {
+		t.Errorf("Expected 2 entries after SETID 0, got %v (%v)", res, err)
+	}
+
+	if n, err := client.XGroupDestroy(ctx, key, "g").Result(); err != nil || n != 1 {
+		t.Errorf("Expected XGROUP DESTROY to return 1, got %d (%v)", n, err)
+	}
+	if n, _ := client.XGroupDestroy(ctx, key, "g").Result(); n != 0 {
+		t.Errorf("Expected XGROUP DESTROY of a missing group to return 0, got %d", n)
+	}
+	if err := client.Do(ctx, "XGROUP", "FOO", key).Err(); err == nil || !strings.Contains(err.Error(), "unknown subcommand") {
+		t.Errorf("Expected an unknown subcommand error, got %v", err)
+	}
+}
+
+// TestXReadGroup tests that a group spreads entries over its consumers, keeps them pending
+// until XACK and lets a consumer read back its own pending entries
+func TestXReadGroup(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xreadgroup"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, key, "g", "0")
+
+	read := func(consumer string, count int64, id string) []redis.XMessage {
+		res, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: consumer, Count: count, Streams: []string{key, id}, Block: -1}).Result()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil || len(res) != 1 {
+			t.Fatalf("XREADGROUP failed: %v (%v)", res, err)
+		}
+		return res[0].Messages
+	}
+
+	alice := read("alice", 2, ">")
+	bob := read("bob", 0, ">")
+	if len(alice) != 2 || alice[0].ID != "1-1" || alice[1].ID != "1-2" || alice[0].Values["n"] != "1" {
+		t.Errorf("Expected alice to get 1-1 and 1-2, got %v", alice)
+	}
+	if len(bob) != 3 || bob[0].ID != "1-3" {
+		t.Errorf("Expected bob to get the 3 other entries, got %v", bob)
+	}
+	if msgs := read("alice", 0, ">"); len(msgs) != 0 {
+		t.Errorf("Expected no new entries, got %v", msgs)
+	}
+
+	// A consumer reads back its own pending entries, even without new ones
+	if history := read("alice", 0, "0"); len(history) != 2 || history[1].ID != "1-2" {
+		t.Errorf("Expected alice's history to be 1-1 and 1-2, got %v", history)
+	}
+	if history := read("alice", 0, "1-1"); len(history) != 1 || history[0].ID != "1-2" {
+		t.Errorf("Expected the history after 1-1 to be 1-2, got %v", history)
+	}
+
+	if n, err := client.XAck(ctx, key, "g", "1-1", "1-3", "9-9").Result(); err != nil || n != 2 {
+		t.Errorf("Expected XACK to acknowledge 2 entries, got %d (%v)", n, err)
+	}
+	if history := read("alice", 0, "0"); len(history) != 1 || history[0].ID != "1-2" {
+		t.Errorf("Expected alice's history to be 1-2 after XACK, got %v", history)
+	}
+	if n, _ := client.XAck(ctx, key, "missing", "1-2").Result(); n != 0 {
+		t.Errorf("Expected XACK on a missing group to return 0, got %d", n)
+	}
+
+	// NOACK entries are never pending
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "2-1", Values: []interface{}{"n", 6}})
+	res, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "carol", Streams: []string{key, ">"}, NoAck: true, Block: -1}).Result()
+	if err != nil || len(res[0].Messages) != 1 {
+		t.Fatalf("XREADGROUP NOACK failed: %v (%v)", res, err)
+	}
+	if history := read("carol", 0, "0"); len(history) != 0 {
+		t.Errorf("Expected NOACK to leave nothing pending, got %v", history)
+	}
+
+	err = client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "missing", Consumer: "c", Streams: []string{key, ">"}, Block: -1}).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Errorf("Expected NOGROUP, got %v", err)
+	}
+}
+
+// TestXReadGroupBlock tests that XREADGROUP with BLOCK waits for a new entry, and that a
+// client blocked on a group that gets destroyed is released with an error
+func TestXReadGroupBlock(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xreadgroup:block"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.XGroupCreateMkStream(ctx, key, "g", "$")
+
+	done := make(chan []redis.XStream, 1)
+	go func() {
+		res, _ := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "c", Streams: []string{key, ">"}, Block: 5 * time.Second}).Result()
+		done <- res
+	}()
+	time.Sleep(100 * time.Millisecond)
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "5-1", Values: []interface{}{"f", "v"}})
+
+	select {
+	case res := <-done:
+		if len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != "5-1" {
+			t.Errorf("Expected the blocked consumer to get 5-1, got %v", res)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("XREADGROUP was not woken by XADD")
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "c", Streams: []string{key, ">"}, Block: 5 * time.Second}).Err()
+	}()
+	time.Sleep(100 * time.Millisecond)
+	client.XGroupDestroy(ctx, key, "g")
+
+	select {
+	case err := <-errs:
+		if err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+			t.Errorf("Expected NOGROUP once the group is destroyed, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("XREADGROUP was not released by XGROUP DESTROY")
+	}
+}
+
+// TestXPending tests the summary and the extended form of XPENDING
+func TestXPending(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xpending"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 4; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, key, "g", "0")
+
+	summary, err := client.XPending(ctx, key, "g").Result()
+	if err != nil || summary.Count != 0 || len(summary.Consumers) != 0 {
+		t.Errorf("Expected an empty summary, got %+v (%v)", summary, err)
+	}
+
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Count: 1, Streams: []string{key, ">"}, Block: -1})
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "bob", Streams: []string{key, ">"}, Block: -1})
+	time.Sleep(50 * time.Millisecond)
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "bob", Streams: []string{key, "1-3"}, Block: -1})
+
+	summary, err = client.XPending(ctx, key, "g").Result()
+	if err != nil || summary.Count != 4 || summary.Lower != "1-1" || summary.Higher != "1-4" ||
+		summary.Consumers["alice"] != 1 || summary.Consumers["bob"] != 3 {
+		t.Errorf("Unexpected summary %+v (%v)", summary, err)
+	}
+
+	ext, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Start: "-", End: "+", Count: 10}).Result()
+	if err != nil || len(ext) != 4 {
+		t.Fatalf("Expected 4 pending entries, got %v (%v)", ext, err)
+	}
+	if ext[0].ID != "1-1" || ext[0].Consumer != "alice" || ext[0].RetryCount != 1 {
+		t.Errorf("Unexpected first pending entry %+v", ext[0])
+	}
+	if ext[3].ID != "1-4" || ext[3].RetryCount != 2 || ext[2].RetryCount != 1 {
+		t.Errorf("Expected reading 1-4 back to count as a second delivery, got %+v", ext)
+	}
+
+	ext, _ = client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Start: "1-2", End: "+", Count: 2, Consumer: "bob"}).Result()
+	if len(ext) != 2 || ext[0].ID != "1-2" || ext[1].ID != "1-3" {
+		t.Errorf("Expected bob's entries 1-2 and 1-3, got %v", ext)
+	}
+
+	// 1-4 was delivered again just now, the others have been idle longer
+	ext, _ = client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Idle: 40 * time.Millisecond, Start: "-", End: "+", Count: 10}).Result()
+	if len(ext) != 3 || ext[2].ID != "1-3" || ext[0].Idle < 40*time.Millisecond {
+		t.Errorf("Expected the 3 entries idle for 40ms, got %v", ext)
+	}
+
+	if err := client.XPending(ctx, key, "missing").Err(); err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Errorf("Expected NOGROUP, got %v", err)
+	}
+}
+
+// TestXClaim tests that XCLAIM takes over pending entries idle long enough and its options
+func TestXClaim(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xclaim"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 3; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, key, "g", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Count: 2, Streams: []string{key, ">"}, Block: -1})
+
+	// Not idle for an hour yet
+	claimed, err := client.XClaim(ctx, &redis.XClaimArgs{Stream: key, Group: "g", Consumer: "bob", MinIdle: time.Hour, Messages: []string{"1-1"}}).Result()
+	if err != nil || len(claimed) != 0 {
+		t.Errorf("Expected nothing to be claimed, got %v (%v)", claimed, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	claimed, err = client.XClaim(ctx, &redis.XClaimArgs{Stream: key, Group: "g", Consumer: "bob", MinIdle: 10 * time.Millisecond, Messages: []string{"1-1", "1-3"}}).Result()
+	if err != nil || len(claimed) != 1 || claimed[0].ID != "1-1" || claimed[0].Values["n"] != "1" {
+		t.Errorf("Expected bob to claim 1-1 only, 1-3 was never delivered, got %v (%v)", claimed, err)
+	}
+	ext, _ := client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Start: "1-1", End: "1-1", Count: 1}).Result()
+	if len(ext) != 1 || ext[0].Consumer != "bob" || ext[0].RetryCount != 2 {
+		t.Errorf("Expected 1-1 to belong to bob with 2 deliveries, got %v", ext)
+	}
+
+	// JUSTID doesn't count as a delivery, FORCE claims entries that were never delivered
+	ids, err := client.XClaimJustID(ctx, &redis.XClaimArgs{Stream: key, Group: "g", Consumer: "carol", Messages: []string{"1-2", "1-3"}}).Result()
+	if err != nil || len(ids) != 1 || ids[0] != "1-2" {
+		t.Errorf("Expected carol to claim 1-2, got %v (%v)", ids, err)
+	}
+	res, err := client.Do(ctx, "XCLAIM", key, "g", "carol", "0", "1-3", "FORCE", "RETRYCOUNT", "7", "JUSTID").Result()
+	if err != nil || fmt.Sprint(res) != "[1-3]" {
+		t.Errorf("Expected FORCE to claim 1-3, got %v (%v)", res, err)
+	}
+	ext, _ = client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Start: "1-2", End: "+", Count: 10}).Result()
+	if len(ext) != 2 || ext[0].Consumer != "carol" || ext[0].RetryCount != 1 || ext[1].RetryCount != 7 {
+		t.Errorf("Unexpected pending entries %v", ext)
+	}
+
+	// IDLE backdates the delivery
+	client.Do(ctx, "XCLAIM", key, "g", "dave", "0", "1-2", "IDLE", "60000")
+	ext, _ = client.XPendingExt(ctx, &redis.XPendingExtArgs{Stream: key, Group: "g", Start: "1-2", End: "1-2", Count: 1}).Result()
+	if len(ext) != 1 || ext[0].Consumer != "dave" || ext[0].Idle < time.Minute {
+		t.Errorf("Expected 1-2 to belong to dave and be idle for a minute, got %v", ext)
+	}
+}
+
+// TestXAutoClaim tests that XAUTOCLAIM scans the PEL with a cursor
+func TestXAutoClaim(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xautoclaim"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, key, "g", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Streams: []string{key, ">"}, Block: -1})
+	client.XAck(ctx, key, "g", "1-2")
+	time.Sleep(20 * time.Millisecond)
+
+	msgs, next, err := client.XAutoClaim(ctx, &redis.XAutoClaimArgs{Stream: key, Group: "g", Consumer: "bob", MinIdle: 10 * time.Millisecond, Start: "-", Count: 2}).Result()
+	if err != nil || len(msgs) != 2 || msgs[0].ID != "1-1" || msgs[1].ID != "1-3" || next != "1-4" {
+		t.Errorf("Expected 1-1 and 1-3 with cursor 1-4, got %v %s (%v)", msgs, next, err)
+	}
+	ids, next, err := client.XAutoClaimJustID(ctx, &redis.XAutoClaimArgs{Stream: key, Group: "g", Consumer: "bob", MinIdle: 10 * time.Millisecond, Start: next, Count: 2}).Result()
+	if err != nil || fmt.Sprint(ids) != "[1-4 1-5]" || next != "0-0" {
+		t.Errorf("Expected 1-4 and 1-5 with cursor 0-0, got %v %s (%v)", ids, next, err)
+	}
+
+	// Nothing is idle long enough right after being claimed
+	msgs, next, _ = client.XAutoClaim(ctx, &redis.XAutoClaimArgs{Stream: key, Group: "g", Consumer: "carol", MinIdle: time.Hour, Start: "0", Count: 10}).Result()
+	if len(msgs) != 0 || next != "0-0" {
+		t.Errorf("Expected nothing to be claimed, got %v %s", msgs, next)
+	}
+	if summary, _ := client.XPending(ctx, key, "g").Result(); summary.Consumers["bob"] != 4 {
+		t.Errorf("Expected bob to own the 4 pending entries, got %v", summary.Consumers)
+	}
+}
+
+func TestXRangePagination(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xrange:paging"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 10; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: strconv.Itoa(i) + "-1", Values: []interface{}{"n", i}})
+	}
+
+	// Page forward with COUNT and an exclusive start
+	var ids []string
+	start := "-"
+	for {
+		msgs, err := client.XRangeN(ctx, key, start, "+", 3).Result()
+		if err != nil {
+			t.Fatalf("XRANGE failed: %v", err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, m := range msgs {
+			ids = append(ids, m.ID)
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+	if len(ids) != 10 || ids[0] != "1-1" || ids[9] != "10-1" {
+		t.Errorf("Expected the 10 entries in order, got %v", ids)
+	}
+
+	// And backward with XREVRANGE
+	ids = nil
+	end := "+"
+	for {
+		msgs, err := client.XRevRangeN(ctx, key, end, "-", 4).Result()
+		if err != nil {
+			t.Fatalf("XREVRANGE failed: %v", err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, m := range msgs {
+			ids = append(ids, m.ID)
+		}
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+	if len(ids) != 10 || ids[0] != "10-1" || ids[9] != "1-1" {
+		t.Errorf("Expected the 10 entries newest first, got %v", ids)
+	}
+
+	if msgs, _ := client.XRevRange(ctx, key, "7", "(3-1").Result(); len(msgs) != 4 || msgs[0].ID != "7-1" || msgs[3].ID != "4-1" {
+		t.Errorf("Expected 7-1 down to 4-1, got %v", msgs)
+	}
+	if msgs, _ := client.XRange(ctx, key, "(2-1", "(5-1").Result(); len(msgs) != 2 || msgs[0].ID != "3-1" {
+		t.Errorf("Expected 3-1 and 4-1, got %v", msgs)
+	}
+	if msgs, err := client.XRangeN(ctx, key, "-", "+", 0).Result(); err != nil || len(msgs) != 0 {
+		t.Errorf("Expected COUNT 0 to return nothing, got %v (%v)", msgs, err)
+	}
+	if msgs, _ := client.XRevRange(ctx, key, "-", "+").Result(); len(msgs) != 0 {
+		t.Errorf("Expected an inverted XREVRANGE to return nothing, got %v", msgs)
+	}
+
+	for _, args := range [][]interface{}{
+		{"XRANGE", key, "(-", "+"},
+		{"XRANGE", key, "-", "(0-0"},
+		{"XRANGE", key, "(18446744073709551615-18446744073709551615", "+"},
+		{"XRANGE", key, "-", "+", "COUNT"},
+		{"XREVRANGE", key, "+", "-", "LIMIT", "1"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+func TestXReadCount(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xread:count"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+
+	res, err := client.XRead(ctx, &redis.XReadArgs{Streams: []string{key, "1-1"}, Count: 2, Block: -1}).Result()
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 2 || res[0].Messages[0].ID != "1-2" || res[0].Messages[1].ID != "1-3" {
+		t.Errorf("Expected 1-2 and 1-3, got %v (%v)", res, err)
+	}
+	res, err = client.XRead(ctx, &redis.XReadArgs{Streams: []string{key, "1-3"}, Count: 10, Block: -1}).Result()
+	if err != nil || len(res) != 1 || len(res[0].Messages) != 2 {
+		t.Errorf("Expected the 2 remaining entries, got %v (%v)", res, err)
+	}
+	if _, err := client.XRead(ctx, &redis.XReadArgs{Streams: []string{key, "1-5"}, Count: 1, Block: -1}).Result(); err != redis.Nil {
+		t.Errorf("Expected nothing after the last entry, got %v", err)
+	}
+}
+
+func TestXAddFieldOrder(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xadd:order"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	client.Do(ctx, "XADD", key, "1-1", "zeta", "1", "alpha", "2", "zeta", "3", "mid", "4")
+
+	// Fields come back in the order they were added, repeated names included
+	want := "[[1-1 [zeta 1 alpha 2 zeta 3 mid 4]]]"
+	for _, args := range [][]interface{}{
+		{"XRANGE", key, "-", "+"},
+		{"XREVRANGE", key, "+", "-"},
+	} {
+		res, err := client.Do(ctx, args...).Slice()
+		if err != nil || fmt.Sprint(res) != want {
+			t.Errorf("%v: expected %s, got %v (%v)", args[0], want, res, err)
+		}
+	}
+	read, err := client.Do(ctx, "XREAD", "STREAMS", key, "0").Result()
+	if err != nil || fmt.Sprint(read) != "map["+key+":"+want+"]" {
+		t.Errorf("XREAD: expected the fields in order, got %v (%v)", read, err)
+	}
+}
+
+func TestXInfo(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xinfo"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	if err := client.XInfoStream(ctx, key).Err(); err == nil {
+		t.Error("Expected XINFO STREAM on a missing key to fail")
+	}
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+	client.XGroupCreate(ctx, key, "g", "0")
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Count: 2, Streams: []string{key, ">"}, Block: -1})
+	client.XGroupCreateConsumer(ctx, key, "g", "bob")
+	client.XDel(ctx, key, "1-5")
+
+	info, err := client.XInfoStream(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("XINFO STREAM failed: %v", err)
+	}
+	if info.Length != 4 || info.LastGeneratedID != "1-5" || info.MaxDeletedEntryID != "1-5" || info.EntriesAdded != 5 ||
+		info.RecordedFirstEntryID != "1-1" || info.Groups != 1 || info.RadixTreeKeys != 1 || info.RadixTreeNodes < 2 {
+		t.Errorf("Unexpected XINFO STREAM reply: %+v", info)
+	}
+	if info.FirstEntry.ID != "1-1" || info.LastEntry.ID != "1-4" || info.LastEntry.Values["n"] != "4" {
+		t.Errorf("Expected first entry 1-1 and last entry 1-4, got %v and %v", info.FirstEntry, info.LastEntry)
+	}
+
+	groups, err := client.XInfoGroups(ctx, key).Result()
+	if err != nil || len(groups) != 1 || groups[0].Name != "g" || groups[0].Consumers != 2 || groups[0].Pending != 2 || groups[0].LastDeliveredID != "1-2" {
+		t.Errorf("Unexpected XINFO GROUPS reply: %+v (%v)", groups, err)
+	}
+
+	consumers, err := client.XInfoConsumers(ctx, key, "g").Result()
+	if err != nil || len(consumers) != 2 || consumers[0].Name != "alice" || consumers[0].Pending != 2 || consumers[1].Name != "bob" || consumers[1].Inactive != -time.Millisecond {
+		t.Errorf("Unexpected XINFO CONSUMERS reply: %+v (%v)", consumers, err)
+	}
+	if err := client.XInfoConsumers(ctx, key, "nope").Err(); err == nil || !strings.HasPrefix(err.Error(), "NOGROUP") {
+		t.Errorf("Expected a NOGROUP error, got %v", err)
+	}
+	if err := client.Do(ctx, "XINFO", "HELP").Err(); err == nil || err.Error() != "ERR unknown subcommand 'HELP'" {
+		t.Errorf("Expected an unknown subcommand error, got %v", err)
+	}
+
+	full, err := client.XInfoStreamFull(ctx, key, 3).Result()
+	if err != nil {
+		t.Fatalf("XINFO STREAM FULL failed: %v", err)
+	}
+	if full.Length != 4 || len(full.Entries) != 3 || full.Entries[2].ID != "1-3" || len(full.Groups) != 1 {
+		t.Fatalf("Unexpected XINFO STREAM FULL reply: %+v", full)
+	}
+	g := full.Groups[0]
+	if g.PelCount != 2 || len(g.Pending) != 2 || g.Pending[0].Consumer != "alice" || len(g.Consumers) != 2 || g.Consumers[0].PelCount != 2 || len(g.Consumers[0].Pending) != 2 {
+		t.Errorf("Unexpected group in XINFO STREAM FULL: %+v", g)
+	}
+
+	// Without deletions the group lag is known
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "2-1", Values: []interface{}{"n", 6}})
+	client.XTrimMaxLen(ctx, key, 2)
+	client.Do(ctx, "XSETID", key, "2-1", "MAXDELETEDID", "0-0")
+	if groups, _ := client.XInfoGroups(ctx, key).Result(); len(groups) != 1 || groups[0].EntriesRead != 2 || groups[0].Lag != 2 {
+		t.Errorf("Expected 2 entries read and a lag of 2, got %+v", groups)
+	}
+
+	// ENTRIESREAD sets the count of entries read, reading on from there keeps counting
+	if err := client.Do(ctx, "XGROUP", "SETID", key, "g", "1-4", "ENTRIESREAD", "5").Err(); err != nil {
+		t.Fatalf("XGROUP SETID with ENTRIESREAD failed: %v", err)
+	}
+	if groups, _ := client.XInfoGroups(ctx, key).Result(); len(groups) != 1 || groups[0].EntriesRead != 5 || groups[0].Lag != 1 {
+		t.Errorf("Expected 5 entries read and a lag of 1, got %+v", groups)
+	}
+	client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "alice", Streams: []string{key, ">"}, Block: -1})
+	if groups, _ := client.XInfoGroups(ctx, key).Result(); len(groups) != 1 || groups[0].EntriesRead != 6 || groups[0].Lag != 0 {
+		t.Errorf("Expected 6 entries read and no lag, got %+v", groups)
+	}
+	if err := client.Do(ctx, "XGROUP", "CREATE", key, "g2", "0", "ENTRIESREAD", "3", "MKSTREAM").Err(); err != nil {
+		t.Fatalf("XGROUP CREATE with ENTRIESREAD failed: %v", err)
+	}
+	if groups, _ := client.XInfoGroups(ctx, key).Result(); len(groups) != 2 || groups[1].EntriesRead != 3 {
+		t.Errorf("Expected g2 to have read 3 entries, got %+v", groups)
+	}
+	if err := client.Do(ctx, "XGROUP", "SETID", key, "g2", "0", "ENTRIESREAD", "-2").Err(); err == nil || err.Error() != "ERR value for ENTRIESREAD must be positive or -1" {
+		t.Errorf("Expected a negative ENTRIESREAD to be rejected, got %v", err)
+	}
+	if err := client.Do(ctx, "XGROUP", "SETID", key, "g2", "0", "MKSTREAM").Err(); err == nil || !strings.Contains(err.Error(), "syntax") {
+		t.Errorf("Expected XGROUP SETID to reject MKSTREAM, got %v", err)
+	}
+}
+
+func TestXDelAndXLen(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xdel"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	if n, err := client.XLen(ctx, key).Result(); err != nil || n != 0 {
+		t.Errorf("Expected 0 for a missing key, got %d (%v)", n, err)
+	}
+	for i := 1; i <= 3; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-" + strconv.Itoa(i), Values: []interface{}{"n", i}})
+	}
+
+	if n, err := client.XDel(ctx, key, "1-3", "1-1", "9-9").Result(); err != nil || n != 2 {
+		t.Errorf("Expected 2 deleted entries, got %d (%v)", n, err)
+	}
+	if n, _ := client.XLen(ctx, key).Result(); n != 1 {
+		t.Errorf("Expected 1 entry left, got %d", n)
+	}
+	if msgs, _ := client.XRange(ctx, key, "-", "+").Result(); len(msgs) != 1 || msgs[0].ID != "1-2" {
+		t.Errorf("Expected only 1-2 left, got %v", msgs)
+	}
+
+	// The deleted top entry's ID can't be reused, and the empty stream stays
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-3", Values: []interface{}{"n", 3}}).Err(); err == nil {
+		t.Error("Expected XADD with a deleted ID to fail")
+	}
+	if id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-*", Values: []interface{}{"n", 4}}).Result(); err != nil || id != "1-4" {
+		t.Errorf("Expected 1-4, got %s (%v)", id, err)
+	}
+	client.XDel(ctx, key, "1-2", "1-4")
+	if n, err := client.XLen(ctx, key).Result(); err != nil || n != 0 {
+		t.Errorf("Expected an empty stream, got %d (%v)", n, err)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "stream" {
+		t.Errorf("Expected the empty stream to remain, got type %s", typ)
+	}
+
+	if err := client.XDel(ctx, key, "bad").Err(); err == nil {
+		t.Error("Expected an invalid ID to fail")
+	}
+}
+
+func TestXTrim(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xtrim"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	for i := 1; i <= 250; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: strconv.Itoa(i) + "-0", Values: []interface{}{"n", i}})
+	}
+
+	// An approximate trim only removes whole batches of 100 entries
+	if n, err := client.XTrimMaxLenApprox(ctx, key, 120, 0).Result(); err != nil || n != 100 {
+		t.Errorf("Expected 100 entries trimmed, got %d (%v)", n, err)
+	}
+	if n, _ := client.XTrimMaxLenApprox(ctx, key, 10, 50).Result(); n != 0 {
+		t.Errorf("Expected LIMIT to prevent trimming, got %d", n)
+	}
+	if n, err := client.XTrimMaxLen(ctx, key, 100).Result(); err != nil || n != 50 {
+		t.Errorf("Expected 50 entries trimmed, got %d (%v)", n, err)
+	}
+	if n, err := client.XTrimMinID(ctx, key, "161-0").Result(); err != nil || n != 10 {
+		t.Errorf("Expected 10 entries trimmed, got %d (%v)", n, err)
+	}
+	if msgs, _ := client.XRange(ctx, key, "-", "+").Result(); len(msgs) == 0 || msgs[0].ID != "161-0" {
+		t.Errorf("Expected 161-0 to be the first entry, got %d entries", len(msgs))
+	}
+	if n, _ := client.XLen(ctx, key).Result(); n != 90 {
+		t.Errorf("Expected 90 entries, got %d", n)
+	}
+	if n, _ := client.XTrimMaxLen(ctx, "test:xtrim:missing", 0).Result(); n != 0 {
+		t.Errorf("Expected 0 for a missing key, got %d", n)
+	}
+
+	for _, args := range [][]interface{}{
+		{"XTRIM", key, "MAXLEN", "-1"},
+		{"XTRIM", key, "MAXLEN", "10", "LIMIT", "5"},
+		{"XTRIM", key, "MAXLEN", "~", "10", "LIMIT", "-1"},
+		{"XTRIM", key, "MINID", "bad"},
+		{"XTRIM", key, "SIZE", "10"},
+	} {
+		if err := client.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
+
+func TestXAddTrimOptions(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xadd:trim"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	res, err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, NoMkStream: true, Values: []interface{}{"f", "v"}}).Result()
+	if err != redis.Nil {
+		t.Errorf("Expected a nil reply with NOMKSTREAM, got %q (%v)", res, err)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected NOMKSTREAM not to create the stream")
+	}
+
+	for i := 1; i <= 5; i++ {
+		client.XAdd(ctx, &redis.XAddArgs{Stream: key, MaxLen: 3, ID: strconv.Itoa(i) + "-0", Values: []interface{}{"n", i}})
+	}
+	if msgs, _ := client.XRange(ctx, key, "-", "+").Result(); len(msgs) != 3 || msgs[0].ID != "3-0" {
+		t.Errorf("Expected 3-0 to 5-0, got %v", msgs)
+	}
+	if _, err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, NoMkStream: true, MinID: "5-0", ID: "6-0", Values: []interface{}{"n", 6}}).Result(); err != nil {
+		t.Errorf("XADD NOMKSTREAM MINID failed: %v", err)
+	}
+	if msgs, _ := client.XRange(ctx, key, "-", "+").Result(); len(msgs) != 2 || msgs[0].ID != "5-0" {
+		t.Errorf("Expected 5-0 and 6-0, got %v", msgs)
+	}
+	if _, err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, MaxLen: 1, Approx: true, ID: "7-0", Values: []interface{}{"n", 7}}).Result(); err != nil {
+		t.Errorf("XADD MAXLEN ~ failed: %v", err)
+	}
+	if n, _ := client.XLen(ctx, key).Result(); n != 3 {
+		t.Errorf("Expected an approximate trim to keep a partial batch, got %d entries", n)
+	}
+
+	if err := client.Do(ctx, "XADD", key, "MAXLEN", "2", "LIMIT", "10", "*", "f", "v").Err(); err == nil {
+		t.Error("Expected LIMIT without ~ to fail")
+	}
+	if err := client.Do(ctx, "XADD", key, "MAXLEN", "2", "*", "f").Err(); err == nil {
+		t.Error("Expected a field without a value to fail")
+	}
+}
+
+func TestXSetID(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:xsetid"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+	if err := client.Do(ctx, "XSETID", key, "1-0").Err(); err == nil {
+		t.Error("Expected XSETID on a missing key to fail")
+	}
+
+	client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "5-0", Values: []interface{}{"f", "v"}})
+	if err := client.Do(ctx, "XSETID", key, "4-0").Err(); err == nil {
+		t.Error("Expected an ID smaller than the top item to fail")
+	}
+	if err := client.Do(ctx, "XSETID", key, "10-0", "ENTRIESADDED", "0").Err(); err == nil {
+		t.Error("Expected entries added smaller than the length to fail")
+	}
+	if err := client.Do(ctx, "XSETID", key, "10-0", "MAXDELETEDID", "11-0").Err(); err == nil {
+		t.Error("Expected a max deleted ID greater than the last ID to fail")
+	}
+	if err := client.Do(ctx, "XSETID", key, "10-0", "ENTRIESADDED", "7", "MAXDELETEDID", "9-0").Err(); err != nil {
+		t.Fatalf("XSETID failed: %v", err)
+	}
+	if id, err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "10-*", Values: []interface{}{"f", "v"}}).Result(); err != nil || id != "10-1" {
+		t.Errorf("Expected 10-1 after XSETID, got %s (%v)", id, err)
+	}
+
+	// The stream's own max deleted ID is a bound too, a deleted ID can't be handed out again
+	client.XDel(ctx, key, "10-1")
+	if err := client.Do(ctx, "XSETID", key, "10-0").Err(); err == nil || !strings.Contains(err.Error(), "max_deleted_entry_id") {
+		t.Errorf("Expected an ID smaller than the max deleted ID to fail, got %v", err)
+	}
+	if err := client.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "10-1", Values: []interface{}{"f", "v"}}).Err(); err == nil {
+		t.Error("Expected XADD to refuse the deleted ID 10-1")
+	}
+}
+
+// =============================================================================
+// Keyspace Tests
+// =============================================================================
+
+// TestWrongType tests that commands fail on keys holding another type instead of
+// creating a second value under the same key
+func TestWrongType(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	stringKey := "test:wrongtype:string"
+	listKey := "test:wrongtype:list"
+	streamKey := "test:wrongtype:stream"
+	client.Set(ctx, stringKey, "value", 0)
+	client.RPush(ctx, listKey, "item")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: streamKey, ID: "1-1", Values: []interface{}{"f", "v"}})
+	defer client.Del(ctx, stringKey, listKey, streamKey)
+
+	checks := []struct {
+		name string
+		err  error
+	}{
+		{"RPUSH on string", client.RPush(ctx, stringKey, "x").Err()},
+		{"LPUSH on string", client.LPush(ctx, stringKey, "x").Err()},
+		{"LPOP on string", client.LPop(ctx, stringKey).Err()},
+		{"LLEN on string", client.LLen(ctx, stringKey).Err()},
+		{"LRANGE on string", client.LRange(ctx, stringKey, 0, -1).Err()},
+		{"BLPOP on string", client.Do(ctx, "BLPOP", stringKey, "0.1").Err()},
+		{"RPOP on string", client.RPop(ctx, stringKey).Err()},
+		{"LINDEX on string", client.LIndex(ctx, stringKey, 0).Err()},
+		{"LSET on string", client.LSet(ctx, stringKey, 0, "x").Err()},
+		{"LREM on string", client.LRem(ctx, stringKey, 0, "x").Err()},
+		{"LTRIM on string", client.LTrim(ctx, stringKey, 0, 1).Err()},
+		{"LPOS on string", client.LPos(ctx, stringKey, "x", redis.LPosArgs{}).Err()},
+		{"LPUSHX on string", client.LPushX(ctx, stringKey, "x").Err()},
+		{"XADD on string", client.XAdd(ctx, &redis.XAddArgs{Stream: stringKey, Values: []interface{}{"f", "v"}}).Err()},
+		{"XRANGE on list", client.XRange(ctx, listKey, "-", "+").Err()},
+		{"XREVRANGE on list", client.XRevRange(ctx, listKey, "+", "-").Err()},
+		{"XINFO STREAM on list", client.XInfoStream(ctx, listKey).Err()},
+		{"XREAD on list", client.XRead(ctx, &redis.XReadArgs{Streams: []string{listKey, "0-0"}, Block: -1}).Err()},
+		{"XGROUP CREATE on list", client.XGroupCreate(ctx, listKey, "g", "0").Err()},
+		{"XREADGROUP on list", client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "g", Consumer: "c", Streams: []string{listKey, ">"}, Block: -1}).Err()},
+		{"XACK on list", client.XAck(ctx, listKey, "g", "1-1").Err()},
+		{"XLEN on string", client.XLen(ctx, stringKey).Err()},
+		{"XDEL on list", client.XDel(ctx, listKey, "1-1").Err()},
+		{"XTRIM on string", client.XTrimMaxLen(ctx, stringKey, 1).Err()},
+		{"GET on list", client.Get(ctx, listKey).Err()},
+		{"GET on stream", client.Get(ctx, streamKey).Err()},
+		{"RPUSH on stream", client.RPush(ctx, streamKey, "x").Err()},
+		{"HSET on string", client.HSet(ctx, stringKey, "f", "v").Err()},
+		{"HGET on list", client.HGet(ctx, listKey, "f").Err()},
+		{"HGETALL on stream", client.HGetAll(ctx, streamKey).Err()},
+		{"SADD on string", client.SAdd(ctx, stringKey, "m").Err()},
+		{"SMEMBERS on list", client.SMembers(ctx, listKey).Err()},
+		{"ZADD on string", client.ZAdd(ctx, stringKey, redis.Z{Score: 1, Member: "m"}).Err()},
+		{"ZRANGE on list", client.ZRange(ctx, listKey, 0, -1).Err()},
+		{"ZUNIONSTORE from string", client.ZUnionStore(ctx, "test:wrongtype:dest", &redis.ZStore{Keys: []string{stringKey}}).Err()},
+	}
+	for _, check := range checks {
+		if check.err == nil || !strings.HasPrefix(check.err.Error(), "WRONGTYPE") {
+			t.Errorf("%s: expected WRONGTYPE error, got %v", check.name, check.err)
+		}
+	}
+
+	// The original values must be untouched
+	if val, err := client.Get(ctx, stringKey).Result(); err != nil || val != "value" {
+		t.Errorf("Expected %s = value, got %q (%v)", stringKey, val, err)
+	}
+	if n, err := client.LLen(ctx, listKey).Result(); err != nil || n != 1 {
+		t.Errorf("Expected %s to have 1 element, got %d (%v)", listKey, n, err)
+	}
+}
+
+// TestKeyCommandsAllTypes tests that DEL, EXISTS and TYPE see lists and streams
+func TestKeyCommandsAllTypes(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	listKey := "test:keyspace:list"
+	streamKey := "test:keyspace:stream"
+	client.Del(ctx, listKey, streamKey)
+	client.RPush(ctx, listKey, "a", "b")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: streamKey, ID: "1-1", Values: []interface{}{"f", "v"}})
+
+	if typ, _ := client.Type(ctx, streamKey).Result(); typ != "stream" {
+		t.Errorf("Expected type 'stream', got '%s'", typ)
+	}
+	if n, _ := client.Exists(ctx, listKey, streamKey).Result(); n != 2 {
+		t.Errorf("Expected EXISTS to count 2 keys, got %d", n)
+	}
+
+	deleted, err := client.Del(ctx, listKey, streamKey).Result()
+	if err != nil {
+		t.Fatalf("DEL failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected DEL to delete 2 keys, got %d", deleted)
+	}
+
+	if n, _ := client.Exists(ctx, listKey, streamKey).Result(); n != 0 {
+		t.Errorf("Expected EXISTS to count 0 keys after DEL, got %d", n)
+	}
+	for _, key := range []string{listKey, streamKey} {
+		if typ, _ := client.Type(ctx, key).Result(); typ != "none" {
+			t.Errorf("Expected type 'none' for %s after DEL, got '%s'", key, typ)
+		}
+	}
+	if entries, err := client.XRange(ctx, streamKey, "-", "+").Result(); err != nil || len(entries) != 0 {
+		t.Errorf("Expected deleted stream to be empty, got %v (%v)", entries, err)
+	}
+
+	// A deleted key can be reused with another type
+	if err := client.Set(ctx, listKey, "now a string", 0).Err(); err != nil {
+		t.Errorf("SET on a deleted list failed: %v", err)
+	}
+	client.Del(ctx, listKey)
+}
+
+// TestSetReplacesOtherTypes tests that SET overwrites a key of any type
+func TestSetReplacesOtherTypes(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:keyspace:replace"
+	client.Del(ctx, key)
+	client.RPush(ctx, key, "item")
+	defer client.Del(ctx, key)
+
+	if err := client.Set(ctx, key, "value", 0).Err(); err != nil {
+		t.Fatalf("SET failed: %v", err)
+	}
+	if typ, _ := client.Type(ctx, key).Result(); typ != "string" {
+		t.Errorf("Expected type 'string', got '%s'", typ)
+	}
+	if val, err := client.Get(ctx, key).Result(); err != nil || val != "value" {
+		t.Errorf("Expected value, got %q (%v)", val, err)
+	}
+}
+
+// TestEmptyListIsDeleted tests that a list stops existing once its last element is
+// popped and that blocking on a missing key doesn't create it
+func TestEmptyListIsDeleted(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:keyspace:emptylist"
+	client.Del(ctx, key)
+	client.RPush(ctx, key, "only")
+	client.LPop(ctx, key)
+
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Errorf("Expected empty list to be deleted, EXISTS returned %d", n)
+	}
+
+	client.Do(ctx, "BLPOP", key, "0.1")
+	if typ, _ := client.Type(ctx, key).Result(); typ != "none" {
+		t.Errorf("Expected BLPOP not to create the key, TYPE returned '%s'", typ)
+	}
+}
+
+// =============================================================================
+// Expiry Tests
+// =============================================================================
+
+// TestExpireAndTTL tests EXPIRE, TTL and PTTL including the -1 and -2 replies
+func TestExpireAndTTL(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:expire:ttl"
+	client.Set(ctx, key, "value", 0)
+	defer client.Del(ctx, key)
+
+	if ttl, _ := client.Do(ctx, "TTL", key).Int64(); ttl != -1 {
+		t.Errorf("Expected TTL -1 for a key without expiry, got %d", ttl)
+	}
+	if ttl, _ := client.Do(ctx, "TTL", "test:expire:missing").Int64(); ttl != -2 {
+		t.Errorf("Expected TTL -2 for a missing key, got %d", ttl)
+	}
+
+	if ok, err := client.Expire(ctx, key, 100*time.Second).Result(); err != nil || !ok {
+		t.Fatalf("EXPIRE failed: %v %v", ok, err)
+	}
+	if ttl, _ := client.Do(ctx, "TTL", key).Int64(); ttl != 100 {
+		t.Errorf("Expected TTL 100, got %d", ttl)
+	}
+	if pttl, _ := client.Do(ctx, "PTTL", key).Int64(); pttl <= 99000 || pttl > 100000 {
+		t.Errorf("Expected PTTL close to 100000, got %d", pttl)
+	}
+
+	if ok, _ := client.Expire(ctx, "test:expire:missing", time.Second).Result(); ok {
+		t.Error("EXPIRE on a missing key should return 0")
+	}
+}
+
+// TestPExpireRemovesKey tests that a key is gone once its ttl elapses
+func TestPExpireRemovesKey(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:expire:pexpire"
+	client.Set(ctx, key, "value", 0)
+	client.PExpire(ctx, key, 100*time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	if _, err := client.Get(ctx, key).Result(); err != redis.Nil {
+		t.Errorf("Expected key to have expired, got %v", err)
+	}
+	if ttl, _ := client.Do(ctx, "PTTL", key).Int64(); ttl != -2 {
+		t.Errorf("Expected PTTL -2 after expiry, got %d", ttl)
+	}
+}
+
+// TestExpireAtAndExpireTime tests absolute deadlines
+func TestExpireAtAndExpireTime(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:expire:at"
+	client.Set(ctx, key, "value", 0)
+	defer client.Del(ctx, key)
+
+	if et, _ := client.Do(ctx, "EXPIRETIME", key).Int64(); et != -1 {
+		t.Errorf("Expected EXPIRETIME -1, got %d", et)
+	}
+
+	deadline := time.Now().Add(time.Hour).Unix()
+	client.Do(ctx, "EXPIREAT", key, deadline)
+	if et, _ := client.Do(ctx, "EXPIRETIME", key).Int64(); et != deadline {
+		t.Errorf("Expected EXPIRETIME %d, got %d", deadline, et)
+	}
+	if pet, _ := client.Do(ctx, "PEXPIRETIME", key).Int64(); pet != deadline*1000 {
+		t.Errorf("Expected PEXPIRETIME %d, got %d", deadline*1000, pet)
+	}
+
+	// A deadline in the past deletes the key right away
+	if n, _ := client.Do(ctx, "PEXPIREAT", key, 1).Int64(); n != 1 {
+		t.Errorf("Expected PEXPIREAT in the past to return 1, got %d", n)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected key to be deleted by a deadline in the past")
+	}
+}
+
+// TestPersist tests removing an expiry
+func TestPersist(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:expire:persist"
+	client.Set(ctx, key, "value", time.Minute)
+	defer client.Del(ctx, key)
+
+	if ok, _ := client.Persist(ctx, key).Result(); !ok {
+		t.Error("Expected PERSIST to return 1 for a key with a ttl")
+	}
+	if ttl, _ := client.Do(ctx, "TTL", key).Int64(); ttl != -1 {
+		t.Errorf("Expected TTL -1 after PERSIST, got %d", ttl)
+	}
+	if ok, _ := client.Persist(ctx, key).Result(); ok {
+		t.Error("Expected PERSIST to return 0 for a key without a ttl")
+	}
+}
+
+// TestExpireFlags tests the NX, XX, GT and LT options
+func TestExpireFlags(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:expire:flags"
+	client.Set(ctx, key, "value", 0)
+	defer client.Del(ctx, key)
+
+	steps := []struct {
+		args []interface{}
+		want int64
+		ttl  int64
+	}{
+		{[]interface{}{"EXPIRE", key, 100, "XX"}, 0, -1}, // no ttl yet
+		{[]interface{}{"EXPIRE", key, 100, "GT"}, 0, -1}, // no ttl counts as infinite
+		{[]interface{}{"EXPIRE", key, 100, "NX"}, 1, 100},
+		{[]interface{}{"EXPIRE", key, 200, "NX"}, 0, 100},
+		{[]interface{}{"EXPIRE", key, 50, "GT"}, 0, 100},
+		{[]interface{}{"EXPIRE", key, 200, "GT"}, 1, 200},
+		{[]interface{}{"EXPIRE", key, 300, "LT"}, 0, 200},
+		{[]interface{}{"EXPIRE", key, 150, "LT"}, 1, 150},
+		{[]interface{}{"EXPIRE", key, 120, "XX", "LT"}, 1, 120},
+	}
+	for _, step := range steps {
+		got, err := client.Do(ctx, step.args...).Int64()
+		if err != nil || got != step.want {
+			t.Errorf("%v: expected %d, got %d (%v)", step.args, step.want, got, err)
+		}
+		if ttl, _ := client.Do(ctx, "TTL", key).Int64(); ttl != step.ttl {
+			t.Errorf("%v: expected TTL %d, got %d", step.args, step.ttl, ttl)
+		}
+	}
+
+	if err := client.Do(ctx, "EXPIRE", key, 10, "NX", "XX").Err(); err == nil {
+		t.Error("Expected an error for NX together with XX")
+	}
+	if err := client.Do(ctx, "EXPIRE", key, 10, "GT", "LT").Err(); err == nil {
+		t.Error("Expected an error for GT together with LT")
+	}
+	if err := client.Do(ctx, "EXPIRE", key, "soon").Err(); err == nil {
+		t.Error("Expected an error for a non integer ttl")
+	}
+}
+
+// TestExpireAllTypes tests that lists and streams expire like strings
+func TestExpireAllTypes(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	listKey := "test:expire:list"
+	streamKey := "test:expire:stream"
+	client.Del(ctx, listKey, streamKey)
+	client.RPush(ctx, listKey, "a", "b")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: streamKey, ID: "1-1", Values: []interface{}{"f", "v"}})
+
+	for _, key := range []string{listKey, streamKey} {
+		if ok, err := client.PExpire(ctx, key, 100*time.Millisecond).Result(); err != nil || !ok {
+			t.Errorf("PEXPIRE on %s failed: %v %v", key, ok, err)
+		}
+	}
+
+	// Modifying a list keeps its ttl
+	client.RPush(ctx, listKey, "c")
+	if pttl, _ := client.Do(ctx, "PTTL", listKey).Int64(); pttl <= 0 {
+		t.Errorf("Expected RPUSH to keep the ttl, got PTTL %d", pttl)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if n, _ := client.Exists(ctx, listKey, streamKey).Result(); n != 0 {
+		t.Errorf("Expected both keys to have expired, EXISTS returned %d", n)
+	}
+	if n, _ := client.LLen(ctx, listKey).Result(); n != 0 {
+		t.Errorf("Expected expired list to be empty, got %d", n)
+	}
+}
+
+// infoField returns the value of a field in the output of INFO
+func infoField(t *testing.T, client *redis.Client, section, field string) string {
+	t.Helper()
+	info, err := client.Info(context.Background(), section).Result()
+	if err != nil {
+		t.Fatalf("INFO failed: %v", err)
+	}
+	for _, line := range strings.Split(info, "\r\n") {
+		if value, ok := strings.CutPrefix(line, field+":"); ok {
+			return value
+		}
+	}
+	t.Fatalf("INFO %s has no field %s:\n%s", section, field, info)
+	return ""
+}
+
+// TestActiveExpire tests that expired keys are removed without being accessed
+func TestActiveExpire(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	before, _ := strconv.Atoi(infoField(t, client, "stats", "expired_keys"))
+
+	pipe := client.Pipeline()
+	for i := 0; i < 500; i++ {
+		pipe.Set(ctx, "test:activeexpire:"+strconv.Itoa(i), "value", 50*time.Millisecond)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatalf("Pipeline failed: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		after, _ := strconv.Atoi(infoField(t, client, "stats", "expired_keys"))
+		if after-before >= 500 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 500 keys to be actively expired, only %d were", after-before)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if _, err := strconv.ParseFloat(infoField(t, client, "stats", "expired_stale_perc"), 64); err != nil {
+		t.Errorf("expired_stale_perc is not a number: %v", err)
+	}
+}
+
+// TestConfigHz tests that hz is clamped to the supported range
+func TestConfigHz(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+	defer client.ConfigSet(ctx, "hz", "10")
+
+	if err := client.ConfigSet(ctx, "hz", "1000").Err(); err != nil {
+		t.Fatalf("CONFIG SET hz failed: %v", err)
+	}
+	if hz := client.ConfigGet(ctx, "hz").Val()["hz"]; hz != "500" {
+		t.Errorf("Expected hz to be clamped to 500, got %s", hz)
+	}
+	if infoField(t, client, "server", "hz") != "500" {
+		t.Error("Expected INFO to report the new hz")
+	}
+	if err := client.ConfigSet(ctx, "hz", "fast").Err(); err == nil {
+		t.Error("Expected an error for a non integer hz")
+	}
+}
+
+// TestConfigSetRejected tests that a value CONFIG SET rejects leaves the parameter and its
+// side effects untouched
+func TestConfigSetRejected(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	before := client.ConfigGet(ctx, "*").Val()
+	rejected := [][2]string{
+		{"hz", "fast"},
+		{"notify-keyspace-events", "KEZ"},
+		{"client-output-buffer-limit", "pubsub 1mb"},
+		{"appendfsync", "sometimes"},
+		{"appendonly", "maybe"},
+	}
+	for _, r := range rejected {
+		if err := client.ConfigSet(ctx, r[0], r[1]).Err(); err == nil {
+			t.Errorf("Expected CONFIG SET %s %s to fail", r[0], r[1])
+		}
+	}
+
+	// Turning the AOF on fails when its file can't be created, it must stay off
+	if err := client.ConfigSet(ctx, "dir", "/nonexistent/keyforge").Err(); err != nil {
+		t.Fatalf("CONFIG SET dir failed: %v", err)
+	}
+	err := client.ConfigSet(ctx, "appendonly", "yes").Err()
+	client.ConfigSet(ctx, "dir", before["dir"])
+	if err == nil {
+		client.ConfigSet(ctx, "appendonly", "no")
+		t.Fatal("Expected CONFIG SET appendonly yes to fail without a writable dir")
+	}
+	if infoField(t, client, "persistence", "aof_enabled") != "0" {
+		t.Error("Expected the AOF to stay disabled")
+	}
+
+	after := client.ConfigGet(ctx, "*").Val()
+	for param, value := range before {
+		if after[param] != value {
+			t.Errorf("Expected %s to stay %q, got %q", param, value, after[param])
+		}
+	}
+}
+
+// =============================================================================
+// Transaction Tests
+// =============================================================================
+
+// TestMultiExec tests that queued commands run on EXEC and reply in order, with keys of
+// different types spread over several shards
+func TestMultiExec(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	keys := []string{"test:multi:str", "test:multi:list", "test:multi:set", "test:multi:zset"}
+	client.Del(ctx, keys...)
+	defer client.Del(ctx, keys...)
+
+	conn := client.Conn()
+	defer conn.Close()
+
+	if res, err := conn.Do(ctx, "MULTI").Result(); err != nil || res != "OK" {
+		t.Fatalf("Expected MULTI to reply OK, got %v (%v)", res, err)
+	}
+	if res, _ := conn.Do(ctx, "SET", keys[0], "v").Result(); res != "QUEUED" {
+		t.Errorf("Expected SET to be QUEUED, got %v", res)
+	}
+	conn.Do(ctx, "RPUSH", keys[1], "a", "b")
+	conn.Do(ctx, "SADD", keys[2], "m")
+	conn.Do(ctx, "ZADD", keys[3], "1", "z")
+	conn.Do(ctx, "GET", keys[0])
+	conn.Do(ctx, "LPOP", keys[1])
+
+	// Nothing runs before EXEC
+	if n, _ := client.Exists(ctx, keys...).Result(); n != 0 {
+		t.Errorf("Expected no key to exist before EXEC, %d do", n)
+	}
+
+	res, err := conn.Do(ctx, "EXEC").Slice()
+	if err != nil {
+		t.Fatalf("EXEC failed: %v", err)
+	}
+	want := []interface{}{"OK", int64(2), int64(1), int64(1), "v", "a"}
+	if fmt.Sprint(res) != fmt.Sprint(want) {
+		t.Errorf("Expected EXEC to reply %v, got %v", want, res)
+	}
+	if n, _ := client.Exists(ctx, keys...).Result(); n != 4 {
+		t.Errorf("Expected 4 keys after EXEC, got %d", n)
+	}
+
+	// Errors of a queued command don't stop the others
+	cmds, _ := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, keys[0], "x")
+		pipe.Get(ctx, keys[0])
+		return nil
+	})
+	if len(cmds) != 2 || !strings.Contains(fmt.Sprint(cmds[0].Err()), "WRONGTYPE") || cmds[1].(*redis.StringCmd).Val() != "v" {
+		t.Errorf("Unexpected replies with a failing command: %v", cmds)
+	}
+}
+
+// TestMultiErrors tests DISCARD, EXEC and DISCARD without MULTI, nested MULTI and EXECABORT
+func TestMultiErrors(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:multi:errors"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	conn := client.Conn()
+	defer conn.Close()
+
+	for _, cmd := range []string{"EXEC", "DISCARD"} {
+		if err := conn.Do(ctx, cmd).Err(); err == nil || !strings.Contains(err.Error(), "without MULTI") {
+			t.Errorf("Expected %s without MULTI to fail, got %v", cmd, err)
+		}
+	}
+
+	conn.Do(ctx, "MULTI")
+	if err := conn.Do(ctx, "MULTI").Err(); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Expected nested MULTI to fail, got %v", err)
+	}
+	if err := conn.Do(ctx, "WATCH", key).Err(); err == nil || !strings.Contains(err.Error(), "inside MULTI") {
+		t.Errorf("Expected WATCH inside MULTI to fail, got %v", err)
+	}
+	conn.Do(ctx, "SET", key, "1")
+	if res, _ := conn.Do(ctx, "DISCARD").Result(); res != "OK" {
+		t.Errorf("Expected DISCARD to reply OK, got %v", res)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected a discarded SET not to run")
+	}
+
+	conn.Do(ctx, "MULTI")
+	conn.Do(ctx, "SET", key, "1")
+	if err := conn.Do(ctx, "NOTACOMMAND").Err(); err == nil {
+		t.Error("Expected an unknown command to be refused")
+	}
+	if err := conn.Do(ctx, "SAVE").Err(); err == nil {
+		t.Error("Expected SAVE to be refused inside MULTI")
+	}
+	if err := conn.Do(ctx, "EXEC").Err(); err == nil || !strings.HasPrefix(err.Error(), "EXECABORT") {
+		t.Errorf("Expected EXECABORT, got %v", err)
+	}
+	if n, _ := client.Exists(ctx, key).Result(); n != 0 {
+		t.Error("Expected an aborted transaction not to run")
+	}
+
+	// The connection is out of MULTI again
+	if res, _ := conn.Do(ctx, "SET", key, "2").Result(); res != "OK" {
+		t.Errorf("Expected SET after EXECABORT to run, got %v", res)
+	}
+}
+
+// TestWatch tests that EXEC fails once a watched key is modified, deleted or expired, and
+// runs when it isn't
+func TestWatch(t *testing.T) {
+	client := newTestClient()
+	other := newTestClient()
+	defer client.Close()
+	defer other.Close()
+	ctx := context.Background()
+
+	key := "test:watch:key"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	incr := func(modify func()) error {
+		return client.Watch(ctx, func(tx *redis.Tx) error {
+			n, err := tx.Get(ctx, key).Int()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			modify()
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, n+1, 0)
+				return nil
+			})
+			return err
+		}, key)
+	}
+
+	if err := incr(func() {}); err != nil {
+		t.Errorf("Expected an unmodified watched key to let EXEC run, got %v", err)
+	}
+	if err := incr(func() { other.Set(ctx, key, "10", 0) }); err != redis.TxFailedErr {
+		t.Errorf("Expected a SET by another client to abort EXEC, got %v", err)
+	}
+	if err := incr(func() { other.Del(ctx, key) }); err != redis.TxFailedErr {
+		t.Errorf("Expected a DEL by another client to abort EXEC, got %v", err)
+	}
+	if err := incr(func() { other.RPush(ctx, "test:watch:other", "x") }); err != nil {
+		t.Errorf("Expected a write to another key not to abort EXEC, got %v", err)
+	}
+	other.Del(ctx, "test:watch:other")
+	if v, _ := client.Get(ctx, key).Result(); v != "1" {
+		t.Errorf("Expected 1, got %s", v)
+	}
+
+	// A key expiring while watched counts as modified
+	client.Set(ctx, key, "1", 50*time.Millisecond)
+	if err := incr(func() { time.Sleep(100 * time.Millisecond) }); err != redis.TxFailedErr {
+		t.Errorf("Expected an expired watched key to abort EXEC, got %v", err)
+	}
+
+	// UNWATCH forgets the keys
+	conn := client.Conn()
+	defer conn.Close()
+	conn.Do(ctx, "WATCH", key)
+	other.Set(ctx, key, "5", 0)
+	conn.Do(ctx, "UNWATCH")
+	conn.Do(ctx, "MULTI")
+	conn.Do(ctx, "GET", key)
+	if res, err := conn.Do(ctx, "EXEC").Slice(); err != nil || len(res) != 1 || res[0] != "5" {
+		t.Errorf("Expected EXEC after UNWATCH to run, got %v (%v)", res, err)
+	}
+}
+
+// TestMultiAtomic tests that a transaction writing keys on different shards is never seen
+// half applied by another transaction
+func TestMultiAtomic(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	keys := []string{"test:multi:atomic:a", "test:multi:atomic:b", "test:multi:atomic:c"}
+	client.Del(ctx, keys...)
+	defer client.Del(ctx, keys...)
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					for _, key := range keys {
+						pipe.Set(ctx, key, fmt.Sprintf("%d-%d", i, j), 0)
+					}
+					return nil
+				})
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		cmds, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			t.Fatalf("EXEC failed: %v", err)
+		}
+		first := cmds[0].(*redis.StringCmd).Val()
+		for _, cmd := range cmds[1:] {
+			if v := cmd.(*redis.StringCmd).Val(); v != first {
+				t.Fatalf("Saw a partial transaction: %s and %s", first, v)
+			}
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+// TestBlockingInMulti tests that blocking commands queued in a transaction don't block
+func TestBlockingInMulti(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	key := "test:multi:blocking"
+	client.Del(ctx, key)
+	defer client.Del(ctx, key)
+
+	start := time.Now()
+	cmds, _ := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.BLPop(ctx, 0, key)
+		pipe.RPush(ctx, key, "a")
+		pipe.BLPop(ctx, 0, key)
+		return nil
+	})
+	if time.Since(start) > time.Second {
+		t.Error("Expected BLPOP inside MULTI not to block")
+	}
+	if len(cmds) != 3 || cmds[0].Err() != redis.Nil || fmt.Sprint(cmds[2].(*redis.StringSliceCmd).Val()) != "["+key+" a]" {
+		t.Errorf("Unexpected replies: %v", cmds)
+	}
+}
+
+// =============================================================================
+// Pub/Sub Tests
+// =============================================================================
+
+// TestPubSubBasic tests basic publish/subscribe functionality
+func TestPubSubBasic(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:basic"
+	message := "Hello, PubSub!"
+
+	// Subscribe to channel
+	pubsub := subscriber.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	// Wait for subscription confirmation
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		t.Fatalf("Failed to receive subscription confirmation: %v", err)
+	}
+
+	// Publish message
+	receiversCount, err := publisher.Publish(ctx, channel, message).Result()
+	if err != nil {
+		t.Fatalf("PUBLISH failed: %v", err)
+	}
+	if receiversCount != 1 {
+		t.Errorf("Expected 1 receiver, got %d", receiversCount)
+	}
+
+	// Receive the message
+	msg, err := pubsub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("Failed to receive message: %v", err)
+	}
+	if msg.Channel != channel {
+		t.Errorf("Expected channel %s, got %s", channel, msg.Channel)
+	}
+	if msg.Payload != message {
+		t.Errorf("Expected message %s, got %s", message, msg.Payload)
+	}
+}
+
+// TestPubSubMultipleSubscribers tests publishing to multiple subscribers
+func TestPubSubMultipleSubscribers(t *testing.T) {
+	publisher := newTestClient()
+	sub1 := newTestClient()
+	sub2 := newTestClient()
+	defer publisher.Close()
+	defer sub1.Close()
+	defer sub2.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:multi"
+
+	// Subscribe both clients
+	pubsub1 := sub1.Subscribe(ctx, channel)
+	pubsub2 := sub2.Subscribe(ctx, channel)
+	defer pubsub1.Close()
+	defer pubsub2.Close()
+
+	// Wait for subscription confirmations
+	pubsub1.Receive(ctx)
+	pubsub2.Receive(ctx)
+
+	// Publish
+	receiversCount, err := publisher.Publish(ctx, channel, "broadcast").Result()
+	if err != nil {
+		t.Fatalf("PUBLISH failed: %v", err)
+	}
+	if receiversCount != 2 {
+		t.Errorf("Expected 2 receivers, got %d", receiversCount)
+	}
+
+	// Both should receive
+	msg1, _ := pubsub1.ReceiveMessage(ctx)
+	msg2, _ := pubsub2.ReceiveMessage(ctx)
+
+	if msg1.Payload != "broadcast" {
+		t.Errorf("Subscriber 1: expected 'broadcast', got '%s'", msg1.Payload)
+	}
+	if msg2.Payload != "broadcast" {
+		t.Errorf("Subscriber 2: expected 'broadcast', got '%s'", msg2.Payload)
+	}
+}
+
+// TestPubSubMultipleChannels tests subscribing to multiple channels
+func TestPubSubMultipleChannels(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	ch1 := "test:pubsub:ch1"
+	ch2 := "test:pubsub:ch2"
+
+	// Subscribe to both channels
+	pubsub := subscriber.Subscribe(ctx, ch1, ch2)
+	defer pubsub.Close()
+
+	// Wait for both subscription confirmations
+	pubsub.Receive(ctx)
+	pubsub.Receive(ctx)
+
+	// Publish to channel 1
+	publisher.Publish(ctx, ch1, "message1")
+	msg, _ := pubsub.ReceiveMessage(ctx)
+	if msg.Channel != ch1 || msg.Payload != "message1" {
+		t.Errorf("Expected message1 on %s, got %s on %s", ch1, msg.Payload, msg.Channel)
+	}
+
+	// Publish to channel 2
+	publisher.Publish(ctx, ch2, "message2")
+	msg, _ = pubsub.ReceiveMessage(ctx)
+	if msg.Channel != ch2 || msg.Payload != "message2" {
+		t.Errorf("Expected message2 on %s, got %s on %s", ch2, msg.Payload, msg.Channel)
+	}
+}
+
+// TestPubSubUnsubscribe tests unsubscribing from channels
+func TestPubSubUnsubscribe(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:unsub"
+
+	// Subscribe
+	pubsub := subscriber.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	pubsub.Receive(ctx)
+
+	// Initial publish should reach subscriber
+	receiversCount, _ := publisher.Publish(ctx, channel, "before unsub").Result()
+	if receiversCount != 1 {
+		t.Errorf("Expected 1 receiver before unsub, got %d", receiversCount)
+	}
+
+	// Consume the message
+	pubsub.ReceiveMessage(ctx)
+
+	// Unsubscribe
+	err := pubsub.Unsubscribe(ctx, channel)
+	if err != nil {
+		t.Fatalf("UNSUBSCRIBE failed: %v", err)
+	}
+
+	// Wait a bit for unsubscribe to process
+	time.Sleep(100 * time.Millisecond)
+
+	// Publish after unsubscribe should reach 0 subscribers
+	receiversCount, _ = publisher.Publish(ctx, channel, "after unsub").Result()
+	if receiversCount != 0 {
+		t.Errorf("Expected 0 receivers after unsub, got %d", receiversCount)
+	}
+}
+
+// TestPubSubPublishNoSubscribers tests publishing with no subscribers
+func TestPubSubPublishNoSubscribers(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+
+	receiversCount, err := client.Publish(ctx, "no:subscribers:channel", "message").Result()
+	if err != nil {
+		t.Fatalf("PUBLISH failed: %v", err)
+	}
+	if receiversCount != 0 {
+		t.Errorf("Expected 0 receivers, got %d", receiversCount)
+	}
+}
+
+// TestPubSubChannel tests using the channel interface
+func TestPubSubChannel(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:channel"
+	numMessages := 5
+
+	pubsub := subscriber.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	// Get channel for messages
+	ch := pubsub.Channel()
+
+	// Wait for subscription
+	time.Sleep(100 * time.Millisecond)
+
+	// Publish messages
+	for i := 0; i < numMessages; i++ {
+		publisher.Publish(ctx, channel, i)
+	}
+
+	// Receive messages
+	received := 0
+	timeout := time.After(2 * time.Second)
+	for received < numMessages {
+		select {
+		case msg := <-ch:
+			if msg.Channel != channel {
+				t.Errorf("Expected channel %s, got %s", channel, msg.Channel)
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("Timeout: only received %d of %d messages", received, numMessages)
+		}
+	}
+}
+
+// TestPubSubPingInSubscribedMode tests PING while subscribed
+func TestPubSubPingInSubscribedMode(t *testing.T) {
+	subscriber := newTestClient()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	pubsub := subscriber.Subscribe(ctx, "test:pubsub:ping")
+	defer pubsub.Close()
+
+	// Wait for subscription
+	pubsub.Receive(ctx)
+
+	// Ping should still work in subscribed mode
+	err := pubsub.Ping(ctx)
+	if err != nil {
+		t.Fatalf("PING in subscribed mode failed: %v", err)
+	}
+}
+
+// TestPubSubConcurrentPublish tests concurrent publishing
+func TestPubSubConcurrentPublish(t *testing.T) {
+	subscriber := newTestClient()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:concurrent"
+	numPublishers := 5
+	messagesPerPublisher := 10
+
+	pubsub := subscriber.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	pubsub.Receive(ctx)
+
+	// Get channel
+	ch := pubsub.Channel()
+
+	// Start concurrent publishers
+	var wg sync.WaitGroup
+	for i := 0; i < numPublishers; i++ {
+		wg.Add(1)
+		go func(publisherID int) {
+			defer wg.Done()
+			pub := newTestClient()
+			defer pub.Close()
+			for j := 0; j < messagesPerPublisher; j++ {
+				pub.Publish(ctx, channel, publisherID*100+j)
+			}
+		}(i)
+	}
+
+	// Receive all messages
+	totalExpected := numPublishers * messagesPerPublisher
+	received := 0
+	timeout := time.After(5 * time.Second)
+
+	for received < totalExpected {
+		select {
+		case <-ch:
+			received++
+		case <-timeout:
+			t.Fatalf("Timeout: received %d of %d messages", received, totalExpected)
+		}
+	}
+
+	wg.Wait()
+}
+
+// TestPubSubIntrospection tests PUBSUB CHANNELS, NUMSUB and NUMPAT
+func TestPubSubIntrospection(t *testing.T) {
+	client := newTestClient()
+	first := newTestClient()
+	second := newTestClient()
+	defer client.Close()
+	defer first.Close()
+	defer second.Close()
+	ctx := context.Background()
+
+	numPat := client.PubSubNumPat(ctx).Val()
+	a, b := "test:introspect:a", "test:introspect:b"
+	sub1 := first.Subscribe(ctx, a, b)
+	defer sub1.Close()
+	sub1.Receive(ctx)
+	sub1.Receive(ctx)
+	sub2 := second.Subscribe(ctx, a)
+	defer sub2.Close()
+	sub2.Receive(ctx)
+	psub := second.PSubscribe(ctx, "test:introspect:*", "test:introspect:?")
+	defer psub.Close()
+	psub.Receive(ctx)
+	psub.Receive(ctx)
+
+	channels, err := client.PubSubChannels(ctx, "test:introspect:*").Result()
+	sort.Strings(channels)
+	if err != nil || len(channels) != 2 || channels[0] != a || channels[1] != b {
+		t.Errorf("Expected PUBSUB CHANNELS to list %s and %s, got %v (%v)", a, b, channels, err)
+	}
+	counts, err := client.PubSubNumSub(ctx, a, b, "test:introspect:none").Result()
+	if err != nil || counts[a] != 2 || counts[b] != 1 || counts["test:introspect:none"] != 0 {
+		t.Errorf("Unexpected PUBSUB NUMSUB reply %v (%v)", counts, err)
+	}
+	if reply, err := client.Do(ctx, "PUBSUB", "NUMSUB", a).Result(); err != nil || fmt.Sprint(reply) != "["+a+" 2]" {
+		t.Errorf("Expected PUBSUB NUMSUB to reply with a flat array in RESP3, got %#v (%v)", reply, err)
+	}
+	if n := client.PubSubNumPat(ctx).Val(); n != numPat+2 {
+		t.Errorf("Expected PUBSUB NUMPAT to be %d, got %d", numPat+2, n)
+	}
+
+	// A channel whose last subscriber leaves is no longer listed
+	sub1.Unsubscribe(ctx, b)
+	sub1.Receive(ctx)
+	if channels, _ := client.PubSubChannels(ctx, "test:introspect:*").Result(); len(channels) != 1 || channels[0] != a {
+		t.Errorf("Expected only %s left, got %v", a, channels)
+	}
+	psub.PUnsubscribe(ctx)
+	psub.Receive(ctx)
+	psub.Receive(ctx)
+	if n := client.PubSubNumPat(ctx).Val(); n != numPat {
+		t.Errorf("Expected PUBSUB NUMPAT back to %d, got %d", numPat, n)
+	}
+}
+
+// TestPubSubPattern tests PSUBSCRIBE, pmessage delivery and PUNSUBSCRIBE
+func TestPubSubPattern(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	pubsub := subscriber.PSubscribe(ctx, "test:psub:news.*", "test:psub:[ab]?")
+	defer pubsub.Close()
+	for i := 1; i <= 2; i++ {
+		msg, err := pubsub.Receive(ctx)
+		sub, ok := msg.(*redis.Subscription)
+		if err != nil || !ok || sub.Kind != "psubscribe" || sub.Count != i {
+			t.Fatalf("Expected psubscribe confirmation %d, got %v (%v)", i, msg, err)
+		}
+	}
+
+	tests := []struct {
+		channel string
+		want    int64
+	}{
+		{"test:psub:news.tech", 1},
+		{"test:psub:news", 0},
+		{"test:psub:a1", 1},
+		{"test:psub:c1", 0},
+		{"test:psub:b12", 0},
+	}
+	for _, tt := range tests {
+		if n, err := publisher.Publish(ctx, tt.channel, "hi").Result(); err != nil || n != tt.want {
+			t.Errorf("PUBLISH %s: expected %d receivers, got %d (%v)", tt.channel, tt.want, n, err)
+		}
+	}
+
+	for _, want := range []struct{ pattern, channel string }{
+		{"test:psub:news.*", "test:psub:news.tech"},
+		{"test:psub:[ab]?", "test:psub:a1"},
+	} {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil || msg.Pattern != want.pattern || msg.Channel != want.channel || msg.Payload != "hi" {
+			t.Errorf("Expected pmessage on %s from %s, got %+v (%v)", want.channel, want.pattern, msg, err)
+		}
+	}
+
+	if err := pubsub.PUnsubscribe(ctx, "test:psub:news.*"); err != nil {
+		t.Fatalf("PUNSUBSCRIBE failed: %v", err)
+	}
+	msg, err := pubsub.Receive(ctx)
+	if sub, ok := msg.(*redis.Subscription); err != nil || !ok || sub.Kind != "punsubscribe" || sub.Count != 1 {
+		t.Fatalf("Expected punsubscribe confirmation with 1 left, got %v (%v)", msg, err)
+	}
+	if n, _ := publisher.Publish(ctx, "test:psub:news.tech", "hi").Result(); n != 0 {
+		t.Errorf("Expected no receivers after PUNSUBSCRIBE, got %d", n)
+	}
+}
+
+// TestPubSubPatternAndChannel tests that subscription counts combine channels and patterns
+// and that a client matching both gets the message twice
+func TestPubSubPatternAndChannel(t *testing.T) {
+	publisher := newTestClient()
+	defer publisher.Close()
+	ctx := context.Background()
+
+	conn, err := net.Dial("tcp", "localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	send := func(cmd string) {
+		conn.Write([]byte(cmd + "\r\n"))
+	}
+	expect := func(want string) {
+		t.Helper()
+		msg, err := parser.Parse(r)
+		if err != nil {
+			t.Fatalf("Failed to read a reply: %v", err)
+		}
+		if got := string(msg.ToBytes()); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+
+	send("SUBSCRIBE test:pboth:x")
+	expect("*3\r\n$9\r\nsubscribe\r\n$12\r\ntest:pboth:x\r\n:1\r\n")
+	send("PSUBSCRIBE test:pboth:*")
+	expect("*3\r\n$10\r\npsubscribe\r\n$12\r\ntest:pboth:*\r\n:2\r\n")
+
+	if n, err := publisher.Publish(ctx, "test:pboth:x", "m").Result(); err != nil || n != 2 {
+		t.Errorf("Expected 2 receivers, got %d (%v)", n, err)
+	}
+	expect("*3\r\n$7\r\nmessage\r\n$12\r\ntest:pboth:x\r\n$1\r\nm\r\n")
+	expect("*4\r\n$8\r\npmessage\r\n$12\r\ntest:pboth:*\r\n$12\r\ntest:pboth:x\r\n$1\r\nm\r\n")
+
+	// Other commands stay refused while a pattern is left
+	send("UNSUBSCRIBE test:pboth:x")
+	expect("*3\r\n$11\r\nunsubscribe\r\n$12\r\ntest:pboth:x\r\n:1\r\n")
+	send("GET k")
+	expect("-ERR Can't execute 'get': only (P|S)SUBSCRIBE / (P|S)UNSUBSCRIBE / PING / QUIT / RESET are allowed in this context\r\n")
+
+	send("PUNSUBSCRIBE")
+	expect("*3\r\n$12\r\npunsubscribe\r\n$12\r\ntest:pboth:*\r\n:0\r\n")
+	send("PUNSUBSCRIBE")
+	expect("*3\r\n$12\r\npunsubscribe\r\n$-1\r\n:0\r\n")
+	send("PING")
+	expect("+PONG\r\n")
+}
+
+// TestPubSubOrdering tests that messages published one after the other arrive in that order
+func TestPubSubOrdering(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channel := "test:pubsub:ordering"
+	pubsub := subscriber.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	pubsub.Receive(ctx)
+
+	const n = 500
+	go func() {
+		for i := 0; i < n; i++ {
+			publisher.Publish(ctx, channel, i)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		msg, err := pubsub.ReceiveMessage(ctx)
+		if err != nil {
+			t.Fatalf("Failed to receive message %d: %v", i, err)
+		}
+		if msg.Payload != strconv.Itoa(i) {
+			t.Fatalf("Expected message %d, got %s", i, msg.Payload)
+		}
+	}
+}
+
+// TestPubSubOutputBufferLimit tests that a subscriber that doesn't read is disconnected once
+// its queued messages go over the pubsub hard limit
+func TestPubSubOutputBufferLimit(t *testing.T) {
+	client := newTestClient()
+	defer client.Close()
+	ctx := context.Background()
+	defaults := client.ConfigGet(ctx, "client-output-buffer-limit").Val()["client-output-buffer-limit"]
+	defer client.ConfigSet(ctx, "client-output-buffer-limit", defaults)
+
+	if err := client.ConfigSet(ctx, "client-output-buffer-limit", "pubsub 1mb 256kb 10").Err(); err != nil {
+		t.Fatalf("CONFIG SET client-output-buffer-limit failed: %v", err)
+	}
+	want := "normal 0 0 0 slave 268435456 67108864 60 pubsub 1048576 262144 10"
+	if got := client.ConfigGet(ctx, "client-output-buffer-limit").Val()["client-output-buffer-limit"]; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if err := client.ConfigSet(ctx, "client-output-buffer-limit", "pubsub 1mb").Err(); err == nil {
+		t.Error("Expected an error for a limit missing its soft limit and seconds")
+	}
+	if err := client.ConfigSet(ctx, "client-output-buffer-limit", "other 1mb 1mb 0").Err(); err == nil {
+		t.Error("Expected an error for an unknown client class")
+	}
+
+	// The subscriber never reads, so messages pile up once the socket buffers are full
+	conn, err := net.Dial("tcp", "localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	channel := "test:pubsub:limit"
+	conn.Write([]byte("SUBSCRIBE " + channel + "\r\n"))
+	time.Sleep(100 * time.Millisecond)
+
+	before, _ := strconv.Atoi(infoField(t, client, "stats", "client_output_buffer_limit_disconnections"))
+	payload := strings.Repeat("x", 256<<10)
+	disconnected := false
+	for i := 0; i < 1000 && !disconnected; i++ {
+		n, err := client.Publish(ctx, channel, payload).Result()
+		if err != nil {
+			t.Fatalf("PUBLISH failed: %v", err)
+		}
+		if n == 0 {
+			disconnected = true
+		}
+	}
+	if !disconnected {
+		t.Fatal("Expected the subscriber to be disconnected")
+	}
+	after, _ := strconv.Atoi(infoField(t, client, "stats", "client_output_buffer_limit_disconnections"))
+	if after != before+1 {
+		t.Errorf("Expected client_output_buffer_limit_disconnections to go from %d to %d, got %d", before, before+1, after)
+	}
+}
+
+// TestShardedPubSub tests SSUBSCRIBE, SPUBLISH, SUNSUBSCRIBE and the PUBSUB shard channel
+// subcommands
+func TestShardedPubSub(t *testing.T) {
+	publisher := newTestClient()
+	subscriber := newTestClient()
+	defer publisher.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	channels := []string{"test:spubsub:a", "test:spubsub:b"}
+	pubsub := subscriber.SSubscribe(ctx, channels...)
+	defer pubsub.Close()
+	for i := 1; i <= 2; i++ {
+		msg, err := pubsub.Receive(ctx)
+		sub, ok := msg.(*redis.Subscription)
+		if err != nil || !ok || sub.Kind != "ssubscribe" || sub.Count != i {
+			t.Fatalf("Expected ssubscribe confirmation %d, got %v (%v)", i, msg, err)
+		}
+	}
+
+	// SPUBLISH only reaches shard channel subscribers and PUBLISH doesn't reach them
+	if n, err := publisher.SPublish(ctx, channels[0], "hello").Result(); err != nil || n != 1 {
+		t.Errorf("Expected SPUBLISH to reach 1 subscriber, got %d (%v)", n, err)
+	}
+	if n, _ := publisher.Publish(ctx, channels[0], "plain").Result(); n != 0 {
+		t.Errorf("Expected PUBLISH to reach no shard channel subscriber, got %d", n)
+	}
+	msg, err := pubsub.ReceiveMessage(ctx)
+	if err != nil || msg.Channel != channels[0] || msg.Payload != "hello" {
+		t.Errorf("Expected smessage hello on %s, got %+v (%v)", channels[0], msg, err)
+	}
+
+	names, err := publisher.PubSubShardChannels(ctx, "test:spubsub:*").Result()
+	sort.Strings(names)
+	if err != nil || len(names) != 2 || names[0] != channels[0] || names[1] != channels[1] {
+		t.Errorf("Expected PUBSUB SHARDCHANNELS to list %v, got %v (%v)", channels, names, err)
+	}
+	counts, err := publisher.PubSubShardNumSub(ctx, channels[0], "test:spubsub:none").Result()
+	if err != nil || counts[channels[0]] != 1 || counts["test:spubsub:none"] != 0 {
+		t.Errorf("Unexpected PUBSUB SHARDNUMSUB reply %v (%v)", counts, err)
+	}
+
+	if err := pubsub.SUnsubscribe(ctx, channels[0]); err != nil {
+		t.Fatalf("SUNSUBSCRIBE failed: %v", err)
+	}
+	msgs, err := pubsub.Receive(ctx)
+	if sub, ok := msgs.(*redis.Subscription); err != nil || !ok || sub.Kind != "sunsubscribe" || sub.Count != 1 {
+		t.Fatalf("Expected sunsubscribe confirmation with 1 left, got %v (%v)", msgs, err)
+	}
+	if n, _ := publisher.SPublish(ctx, channels[0], "gone").Result(); n != 0 {
+		t.Errorf("Expected no receivers after SUNSUBSCRIBE, got %d", n)
+	}
+	if names, _ := publisher.PubSubShardChannels(ctx, "test:spubsub:*").Result(); len(names) != 1 || names[0] != channels[1] {
+		t.Errorf("Expected only %s left, got %v", channels[1], names)
+	}
+	if err := publisher.Do(ctx, "PUBSUB", "NOPE").Err(); err == nil || !strings.Contains(err.Error(), "unknown subcommand") {
+		t.Errorf("Expected an unknown subcommand error, got %v", err)
+	}
+}
+
+// TestKeyspaceNotifications tests the keyspace and keyevent messages published for string,
+// list, stream and generic commands and for keys reclaimed by expiry
+func TestKeyspaceNotifications(t *testing.T) {
+	client := newTestClient()
+	subscriber := newTestClient()
+	defer client.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "KEZ").Err(); err == nil {
+		t.Errorf("Expected an unknown event class to be rejected")
+	}
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "Elg$xtK").Err(); err != nil {
+		t.Fatalf("CONFIG SET notify-keyspace-events failed: %v", err)
+	}
+	defer client.ConfigSet(ctx, "notify-keyspace-events", "")
+	if flags := client.ConfigGet(ctx, "notify-keyspace-events").Val()["notify-keyspace-events"]; flags != "g$lxtKE" {
+		t.Errorf("Expected the flags to be normalized to g$lxtKE, got %q", flags)
+	}
+
+	pubsub := subscriber.PSubscribe(ctx, "__keyspace@0__:test:notify:*", "__keyevent@0__:*")
+	defer pubsub.Close()
+	for i := 1; i <= 2; i++ {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			t.Fatalf("Expected psubscribe confirmation %d, got %v", i, err)
+		}
+	}
+
+	client.Set(ctx, "test:notify:str", "v", 0)
+	client.Get(ctx, "test:notify:missing")
+	client.RPush(ctx, "test:notify:list", "a")
+	client.LPop(ctx, "test:notify:list")
+	client.XAdd(ctx, &redis.XAddArgs{Stream: "test:notify:stream", ID: "1-1", Values: []string{"f", "v"}})
+	client.Del(ctx, "test:notify:str", "test:notify:stream")
+	client.Set(ctx, "test:notify:ttl", "v", 50*time.Millisecond)
+
+	// Every event is published on the keyspace channel of the key, then on the keyevent
+	// channel of the event. A key miss isn't enabled and publishes nothing
+	events := []struct{ key, event string }{
+		{"test:notify:str", "set"},
+		{"test:notify:list", "rpush"},
+		{"test:notify:list", "lpop"},
+		{"test:notify:list", "del"},
+		{"test:notify:stream", "xadd"},
+		{"test:notify:str", "del"},
+		{"test:notify:stream", "del"},
+		{"test:notify:ttl", "set"},
+		{"test:notify:ttl", "expire"},
+		{"test:notify:ttl", "expired"},
+	}
+	for _, e := range events {
+		wctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		keyspace, err := pubsub.ReceiveMessage(wctx)
+		if err != nil || keyspace.Channel != "__keyspace@0__:"+e.key || keyspace.Payload != e.event {
+			cancel()
+			t.Fatalf("Expected %s on the keyspace channel of %s, got %+v (%v)", e.event, e.key, keyspace, err)
+		}
+		keyevent, err := pubsub.ReceiveMessage(wctx)
+		cancel()
+		if err != nil || keyevent.Channel != "__keyevent@0__:"+e.event || keyevent.Payload != e.key {
+			t.Fatalf("Expected %s on the keyevent channel of %s, got %+v (%v)", e.key, e.event, keyevent, err)
+		}
+	}
+}
+
+// TestKeyspaceNotificationsCollections tests the events published by hash, set and sorted
+// set commands, including the del of a key emptied by a command
+func TestKeyspaceNotificationsCollections(t *testing.T) {
+	client := newTestClient()
+	subscriber := newTestClient()
+	defer client.Close()
+	defer subscriber.Close()
+	ctx := context.Background()
+
+	client.Del(ctx, "test:notifyc:hash", "test:notifyc:set", "test:notifyc:dst", "test:notifyc:zset", "test:notifyc:zdst")
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "KEghsz").Err(); err != nil {
+		t.Fatalf("CONFIG SET notify-keyspace-events failed: %v", err)
+	}
+	defer client.ConfigSet(ctx, "notify-keyspace-events", "")
+
+	pubsub := subscriber.PSubscribe(ctx, "__keyspace@0__:test:notifyc:*", "__keyevent@0__:*")
+	defer pubsub.Close()
+	for i := 1; i <= 2; i++ {
+		if _, err := pubsub.Receive(ctx); err != nil {
+			t.Fatalf("Expected psubscribe confirmation %d, got %v", i, err)
+		}
+	}
+
+	client.HSet(ctx, "test:notifyc:hash", "f", "1")
+	client.HIncrBy(ctx, "test:notifyc:hash", "f", 2)
+	client.HIncrByFloat(ctx, "test:notifyc:hash", "f", 0.5)
+	client.HDel(ctx, "test:notifyc:hash", "f", "missing")
+	client.SAdd(ctx, "test:notifyc:set", "a", "b")
+	client.SMove(ctx, "test:notifyc:set", "test:notifyc:dst", "a")
+	client.SRem(ctx, "test:notifyc:set", "b")
+	client.SInterStore(ctx, "test:notifyc:dst", "test:notifyc:set")
+	client.ZAdd(ctx, "test:notifyc:zset", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 2, Member: "b"})
+	client.ZIncrBy(ctx, "test:notifyc:zset", 1, "a")
+	client.ZUnionStore(ctx, "test:notifyc:zdst", &redis.ZStore{Keys: []string{"test:notifyc:zset"}})
+	client.ZRem(ctx, "test:notifyc:zset", "a")
+	client.ZPopMax(ctx, "test:notifyc:zset")
+	client.Del(ctx, "test:notifyc:zdst")
+
+	events := []struct{ key, event string }{
+		{"test:notifyc:hash", "hset"},
+		{"test:notifyc:hash", "hincrby"},
+		{"test:notifyc:hash", "hincrbyfloat"},
+		{"test:notifyc:hash", "hdel"},
+		{"test:notifyc:hash", "del"},
+		{"test:notifyc:set", "sadd"},
+		{"test:notifyc:set", "srem"},
+		{"test:notifyc:dst", "sadd"},
+		{"test:notifyc:set", "srem"},
+		{"test:notifyc:set", "del"},
+		{"test:notifyc:dst", "del"},
+		{"test:notifyc:zset", "zadd"},
+		{"test:notifyc:zset", "zincr"},
+		{"test:notifyc:zdst", "zunionstore"},
+		{"test:notifyc:zset", "zrem"},
+		{"test:notifyc:zset", "zpopmax"},
+		{"test:notifyc:zset", "del"},
+		{"test:notifyc:zdst", "del"},
+	}
+	for _, e := range events {
+		wctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		keyspace, err := pubsub.ReceiveMessage(wctx)
+		if err != nil || keyspace.Channel != "__keyspace@0__:"+e.key || keyspace.Payload != e.event {
+			cancel()
+			t.Fatalf("Expected %s on the keyspace channel of %s, got %+v (%v)", e.event, e.key, keyspace, err)
+		}
+		keyevent, err := pubsub.ReceiveMessage(wctx)
+		cancel()
+		if err != nil || keyevent.Channel != "__keyevent@0__:"+e.event || keyevent.Payload != e.key {
+			t.Fatalf("Expected %s on the keyevent channel of %s, got %+v (%v)", e.key, e.event, keyevent, err)
+		}
+	}
+}
+
+// =============================================================================
+// RESP3 Tests
+// =============================================================================
+
+// newProtocolClient creates a client speaking the given RESP version
+func newProtocolClient(protocol int) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     "localhost:6379",
+		Protocol: protocol,
+	})
+}
+
+// TestHello tests switching protocols with HELLO and its error cases
+func TestHello(t *testing.T) {
+	client := newProtocolClient(2)
+	defer client.Close()
+	ctx := context.Background()
+
+	conn := client.Conn()
+	defer conn.Close()
+
+	res, err := conn.Do(ctx, "HELLO").Slice()
+	if err != nil || len(res) != 14 || res[4] != "proto" || res[5] != int64(2) {
+		t.Errorf("Expected a flat RESP2 reply with proto 2, got %v (%v)", res, err)
+	}
+	if id, _ := conn.ClientID(ctx).Result(); len(res) == 14 && (res[6] != "id" || res[7] != id) {
+		t.Errorf("Expected HELLO to report the client id %d, got %v", id, res[6:8])
+	}
+
+	reply, err := conn.Do(ctx, "HELLO", "3", "SETNAME", "resp3-client").Result()
+	info, ok := reply.(map[interface{}]interface{})
+	if err != nil || !ok || info["proto"] != int64(3) || info["server"] != "redis" {
+		t.Fatalf("Expected a map with proto 3, got %v (%v)", reply, err)
+	}
+	if name, _ := conn.Do(ctx, "CLIENT", "GETNAME").Result(); name != "resp3-client" {
+		t.Errorf("Expected HELLO SETNAME to set the name, got %v", name)
+	}
+
+	for _, args := range [][]interface{}{
+		{"HELLO", "4"},
+		{"HELLO", "three"},
+		{"HELLO", "2", "SETNAME"},
+		{"HELLO", "2", "AUTH", "user"},
+		{"HELLO", "2", "BOGUS"},
+	} {
+		if err := conn.Do(ctx, args...).Err(); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+	// A failed HELLO leaves the protocol as it was
+	if score, _ := conn.Do(ctx, "HELLO").Result(); fmt.Sprintf("%T", score) != "map[interface {}]interface {}" {
+		t.Errorf("Expected the connection to still speak RESP3, got %T", score)
+	}
+}
+
+// TestRESP3Replies tests that replies use the native RESP3 types, and stay RESP2 compatible
+// for RESP2 clients
+func TestRESP3Replies(t *testing.T) {
+	client3 := newProtocolClient(3)
+	client2 := newProtocolClient(2)
+	defer client3.Close()
+	defer client2.Close()
+	ctx := context.Background()
+
+	hash, set, zset := "test:resp3:hash", "test:resp3:set", "test:resp3:zset"
+	client3.Del(ctx, hash, set, zset)
+	defer client3.Del(ctx, hash, set, zset)
+	client3.HSet(ctx, hash, "f", "v")
+	client3.SAdd(ctx, set, "m")
+	client3.ZAdd(ctx, zset, redis.Z{Score: 1.5, Member: "a"}, redis.Z{Score: 2, Member: "b"})
+
+	tests := []struct {
+		args  []interface{}
+		resp3 string
+		resp2 string
+	}{
+		{[]interface{}{"HGETALL", hash}, "map[f:v]", "[f v]"},
+		{[]interface{}{"CONFIG", "GET", "appendonly"}, "map[appendonly:no]", "[appendonly no]"},
+		{[]interface{}{"SMEMBERS", set}, "[m]", "[m]"},
+		{[]interface{}{"ZSCORE", zset, "a"}, "float64 1.5", "string 1.5"},
+		{[]interface{}{"ZINCRBY", zset, "1", "b"}, "float64 3", "string 3"},
+		{[]interface{}{"ZRANGE", zset, "0", "-1", "WITHSCORES"}, "[[a 1.5] [b 2]]", "[a 1.5 b 2]"},
+		{[]interface{}{"ZPOPMIN", zset}, "[a 1.5]", "[a 1.5]"},
+		{[]interface{}{"ZPOPMIN", zset, "2"}, "[[a 1.5] [b 2]]", "[a 1.5 b 2]"},
+	}
+
+	for _, tt := range tests {
+		// Some of the commands write, each one runs once per client on the same data
+		client3.ZAdd(ctx, zset, redis.Z{Score: 1.5, Member: "a"}, redis.Z{Score: 2, Member: "b"})
+		got3, err := client3.Do(ctx, tt.args...).Result()
+		if err != nil {
+			t.Errorf("%v failed over RESP3: %v", tt.args, err)
+		}
+		client3.ZAdd(ctx, zset, redis.Z{Score: 1.5, Member: "a"}, redis.Z{Score: 2, Member: "b"})
+		got2, err := client2.Do(ctx, tt.args...).Result()
+		if err != nil {
+			t.Errorf("%v failed over RESP2: %v", tt.args, err)
+		}
+
+		format := func(v interface{}) string {
+			if strings.HasPrefix(tt.resp3, "float64") || strings.HasPrefix(tt.resp3, "string") {
+				return fmt.Sprintf("%T %v", v, v)
+			}
+			return fmt.Sprint(v)
+		}
+		if format(got3) != tt.resp3 {
+			t.Errorf("%v over RESP3 = %s, want %s", tt.args, format(got3), tt.resp3)
+		}
+		if format(got2) != tt.resp2 {
+			t.Errorf("%v over RESP2 = %s, want %s", tt.args, format(got2), tt.resp2)
+		}
+	}
+
+	// Missing values are the null type, which the client reports as nil either way
+	if err := client3.Do(ctx, "GET", "test:resp3:missing").Err(); err != redis.Nil {
+		t.Errorf("Expected a null reply, got %v", err)
+	}
+	if err := client3.Do(ctx, "BLPOP", "test:resp3:missing", "0.01").Err(); err != redis.Nil {
+		t.Errorf("Expected a null reply on timeout, got %v", err)
+	}
+}
+
+// TestRESP3PubSub tests that pub/sub messages are push frames for RESP3 clients, which can
+// keep running commands while subscribed
+func TestRESP3PubSub(t *testing.T) {
+	publisher := newTestClient()
+	defer publisher.Close()
+	ctx := context.Background()
+
+	conn, err := net.Dial("tcp", "localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	read := func() resp.Message {
+		msg, err := parser.Parse(r)
+		if err != nil {
+			t.Fatalf("Failed to read a reply: %v", err)
+		}
+		return msg
+	}
+
+	channel := "test:resp3:channel"
+	conn.Write([]byte("HELLO 3\r\nSUBSCRIBE " + channel + "\r\n"))
+	if _, ok := read().(*resp.Map); !ok {
+		t.Fatal("Expected HELLO 3 to reply with a map")
+	}
+	if msg, ok := read().(*resp.Push); !ok || len(msg.Val) != 3 {
+		t.Fatalf("Expected the subscription to be confirmed with a push, got %v", msg)
+	}
+
+	// Any command runs while subscribed
+	conn.Write([]byte("PING\r\n"))
+	if msg, ok := read().(*resp.SimpleString); !ok || string(msg.Val) != "PONG" {
+		t.Errorf("Expected PONG, got %v", msg)
+	}
+
+	publisher.Publish(ctx, channel, "hello")
+	msg, ok := read().(*resp.Push)
+	if !ok || len(msg.Val) != 3 || string(msg.Val[2].(*resp.BulkString).Str) != "hello" {
+		t.Errorf("Expected the message as a push, got %v", msg)
+	}
+}
+
+// =============================================================================
+// Client Tracking Tests
+// =============================================================================
+
+// dialRaw opens a connection sending inline commands, read returns the next reply or push
+func dialRaw(t *testing.T) (net.Conn, func() resp.Message) {
+	conn, err := net.Dial("tcp", "localhost:6379")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	return conn, func() resp.Message {
+		msg, err := parser.Parse(r)
+		if err != nil {
+			t.Fatalf("Failed to read a reply: %v", err)
+		}
+		return msg
+	}
+}
+
+// invalidatedKeys returns the keys of an invalidate push, nil if msg is something else
+func invalidatedKeys(msg resp.Message) []string {
+	push, ok := msg.(*resp.Push)
+	if !ok || len(push.Val) != 2 {
+		return nil
+	}
+	if kind, ok := push.Val[0].(*resp.BulkString); !ok || string(kind.Str) != "invalidate" {
+		return nil
+	}
+	arr, ok := push.Val[1].(*resp.Array)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, key := range arr.Val {
+		keys = append(keys, string(key.(*resp.BulkString).Str))
+	}
+	return keys
+}
+
+// TestClientTracking tests that a RESP3 client is sent one invalidation per key it read,
+// that a second change without a new read sends nothing and that NOLOOP leaves out the
+// client's own changes
+func TestClientTracking(t *testing.T) {
+	writer := newTestClient()
+	defer writer.Close()
+	ctx := context.Background()
+
+	conn, read := dialRaw(t)
+	defer conn.Close()
+
+	conn.Write([]byte("HELLO 3\r\nCLIENT GETREDIR\r\nCLIENT TRACKING ON\r\nCLIENT GETREDIR\r\n"))
+	read()
+	if msg, ok := read().(*resp.Integer); !ok || msg.Val != -1 {
+		t.Errorf("Expected CLIENT GETREDIR to reply -1 with tracking off, got %v", msg)
+	}
+	if msg, ok := read().(*resp.SimpleString); !ok || string(msg.Val) != "OK" {
+		t.Fatalf("Expected CLIENT TRACKING ON to reply OK, got %v", msg)
+	}
+	if msg, ok := read().(*resp.Integer); !ok || msg.Val != 0 {
+		t.Errorf("Expected CLIENT GETREDIR to reply 0 without redirection, got %v", msg)
+	}
+
+	writer.Set(ctx, "test:tracking:a", "1", 0)
+	writer.Set(ctx, "test:tracking:b", "1", 0)
+	conn.Write([]byte("GET test:tracking:a\r\nLLEN test:tracking:list\r\n"))
+	read()
+	read()
+
+	// Only the keys read are tracked, and only until their next change
+	writer.Set(ctx, "test:tracking:b", "2", 0)
+	writer.Set(ctx, "test:tracking:a", "2", 0)
+	writer.Set(ctx, "test:tracking:a", "3", 0)
+	writer.RPush(ctx, "test:tracking:list", "x")
+	defer writer.Del(ctx, "test:tracking:list")
+	for _, want := range []string{"test:tracking:a", "test:tracking:list"} {
+		if keys := invalidatedKeys(read()); len(keys) != 1 || keys[0] != want {
+			t.Fatalf("Expected an invalidation of %s, got %v", want, keys)
+		}
+	}
+	conn.Write([]byte("PING\r\n"))
+	if msg, ok := read().(*resp.SimpleString); !ok || string(msg.Val) != "PONG" {
+		t.Fatalf("Expected no other invalidation before PONG, got %v", msg)
+	}
+
+	// With NOLOOP the client's own writes aren't sent back to it
+	conn.Write([]byte("CLIENT TRACKING ON NOLOOP\r\nGET test:tracking:a\r\nSET test:tracking:a 4\r\nPING\r\n"))
+	for _, want := range []string{"OK", "3", "OK", "PONG"} {
+		var got string
+		switch msg := read().(type) {
+		case *resp.SimpleString:
+			got = string(msg.Val)
+		case *resp.BulkString:
+			got = string(msg.Str)
+		}
+		if got != want {
+			t.Fatalf("Expected %s with NOLOOP, got %q", want, got)
+		}
+	}
+
+	// Without it the invalidation of the client's own write follows the reply to the write
+	conn.Write([]byte("CLIENT TRACKING ON\r\nGET test:tracking:a\r\nSET test:tracking:a 5\r\n"))
+	read()
+	read()
+	if msg, ok := read().(*resp.SimpleString); !ok || string(msg.Val) != "OK" {
+		t.Fatalf("Expected the reply to SET before the invalidation, got %v", msg)
+	}
+	if keys := invalidatedKeys(read()); len(keys) != 1 || keys[0] != "test:tracking:a" {
+		t.Fatalf("Expected an invalidation of the key the client wrote, got %v", keys)
+	}
+
+	// Keys changed by expiry are invalidated too
+	conn.Write([]byte("CLIENT TRACKING OFF\r\nCLIENT TRACKING ON\r\nGET test:tracking:a\r\n"))
+	read()
+	read()
+	read()
+	writer.PExpire(ctx, "test:tracking:a", 50*time.Millisecond)
+	if keys := invalidatedKeys(read()); len(keys) != 1 || keys[0] != "test:tracking:a" {
+		t.Fatalf("Expected an invalidation when the ttl is set, got %v", keys)
+	}
+}
+
+// TestClientTrackingModes tests the BCAST, OPTIN and REDIRECT modes of CLIENT TRACKING and
+// their errors
+func TestClientTrackingModes(t *testing.T) {
+	writer := newTestClient()
+	defer writer.Close()
+	ctx := context.Background()
+
+	conn, read := dialRaw(t)
+	defer conn.Close()
+	conn.Write([]byte("HELLO 3\r\n"))
+	read()
+
+	errorCases := []string{
+		"CLIENT TRACKING ON PREFIX test:",
+		"CLIENT TRACKING ON OPTIN OPTOUT",
+		"CLIENT TRACKING ON BCAST OPTIN",
+		"CLIENT TRACKING ON REDIRECT 999999",
+		"CLIENT CACHING YES",
+	}
+	for _, cmd := range errorCases {
+		conn.Write([]byte(cmd + "\r\n"))
+		if msg, ok := read().(*resp.SimpleError); !ok {
+			t.Errorf("Expected %q to fail, got %v", cmd, msg)
+		}
+	}
+
+	// BCAST sends every change to a key matching a prefix, read or not
+	conn.Write([]byte("CLIENT TRACKING ON BCAST PREFIX test:bcast:\r\n"))
+	read()
+	writer.Set(ctx, "test:other", "1", 0)
+	writer.Set(ctx, "test:bcast:x", "1", 0)
+	if keys := invalidatedKeys(read()); len(keys) != 1 || keys[0] != "test:bcast:x" {
+		t.Fatalf("Expected an invalidation of test:bcast:x, got %v", keys)
+	}
+
+	// OPTIN only tracks the reads right after CLIENT CACHING YES
+	conn.Write([]byte("CLIENT TRACKING OFF\r\nCLIENT TRACKING ON OPTIN\r\nGET test:optin:a\r\nCLIENT CACHING YES\r\nGET test:optin:b\r\nGET test:optin:c\r\n"))
+	for range 6 {
+		read()
+	}
+	writer.Set(ctx, "test:optin:a", "1", 0)
+	writer.Set(ctx, "test:optin:c", "1", 0)
+	writer.Set(ctx, "test:optin:b", "1", 0)
+	if keys := invalidatedKeys(read()); len(keys) != 1 || keys[0] != "test:optin:b" {
+		t.Fatalf("Expected an invalidation of test:optin:b only, got %v", keys)
+	}
+
+	// A RESP2 client subscribed to __redis__:invalidate gets the invalidations redirected to it
+	sub, readSub := dialRaw(t)
+	defer sub.Close()
+	sub.Write([]byte("CLIENT ID\r\nSUBSCRIBE __redis__:invalidate\r\n"))
+	id := readSub().(*resp.Integer).Val
+	readSub()
+
+	redirect := fmt.Sprintf("CLIENT TRACKING OFF\r\nCLIENT TRACKING ON REDIRECT %d\r\nCLIENT GETREDIR\r\nGET test:redirect\r\n", id)
+	conn.Write([]byte(redirect))
+	read()
+	read()
+	if msg, ok := read().(*resp.Integer); !ok || msg.Val != id {
+		t.Errorf("Expected CLIENT GETREDIR to reply %d, got %v", id, msg)
+	}
+	read()
+	writer.Set(ctx, "test:redirect", "1", 0)
+	msg, ok := readSub().(*resp.Array)
+	if !ok || len(msg.Val) != 3 || string(msg.Val[1].(*resp.BulkString).Str) != "__redis__:invalidate" {
+		t.Fatalf("Expected a message on __redis__:invalidate, got %v", msg)
+	}
+	if keys, ok := msg.Val[2].(*resp.Array); !ok || len(keys.Val) != 1 || string(keys.Val[0].(*resp.BulkString).Str) != "test:redirect" {
+		t.Errorf("Expected the message to hold test:redirect, got %v", msg.Val[2])
+	}
 }
 
 // =============================================================================